@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -17,7 +19,6 @@ import (
 	// "github.com/golang-migrate/migrate/v4"                     // Added golang-migrate
 	// _ "github.com/golang-migrate/migrate/v4/database/postgres" // PostgreSQL driver for migrate
 	// _ "github.com/golang-migrate/migrate/v4/source/file"       // File source for migrate
-	"github.com/golang-jwt/jwt/v5"                               // Added golang-jwt
 	"github.com/mkbagandov/kingsman/backend/app/internal/domain" // Added domain
 
 	"github.com/joho/godotenv" // Added godotenv
@@ -27,42 +28,238 @@ import (
 	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
 )
 
-var jwtKey = []byte("YOUR_SECRET_KEY") // TODO: Load from environment variable
+// jwtAuthMiddleware authenticates requests by their access token and rejects tokens of revoked sessions.
+func jwtAuthMiddleware(sessionUseCase *usecase.SessionUseCase) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := r.Header.Get("Authorization")
+			if !strings.HasPrefix(tokenString, "Bearer ") {
+				http.Error(w, "Missing auth token", http.StatusUnauthorized)
+				return
+			}
 
-func jwtAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			http.Error(w, "Missing auth token", http.StatusUnauthorized)
-			return
-		}
+			claims, err := sessionUseCase.Authenticate(r.Context(), tokenString[len("Bearer "):])
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
 
-		tokenString = tokenString[len("Bearer "):]
-		claims := &domain.Claims{}
+			// Add user ID, role and session ID to context for subsequent handlers
+			ctx := context.WithValue(r.Context(), domain.UserContextKey, claims.UserID)
+			ctx = context.WithValue(ctx, domain.RoleContextKey, claims.Role)
+			ctx = context.WithValue(ctx, domain.SessionContextKey, claims.SessionID)
+			r = r.WithContext(ctx)
 
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
+			next.ServeHTTP(w, r)
 		})
+	}
+}
 
-		if err != nil {
-			if err == jwt.ErrSignatureInvalid {
-				http.Error(w, "Invalid token signature", http.StatusUnauthorized)
+// apiKeyAuthMiddleware authenticates service accounts, such as in-store terminals, by their X-API-Key header.
+func apiKeyAuthMiddleware(serviceAccountUseCase *usecase.ServiceAccountUseCase) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rawKey := r.Header.Get("X-API-Key")
+			if rawKey == "" {
+				http.Error(w, "Missing API key", http.StatusUnauthorized)
 				return
 			}
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
+
+			apiKey, err := serviceAccountUseCase.Authenticate(r.Context(), rawKey, delivery.ClientIP(r))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			// Add service account ID and key scopes to context for subsequent handlers
+			ctx := context.WithValue(r.Context(), domain.ServiceAccountContextKey, strconv.Itoa(apiKey.ServiceAccountID))
+			ctx = context.WithValue(ctx, domain.ScopesContextKey, apiKey.Scopes)
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// userOrAPIKeyAuthMiddleware authenticates requests with an X-API-Key header as a service account
+// and all others by their access token, for routes shared by customers and terminals.
+func userOrAPIKeyAuthMiddleware(sessionUseCase *usecase.SessionUseCase, serviceAccountUseCase *usecase.ServiceAccountUseCase) func(http.Handler) http.Handler {
+	jwtAuth := jwtAuthMiddleware(sessionUseCase)
+	apiKeyAuth := apiKeyAuthMiddleware(serviceAccountUseCase)
+	return func(next http.Handler) http.Handler {
+		viaJWT, viaAPIKey := jwtAuth(next), apiKeyAuth(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-API-Key") != "" {
+				viaAPIKey.ServeHTTP(w, r)
+				return
+			}
+			viaJWT.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireScope rejects service account requests whose API key lacks the given scope.
+// Requests authenticated as a user are left to role checks.
+func requireScope(scope domain.APIScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := r.Context().Value(domain.ScopesContextKey).([]domain.APIScope); ok && !domain.HasScope(scopes, scope) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requirePermission rejects requests whose role, as set by jwtAuthMiddleware, lacks the given permission.
+func requirePermission(permission domain.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := r.Context().Value(domain.RoleContextKey).(domain.Role)
+			if !ok {
+				http.Error(w, "Role not found in context", http.StatusUnauthorized)
+				return
+			}
+			if !role.HasPermission(permission) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireUserPermission rejects requests made as a user whose role lacks the given permission.
+// Service account requests are left to scope checks.
+func requireUserPermission(permission domain.Permission) func(http.Handler) http.Handler {
+	checkRole := requirePermission(permission)
+	return func(next http.Handler) http.Handler {
+		viaRole := checkRole(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Value(domain.ScopesContextKey).([]domain.APIScope); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			viaRole.ServeHTTP(w, r)
+		})
+	}
+}
+
+// loadOIDCProviders builds clients for the providers listed in OIDC_PROVIDERS,
+// each configured by OIDC_<NAME>_* variables.
+func loadOIDCProviders() map[string]domain.OIDCProvider {
+	providers := make(map[string]domain.OIDCProvider)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
 		}
-		if !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		var scopes []string
+		if value := os.Getenv(prefix + "SCOPES"); value != "" {
+			scopes = strings.Fields(strings.ReplaceAll(value, ",", " "))
 		}
+		providers[name] = infrastructure.NewOIDCClient(&infrastructure.OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			AuthURL:      os.Getenv(prefix + "AUTH_URL"),
+			TokenURL:     os.Getenv(prefix + "TOKEN_URL"),
+			UserInfoURL:  os.Getenv(prefix + "USERINFO_URL"),
+			JWKSURL:      os.Getenv(prefix + "JWKS_URL"),
+			Scopes:       scopes,
+			TrustEmail:   os.Getenv(prefix+"TRUST_EMAIL") == "true",
+		})
+	}
+	return providers
+}
 
-		// Add user ID to context for subsequent handlers
-		ctx := context.WithValue(r.Context(), domain.UserContextKey, claims.UserID)
-		r = r.WithContext(ctx)
+// loadAppleWallet returns the Apple Wallet pass signer, or nil if no pass type is configured.
+func loadAppleWallet() domain.AppleWallet {
+	if os.Getenv("APPLE_WALLET_PASS_TYPE_ID") == "" {
+		return nil
+	}
+	appleWallet, err := infrastructure.NewAppleWalletService(&infrastructure.AppleWalletConfig{
+		PassTypeID:   os.Getenv("APPLE_WALLET_PASS_TYPE_ID"),
+		TeamID:       os.Getenv("APPLE_WALLET_TEAM_ID"),
+		CertFile:     os.Getenv("APPLE_WALLET_CERT_FILE"),
+		KeyFile:      os.Getenv("APPLE_WALLET_KEY_FILE"),
+		WWDRCertFile: os.Getenv("APPLE_WALLET_WWDR_CERT_FILE"),
+		APNsURL:      os.Getenv("APPLE_WALLET_APNS_URL"),
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize Apple Wallet: %v", err)
+	}
+	return appleWallet
+}
 
-		next.ServeHTTP(w, r)
+// loadGoogleWallet returns the Google Wallet client, or nil if no issuer is configured.
+func loadGoogleWallet() domain.GoogleWallet {
+	if os.Getenv("GOOGLE_WALLET_ISSUER_ID") == "" {
+		return nil
+	}
+	var origins []string
+	if baseURL := os.Getenv("APP_BASE_URL"); baseURL != "" {
+		origins = []string{baseURL}
+	}
+	googleWallet, err := infrastructure.NewGoogleWalletService(&infrastructure.GoogleWalletConfig{
+		IssuerID:           os.Getenv("GOOGLE_WALLET_ISSUER_ID"),
+		ClassID:            os.Getenv("GOOGLE_WALLET_CLASS_ID"),
+		ServiceAccountFile: os.Getenv("GOOGLE_WALLET_SERVICE_ACCOUNT_FILE"),
+		Origins:            origins,
 	})
+	if err != nil {
+		log.Fatalf("Failed to initialize Google Wallet: %v", err)
+	}
+	return googleWallet
+}
+
+// envFloat reads a number from the environment, using fallback when the variable is unset.
+func envFloat(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return number
+}
+
+// runPeriodically runs job at once and then every interval until ctx is cancelled, logging its failures.
+// Running at start keeps frequent restarts from postponing a daily job indefinitely.
+func runPeriodically(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := job(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Job %s failed: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// envInt reads an integer from the environment, using fallback when the variable is unset.
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", name, err)
+	}
+	return number
 }
 
 func main() {
@@ -111,6 +308,23 @@ func main() {
 	// }
 	// log.Println("Database migrations applied successfully!")
 
+	// Load access token signing keys
+	verificationKeyFiles, err := infrastructure.ParseVerificationKeyFiles(os.Getenv("JWT_VERIFICATION_KEYS"))
+	if err != nil {
+		log.Fatalf("Failed to parse JWT verification keys: %v", err)
+	}
+	tokenIssuer, err := infrastructure.NewJWTIssuer(&infrastructure.JWTConfig{
+		Issuer:               os.Getenv("JWT_ISSUER"),
+		Algorithm:            os.Getenv("JWT_ALGORITHM"),
+		KeyID:                os.Getenv("JWT_KEY_ID"),
+		Secret:               os.Getenv("JWT_SECRET"),
+		PrivateKeyFile:       os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		VerificationKeyFiles: verificationKeyFiles,
+	})
+	if err != nil {
+		log.Fatalf("Failed to initialize token issuer: %v", err)
+	}
+
 	// Initialize repositories
 	userRepo := infrastructure.NewPostgreSQLUserRepository(db)
 	storeRepo := infrastructure.NewPostgreSQLStoreRepository(db)
@@ -121,25 +335,178 @@ func main() {
 	cartItemRepo := infrastructure.NewCartItemRepository(db)
 	orderRepo := infrastructure.NewOrderRepository(db)         // Initialize OrderRepository
 	orderItemRepo := infrastructure.NewOrderItemRepository(db) // Initialize OrderItemRepository
+	sessionRepo := infrastructure.NewSessionRepository(db)
+	otpRepo := infrastructure.NewOTPRepository(db)
+	identityRepo := infrastructure.NewIdentityRepository(db)
+	oneTimeTokenRepo := infrastructure.NewOneTimeTokenRepository(db)
+	consentRepo := infrastructure.NewConsentRepository(db)
+	serviceAccountRepo := infrastructure.NewServiceAccountRepository(db)
+	loyaltyLedgerRepo := infrastructure.NewLoyaltyLedgerRepository(db)
+	referralRepo := infrastructure.NewReferralRepository(db)
+	challengeRepo := infrastructure.NewChallengeRepository(db)
+	lifecycleBonusRepo := infrastructure.NewLifecycleBonusRepository(db)
+	earningRuleRepo := infrastructure.NewEarningRuleRepository(db)
+	walletPassRepo := infrastructure.NewWalletPassRepository(db)
+	discountCardRepo := infrastructure.NewDiscountCardRepository(db)
+	adjustmentRepo := infrastructure.NewLoyaltyAdjustmentRepository(db)
+	reportRepo := infrastructure.NewLoyaltyReportRepository(db)
+	fraudRepo := infrastructure.NewFraudRepository(db)
+	pointFreezeRepo := infrastructure.NewPointFreezeRepository(db)
+
+	// SMS delivery; only development stand-ins exist so far
+	var smsSender domain.SMSSender
+	switch os.Getenv("SMS_SENDER") {
+	case "file":
+		smsSender = infrastructure.NewFileSMSSender(os.Getenv("SMS_OUTBOX_FILE"))
+	default:
+		smsSender = infrastructure.NewLogSMSSender()
+	}
+
+	// Email delivery; without an SMTP server configured messages are only kept in memory
+	var mailSender domain.MailSender
+	switch os.Getenv("MAIL_SENDER") {
+	case "smtp":
+		smtpPort, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			log.Fatalf("Invalid SMTP_PORT: %v", err)
+		}
+		mailSender = infrastructure.NewSMTPMailSender(&infrastructure.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     smtpPort,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	default:
+		log.Println("WARNING: MAIL_SENDER is not smtp, outgoing email is kept in memory and never delivered")
+		mailSender = infrastructure.NewMemoryMailSender()
+	}
+
+	// Rate limits and login lockouts are kept in process memory, so they are per instance
+	rateLimitStore := infrastructure.NewMemoryRateLimitStore()
+
+	// Points spent at checkout
+	redemptionPolicy := usecase.RedemptionPolicy{
+		PointValue:    envFloat("LOYALTY_POINT_VALUE", 0.1),
+		MaxOrderShare: envFloat("LOYALTY_MAX_REDEEM_SHARE", 0.5),
+	}
+	expiryPolicy := usecase.ExpiryPolicy{
+		PointsLifetimeMonths: envInt("LOYALTY_POINTS_LIFETIME_MONTHS", 12),
+	}
+	earningPolicy := usecase.EarningPolicy{
+		AmountPerPoint: envFloat("LOYALTY_AMOUNT_PER_POINT", 10),
+	}
+	tierPolicy := usecase.TierPolicy{
+		QualificationMonths: envInt("LOYALTY_TIER_QUALIFICATION_MONTHS", 12),
+		GracePeriodDays:     envInt("LOYALTY_TIER_GRACE_DAYS", 30),
+	}
+	// Services added to online orders; tiers with free shipping or free alterations waive them
+	checkoutPolicy := usecase.CheckoutPolicy{
+		ShippingFee:   envFloat("CHECKOUT_SHIPPING_FEE", 10),
+		AlterationFee: envFloat("CHECKOUT_ALTERATION_FEE", 15),
+	}
+	// Card codes rotate every window and are signed with a secret of their own
+	cardCodeSecret := os.Getenv("CARD_CODE_SECRET")
+	if cardCodeSecret == "" {
+		log.Fatal("CARD_CODE_SECRET is not set")
+	}
+	cardCodePeriod := envInt("CARD_CODE_PERIOD_SECONDS", 30)
+	if cardCodePeriod <= 0 {
+		log.Fatal("CARD_CODE_PERIOD_SECONDS must be positive")
+	}
+	cardCodeSigner := usecase.NewCardCodeSigner(cardCodeSecret, time.Duration(cardCodePeriod)*time.Second)
+	// Wallet passes; each wallet is only offered when its credentials are configured
+	walletPolicy := usecase.WalletPolicy{
+		OrganizationName: "Kingsman",
+		WebServiceURL:    os.Getenv("WALLET_WEB_SERVICE_URL"),
+		AssetsDir:        os.Getenv("WALLET_ASSETS_DIR"),
+	}
+	referralPolicy := usecase.ReferralPolicy{
+		ReferrerPoints: envInt("REFERRAL_REFERRER_POINTS", 500),
+		RefereePoints:  envInt("REFERRAL_REFEREE_POINTS", 250),
+		HoldDays:       envInt("REFERRAL_HOLD_DAYS", 30),
+	}
+	lifecyclePolicy := usecase.LifecyclePolicy{
+		BirthdayPoints:    envInt("LOYALTY_BIRTHDAY_POINTS", 300),
+		AnniversaryPoints: envInt("LOYALTY_ANNIVERSARY_POINTS", 200),
+		WinBackDays:       envInt("LOYALTY_WINBACK_DAYS", 90),
+		WinBackPoints:     envInt("LOYALTY_WINBACK_POINTS", 150),
+	}
+	// Points staff may add or take by hand; larger adjustments need a second staff member
+	adjustmentPolicy := usecase.AdjustmentPolicy{
+		RoleLimits: map[domain.Role]int{
+			domain.RoleStoreStaff: envInt("LOYALTY_ADJUST_LIMIT_STORE_STAFF", 1000),
+			domain.RoleManager:    envInt("LOYALTY_ADJUST_LIMIT_MANAGER", 5000),
+			domain.RoleAdmin:      envInt("LOYALTY_ADJUST_LIMIT_ADMIN", 20000),
+		},
+		RoleDailyLimits: map[domain.Role]int{
+			domain.RoleStoreStaff: envInt("LOYALTY_ADJUST_DAILY_LIMIT_STORE_STAFF", 3000),
+			domain.RoleManager:    envInt("LOYALTY_ADJUST_DAILY_LIMIT_MANAGER", 15000),
+			domain.RoleAdmin:      envInt("LOYALTY_ADJUST_DAILY_LIMIT_ADMIN", 60000),
+		},
+		ApprovalThreshold: envInt("LOYALTY_ADJUST_APPROVAL_THRESHOLD", 500),
+	}
+	fraudPolicy := usecase.FraudPolicy{
+		LookbackDays:           envInt("FRAUD_LOOKBACK_DAYS", 30),
+		SignupWindowHours:      envInt("FRAUD_SIGNUP_WINDOW_HOURS", 24),
+		PhonePrefixLength:      envInt("FRAUD_PHONE_PREFIX_LENGTH", 9),
+		AccountsPerPhonePrefix: envInt("FRAUD_ACCOUNTS_PER_PHONE_PREFIX", 5),
+		AccountsPerDevice:      envInt("FRAUD_ACCOUNTS_PER_DEVICE", 3),
+		EarnRedeemHours:        envInt("FRAUD_EARN_REDEEM_HOURS", 24),
+		EarnRedeemCycles:       envInt("FRAUD_EARN_REDEEM_CYCLES", 5),
+		ReturnsAfterEarning:    envInt("FRAUD_RETURNS_AFTER_EARNING", 3),
+		StaffAdjustmentPoints:  envInt("FRAUD_STAFF_ADJUSTMENT_POINTS", 10000),
+	}
 
 	// Initialize use cases
-	userUseCase := usecase.NewUserUseCase(userRepo)
+	sessionUseCase := usecase.NewSessionUseCase(sessionRepo, userRepo, tokenIssuer)
+	verificationUseCase := usecase.NewVerificationUseCase(userRepo, oneTimeTokenRepo, sessionRepo, tokenIssuer, mailSender, os.Getenv("APP_BASE_URL"))
+	notificationUseCase := usecase.NewNotificationUseCase(notificationRepo, consentRepo, userRepo, smsSender, mailSender)
+	loyaltyUseCase := usecase.NewLoyaltyUseCase(userRepo, loyaltyLedgerRepo, discountCardRepo, pointFreezeRepo, notificationUseCase, redemptionPolicy, expiryPolicy, tierPolicy) // Initialize LoyaltyUseCase
+	referralUseCase := usecase.NewReferralUseCase(referralRepo, orderRepo, loyaltyUseCase, notificationUseCase, referralPolicy, os.Getenv("APP_BASE_URL"))
+	userUseCase := usecase.NewUserUseCase(userRepo, otpRepo, sessionUseCase, verificationUseCase, usecase.NewLoginThrottle(rateLimitStore), referralUseCase, cardCodeSigner)
+	otpUseCase := usecase.NewOTPUseCase(otpRepo, userRepo, smsSender, userUseCase, sessionUseCase)
+	socialLoginUseCase := usecase.NewSocialLoginUseCase(identityRepo, userRepo, loadOIDCProviders(), userUseCase, sessionUseCase)
 	storeUseCase := usecase.NewStoreUseCase(storeRepo)
-	notificationUseCase := usecase.NewNotificationUseCase(notificationRepo)
 	categoryUseCase := usecase.NewCategoryUseCase(categoryRepo)
-	productUseCase := usecase.NewProductUseCase(productRepo)
-	loyaltyUseCase := usecase.NewLoyaltyUseCase(userRepo)                                                                                               // Initialize LoyaltyUseCase
-	cartUseCase := usecase.NewCartUseCase(cartRepo, cartItemRepo, productRepo, orderRepo, orderItemRepo, loyaltyUseCase, notificationUseCase, userRepo) // Updated CartUseCase
-	orderUseCase := usecase.NewOrderUseCase(orderRepo, orderItemRepo, productRepo)                                                                      // Initialize OrderUseCase
+	productUseCase := usecase.NewProductUseCase(productRepo, userRepo)
+	earningRuleUseCase := usecase.NewEarningRuleUseCase(earningRuleRepo, productRepo, loyaltyUseCase, earningPolicy)
+	challengeUseCase := usecase.NewChallengeUseCase(challengeRepo, storeRepo, loyaltyUseCase, notificationUseCase)
+	posUseCase := usecase.NewPOSUseCase(userRepo, storeRepo, cardCodeSigner, loyaltyUseCase, earningRuleUseCase, challengeUseCase)
+	lifecycleUseCase := usecase.NewLifecycleUseCase(lifecycleBonusRepo, loyaltyUseCase, notificationUseCase, lifecyclePolicy)
+	adjustmentUseCase := usecase.NewAdjustmentUseCase(adjustmentRepo, userRepo, loyaltyUseCase, adjustmentPolicy)
+	reportUseCase := usecase.NewReportUseCase(reportRepo, redemptionPolicy)
+	fraudUseCase := usecase.NewFraudUseCase(fraudRepo, loyaltyUseCase, fraudPolicy)
+	walletUseCase := usecase.NewWalletUseCase(walletPassRepo, userRepo, loyaltyUseCase, cardCodeSigner, loadAppleWallet(), loadGoogleWallet(), walletPolicy)
+	cartUseCase := usecase.NewCartUseCase(cartRepo, cartItemRepo, productRepo, orderRepo, orderItemRepo, loyaltyUseCase, earningRuleUseCase, referralUseCase, challengeUseCase, notificationUseCase, userRepo, cardCodeSigner, checkoutPolicy) // Updated CartUseCase
+	orderUseCase := usecase.NewOrderUseCase(orderRepo, orderItemRepo, productRepo, loyaltyUseCase)                                                                                                                                             // Initialize OrderUseCase
+	privacyUseCase := usecase.NewPrivacyUseCase(consentRepo, userRepo, orderRepo, orderItemRepo, notificationRepo, identityRepo, sessionRepo)
+	serviceAccountUseCase := usecase.NewServiceAccountUseCase(serviceAccountRepo)
 
 	// Initialize handlers
-	userHandler := delivery.NewUserHandler(userUseCase, loyaltyUseCase) // Pass loyaltyUseCase
+	userHandler := delivery.NewUserHandler(userUseCase, loyaltyUseCase, sessionUseCase) // Pass loyaltyUseCase
 	storeHandler := delivery.NewStoreHandler(storeUseCase)
 	notificationHandler := delivery.NewNotificationHandler(notificationUseCase)
 	categoryHandler := delivery.NewCategoryHandler(categoryUseCase)
 	productHandler := delivery.NewProductHandler(productUseCase)
 	cartHandler := delivery.NewCartHandler(cartUseCase)
 	orderHandler := delivery.NewOrderHandler(orderUseCase) // Initialize OrderHandler
+	adminHandler := delivery.NewAdminHandler(userUseCase)
+	jwksHandler := delivery.NewJWKSHandler(tokenIssuer)
+	otpHandler := delivery.NewOTPHandler(otpUseCase)
+	socialLoginHandler := delivery.NewSocialLoginHandler(socialLoginUseCase)
+	verificationHandler := delivery.NewVerificationHandler(verificationUseCase)
+	privacyHandler := delivery.NewPrivacyHandler(privacyUseCase)
+	serviceAccountHandler := delivery.NewServiceAccountHandler(serviceAccountUseCase)
+	posHandler := delivery.NewPOSHandler(posUseCase, cartUseCase)
+	loyaltyAdminHandler := delivery.NewLoyaltyAdminHandler(loyaltyUseCase)
+	adjustmentHandler := delivery.NewAdjustmentHandler(adjustmentUseCase)
+	reportHandler := delivery.NewReportHandler(reportUseCase)
+	fraudHandler := delivery.NewFraudHandler(fraudUseCase)
+	earningRuleHandler := delivery.NewEarningRuleHandler(earningRuleUseCase)
+	referralHandler := delivery.NewReferralHandler(referralUseCase)
+	challengeHandler := delivery.NewChallengeHandler(challengeUseCase)
+	walletHandler := delivery.NewWalletHandler(walletUseCase)
 
 	// Setup HTTP router
 	r := chi.NewRouter()
@@ -148,7 +515,7 @@ func main() {
 	corsHandler := cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-API-Key", "Idempotency-Key"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
@@ -164,25 +531,45 @@ func main() {
 		w.Write([]byte("Welcome to the Kingsman Backend!"))
 	})
 
-	// Public routes (registration and login)
-	r.Post("/users/register", userHandler.RegisterUser)
-	r.Post("/users/login", userHandler.LoginUser)
+	// Public verification keys for offline token validation, e.g. by POS terminals
+	r.Get("/.well-known/jwks.json", jwksHandler.GetJWKS)
 
-	// Protected routes
+	r.Get("/consent-texts", privacyHandler.GetConsentTexts)
+
+	// Authenticated requests share one budget per user or service account
+	apiRateLimit := delivery.RateLimitConfig{Name: "api", Rate: 10, Burst: 120, Key: delivery.KeyByUser}
+
+	// Public routes (registration and login), limited per client address
 	r.Group(func(r chi.Router) {
-		r.Use(jwtAuthMiddleware)
+		// Endpoints that check a password, code or token
+		r.Use(delivery.RateLimit(rateLimitStore, delivery.RateLimitConfig{Name: "credentials", Rate: 10.0 / 60, Burst: 10}))
+
+		r.Post("/users/register", userHandler.RegisterUser)
+		r.Post("/users/login", userHandler.LoginUser)
+		r.Post("/users/otp/verify", otpHandler.VerifyOTP)
+		r.Post("/users/password/reset", verificationHandler.ResetPassword)
+		r.Post("/users/email/verify", verificationHandler.VerifyEmail)
+		r.Post("/users/oauth/{provider}/callback", socialLoginHandler.CompleteLogin)
+	})
+	r.Group(func(r chi.Router) {
+		// Endpoints that send an SMS or an email, which cost money and can be used to spam
+		r.Use(delivery.RateLimit(rateLimitStore, delivery.RateLimitConfig{Name: "messaging", Rate: 3.0 / 60, Burst: 5}))
 
-		// User routes
-		r.Get("/users/profile", userHandler.GetUserProfile)
-		r.Get("/users/discount-card", userHandler.GetUserDiscountCard)
-		r.Put("/users/discount-card", userHandler.UpdateUserDiscountCard)
-		r.Get("/users/qrcode", userHandler.GetUserQRCode)
+		r.Post("/users/otp/request", otpHandler.RequestOTP)
+		r.Post("/users/password/forgot", verificationHandler.ForgotPassword)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(delivery.RateLimit(rateLimitStore, delivery.RateLimitConfig{Name: "tokens", Rate: 1, Burst: 30}))
 
-		// Loyalty routes
-		r.Get("/users/loyalty", userHandler.GetUserLoyaltyProfile)
-		r.Post("/users/loyalty-points", userHandler.AddLoyaltyPoints)
-		r.Post("/users/loyalty-activity", userHandler.AddLoyaltyActivity)
-		r.Get("/loyalty-tiers", userHandler.GetLoyaltyTiers)
+		r.Post("/users/token/refresh", userHandler.RefreshToken)
+		r.Get("/users/oauth/{provider}/authorize", socialLoginHandler.StartLogin)
+	})
+
+	// Catalog routes, for customers and for terminals with the catalog:read scope
+	r.Group(func(r chi.Router) {
+		r.Use(userOrAPIKeyAuthMiddleware(sessionUseCase, serviceAccountUseCase))
+		r.Use(requireScope(domain.ScopeCatalogRead))
+		r.Use(delivery.RateLimit(rateLimitStore, apiRateLimit))
 
 		// Store routes
 		r.Get("/stores", storeHandler.GetStores)
@@ -194,9 +581,72 @@ func main() {
 		// Product routes
 		r.Get("/products", productHandler.GetProductCatalog)
 		r.Get("/products/{productID}", productHandler.GetProductByID)
+	})
+
+	// Apple Wallet pass web service, called by devices holding a pass; passes are authenticated by their own token
+	r.Route("/wallet/v1", func(r chi.Router) {
+		r.Use(delivery.RateLimit(rateLimitStore, delivery.RateLimitConfig{Name: "wallet", Rate: 1, Burst: 30}))
+
+		r.Post("/devices/{deviceID}/registrations/{passTypeID}/{serialNumber}", walletHandler.RegisterDevice)
+		r.Delete("/devices/{deviceID}/registrations/{passTypeID}/{serialNumber}", walletHandler.UnregisterDevice)
+		r.Get("/devices/{deviceID}/registrations/{passTypeID}", walletHandler.GetUpdatedSerials)
+		r.Get("/passes/{passTypeID}/{serialNumber}", walletHandler.GetLatestPass)
+		r.Post("/log", walletHandler.Log)
+	})
+
+	// In-store terminal and partner routes
+	r.Route("/pos", func(r chi.Router) {
+		// Cards are scanned by terminals with an API key or by staff signed in with their own account
+		r.Group(func(r chi.Router) {
+			r.Use(userOrAPIKeyAuthMiddleware(sessionUseCase, serviceAccountUseCase))
+			r.Use(delivery.RateLimit(rateLimitStore, apiRateLimit))
+
+			r.With(requireScope(domain.ScopeLoyaltyScan), requireUserPermission(domain.PermissionScanCustomers)).Post("/scan", posHandler.ScanCustomer)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(apiKeyAuthMiddleware(serviceAccountUseCase))
+			r.Use(delivery.RateLimit(rateLimitStore, apiRateLimit))
+
+			r.With(requireScope(domain.ScopeOrdersCreate)).Post("/orders", posHandler.RecordStoreOrder)
+		})
+	})
+
+	// Protected routes
+	r.Group(func(r chi.Router) {
+		r.Use(jwtAuthMiddleware(sessionUseCase))
+		r.Use(delivery.RateLimit(rateLimitStore, apiRateLimit))
+
+		// User routes
+		r.Get("/users/profile", userHandler.GetUserProfile)
+		r.Put("/users/profile", userHandler.UpdateUserProfile)
+		r.Put("/users/password", userHandler.ChangePassword)
+		r.Delete("/users/me", userHandler.DeleteAccount)
+		r.Get("/users/me/consents", privacyHandler.GetConsents)
+		r.Put("/users/me/consents", privacyHandler.UpdateConsents)
+		r.Get("/users/me/export", privacyHandler.ExportUserData)
+		r.Post("/users/logout", userHandler.Logout)
+		r.Post("/users/email/verification", verificationHandler.SendEmailVerification)
+		r.Get("/users/sessions", userHandler.GetSessions)
+		r.Delete("/users/sessions", userHandler.RevokeOtherSessions)
+		r.Delete("/users/sessions/{sessionID}", userHandler.RevokeSession)
+		r.Get("/users/discount-card", userHandler.GetUserDiscountCard)
+		r.Get("/users/discount-card/pass", walletHandler.GetDiscountCardPass)
+		r.Get("/users/qrcode", userHandler.GetUserQRCode)
+		r.Get("/users/identities", socialLoginHandler.GetIdentities)
+		r.Post("/users/identities/{provider}/authorize", socialLoginHandler.StartLink)
+		r.Post("/users/identities/{provider}/callback", socialLoginHandler.CompleteLink)
+		r.Delete("/users/identities/{provider}", socialLoginHandler.UnlinkIdentity)
+
+		// Loyalty routes
+		r.Get("/users/loyalty", userHandler.GetUserLoyaltyProfile)
+		r.Get("/users/loyalty/transactions", userHandler.GetLoyaltyTransactions)
+		r.Get("/loyalty-tiers", userHandler.GetLoyaltyTiers)
+		r.Get("/users/referrals", referralHandler.GetReferralStats)
+		r.Get("/users/challenges", challengeHandler.GetUserChallenges)
 
 		// Notification routes
-		r.Post("/notifications", notificationHandler.SendNotification)
+		r.With(requirePermission(domain.PermissionSendNotifications)).Post("/notifications", notificationHandler.SendNotification)
 		r.Get("/users/notifications", notificationHandler.GetNotifications)
 
 		// Cart routes
@@ -213,6 +663,107 @@ func main() {
 		r.Route("/orders", func(r chi.Router) {
 			r.Get("/", orderHandler.GetUserOrders) // Route to get user's order history
 		})
+
+		// Admin routes
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(requirePermission(domain.PermissionAccessAdmin))
+
+			r.With(requirePermission(domain.PermissionViewUsers)).Get("/users/{userID}", adminHandler.GetUser)
+			r.With(requirePermission(domain.PermissionManageRoles)).Put("/users/{userID}/role", adminHandler.UpdateUserRole)
+			r.With(requirePermission(domain.PermissionManageLoyalty)).Put("/users/{userID}/birth-date", adminHandler.SetBirthDate)
+			r.With(requirePermission(domain.PermissionManageConsents)).Post("/consent-texts", privacyHandler.PublishConsentText)
+			r.With(requirePermission(domain.PermissionManageOrders)).Post("/orders/{orderID}/cancel", orderHandler.CancelOrder)
+
+			r.Route("/loyalty", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionManageLoyalty))
+
+				r.Get("/reconciliation", loyaltyAdminHandler.GetReconciliation)
+				r.Post("/reconciliation", loyaltyAdminHandler.RepairBalances)
+			})
+
+			r.Route("/loyalty-adjustments", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionAdjustPoints))
+
+				r.Get("/", adjustmentHandler.GetAdjustments)
+				r.Get("/{adjustmentID}", adjustmentHandler.GetAdjustment)
+				r.With(requirePermission(domain.PermissionApproveAdjustment)).Post("/{adjustmentID}/approve", adjustmentHandler.ApproveAdjustment)
+				r.With(requirePermission(domain.PermissionApproveAdjustment)).Post("/{adjustmentID}/reject", adjustmentHandler.RejectAdjustment)
+			})
+			r.With(requirePermission(domain.PermissionAdjustPoints)).Post("/users/{userID}/loyalty-adjustments", adjustmentHandler.CreateAdjustment)
+
+			r.Route("/users/{userID}/discount-card", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionManageLoyalty))
+
+				r.Get("/", loyaltyAdminHandler.GetUserDiscountCard)
+				r.Put("/", loyaltyAdminHandler.SetDiscountOverride)
+				r.Delete("/", loyaltyAdminHandler.ClearDiscountOverride)
+			})
+
+			r.Route("/users/{userID}/points-freeze", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionReviewFraud))
+
+				r.Get("/", loyaltyAdminHandler.GetPointFreeze)
+				r.Put("/", loyaltyAdminHandler.FreezePoints)
+				r.Delete("/", loyaltyAdminHandler.UnfreezePoints)
+			})
+
+			r.Route("/fraud/flags", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionReviewFraud))
+
+				r.Get("/", fraudHandler.GetFraudFlags)
+				r.Get("/{flagID}", fraudHandler.GetFraudFlag)
+				r.Post("/{flagID}/confirm", fraudHandler.ConfirmFraudFlag)
+				r.Post("/{flagID}/dismiss", fraudHandler.DismissFraudFlag)
+			})
+
+			r.Route("/loyalty-tiers", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionManageLoyalty))
+
+				r.Get("/", userHandler.GetLoyaltyTiers)
+				r.Post("/", loyaltyAdminHandler.CreateLoyaltyTier)
+				r.Put("/{tierID}", loyaltyAdminHandler.UpdateLoyaltyTier)
+				r.Delete("/{tierID}", loyaltyAdminHandler.DeleteLoyaltyTier)
+			})
+
+			r.Route("/earning-rules", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionManageLoyalty))
+
+				r.Get("/", earningRuleHandler.GetEarningRules)
+				r.Post("/", earningRuleHandler.CreateEarningRule)
+				r.Put("/{ruleID}", earningRuleHandler.UpdateEarningRule)
+				r.Delete("/{ruleID}", earningRuleHandler.DeleteEarningRule)
+			})
+
+			r.Route("/challenges", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionManageLoyalty))
+
+				r.Get("/", challengeHandler.GetChallenges)
+				r.Post("/", challengeHandler.CreateChallenge)
+				r.Put("/{challengeID}", challengeHandler.UpdateChallenge)
+				r.Delete("/{challengeID}", challengeHandler.DeleteChallenge)
+			})
+
+			r.Route("/reports/loyalty", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionViewReports))
+
+				r.Get("/liability", reportHandler.GetLiability)
+				r.Get("/points-flow", reportHandler.GetPointsFlow)
+				r.Get("/expiring-points", reportHandler.GetExpiringPoints)
+				r.Get("/tier-distribution", reportHandler.GetTierDistribution)
+				r.Get("/active-members", reportHandler.GetActiveMembers)
+			})
+
+			r.Route("/service-accounts", func(r chi.Router) {
+				r.Use(requirePermission(domain.PermissionManageAPIKeys))
+
+				r.Get("/", serviceAccountHandler.GetServiceAccounts)
+				r.Post("/", serviceAccountHandler.CreateServiceAccount)
+				r.Delete("/{accountID}", serviceAccountHandler.DisableServiceAccount)
+				r.Post("/{accountID}/keys", serviceAccountHandler.CreateAPIKey)
+				r.Post("/{accountID}/keys/{keyID}/rotate", serviceAccountHandler.RotateAPIKey)
+				r.Delete("/{accountID}/keys/{keyID}", serviceAccountHandler.RevokeAPIKey)
+			})
+		})
 	})
 
 	// Start HTTP server
@@ -229,6 +780,33 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Background jobs run in every instance, so each of them must be safe to run concurrently
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go runPeriodically(jobsCtx, "loyalty ledger reconciliation", 24*time.Hour, func(ctx context.Context) error {
+		report, err := loyaltyUseCase.ReconcileLedger(ctx, false)
+		if err != nil {
+			return err
+		}
+		if len(report.Mismatches) > 0 || len(report.UnbalancedEntryIDs) > 0 {
+			log.Printf("WARNING: loyalty ledger reconciliation found %d balance mismatches and %d unbalanced entries", len(report.Mismatches), len(report.UnbalancedEntryIDs))
+		}
+		return nil
+	})
+
+	go runPeriodically(jobsCtx, "loyalty points expiry", 24*time.Hour, func(ctx context.Context) error {
+		if err := loyaltyUseCase.ExpirePoints(ctx); err != nil {
+			return err
+		}
+		return loyaltyUseCase.SendExpiryWarnings(ctx)
+	})
+	go runPeriodically(jobsCtx, "order points", 10*time.Minute, cartUseCase.AccrueUnpostedOrderPoints)
+	go runPeriodically(jobsCtx, "loyalty tier evaluation", 24*time.Hour, loyaltyUseCase.ReevaluateTiers)
+	go runPeriodically(jobsCtx, "loyalty tier snapshot", 24*time.Hour, reportUseCase.RecordTierSnapshot)
+	go runPeriodically(jobsCtx, "loyalty fraud detection", time.Hour, fraudUseCase.DetectFraud)
+	go runPeriodically(jobsCtx, "referral rewards", 24*time.Hour, referralUseCase.RewardReferrals)
+	go runPeriodically(jobsCtx, "loyalty lifecycle bonuses", 24*time.Hour, lifecycleUseCase.GrantLifecycleBonuses)
+	go runPeriodically(jobsCtx, "wallet pass updates", 5*time.Minute, walletUseCase.UpdatePasses)
+
 	// Graceful shutdown
 	go func() {
 		log.Printf("Starting server on port %s", port)
@@ -241,6 +819,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
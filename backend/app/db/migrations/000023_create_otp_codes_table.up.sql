@@ -0,0 +1,10 @@
+CREATE TABLE otp_codes (
+    phone_number VARCHAR(20) PRIMARY KEY,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Users registered by phone have no email; store NULL so the unique constraint allows many of them
+UPDATE users SET email = NULL WHERE email = '';
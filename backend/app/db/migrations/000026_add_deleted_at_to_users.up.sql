@@ -0,0 +1,2 @@
+-- Deleted accounts are anonymized rather than removed, so orders and loyalty history stay intact for accounting
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,29 @@
+CREATE TABLE consent_texts (
+    id SERIAL PRIMARY KEY,
+    purpose VARCHAR(32) NOT NULL,
+    version INT NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (purpose, version)
+);
+
+-- Append-only: withdrawing a consent adds a record instead of changing the earlier one
+CREATE TABLE consent_records (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(32) NOT NULL,
+    granted BOOLEAN NOT NULL,
+    text_version INT NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    FOREIGN KEY (purpose, text_version) REFERENCES consent_texts(purpose, version)
+);
+
+CREATE INDEX idx_consent_records_user_id_purpose ON consent_records(user_id, purpose, created_at DESC);
+
+INSERT INTO consent_texts (purpose, version, text) VALUES
+('marketing_sms', 1, 'Я согласен получать SMS-сообщения рекламного характера об акциях и новинках MR.KINGSMAN. Согласие можно отозвать в любой момент в настройках профиля.'),
+('marketing_email', 1, 'Я согласен получать рекламные письма об акциях и новинках MR.KINGSMAN на указанный адрес электронной почты. Согласие можно отозвать в любой момент в настройках профиля.'),
+('push', 1, 'Я согласен получать push-уведомления рекламного характера об акциях и новинках MR.KINGSMAN. Согласие можно отозвать в любой момент в настройках профиля.'),
+('personalized_offers', 1, 'Я согласен на обработку истории покупок и участия в программе лояльности для подбора персональных предложений. Согласие можно отозвать в любой момент в настройках профиля.');
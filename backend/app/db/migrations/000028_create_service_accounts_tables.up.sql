@@ -0,0 +1,22 @@
+CREATE TABLE service_accounts (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    disabled_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,
+    service_account_id INT NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    last_used_ip VARCHAR(45) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_api_keys_service_account_id ON api_keys(service_account_id);
@@ -0,0 +1,6 @@
+ALTER TABLE orders
+DROP COLUMN IF EXISTS points_discount,
+DROP COLUMN IF EXISTS points_redeemed;
+
+ALTER TABLE loyalty_tiers
+DROP COLUMN IF EXISTS point_value_multiplier;
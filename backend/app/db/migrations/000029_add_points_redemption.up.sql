@@ -0,0 +1,11 @@
+ALTER TABLE loyalty_tiers
+ADD COLUMN point_value_multiplier NUMERIC(4, 2) NOT NULL DEFAULT 1.0;
+
+-- Higher tiers get more for each point they spend
+UPDATE loyalty_tiers SET point_value_multiplier = 1.10 WHERE name = 'Silver';
+UPDATE loyalty_tiers SET point_value_multiplier = 1.25 WHERE name = 'Gold';
+UPDATE loyalty_tiers SET point_value_multiplier = 1.50 WHERE name = 'Platinum';
+
+ALTER TABLE orders
+ADD COLUMN points_redeemed INT NOT NULL DEFAULT 0,
+ADD COLUMN points_discount NUMERIC(10, 2) NOT NULL DEFAULT 0;
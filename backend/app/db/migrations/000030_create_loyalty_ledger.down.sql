@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS loyalty_postings;
+
+DELETE FROM loyalty_points WHERE type = 'opening_balance';
+
+DROP INDEX IF EXISTS idx_loyalty_points_user_id;
+DROP INDEX IF EXISTS idx_loyalty_points_reverses_id;
+
+ALTER TABLE loyalty_points
+DROP CONSTRAINT IF EXISTS loyalty_points_idempotency_key_key,
+DROP CONSTRAINT IF EXISTS loyalty_points_kind_check,
+DROP COLUMN IF EXISTS balance_after,
+DROP COLUMN IF EXISTS reverses_id,
+DROP COLUMN IF EXISTS reference,
+DROP COLUMN IF EXISTS idempotency_key,
+DROP COLUMN IF EXISTS kind;
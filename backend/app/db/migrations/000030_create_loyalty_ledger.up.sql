@@ -0,0 +1,64 @@
+-- loyalty_points becomes an append-only ledger. Every entry is balanced by two postings,
+-- one on the member's account and one on a program account, which sum to zero.
+ALTER TABLE loyalty_points
+ADD COLUMN kind VARCHAR(16),
+ADD COLUMN idempotency_key VARCHAR(255),
+ADD COLUMN reference VARCHAR(100) NOT NULL DEFAULT '',
+ADD COLUMN reverses_id INT REFERENCES loyalty_points(id),
+ADD COLUMN balance_after INT;
+
+UPDATE loyalty_points SET
+    kind = CASE
+        WHEN type = 'redemption' THEN 'redeem'
+        WHEN points > 0 AND type <> 'redemption_refund' THEN 'earn'
+        ELSE 'adjust'
+    END,
+    idempotency_key = 'legacy:' || id;
+
+CREATE TABLE loyalty_postings (
+    id SERIAL PRIMARY KEY,
+    transaction_id INT NOT NULL REFERENCES loyalty_points(id) ON DELETE CASCADE,
+    account VARCHAR(64) NOT NULL,
+    amount INT NOT NULL
+);
+
+-- Balances drifted from the entries before the ledger existed. An opening adjustment
+-- makes the ledger agree with user_loyalty, which is the balance members were shown.
+INSERT INTO loyalty_points (user_id, points, type, kind, idempotency_key, created_at)
+SELECT ul.user_id, ul.current_points - COALESCE(SUM(p.points), 0), 'opening_balance', 'adjust', 'opening:' || ul.user_id, NOW()
+FROM user_loyalty ul
+LEFT JOIN loyalty_points p ON p.user_id = ul.user_id
+GROUP BY ul.user_id, ul.current_points
+HAVING ul.current_points <> COALESCE(SUM(p.points), 0);
+
+UPDATE loyalty_points p SET balance_after = s.balance
+FROM (SELECT id, SUM(points) OVER (PARTITION BY user_id ORDER BY created_at, id) AS balance FROM loyalty_points) s
+WHERE p.id = s.id;
+
+INSERT INTO loyalty_postings (transaction_id, account, amount)
+SELECT id, 'member:' || user_id, points FROM loyalty_points;
+
+INSERT INTO loyalty_postings (transaction_id, account, amount)
+SELECT id, CASE kind
+        WHEN 'earn' THEN 'program:issued'
+        WHEN 'redeem' THEN 'program:redeemed'
+        ELSE 'program:adjustments'
+    END, -points
+FROM loyalty_points;
+
+UPDATE users u SET current_points = COALESCE(
+    (SELECT SUM(points) FROM loyalty_points p WHERE p.user_id = u.id), 0
+);
+
+ALTER TABLE loyalty_points
+ALTER COLUMN kind SET NOT NULL,
+ALTER COLUMN idempotency_key SET NOT NULL,
+ALTER COLUMN balance_after SET NOT NULL,
+ADD CONSTRAINT loyalty_points_kind_check CHECK (kind IN ('earn', 'redeem', 'expire', 'adjust', 'reverse')),
+ADD CONSTRAINT loyalty_points_idempotency_key_key UNIQUE (idempotency_key);
+
+-- An entry can only be reversed once
+CREATE UNIQUE INDEX idx_loyalty_points_reverses_id ON loyalty_points (reverses_id) WHERE reverses_id IS NOT NULL;
+CREATE INDEX idx_loyalty_points_user_id ON loyalty_points (user_id, id);
+CREATE INDEX idx_loyalty_postings_transaction_id ON loyalty_postings (transaction_id);
+CREATE INDEX idx_loyalty_postings_account ON loyalty_postings (account);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS loyalty_point_lots;
+
+ALTER TABLE loyalty_points
+DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,27 @@
+ALTER TABLE loyalty_points
+ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+
+-- The unspent part of each credit. Debits consume lots soonest-expiring first.
+CREATE TABLE loyalty_point_lots (
+    entry_id INT PRIMARY KEY REFERENCES loyalty_points(id) ON DELETE CASCADE,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    remaining INT NOT NULL CHECK (remaining >= 0),
+    warned_days_before INT -- The closest expiry warning sent for the lot
+);
+
+CREATE INDEX idx_loyalty_point_lots_user_id ON loyalty_point_lots (user_id) WHERE remaining > 0;
+
+-- Points accrued before the policy are left undated here: the expiry job dates them by the
+-- configured LOYALTY_POINTS_LIFETIME_MONTHS, so they expire on the same schedule as new ones
+
+-- Consuming oldest first leaves the newest credits unspent
+INSERT INTO loyalty_point_lots (entry_id, user_id, remaining)
+SELECT c.id, c.user_id, LEAST(c.points, b.balance - c.newer_points)
+FROM (
+    SELECT id, user_id, points,
+        COALESCE(SUM(points) OVER (PARTITION BY user_id ORDER BY created_at DESC, id DESC ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING), 0) AS newer_points
+    FROM loyalty_points
+    WHERE points > 0
+) c
+JOIN (SELECT user_id, SUM(points) AS balance FROM loyalty_points GROUP BY user_id) b ON b.user_id = c.user_id
+WHERE c.newer_points < b.balance;
@@ -0,0 +1,2 @@
+ALTER TABLE user_loyalty
+DROP COLUMN IF EXISTS tier_grace_until;
@@ -0,0 +1,3 @@
+-- Set while a member no longer qualifies for their tier but keeps it for a grace period
+ALTER TABLE user_loyalty
+ADD COLUMN tier_grace_until TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,17 @@
+ALTER TABLE orders
+DROP COLUMN IF EXISTS tier_discount;
+
+ALTER TABLE loyalty_tiers
+ADD COLUMN IF NOT EXISTS benefits TEXT;
+
+UPDATE loyalty_tiers SET benefits = '5% discount on selected items' WHERE name = 'Bronze';
+UPDATE loyalty_tiers SET benefits = '10% discount, early access to sales' WHERE name = 'Silver';
+UPDATE loyalty_tiers SET benefits = '15% discount, dedicated support, birthday gift' WHERE name = 'Gold';
+UPDATE loyalty_tiers SET benefits = '20% discount, personal shopper, exclusive events' WHERE name = 'Platinum';
+
+ALTER TABLE loyalty_tiers
+DROP COLUMN IF EXISTS early_access,
+DROP COLUMN IF EXISTS free_shipping,
+DROP COLUMN IF EXISTS free_alterations,
+DROP COLUMN IF EXISTS points_multiplier,
+DROP COLUMN IF EXISTS discount_percent;
@@ -0,0 +1,18 @@
+-- Tier benefits become typed columns that checkout and earning apply
+ALTER TABLE loyalty_tiers
+ADD COLUMN discount_percent NUMERIC(5, 2) NOT NULL DEFAULT 0 CHECK (discount_percent >= 0 AND discount_percent <= 100),
+ADD COLUMN points_multiplier NUMERIC(4, 2) NOT NULL DEFAULT 1.0 CHECK (points_multiplier > 0),
+ADD COLUMN free_alterations BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN free_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN early_access BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE loyalty_tiers SET discount_percent = 5 WHERE name = 'Bronze';
+UPDATE loyalty_tiers SET discount_percent = 10, early_access = TRUE WHERE name = 'Silver';
+UPDATE loyalty_tiers SET discount_percent = 15, points_multiplier = 1.25, early_access = TRUE, free_alterations = TRUE WHERE name = 'Gold';
+UPDATE loyalty_tiers SET discount_percent = 20, points_multiplier = 1.5, early_access = TRUE, free_alterations = TRUE, free_shipping = TRUE WHERE name = 'Platinum';
+
+ALTER TABLE loyalty_tiers
+DROP COLUMN benefits;
+
+ALTER TABLE orders
+ADD COLUMN tier_discount NUMERIC(10, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS order_points_breakdown;
+
+ALTER TABLE orders
+DROP COLUMN IF EXISTS points_earned,
+DROP COLUMN IF EXISTS store_id;
+
+DROP TABLE IF EXISTS earning_rules;
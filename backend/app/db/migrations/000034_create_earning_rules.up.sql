@@ -0,0 +1,36 @@
+-- Rules that award points at checkout on top of the base rate. Every condition left empty matches any order.
+CREATE TABLE earning_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL, -- Shown to the customer in the points breakdown of their order
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    category_id INT REFERENCES categories(id) ON DELETE CASCADE,
+    product_id INT REFERENCES products(id) ON DELETE CASCADE,
+    store_id INT REFERENCES stores(id) ON DELETE CASCADE,
+    tier_id INT REFERENCES loyalty_tiers(id) ON DELETE CASCADE,
+    days_of_week INT[] NOT NULL DEFAULT '{}', -- 0 is Sunday
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    min_basket NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (min_basket >= 0),
+    multiplier NUMERIC(4, 2) NOT NULL DEFAULT 1 CHECK (multiplier >= 1), -- Applied to the base points of matching lines
+    bonus_points INT NOT NULL DEFAULT 0 CHECK (bonus_points >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX idx_earning_rules_active ON earning_rules (active);
+
+ALTER TABLE orders
+ADD COLUMN store_id INT REFERENCES stores(id) ON DELETE SET NULL,
+ADD COLUMN points_earned INT NOT NULL DEFAULT 0;
+
+-- Which rules awarded the points of each order
+CREATE TABLE order_points_breakdown (
+    id SERIAL PRIMARY KEY,
+    order_id INT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    rule_id INT REFERENCES earning_rules(id) ON DELETE SET NULL, -- Empty for the base rate and the tier bonus
+    description VARCHAR(255) NOT NULL,
+    points INT NOT NULL
+);
+
+CREATE INDEX idx_order_points_breakdown_order_id ON order_points_breakdown (order_id);
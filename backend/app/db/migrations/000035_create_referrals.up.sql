@@ -0,0 +1,28 @@
+-- Each member's invite code, created the first time they ask for it
+CREATE TABLE referral_codes (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    code VARCHAR(16) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- A member who registered with another member's code. Both are rewarded once the referee's
+-- first paid order is past the hold period for returns.
+CREATE TABLE referrals (
+    id SERIAL PRIMARY KEY,
+    referrer_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    referee_id INT UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'qualified', 'rewarded', 'rejected')),
+    rejection_reason VARCHAR(255),
+    device VARCHAR(255) NOT NULL DEFAULT '', -- The referee registered from
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    order_id INT REFERENCES orders(id) ON DELETE SET NULL, -- The referee's first paid order
+    reward_after TIMESTAMP WITH TIME ZONE,
+    referrer_points INT NOT NULL DEFAULT 0,
+    referee_points INT NOT NULL DEFAULT 0,
+    rewarded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (referrer_id <> referee_id)
+);
+
+CREATE INDEX idx_referrals_referrer_id ON referrals (referrer_id);
+CREATE INDEX idx_referrals_reward_after ON referrals (reward_after) WHERE status = 'qualified';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_badges;
+DROP TABLE IF EXISTS challenges;
+DROP TABLE IF EXISTS store_visits;
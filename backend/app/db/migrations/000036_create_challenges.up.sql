@@ -0,0 +1,45 @@
+-- A day a customer was in a store, from an in-store purchase or a scan of their card
+CREATE TABLE store_visits (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    store_id INT NOT NULL REFERENCES stores(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL CHECK (source IN ('order', 'scan')),
+    visited_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_store_visits_user_id ON store_visits (user_id, visited_at);
+
+-- Goals members complete for a badge and points, e.g. "buy 3 shirts this month"
+CREATE TABLE challenges (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    goal VARCHAR(30) NOT NULL CHECK (goal IN ('orders', 'items', 'amount_spent', 'store_visits', 'distinct_stores')),
+    target NUMERIC(10, 2) NOT NULL CHECK (target > 0),
+    category_id INT REFERENCES categories(id) ON DELETE CASCADE, -- Counts only purchases from the category
+    period VARCHAR(20) NOT NULL DEFAULT 'once' CHECK (period IN ('once', 'weekly', 'monthly')),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    badge_name VARCHAR(255) NOT NULL,
+    badge_icon_url VARCHAR(255) NOT NULL DEFAULT '',
+    reward_points INT NOT NULL DEFAULT 0 CHECK (reward_points >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+-- Badges members were awarded, one per challenge and period
+CREATE TABLE user_badges (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    challenge_id INT REFERENCES challenges(id) ON DELETE SET NULL,
+    period_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    icon_url VARCHAR(255) NOT NULL DEFAULT '',
+    points INT NOT NULL DEFAULT 0,
+    awarded_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, challenge_id, period_start)
+);
+
+CREATE INDEX idx_user_badges_user_id ON user_badges (user_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS lifecycle_bonuses;
+
+ALTER TABLE users DROP COLUMN IF EXISTS created_at;
+ALTER TABLE users DROP COLUMN IF EXISTS birth_date;
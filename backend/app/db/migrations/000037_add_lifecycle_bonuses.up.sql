@@ -0,0 +1,26 @@
+ALTER TABLE users ADD COLUMN birth_date DATE;
+ALTER TABLE users ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+-- Existing members joined no later than the first trace they left
+UPDATE users u SET created_at = first_seen.at
+FROM (
+    SELECT user_id, MIN(at) AS at FROM (
+        SELECT user_id, order_date AS at FROM orders
+        UNION ALL SELECT user_id, created_at FROM sessions
+        UNION ALL SELECT user_id, created_at FROM loyalty_points
+    ) traces
+    WHERE at IS NOT NULL
+    GROUP BY user_id
+) first_seen
+WHERE first_seen.user_id = u.id AND first_seen.at < u.created_at;
+
+-- Birthday, anniversary and win-back bonuses, granted once per member and period
+CREATE TABLE lifecycle_bonuses (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('birthday', 'anniversary', 'win_back')),
+    period VARCHAR(10) NOT NULL, -- The year for birthdays and anniversaries, the date of the last activity for win-backs
+    points INT NOT NULL,
+    granted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, kind, period)
+);
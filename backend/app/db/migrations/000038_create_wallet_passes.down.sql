@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS wallet_pass_devices;
+DROP TABLE IF EXISTS wallet_passes;
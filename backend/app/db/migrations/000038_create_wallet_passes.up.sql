@@ -0,0 +1,22 @@
+-- A member's discount card in Apple Wallet and Google Wallet; one pass per member, shared by both wallets
+CREATE TABLE wallet_passes (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    serial_number VARCHAR(36) UNIQUE NOT NULL,
+    auth_token VARCHAR(64) NOT NULL, -- Apple devices present it to the pass web service
+    points INT NOT NULL,             -- What the pass currently shows, to tell when it needs an update
+    tier_id INT REFERENCES loyalty_tiers(id) ON DELETE SET NULL,
+    code_window BIGINT NOT NULL,     -- Day of the card code on the pass
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Apple devices that hold a pass and want push updates for it
+CREATE TABLE wallet_pass_devices (
+    device_library_id VARCHAR(255) NOT NULL,
+    serial_number VARCHAR(36) NOT NULL REFERENCES wallet_passes(serial_number) ON DELETE CASCADE,
+    push_token VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (device_library_id, serial_number)
+);
+
+CREATE INDEX idx_wallet_pass_devices_serial_number ON wallet_pass_devices (serial_number);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS discount_card_audit;
+DROP TABLE IF EXISTS discount_card_overrides;
+
+ALTER TABLE users ADD COLUMN discount_level INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN progress_to_next_level NUMERIC(5, 2) NOT NULL DEFAULT 0.0;
@@ -0,0 +1,26 @@
+-- Discount card level and progress are derived from the loyalty tier; what customers wrote into them is dropped
+ALTER TABLE users DROP COLUMN discount_level;
+ALTER TABLE users DROP COLUMN progress_to_next_level;
+
+-- A discount set by staff in place of the member's tier discount
+CREATE TABLE discount_card_overrides (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    discount_percent NUMERIC(5, 2) NOT NULL CHECK (discount_percent BETWEEN 0 AND 100),
+    reason TEXT NOT NULL,
+    set_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Every override set or removed, kept after the override itself is gone
+CREATE TABLE discount_card_audit (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    admin_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('set', 'clear')),
+    discount_percent NUMERIC(5, 2), -- New discount; empty when the override is removed
+    previous_percent NUMERIC(5, 2), -- Override it replaced, if any
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_discount_card_audit_user_id ON discount_card_audit (user_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS loyalty_adjustment_events;
+DROP TABLE IF EXISTS loyalty_adjustments;
@@ -0,0 +1,31 @@
+-- Points staff add to or take from a member by hand, e.g. a goodwill credit or a correction
+CREATE TABLE loyalty_adjustments (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    points INT NOT NULL CHECK (points <> 0),
+    reason_code VARCHAR(30) NOT NULL CHECK (reason_code IN ('goodwill', 'missed_purchase', 'correction', 'other')),
+    note TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    requested_by INT REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_by INT REFERENCES users(id) ON DELETE SET NULL, -- The requester when no second approver was needed
+    review_note TEXT NOT NULL DEFAULT '',
+    entry_id INT REFERENCES loyalty_points(id), -- Ledger entry of an approved adjustment
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_loyalty_adjustments_user_id ON loyalty_adjustments (user_id, created_at);
+CREATE INDEX idx_loyalty_adjustments_pending ON loyalty_adjustments (created_at) WHERE status = 'pending';
+
+-- Every step of an adjustment and who took it
+CREATE TABLE loyalty_adjustment_events (
+    id SERIAL PRIMARY KEY,
+    adjustment_id INT NOT NULL REFERENCES loyalty_adjustments(id) ON DELETE CASCADE,
+    actor_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('requested', 'approved', 'rejected')),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_loyalty_adjustment_events_adjustment_id ON loyalty_adjustment_events (adjustment_id);
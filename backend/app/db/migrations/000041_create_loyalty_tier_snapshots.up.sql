@@ -0,0 +1,17 @@
+-- How many members each tier had at the end of a day, recorded daily for the tier distribution report.
+-- The tier name is copied so history survives a tier being renamed or removed; '' is members without a tier.
+CREATE TABLE loyalty_tier_snapshots (
+    snapshot_date DATE NOT NULL,
+    tier_id INT REFERENCES loyalty_tiers(id) ON DELETE SET NULL,
+    tier_name VARCHAR(50) NOT NULL,
+    members INT NOT NULL CHECK (members >= 0),
+    PRIMARY KEY (snapshot_date, tier_name)
+);
+
+-- History starts today; earlier distributions were never recorded
+INSERT INTO loyalty_tier_snapshots (snapshot_date, tier_id, tier_name, members)
+SELECT CURRENT_DATE, t.id, COALESCE(t.name, ''), COUNT(*)
+FROM user_loyalty ul
+JOIN users u ON u.id = ul.user_id AND u.deleted_at IS NULL
+LEFT JOIN loyalty_tiers t ON t.id = ul.current_tier_id
+GROUP BY t.id, t.name;
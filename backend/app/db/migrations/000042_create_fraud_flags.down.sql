@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_loyalty_points_kind_created_at;
+DROP INDEX IF EXISTS idx_sessions_device;
+DROP TABLE IF EXISTS loyalty_point_freeze_audit;
+DROP TABLE IF EXISTS loyalty_point_freezes;
+DROP TABLE IF EXISTS fraud_flags;
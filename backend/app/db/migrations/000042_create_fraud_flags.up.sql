@@ -0,0 +1,41 @@
+-- Anomalies found by the fraud rules, queued for staff to review
+CREATE TABLE fraud_flags (
+    id SERIAL PRIMARY KEY,
+    rule VARCHAR(30) NOT NULL CHECK (rule IN ('shared_phone_prefix', 'shared_device', 'earn_redeem_cycle', 'returns_after_earning', 'staff_adjustments')),
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- The member, or the staff member for staff rules
+    details TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'confirmed')),
+    reviewed_by INT REFERENCES users(id) ON DELETE SET NULL,
+    review_note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    reviewed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- A rule flags a user once until the flag is reviewed
+CREATE UNIQUE INDEX idx_fraud_flags_open ON fraud_flags (rule, user_id) WHERE status = 'open';
+CREATE INDEX idx_fraud_flags_status ON fraud_flags (status, created_at);
+
+-- Members whose points are frozen: they keep earning but cannot spend points until staff unfreeze them
+CREATE TABLE loyalty_point_freezes (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    flag_id INT REFERENCES fraud_flags(id) ON DELETE SET NULL,
+    frozen_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Every freeze and unfreeze, kept after the freeze itself is gone
+CREATE TABLE loyalty_point_freeze_audit (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    admin_id INT REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('freeze', 'unfreeze')),
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_loyalty_point_freeze_audit_user_id ON loyalty_point_freeze_audit (user_id, created_at);
+
+-- The fraud rules look back over recent orders, sessions and ledger entries
+CREATE INDEX idx_sessions_device ON sessions (device, ip_address);
+CREATE INDEX idx_loyalty_points_kind_created_at ON loyalty_points (kind, created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN phone_verified_at TIMESTAMP WITH TIME ZONE;
+
+-- Accounts without a password could only have been opened by a login code or a provider-confirmed number
+UPDATE users SET phone_verified_at = created_at WHERE phone_number IS NOT NULL AND password_hash IS NULL;
@@ -0,0 +1,7 @@
+-- Which lots each debit took its points from, so that reversing the debit puts them back with their expiry
+CREATE TABLE loyalty_lot_consumptions (
+    entry_id INT NOT NULL REFERENCES loyalty_points(id) ON DELETE CASCADE,
+    lot_entry_id INT NOT NULL REFERENCES loyalty_point_lots(entry_id) ON DELETE CASCADE,
+    points INT NOT NULL CHECK (points > 0),
+    PRIMARY KEY (entry_id, lot_entry_id)
+);
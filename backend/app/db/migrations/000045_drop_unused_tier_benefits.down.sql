@@ -0,0 +1,8 @@
+ALTER TABLE loyalty_tiers
+ADD COLUMN free_alterations BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN free_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN early_access BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE loyalty_tiers SET early_access = TRUE WHERE name = 'Silver';
+UPDATE loyalty_tiers SET early_access = TRUE, free_alterations = TRUE WHERE name = 'Gold';
+UPDATE loyalty_tiers SET early_access = TRUE, free_alterations = TRUE, free_shipping = TRUE WHERE name = 'Platinum';
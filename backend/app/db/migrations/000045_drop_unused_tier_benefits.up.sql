@@ -0,0 +1,5 @@
+-- There is no shipping, alterations or early access in the store for these flags to apply to
+ALTER TABLE loyalty_tiers
+DROP COLUMN free_alterations,
+DROP COLUMN free_shipping,
+DROP COLUMN early_access;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_orders_points_unposted;
+
+ALTER TABLE orders
+DROP COLUMN IF EXISTS points_posted_at;
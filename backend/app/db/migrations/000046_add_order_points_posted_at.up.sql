@@ -0,0 +1,8 @@
+-- When the points a paid order earned were posted; orders still without it are retried by a job
+ALTER TABLE orders
+ADD COLUMN points_posted_at TIMESTAMP WITH TIME ZONE;
+
+-- Orders placed earlier were settled when they were placed
+UPDATE orders SET points_posted_at = NOW();
+
+CREATE INDEX idx_orders_points_unposted ON orders (created_at) WHERE points_posted_at IS NULL AND payment_status = 'paid';
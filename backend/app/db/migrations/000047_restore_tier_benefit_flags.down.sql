@@ -0,0 +1,11 @@
+ALTER TABLE products
+DROP COLUMN IF EXISTS early_access_until;
+
+ALTER TABLE orders
+DROP COLUMN IF EXISTS alteration_fee,
+DROP COLUMN IF EXISTS shipping_fee;
+
+ALTER TABLE loyalty_tiers
+DROP COLUMN IF EXISTS early_access,
+DROP COLUMN IF EXISTS free_shipping,
+DROP COLUMN IF EXISTS free_alterations;
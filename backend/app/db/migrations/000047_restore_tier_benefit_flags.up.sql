@@ -0,0 +1,18 @@
+-- Shipping and alteration fees at checkout and early access to products and promotions bring these flags back
+ALTER TABLE loyalty_tiers
+ADD COLUMN free_alterations BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN free_shipping BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN early_access BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE loyalty_tiers SET early_access = TRUE WHERE name = 'Silver';
+UPDATE loyalty_tiers SET early_access = TRUE, free_alterations = TRUE WHERE name = 'Gold';
+UPDATE loyalty_tiers SET early_access = TRUE, free_alterations = TRUE, free_shipping = TRUE WHERE name = 'Platinum';
+
+-- Fees charged for services added to an online order, left at zero when the member's tier waives them
+ALTER TABLE orders
+ADD COLUMN shipping_fee NUMERIC(10, 2) NOT NULL DEFAULT 0,
+ADD COLUMN alteration_fee NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+-- Until then only members whose tier has early access see the product
+ALTER TABLE products
+ADD COLUMN early_access_until TIMESTAMP WITH TIME ZONE;
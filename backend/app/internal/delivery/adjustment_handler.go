@@ -0,0 +1,171 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type AdjustmentHandler struct {
+	adjustmentUseCase *usecase.AdjustmentUseCase
+}
+
+func NewAdjustmentHandler(adjustmentUseCase *usecase.AdjustmentUseCase) *AdjustmentHandler {
+	return &AdjustmentHandler{adjustmentUseCase: adjustmentUseCase}
+}
+
+// CreateAdjustment handles the request to add points to or take points from a member.
+func (h *AdjustmentHandler) CreateAdjustment(w http.ResponseWriter, r *http.Request) {
+	staff, ok := staffFromContext(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var req usecase.CreateAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// A retried request with the same Idempotency-Key header makes the adjustment only once
+	idempotencyKey := uuid.NewString()
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		idempotencyKey = key
+	}
+	resp, err := h.adjustmentUseCase.CreateAdjustment(r.Context(), userID, staff, &req, idempotencyKey)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetAdjustments handles the request to list adjustments, filtered by the user_id and status query parameters.
+func (h *AdjustmentHandler) GetAdjustments(w http.ResponseWriter, r *http.Request) {
+	userID := 0
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		var err error
+		if userID, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp, err := h.adjustmentUseCase.GetAdjustments(r.Context(), userID, domain.AdjustmentStatus(r.URL.Query().Get("status")))
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetAdjustment handles the request to view an adjustment with its audit trail.
+func (h *AdjustmentHandler) GetAdjustment(w http.ResponseWriter, r *http.Request) {
+	adjustmentID, err := strconv.Atoi(chi.URLParam(r, "adjustmentID"))
+	if err != nil {
+		http.Error(w, "Invalid adjustment ID", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.adjustmentUseCase.GetAdjustment(r.Context(), adjustmentID)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ApproveAdjustment handles the request to approve an adjustment requested by someone else.
+func (h *AdjustmentHandler) ApproveAdjustment(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.adjustmentUseCase.ApproveAdjustment)
+}
+
+// RejectAdjustment handles the request to turn down an adjustment requested by someone else.
+func (h *AdjustmentHandler) RejectAdjustment(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.adjustmentUseCase.RejectAdjustment)
+}
+
+type reviewAdjustmentFunc func(ctx context.Context, adjustmentID int, staff usecase.Staff, req *usecase.ReviewAdjustmentRequest) (*usecase.AdjustmentResponse, error)
+
+func (h *AdjustmentHandler) review(w http.ResponseWriter, r *http.Request, review reviewAdjustmentFunc) {
+	staff, ok := staffFromContext(w, r)
+	if !ok {
+		return
+	}
+	adjustmentID, err := strconv.Atoi(chi.URLParam(r, "adjustmentID"))
+	if err != nil {
+		http.Error(w, "Invalid adjustment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req usecase.ReviewAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := review(r.Context(), adjustmentID, staff, &req)
+	if err != nil {
+		writeAdjustmentError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// staffFromContext returns the signed-in staff member with their role.
+func staffFromContext(w http.ResponseWriter, r *http.Request) (usecase.Staff, bool) {
+	ctxUserID := r.Context().Value(domain.UserContextKey)
+	if ctxUserID == nil {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return usecase.Staff{}, false
+	}
+	staffID, err := strconv.Atoi(ctxUserID.(string))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return usecase.Staff{}, false
+	}
+	role, _ := r.Context().Value(domain.RoleContextKey).(domain.Role)
+	return usecase.Staff{ID: staffID, Role: role}, true
+}
+
+func writeAdjustmentError(w http.ResponseWriter, err error) {
+	msg := err.Error()
+	if msg == "user not found" || msg == "loyalty adjustment not found" {
+		http.Error(w, msg, http.StatusNotFound)
+		return
+	}
+	if msg == "adjustments must be reviewed by someone other than the requester" || strings.HasPrefix(msg, "staff cannot") || strings.HasPrefix(msg, "adjustment exceeds your") {
+		http.Error(w, msg, http.StatusForbidden)
+		return
+	}
+	if msg == "loyalty adjustment is not pending" || msg == "insufficient points" {
+		http.Error(w, msg, http.StatusConflict)
+		return
+	}
+	if strings.HasPrefix(msg, "failed to") {
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, msg, http.StatusBadRequest)
+}
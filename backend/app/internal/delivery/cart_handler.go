@@ -25,7 +25,15 @@ func (h *CartHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req := usecase.PlaceOrderRequest{UserID: userID}
+	// The body is optional; it carries the points to redeem and the services to add
+	var req usecase.PlaceOrderRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	req.UserID = userID
 
 	resp, err := h.cartUseCase.PlaceOrder(r.Context(), &req)
 	if err != nil {
@@ -0,0 +1,121 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type ChallengeHandler struct {
+	challengeUseCase *usecase.ChallengeUseCase
+}
+
+func NewChallengeHandler(challengeUseCase *usecase.ChallengeUseCase) *ChallengeHandler {
+	return &ChallengeHandler{challengeUseCase: challengeUseCase}
+}
+
+// GetUserChallenges handles the request for the user's progress with running challenges and their badges.
+func (h *ChallengeHandler) GetUserChallenges(w http.ResponseWriter, r *http.Request) {
+	ctxUserID := r.Context().Value(domain.UserContextKey)
+	if ctxUserID == nil {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := strconv.Atoi(ctxUserID.(string))
+	if err != nil {
+		http.Error(w, "Invalid User ID format in JWT", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.challengeUseCase.GetUserChallenges(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetChallenges handles the request to list every challenge, running or not.
+func (h *ChallengeHandler) GetChallenges(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.challengeUseCase.GetChallenges(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CreateChallenge handles the request to add a challenge.
+func (h *ChallengeHandler) CreateChallenge(w http.ResponseWriter, r *http.Request) {
+	var req usecase.ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := h.challengeUseCase.CreateChallenge(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(challenge)
+}
+
+// UpdateChallenge handles the request to change a challenge.
+func (h *ChallengeHandler) UpdateChallenge(w http.ResponseWriter, r *http.Request) {
+	challengeID, err := strconv.Atoi(chi.URLParam(r, "challengeID"))
+	if err != nil {
+		http.Error(w, "Invalid challenge ID", http.StatusBadRequest)
+		return
+	}
+
+	var req usecase.ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := h.challengeUseCase.UpdateChallenge(r.Context(), challengeID, &req)
+	if err != nil {
+		if err.Error() == "challenge not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(challenge)
+}
+
+// DeleteChallenge handles the request to remove a challenge.
+func (h *ChallengeHandler) DeleteChallenge(w http.ResponseWriter, r *http.Request) {
+	challengeID, err := strconv.Atoi(chi.URLParam(r, "challengeID"))
+	if err != nil {
+		http.Error(w, "Invalid challenge ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.challengeUseCase.DeleteChallenge(r.Context(), challengeID); err != nil {
+		if err.Error() == "challenge not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
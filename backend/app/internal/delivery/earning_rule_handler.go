@@ -0,0 +1,97 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type EarningRuleHandler struct {
+	earningRuleUseCase *usecase.EarningRuleUseCase
+}
+
+func NewEarningRuleHandler(earningRuleUseCase *usecase.EarningRuleUseCase) *EarningRuleHandler {
+	return &EarningRuleHandler{earningRuleUseCase: earningRuleUseCase}
+}
+
+// GetEarningRules handles the request to list every earning rule, active or not.
+func (h *EarningRuleHandler) GetEarningRules(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.earningRuleUseCase.GetEarningRules(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CreateEarningRule handles the request to add an earning rule.
+func (h *EarningRuleHandler) CreateEarningRule(w http.ResponseWriter, r *http.Request) {
+	var req usecase.EarningRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.earningRuleUseCase.CreateEarningRule(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rule)
+}
+
+// UpdateEarningRule handles the request to change an earning rule.
+func (h *EarningRuleHandler) UpdateEarningRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	var req usecase.EarningRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.earningRuleUseCase.UpdateEarningRule(r.Context(), ruleID, &req)
+	if err != nil {
+		if err.Error() == "earning rule not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+// DeleteEarningRule handles the request to remove an earning rule.
+func (h *EarningRuleHandler) DeleteEarningRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := strconv.Atoi(chi.URLParam(r, "ruleID"))
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.earningRuleUseCase.DeleteEarningRule(r.Context(), ruleID); err != nil {
+		if err.Error() == "earning rule not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,32 @@
+package delivery
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+// writeUseCaseError responds with 429 and a Retry-After header for throttled requests,
+// with 409 for actions the user has not consented to or has no early access for, and with status otherwise.
+func writeUseCaseError(w http.ResponseWriter, err error, status int) {
+	var retryErr *usecase.RetryAfterError
+	if errors.As(err, &retryErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryErr.RetryAfter.Seconds()))))
+		http.Error(w, retryErr.Error(), http.StatusTooManyRequests)
+		return
+	}
+	var consentErr *usecase.ConsentRequiredError
+	if errors.As(err, &consentErr) {
+		http.Error(w, consentErr.Error(), http.StatusConflict)
+		return
+	}
+	var earlyAccessErr *usecase.EarlyAccessError
+	if errors.As(err, &earlyAccessErr) {
+		http.Error(w, earlyAccessErr.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}
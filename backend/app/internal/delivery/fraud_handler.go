@@ -0,0 +1,111 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type FraudHandler struct {
+	fraudUseCase *usecase.FraudUseCase
+}
+
+func NewFraudHandler(fraudUseCase *usecase.FraudUseCase) *FraudHandler {
+	return &FraudHandler{fraudUseCase: fraudUseCase}
+}
+
+// GetFraudFlags handles the request for the fraud review queue, filtered by the status query parameter.
+func (h *FraudHandler) GetFraudFlags(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.fraudUseCase.GetFraudFlags(r.Context(), domain.FraudFlagStatus(r.URL.Query().Get("status")))
+	if err != nil {
+		writeFraudError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetFraudFlag handles the request to view a fraud flag.
+func (h *FraudHandler) GetFraudFlag(w http.ResponseWriter, r *http.Request) {
+	flagID, err := strconv.Atoi(chi.URLParam(r, "flagID"))
+	if err != nil {
+		http.Error(w, "Invalid flag ID", http.StatusBadRequest)
+		return
+	}
+
+	flag, err := h.fraudUseCase.GetFraudFlag(r.Context(), flagID)
+	if err != nil {
+		writeFraudError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flag)
+}
+
+// ConfirmFraudFlag handles the request to mark a flag as abuse, optionally freezing the user's points.
+func (h *FraudHandler) ConfirmFraudFlag(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.fraudUseCase.ConfirmFraudFlag)
+}
+
+// DismissFraudFlag handles the request to mark a flag as harmless.
+func (h *FraudHandler) DismissFraudFlag(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, h.fraudUseCase.DismissFraudFlag)
+}
+
+type reviewFraudFlagFunc func(ctx context.Context, flagID, reviewerID int, req *usecase.ReviewFraudFlagRequest) (*domain.FraudFlag, error)
+
+func (h *FraudHandler) review(w http.ResponseWriter, r *http.Request, review reviewFraudFlagFunc) {
+	staff, ok := staffFromContext(w, r)
+	if !ok {
+		return
+	}
+	flagID, err := strconv.Atoi(chi.URLParam(r, "flagID"))
+	if err != nil {
+		http.Error(w, "Invalid flag ID", http.StatusBadRequest)
+		return
+	}
+
+	var req usecase.ReviewFraudFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flag, err := review(r.Context(), flagID, staff.ID, &req)
+	if err != nil {
+		writeFraudError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flag)
+}
+
+func writeFraudError(w http.ResponseWriter, err error) {
+	msg := err.Error()
+	if msg == "fraud flag not found" || msg == "user not found" {
+		http.Error(w, msg, http.StatusNotFound)
+		return
+	}
+	if msg == "flags must be reviewed by someone other than the flagged user" {
+		http.Error(w, msg, http.StatusForbidden)
+		return
+	}
+	if msg == "fraud flag is not open" {
+		http.Error(w, msg, http.StatusConflict)
+		return
+	}
+	if strings.HasPrefix(msg, "failed to") {
+		http.Error(w, msg, http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, msg, http.StatusBadRequest)
+}
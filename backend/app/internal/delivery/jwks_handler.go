@@ -0,0 +1,23 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type JWKSHandler struct {
+	tokenIssuer domain.TokenIssuer
+}
+
+func NewJWKSHandler(tokenIssuer domain.TokenIssuer) *JWKSHandler {
+	return &JWKSHandler{tokenIssuer: tokenIssuer}
+}
+
+// GetJWKS serves the public keys access tokens can be verified with.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(h.tokenIssuer.JWKS())
+}
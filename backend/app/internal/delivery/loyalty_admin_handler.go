@@ -0,0 +1,275 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type LoyaltyAdminHandler struct {
+	loyaltyUseCase *usecase.LoyaltyUseCase
+}
+
+func NewLoyaltyAdminHandler(loyaltyUseCase *usecase.LoyaltyUseCase) *LoyaltyAdminHandler {
+	return &LoyaltyAdminHandler{loyaltyUseCase: loyaltyUseCase}
+}
+
+// GetReconciliation handles the request to report balances that disagree with the ledger.
+func (h *LoyaltyAdminHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	h.reconcile(w, r, false)
+}
+
+// RepairBalances handles the request to reset balances that disagree with the ledger.
+func (h *LoyaltyAdminHandler) RepairBalances(w http.ResponseWriter, r *http.Request) {
+	h.reconcile(w, r, true)
+}
+
+func (h *LoyaltyAdminHandler) reconcile(w http.ResponseWriter, r *http.Request, repair bool) {
+	report, err := h.loyaltyUseCase.ReconcileLedger(r.Context(), repair)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// CreateLoyaltyTier handles the request to add a loyalty tier.
+func (h *LoyaltyAdminHandler) CreateLoyaltyTier(w http.ResponseWriter, r *http.Request) {
+	var req usecase.LoyaltyTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tier, err := h.loyaltyUseCase.CreateLoyaltyTier(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(tier)
+}
+
+// UpdateLoyaltyTier handles the request to change a loyalty tier.
+func (h *LoyaltyAdminHandler) UpdateLoyaltyTier(w http.ResponseWriter, r *http.Request) {
+	tierID, err := strconv.Atoi(chi.URLParam(r, "tierID"))
+	if err != nil {
+		http.Error(w, "Invalid tier ID", http.StatusBadRequest)
+		return
+	}
+
+	var req usecase.LoyaltyTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tier, err := h.loyaltyUseCase.UpdateLoyaltyTier(r.Context(), tierID, &req)
+	if err != nil {
+		if err.Error() == "loyalty tier not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tier)
+}
+
+// DeleteLoyaltyTier handles the request to remove a loyalty tier no member holds.
+func (h *LoyaltyAdminHandler) DeleteLoyaltyTier(w http.ResponseWriter, r *http.Request) {
+	tierID, err := strconv.Atoi(chi.URLParam(r, "tierID"))
+	if err != nil {
+		http.Error(w, "Invalid tier ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.loyaltyUseCase.DeleteLoyaltyTier(r.Context(), tierID); err != nil {
+		if err.Error() == "loyalty tier not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserDiscountCard handles the request to view a member's discount card with its override and audit trail.
+func (h *LoyaltyAdminHandler) GetUserDiscountCard(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.loyaltyUseCase.GetAdminDiscountCard(r.Context(), userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// SetDiscountOverride handles the request to give a member a discount in place of their tier's.
+func (h *LoyaltyAdminHandler) SetDiscountOverride(w http.ResponseWriter, r *http.Request) {
+	userID, adminID, ok := memberTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req usecase.DiscountOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.loyaltyUseCase.SetDiscountOverride(r.Context(), userID, adminID, &req)
+	if err != nil {
+		if err.Error() == "user not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ClearDiscountOverride handles the request to give a member their tier's discount back.
+func (h *LoyaltyAdminHandler) ClearDiscountOverride(w http.ResponseWriter, r *http.Request) {
+	userID, adminID, ok := memberTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req usecase.DiscountOverrideClearRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.loyaltyUseCase.ClearDiscountOverride(r.Context(), userID, adminID, &req); err != nil {
+		if err.Error() == "discount card override not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPointFreeze handles the request to view whether a member's points are frozen, with the audit trail.
+func (h *LoyaltyAdminHandler) GetPointFreeze(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.loyaltyUseCase.GetPointFreeze(r.Context(), userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// FreezePoints handles the request to stop a member from spending their points.
+func (h *LoyaltyAdminHandler) FreezePoints(w http.ResponseWriter, r *http.Request) {
+	userID, adminID, ok := memberTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req usecase.PointFreezeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.loyaltyUseCase.FreezePoints(r.Context(), userID, adminID, &req)
+	if err != nil {
+		if err.Error() == "user not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UnfreezePoints handles the request to let a member spend their points again.
+func (h *LoyaltyAdminHandler) UnfreezePoints(w http.ResponseWriter, r *http.Request) {
+	userID, adminID, ok := memberTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req usecase.PointFreezeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.loyaltyUseCase.UnfreezePoints(r.Context(), userID, adminID, &req); err != nil {
+		if err.Error() == "point freeze not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// memberTarget returns the member being changed and the staff member changing them.
+func memberTarget(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	ctxUserID := r.Context().Value(domain.UserContextKey)
+	if ctxUserID == nil {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+	adminID, err := strconv.Atoi(ctxUserID.(string))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return userID, adminID, true
+}
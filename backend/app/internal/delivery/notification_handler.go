@@ -26,14 +26,16 @@ func (h *NotificationHandler) SendNotification(w http.ResponseWriter, r *http.Re
 
 	// Create a new SendNotificationRequest with UserID from context
 	req := &usecase.SendNotificationRequest{
-		UserID:  reqBody.UserID,
-		Type:    reqBody.Type,
-		Title:   reqBody.Title,
-		Message: reqBody.Message,
+		UserID:           reqBody.UserID,
+		Type:             reqBody.Type,
+		Title:            reqBody.Title,
+		Message:          reqBody.Message,
+		Channels:         reqBody.Channels,
+		EarlyAccessUntil: reqBody.EarlyAccessUntil,
 	}
 
 	if err := h.notificationUseCase.SendNotification(r.Context(), req); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeUseCaseError(w, err, http.StatusInternalServerError)
 		return
 	}
 
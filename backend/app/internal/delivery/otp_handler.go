@@ -0,0 +1,52 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type OTPHandler struct {
+	otpUseCase *usecase.OTPUseCase
+}
+
+func NewOTPHandler(otpUseCase *usecase.OTPUseCase) *OTPHandler {
+	return &OTPHandler{otpUseCase: otpUseCase}
+}
+
+// RequestOTP handles the request to send a login code by SMS.
+func (h *OTPHandler) RequestOTP(w http.ResponseWriter, r *http.Request) {
+	var req usecase.RequestOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.otpUseCase.RequestOTP(r.Context(), &req)
+	if err != nil {
+		writeUseCaseError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// VerifyOTP handles the request to log in with a code received by SMS.
+func (h *OTPHandler) VerifyOTP(w http.ResponseWriter, r *http.Request) {
+	var req usecase.VerifyOTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.otpUseCase.VerifyOTP(r.Context(), &req, sessionMetadata(r))
+	if err != nil {
+		writeUseCaseError(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
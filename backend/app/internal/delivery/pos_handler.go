@@ -0,0 +1,59 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+// POSHandler serves in-store terminals, which authenticate with an API key.
+type POSHandler struct {
+	posUseCase  *usecase.POSUseCase
+	cartUseCase *usecase.CartUseCase
+}
+
+func NewPOSHandler(posUseCase *usecase.POSUseCase, cartUseCase *usecase.CartUseCase) *POSHandler {
+	return &POSHandler{posUseCase: posUseCase, cartUseCase: cartUseCase}
+}
+
+// ScanCustomer handles the request to identify a customer by the rotating code on their discount card.
+func (h *POSHandler) ScanCustomer(w http.ResponseWriter, r *http.Request) {
+	var req usecase.ScanCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.posUseCase.ScanCustomer(r.Context(), &req)
+	if err != nil {
+		if err.Error() == "store not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RecordStoreOrder handles the request to record a purchase made at the till for a customer.
+func (h *POSHandler) RecordStoreOrder(w http.ResponseWriter, r *http.Request) {
+	var req usecase.RecordStoreOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.cartUseCase.RecordStoreOrder(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,124 @@
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type PrivacyHandler struct {
+	privacyUseCase *usecase.PrivacyUseCase
+}
+
+func NewPrivacyHandler(privacyUseCase *usecase.PrivacyUseCase) *PrivacyHandler {
+	return &PrivacyHandler{privacyUseCase: privacyUseCase}
+}
+
+// GetConsentTexts handles the request for the current consent wording.
+func (h *PrivacyHandler) GetConsentTexts(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.privacyUseCase.GetConsentTexts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// PublishConsentText handles the request to publish a new version of a consent text.
+func (h *PrivacyHandler) PublishConsentText(w http.ResponseWriter, r *http.Request) {
+	var req usecase.PublishConsentTextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.privacyUseCase.PublishConsentText(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetConsents handles the request to view the user's consents.
+func (h *PrivacyHandler) GetConsents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.privacyUseCase.GetConsents(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UpdateConsents handles the request to give or withdraw consents.
+func (h *PrivacyHandler) UpdateConsents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req usecase.UpdateConsentsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.privacyUseCase.UpdateConsents(r.Context(), userID, &req, sessionMetadata(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ExportUserData handles the request to download everything stored about the user,
+// as a ZIP archive by default or as a single JSON document with ?format=json.
+func (h *PrivacyHandler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		resp, err := h.privacyUseCase.ExportUserData(r.Context(), userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	archive, err := h.privacyUseCase.ExportUserDataArchive(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("kingsman-data-%s-%s.zip", userID, time.Now().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(archive)
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
 	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
 )
 
@@ -66,7 +67,10 @@ func (h *ProductHandler) GetProductCatalog(w http.ResponseWriter, r *http.Reques
 		offset = o
 	}
 
+	// Terminals signed in with an API key have no user and see the whole catalog
+	userID, _ := r.Context().Value(domain.UserContextKey).(string)
 	req := &usecase.GetProductCatalogRequest{
+		UserID:     userID,
 		CategoryID: &categoryID,
 		MinPrice:   minPrice,
 		MaxPrice:   maxPrice,
@@ -93,7 +97,8 @@ func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	resp, err := h.productUseCase.GetProductByID(r.Context(), productID)
+	userID, _ := r.Context().Value(domain.UserContextKey).(string)
+	resp, err := h.productUseCase.GetProductByID(r.Context(), userID, productID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -0,0 +1,66 @@
+package delivery
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+// RateLimitConfig describes the limit applied to a group of routes.
+type RateLimitConfig struct {
+	Name  string  // Separates the buckets of different route groups
+	Rate  float64 // Requests per second allowed on average
+	Burst int     // Requests allowed at once before the rate applies
+	// Key identifies who the limit applies to; KeyByIP when nil.
+	Key func(r *http.Request) string
+}
+
+// KeyByIP limits each client address separately.
+func KeyByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// KeyByUser limits each authenticated account or service account separately, falling back to the client address.
+// It must run after the authentication middleware.
+func KeyByUser(r *http.Request) string {
+	if userID, ok := r.Context().Value(domain.UserContextKey).(string); ok && userID != "" {
+		return "user:" + userID
+	}
+	if accountID, ok := r.Context().Value(domain.ServiceAccountContextKey).(string); ok && accountID != "" {
+		return "service:" + accountID
+	}
+	return KeyByIP(r)
+}
+
+// RateLimit throttles requests with a token bucket per key, answering 429 with a Retry-After header.
+// If the store fails, requests are let through rather than taking the API down with it.
+func RateLimit(store domain.RateLimitStore, cfg RateLimitConfig) func(http.Handler) http.Handler {
+	key := cfg.Key
+	if key == nil {
+		key = KeyByIP
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			allowed, retryAfter, err := store.Take(r.Context(), "ratelimit:"+cfg.Name+":"+key(r), cfg.Rate, cfg.Burst)
+			if err != nil {
+				log.Printf("Rate limit store error, letting request through: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !allowed {
+				writeUseCaseError(w, &usecase.RetryAfterError{
+					Message:    fmt.Sprintf("too many requests, retry in %d seconds", int(math.Ceil(retryAfter.Seconds()))),
+					RetryAfter: retryAfter,
+				}, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
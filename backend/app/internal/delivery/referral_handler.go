@@ -0,0 +1,41 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type ReferralHandler struct {
+	referralUseCase *usecase.ReferralUseCase
+}
+
+func NewReferralHandler(referralUseCase *usecase.ReferralUseCase) *ReferralHandler {
+	return &ReferralHandler{referralUseCase: referralUseCase}
+}
+
+// GetReferralStats handles the request for the user's invite code and how their invitations are doing.
+func (h *ReferralHandler) GetReferralStats(w http.ResponseWriter, r *http.Request) {
+	ctxUserID := r.Context().Value(domain.UserContextKey)
+	if ctxUserID == nil {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := strconv.Atoi(ctxUserID.(string))
+	if err != nil {
+		http.Error(w, "Invalid User ID format in JWT", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.referralUseCase.GetReferralStats(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
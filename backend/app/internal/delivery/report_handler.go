@@ -0,0 +1,144 @@
+package delivery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+// ReportHandler serves the loyalty program reports as JSON, or as CSV with ?format=csv.
+type ReportHandler struct {
+	reportUseCase *usecase.ReportUseCase
+}
+
+func NewReportHandler(reportUseCase *usecase.ReportUseCase) *ReportHandler {
+	return &ReportHandler{reportUseCase: reportUseCase}
+}
+
+// GetLiability handles the request for the outstanding points and their value, by tier.
+func (h *ReportHandler) GetLiability(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reportUseCase.GetLiabilityReport(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	records := [][]string{{"tier_id", "tier_name", "members", "points", "point_value_multiplier", "value"}}
+	for _, t := range report.Tiers {
+		records = append(records, []string{strconv.Itoa(t.TierID), t.TierName, strconv.Itoa(t.Members), strconv.Itoa(t.Points),
+			formatFloat(t.PointValueMultiplier), formatFloat(t.Value)})
+	}
+	records = append(records, []string{"", "total", strconv.Itoa(report.Members), strconv.Itoa(report.OutstandingPoints), "", formatFloat(report.Liability)})
+	writeReport(w, r, "points-liability", report, records)
+}
+
+// GetPointsFlow handles the request for points earned and burned by month.
+func (h *ReportHandler) GetPointsFlow(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reportUseCase.GetPointsFlowReport(r.Context(), reportPeriod(r))
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+
+	records := [][]string{{"month", "earned", "redeemed", "expired", "adjusted", "reversed", "net", "burn_rate"}}
+	for _, m := range report.Months {
+		records = append(records, []string{m.Month, strconv.Itoa(m.Earned), strconv.Itoa(m.Redeemed), strconv.Itoa(m.Expired),
+			strconv.Itoa(m.Adjusted), strconv.Itoa(m.Reversed), strconv.Itoa(m.Net), formatFloat(m.BurnRate)})
+	}
+	writeReport(w, r, "points-flow", report, records)
+}
+
+// GetExpiringPoints handles the request for the forecast of points expiring by month.
+func (h *ReportHandler) GetExpiringPoints(w http.ResponseWriter, r *http.Request) {
+	months := 0
+	if v := r.URL.Query().Get("months"); v != "" {
+		var err error
+		if months, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid months", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.reportUseCase.GetExpiringPointsReport(r.Context(), months)
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+
+	records := [][]string{{"month", "points", "members"}}
+	for _, m := range report.Months {
+		records = append(records, []string{m.Month, strconv.Itoa(m.Points), strconv.Itoa(m.Members)})
+	}
+	writeReport(w, r, "expiring-points", report, records)
+}
+
+// GetTierDistribution handles the request for the members of each tier at the end of each month.
+func (h *ReportHandler) GetTierDistribution(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reportUseCase.GetTierDistributionReport(r.Context(), reportPeriod(r))
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+
+	records := [][]string{{"date", "tier_id", "tier_name", "members"}}
+	for _, s := range report.Snapshots {
+		tierID := ""
+		if s.TierID != nil {
+			tierID = strconv.Itoa(*s.TierID)
+		}
+		records = append(records, []string{s.Date, tierID, s.TierName, strconv.Itoa(s.Members)})
+	}
+	writeReport(w, r, "tier-distribution", report, records)
+}
+
+// GetActiveMembers handles the request for active member counts by month.
+func (h *ReportHandler) GetActiveMembers(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reportUseCase.GetActiveMembersReport(r.Context(), reportPeriod(r))
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+
+	records := [][]string{{"month", "active", "purchasing", "earning", "redeeming", "new_members"}}
+	for _, m := range report.Months {
+		records = append(records, []string{m.Month, strconv.Itoa(m.Active), strconv.Itoa(m.Purchasing), strconv.Itoa(m.Earning),
+			strconv.Itoa(m.Redeeming), strconv.Itoa(m.NewMembers)})
+	}
+	writeReport(w, r, "active-members", report, records)
+}
+
+func reportPeriod(r *http.Request) usecase.ReportPeriod {
+	return usecase.ReportPeriod{From: r.URL.Query().Get("from"), To: r.URL.Query().Get("to")}
+}
+
+// writeReport writes the report as JSON, or its records as a CSV attachment if the request asks for CSV.
+func writeReport(w http.ResponseWriter, r *http.Request, name string, report interface{}, records [][]string) {
+	if r.URL.Query().Get("format") != "csv" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv"`, name, time.Now().Format("2006-01-02")))
+	writer := csv.NewWriter(w)
+	writer.WriteAll(records)
+}
+
+func writeReportError(w http.ResponseWriter, err error) {
+	if strings.HasPrefix(err.Error(), "failed to") {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,145 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type ServiceAccountHandler struct {
+	serviceAccountUseCase *usecase.ServiceAccountUseCase
+}
+
+func NewServiceAccountHandler(serviceAccountUseCase *usecase.ServiceAccountUseCase) *ServiceAccountHandler {
+	return &ServiceAccountHandler{serviceAccountUseCase: serviceAccountUseCase}
+}
+
+// GetServiceAccounts handles the request to list service accounts and their keys.
+func (h *ServiceAccountHandler) GetServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.serviceAccountUseCase.GetServiceAccounts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CreateServiceAccount handles the request to register a terminal or partner system.
+func (h *ServiceAccountHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	var req usecase.CreateServiceAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.serviceAccountUseCase.CreateServiceAccount(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// DisableServiceAccount handles the request to disable a service account and all of its keys.
+func (h *ServiceAccountHandler) DisableServiceAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.Atoi(chi.URLParam(r, "accountID"))
+	if err != nil {
+		http.Error(w, "Invalid service account ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.serviceAccountUseCase.DisableServiceAccount(r.Context(), accountID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateAPIKey handles the request to issue a new key. The key is returned only in this response.
+func (h *ServiceAccountHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.Atoi(chi.URLParam(r, "accountID"))
+	if err != nil {
+		http.Error(w, "Invalid service account ID", http.StatusBadRequest)
+		return
+	}
+
+	var req usecase.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.serviceAccountUseCase.CreateAPIKey(r.Context(), accountID, &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RotateAPIKey handles the request to replace a key, keeping the old one valid for a grace period.
+func (h *ServiceAccountHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	accountID, keyID, ok := apiKeyURLParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req usecase.RotateAPIKeyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp, err := h.serviceAccountUseCase.RotateAPIKey(r.Context(), accountID, keyID, &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// RevokeAPIKey handles the request to revoke a key immediately.
+func (h *ServiceAccountHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	accountID, keyID, ok := apiKeyURLParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.serviceAccountUseCase.RevokeAPIKey(r.Context(), accountID, keyID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiKeyURLParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	accountID, err := strconv.Atoi(chi.URLParam(r, "accountID"))
+	if err != nil {
+		http.Error(w, "Invalid service account ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	keyID, err := strconv.Atoi(chi.URLParam(r, "keyID"))
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return accountID, keyID, true
+}
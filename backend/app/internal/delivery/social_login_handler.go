@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type SocialLoginHandler struct {
+	socialLoginUseCase *usecase.SocialLoginUseCase
+}
+
+func NewSocialLoginHandler(socialLoginUseCase *usecase.SocialLoginUseCase) *SocialLoginHandler {
+	return &SocialLoginHandler{socialLoginUseCase: socialLoginUseCase}
+}
+
+// StartLogin handles the request for a provider's authorization URL to log in with.
+func (h *SocialLoginHandler) StartLogin(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.socialLoginUseCase.StartLogin(r.Context(), chi.URLParam(r, "provider"), "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CompleteLogin handles the provider's redirect back with an authorization code.
+func (h *SocialLoginHandler) CompleteLogin(w http.ResponseWriter, r *http.Request) {
+	var req usecase.SocialLoginCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.socialLoginUseCase.CompleteLogin(r.Context(), chi.URLParam(r, "provider"), &req, sessionMetadata(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetIdentities handles the request to list the user's linked accounts.
+func (h *SocialLoginHandler) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.socialLoginUseCase.GetIdentities(r.Context(), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// StartLink handles the request for a provider's authorization URL to link an account with.
+func (h *SocialLoginHandler) StartLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.socialLoginUseCase.StartLogin(r.Context(), chi.URLParam(r, "provider"), userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CompleteLink handles the provider's redirect back when linking an account.
+func (h *SocialLoginHandler) CompleteLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req usecase.SocialLoginCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.socialLoginUseCase.CompleteLink(r.Context(), userID, chi.URLParam(r, "provider"), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UnlinkIdentity handles the request to remove a linked account.
+func (h *SocialLoginHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	err := h.socialLoginUseCase.UnlinkIdentity(r.Context(), userID, chi.URLParam(r, "provider"))
+	if err != nil {
+		if err.Error() == "identity not found" {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
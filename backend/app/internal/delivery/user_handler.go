@@ -2,10 +2,11 @@ package delivery
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"strconv"
 
-	// "github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5"
 	"github.com/mkbagandov/kingsman/backend/app/internal/domain" // Import the domain package to access UserContextKey
 	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
 )
@@ -13,10 +14,11 @@ import (
 type UserHandler struct {
 	userUseCase    *usecase.UserUseCase
 	loyaltyUseCase *usecase.LoyaltyUseCase
+	sessionUseCase *usecase.SessionUseCase
 }
 
-func NewUserHandler(userUseCase *usecase.UserUseCase, loyaltyUseCase *usecase.LoyaltyUseCase) *UserHandler {
-	return &UserHandler{userUseCase: userUseCase, loyaltyUseCase: loyaltyUseCase}
+func NewUserHandler(userUseCase *usecase.UserUseCase, loyaltyUseCase *usecase.LoyaltyUseCase, sessionUseCase *usecase.SessionUseCase) *UserHandler {
+	return &UserHandler{userUseCase: userUseCase, loyaltyUseCase: loyaltyUseCase, sessionUseCase: sessionUseCase}
 }
 
 func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
@@ -26,7 +28,7 @@ func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.userUseCase.RegisterUser(r.Context(), &req)
+	resp, err := h.userUseCase.RegisterUser(r.Context(), &req, sessionMetadata(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -43,9 +45,9 @@ func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.userUseCase.LoginUser(r.Context(), &req)
+	resp, err := h.userUseCase.LoginUser(r.Context(), &req, sessionMetadata(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		writeUseCaseError(w, err, http.StatusUnauthorized)
 		return
 	}
 
@@ -53,17 +55,17 @@ func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
-	ctxUserID := r.Context().Value(domain.UserContextKey)
-	if ctxUserID == nil {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+// RefreshToken handles the request to exchange a refresh token for a new token pair.
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req usecase.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	userID := ctxUserID.(string)
 
-	resp, err := h.userUseCase.GetUserProfile(r.Context(), userID)
+	resp, err := h.sessionUseCase.RefreshSession(r.Context(), &req, sessionMetadata(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
@@ -71,22 +73,32 @@ func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-// GetUserLoyaltyProfile handles the request to get a user's loyalty profile.
-func (h *UserHandler) GetUserLoyaltyProfile(w http.ResponseWriter, r *http.Request) {
-	ctxUserID := r.Context().Value(domain.UserContextKey)
-	if ctxUserID == nil {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+// Logout handles the request to end the current session.
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := r.Context().Value(domain.SessionContextKey).(string)
+	if !ok || sessionID == "" {
+		http.Error(w, "Session ID not found in context", http.StatusUnauthorized)
 		return
 	}
-	userIDStr := ctxUserID.(string)
 
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		http.Error(w, "Invalid User ID format in JWT", http.StatusBadRequest)
+	if err := h.sessionUseCase.Logout(r.Context(), sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	resp, err := h.loyaltyUseCase.GetUserLoyaltyProfile(r.Context(), userID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSessions handles the request to list the user's active sessions.
+func (h *UserHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := r.Context().Value(domain.SessionContextKey).(string)
+
+	resp, err := h.sessionUseCase.GetSessions(r.Context(), userID, sessionID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -96,77 +108,153 @@ func (h *UserHandler) GetUserLoyaltyProfile(w http.ResponseWriter, r *http.Reque
 	json.NewEncoder(w).Encode(resp)
 }
 
-type AddLoyaltyPointsRequest struct {
-	Points int    `json:"points"`
-	Type   string `json:"type"`
+// RevokeSession handles the request to terminate one of the user's sessions.
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "sessionID")
+	if sessionID == "" {
+		http.Error(w, "Session ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sessionUseCase.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// AddLoyaltyPoints handles the request to add loyalty points to a user.
-func (h *UserHandler) AddLoyaltyPoints(w http.ResponseWriter, r *http.Request) {
+// RevokeOtherSessions handles the request to terminate every session except the current one.
+func (h *UserHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := r.Context().Value(domain.SessionContextKey).(string)
+
+	if err := h.sessionUseCase.RevokeOtherSessions(r.Context(), userID, sessionID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	ctxUserID := r.Context().Value(domain.UserContextKey)
 	if ctxUserID == nil {
 		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
 		return
 	}
-	userIDStr := ctxUserID.(string)
+	userID := ctxUserID.(string)
 
-	userID, err := strconv.Atoi(userIDStr)
+	resp, err := h.userUseCase.GetUserProfile(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Invalid User ID format in JWT", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	var req AddLoyaltyPointsRequest
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// UpdateUserProfile handles the request to change the user's name, phone number or email.
+func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req usecase.UpdateUserProfileRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	req.ID = userID
 
-	if err := h.loyaltyUseCase.AddLoyaltyPoints(r.Context(), userID, req.Points, req.Type); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	resp, err := h.userUseCase.UpdateUserProfile(r.Context(), &req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-}
-
-type AddLoyaltyActivityRequest struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
-// AddLoyaltyActivity handles the request to record a loyalty activity for a user.
-func (h *UserHandler) AddLoyaltyActivity(w http.ResponseWriter, r *http.Request) {
-	ctxUserID := r.Context().Value(domain.UserContextKey)
-	if ctxUserID == nil {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+// ChangePassword handles the request to change the user's password.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	userIDStr := ctxUserID.(string)
+	sessionID, _ := r.Context().Value(domain.SessionContextKey).(string)
 
-	userID, err := strconv.Atoi(userIDStr)
-	if err != nil {
-		http.Error(w, "Invalid User ID format in JWT", http.StatusBadRequest)
+	var req usecase.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var req AddLoyaltyActivityRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := h.userUseCase.ChangePassword(r.Context(), userID, sessionID, &req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.loyaltyUseCase.AddLoyaltyActivity(r.Context(), userID, req.Type, req.Description); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAccount handles the request to delete the user's account.
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessionID, _ := r.Context().Value(domain.SessionContextKey).(string)
+
+	// The body is optional for accounts without a password that signed in recently
+	var req usecase.DeleteAccountRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.userUseCase.DeleteAccount(r.Context(), userID, sessionID, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-// GetLoyaltyTiers handles the request to get all loyalty tiers.
-func (h *UserHandler) GetLoyaltyTiers(w http.ResponseWriter, r *http.Request) {
-	resp, err := h.loyaltyUseCase.GetLoyaltyTiers(r.Context())
+// GetUserLoyaltyProfile handles the request to get a user's loyalty profile.
+func (h *UserHandler) GetUserLoyaltyProfile(w http.ResponseWriter, r *http.Request) {
+	ctxUserID := r.Context().Value(domain.UserContextKey)
+	if ctxUserID == nil {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userIDStr := ctxUserID.(string)
+
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		http.Error(w, "Invalid User ID format in JWT", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := h.loyaltyUseCase.GetUserLoyaltyProfile(r.Context(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -176,18 +264,37 @@ func (h *UserHandler) GetLoyaltyTiers(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
-// GetUserDiscountCard handles the request to get a user's discount card information.
-func (h *UserHandler) GetUserDiscountCard(w http.ResponseWriter, r *http.Request) {
+// GetLoyaltyTransactions handles the request to get a page of the user's points statement.
+func (h *UserHandler) GetLoyaltyTransactions(w http.ResponseWriter, r *http.Request) {
 	ctxUserID := r.Context().Value(domain.UserContextKey)
 	if ctxUserID == nil {
 		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
 		return
 	}
-	userID := ctxUserID.(string)
+	userID, err := strconv.Atoi(ctxUserID.(string))
+	if err != nil {
+		http.Error(w, "Invalid User ID format in JWT", http.StatusBadRequest)
+		return
+	}
 
-	resp, err := h.userUseCase.GetUserDiscountCard(r.Context(), userID)
+	limit := 20 // Default limit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	offset := 0 // Default offset
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp, err := h.loyaltyUseCase.GetLoyaltyTransactions(r.Context(), userID, limit, offset)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -195,35 +302,42 @@ func (h *UserHandler) GetUserDiscountCard(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(resp)
 }
 
-type UpdateUserDiscountCardRequest struct {
-	DiscountLevel       int     `json:"discount_level"`
-	ProgressToNextLevel float64 `json:"progress_to_next_level"`
+// GetLoyaltyTiers handles the request to get all loyalty tiers.
+func (h *UserHandler) GetLoyaltyTiers(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.loyaltyUseCase.GetLoyaltyTiers(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
-// UpdateUserDiscountCard handles the request to update a user's discount card information.
-func (h *UserHandler) UpdateUserDiscountCard(w http.ResponseWriter, r *http.Request) {
+// GetUserDiscountCard handles the request to get a user's discount card information.
+func (h *UserHandler) GetUserDiscountCard(w http.ResponseWriter, r *http.Request) {
 	ctxUserID := r.Context().Value(domain.UserContextKey)
 	if ctxUserID == nil {
 		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
 		return
 	}
-	userID := ctxUserID.(string)
-
-	var req UpdateUserDiscountCardRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	userID, err := strconv.Atoi(ctxUserID.(string))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.userUseCase.UpdateUserDiscountCard(r.Context(), userID, req.DiscountLevel, req.ProgressToNextLevel); err != nil {
+	resp, err := h.loyaltyUseCase.GetDiscountCard(r.Context(), userID)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
-// GetUserQRCode handles the request to get a user's QR code image.
+// GetUserQRCode handles the request to get the image of the user's current card code.
 func (h *UserHandler) GetUserQRCode(w http.ResponseWriter, r *http.Request) {
 	ctxUserID := r.Context().Value(domain.UserContextKey)
 	if ctxUserID == nil {
@@ -232,12 +346,33 @@ func (h *UserHandler) GetUserQRCode(w http.ResponseWriter, r *http.Request) {
 	}
 	userID := ctxUserID.(string)
 
-	qrCodeImage, err := h.userUseCase.GenerateQRCodeImage(r.Context(), userID)
+	qrCodeImage, expiresAt, err := h.userUseCase.GenerateQRCodeImage(r.Context(), userID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// The code rotates, so clients fetch a new image once this one expires
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Expires", expiresAt.UTC().Format(http.TimeFormat))
 	w.Header().Set("Content-Type", "image/png")
 	w.Write(qrCodeImage)
 }
+
+// sessionMetadata describes the client that sent the request.
+func sessionMetadata(r *http.Request) *usecase.SessionMetadata {
+	device := r.UserAgent()
+	if len(device) > 255 {
+		device = device[:255]
+	}
+	return &usecase.SessionMetadata{Device: device, IPAddress: ClientIP(r)}
+}
+
+// ClientIP returns the request's remote address without the port.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,81 @@
+package delivery
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type VerificationHandler struct {
+	verificationUseCase *usecase.VerificationUseCase
+}
+
+func NewVerificationHandler(verificationUseCase *usecase.VerificationUseCase) *VerificationHandler {
+	return &VerificationHandler{verificationUseCase: verificationUseCase}
+}
+
+// ForgotPassword handles the request to email a password reset link.
+func (h *VerificationHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req usecase.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.verificationUseCase.RequestPasswordReset(r.Context(), &req); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword handles the request to set a new password with a reset link.
+func (h *VerificationHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req usecase.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.verificationUseCase.ResetPassword(r.Context(), &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendEmailVerification handles the request to resend the email verification link.
+func (h *VerificationHandler) SendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(domain.UserContextKey).(string)
+	if !ok || userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.verificationUseCase.SendEmailVerification(r.Context(), userID); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// VerifyEmail handles the request to confirm an email address with a verification link.
+func (h *VerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req usecase.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.verificationUseCase.VerifyEmail(r.Context(), &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,168 @@
+package delivery
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"github.com/mkbagandov/kingsman/backend/app/internal/usecase"
+)
+
+type WalletHandler struct {
+	walletUseCase *usecase.WalletUseCase
+}
+
+func NewWalletHandler(walletUseCase *usecase.WalletUseCase) *WalletHandler {
+	return &WalletHandler{walletUseCase: walletUseCase}
+}
+
+// GetDiscountCardPass handles the request for the user's discount card as a wallet pass:
+// a .pkpass file for ?platform=apple or a "Save to Google Wallet" link for ?platform=google.
+func (h *WalletHandler) GetDiscountCardPass(w http.ResponseWriter, r *http.Request) {
+	ctxUserID := r.Context().Value(domain.UserContextKey)
+	if ctxUserID == nil {
+		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		return
+	}
+	userID, err := strconv.Atoi(ctxUserID.(string))
+	if err != nil {
+		http.Error(w, "Invalid User ID format in JWT", http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Query().Get("platform") {
+	case "apple":
+		pass, err := h.walletUseCase.GetApplePass(r.Context(), userID)
+		if err != nil {
+			writeWalletError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.apple.pkpass")
+		w.Header().Set("Content-Disposition", `attachment; filename="kingsman.pkpass"`)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write(pass)
+	case "google":
+		resp, err := h.walletUseCase.GetGooglePass(r.Context(), userID)
+		if err != nil {
+			writeWalletError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	default:
+		http.Error(w, "platform must be apple or google", http.StatusBadRequest)
+	}
+}
+
+// The handlers below implement the web service Apple Wallet calls to keep passes current.
+// Devices authenticate with the "ApplePass <token>" header carrying the token from pass.json.
+
+// RegisterDevice handles a device subscribing to updates of a pass.
+func (h *WalletHandler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PushToken string `json:"pushToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.walletUseCase.RegisterDevice(r.Context(), chi.URLParam(r, "deviceID"), chi.URLParam(r, "passTypeID"), chi.URLParam(r, "serialNumber"), applePassToken(r), req.PushToken)
+	if err != nil {
+		writeWalletError(w, err)
+		return
+	}
+	if created {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// UnregisterDevice handles a device dropping a pass.
+func (h *WalletHandler) UnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	err := h.walletUseCase.UnregisterDevice(r.Context(), chi.URLParam(r, "deviceID"), chi.URLParam(r, "passTypeID"), chi.URLParam(r, "serialNumber"), applePassToken(r))
+	if err != nil {
+		writeWalletError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetUpdatedSerials handles a device asking which of its passes changed after it got a push.
+func (h *WalletHandler) GetUpdatedSerials(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.walletUseCase.GetUpdatedSerials(r.Context(), chi.URLParam(r, "deviceID"), chi.URLParam(r, "passTypeID"), r.URL.Query().Get("passesUpdatedSince"))
+	if err != nil {
+		writeWalletError(w, err)
+		return
+	}
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetLatestPass handles a device downloading the current version of a pass.
+func (h *WalletHandler) GetLatestPass(w http.ResponseWriter, r *http.Request) {
+	pass, updatedAt, err := h.walletUseCase.GetLatestPass(r.Context(), chi.URLParam(r, "passTypeID"), chi.URLParam(r, "serialNumber"), applePassToken(r))
+	if err != nil {
+		writeWalletError(w, err)
+		return
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !updatedAt.Truncate(time.Second).After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.pkpass")
+	w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+	w.Write(pass)
+}
+
+// Log handles the error messages devices report about passes, which only end up in the server log.
+func (h *WalletHandler) Log(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Logs []string `json:"logs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, message := range req.Logs {
+		log.Printf("Apple Wallet: %s", message)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func applePassToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "ApplePass ")
+}
+
+// writeWalletError responds with 501 for a wallet the server has no credentials for,
+// with 401 for a wrong pass token, with 404 for unknown passes and with 400 for malformed requests.
+func writeWalletError(w http.ResponseWriter, err error) {
+	if err.Error() == "apple wallet is not configured" || err.Error() == "google wallet is not configured" {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err.Error() == "invalid wallet pass token" {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if err.Error() == "wallet pass not found" || err.Error() == "user not found" {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err.Error() == "pushToken is required" || err.Error() == "invalid passesUpdatedSince" {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
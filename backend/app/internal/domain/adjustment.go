@@ -0,0 +1,66 @@
+package domain
+
+// AdjustmentReason is why staff changed a member's points by hand.
+type AdjustmentReason string
+
+const (
+	AdjustmentGoodwill       AdjustmentReason = "goodwill"        // A credit to make up for a bad experience
+	AdjustmentMissedPurchase AdjustmentReason = "missed_purchase" // Points a purchase should have earned but did not
+	AdjustmentCorrection     AdjustmentReason = "correction"      // Points credited or taken by mistake
+	AdjustmentOther          AdjustmentReason = "other"           // Explained in the note
+)
+
+// IsValid reports whether the reason is one of the known reasons.
+func (r AdjustmentReason) IsValid() bool {
+	switch r {
+	case AdjustmentGoodwill, AdjustmentMissedPurchase, AdjustmentCorrection, AdjustmentOther:
+		return true
+	}
+	return false
+}
+
+// AdjustmentStatus is how far an adjustment has got.
+type AdjustmentStatus string
+
+const (
+	AdjustmentPending  AdjustmentStatus = "pending" // Waiting for a second staff member to approve it
+	AdjustmentApproved AdjustmentStatus = "approved"
+	AdjustmentRejected AdjustmentStatus = "rejected"
+)
+
+// LoyaltyAdjustment is points added to or taken from a member by staff. An approved adjustment is posted
+// to the ledger as an adjust entry; adjustments never count towards the member's tier.
+type LoyaltyAdjustment struct {
+	ID             int              `json:"id"`
+	UserID         int              `json:"user_id"`
+	Points         int              `json:"points"` // Negative to take points away
+	ReasonCode     AdjustmentReason `json:"reason_code"`
+	Note           string           `json:"note,omitempty"`
+	Status         AdjustmentStatus `json:"status"`
+	RequestedBy    *int             `json:"requested_by,omitempty"` // Empty once that staff account is deleted
+	ReviewedBy     *int             `json:"reviewed_by,omitempty"`
+	ReviewNote     string           `json:"review_note,omitempty"`
+	EntryID        *int             `json:"entry_id,omitempty"`
+	IdempotencyKey string           `json:"-"`
+	CreatedAt      string           `json:"created_at"`
+	ReviewedAt     *string          `json:"reviewed_at,omitempty"`
+}
+
+// AdjustmentEventAction is a step in the life of an adjustment.
+type AdjustmentEventAction string
+
+const (
+	AdjustmentRequested     AdjustmentEventAction = "requested"
+	AdjustmentEventApproved AdjustmentEventAction = "approved"
+	AdjustmentEventRejected AdjustmentEventAction = "rejected"
+)
+
+// LoyaltyAdjustmentEvent records a step of an adjustment, who took it and why.
+type LoyaltyAdjustmentEvent struct {
+	ID           int                   `json:"id"`
+	AdjustmentID int                   `json:"adjustment_id"`
+	ActorID      *int                  `json:"actor_id,omitempty"`
+	Action       AdjustmentEventAction `json:"action"`
+	Note         string                `json:"note,omitempty"`
+	CreatedAt    string                `json:"created_at"`
+}
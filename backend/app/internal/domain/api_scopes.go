@@ -0,0 +1,37 @@
+package domain
+
+// APIScope names an action an API key is allowed to perform on behalf of its service account.
+type APIScope string
+
+const (
+	ScopeLoyaltyScan  APIScope = "loyalty:scan"
+	ScopeOrdersCreate APIScope = "orders:create"
+	ScopeCatalogRead  APIScope = "catalog:read"
+)
+
+// APIScopes lists every scope a key can be issued with.
+var APIScopes = []APIScope{
+	ScopeLoyaltyScan,
+	ScopeOrdersCreate,
+	ScopeCatalogRead,
+}
+
+// IsValid reports whether the scope is one of the known scopes.
+func (s APIScope) IsValid() bool {
+	for _, scope := range APIScopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the scope is among the granted ones.
+func HasScope(granted []APIScope, scope APIScope) bool {
+	for _, s := range granted {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package domain
+
+// ChallengeGoal is what a challenge counts towards its target.
+type ChallengeGoal string
+
+const (
+	ChallengeGoalOrders         ChallengeGoal = "orders"       // Paid orders
+	ChallengeGoalItems          ChallengeGoal = "items"        // Units bought
+	ChallengeGoalAmountSpent    ChallengeGoal = "amount_spent" // At list price, before discounts
+	ChallengeGoalStoreVisits    ChallengeGoal = "store_visits" // Days spent in a store, counted once per store
+	ChallengeGoalDistinctStores ChallengeGoal = "distinct_stores"
+)
+
+// ChallengePeriod is how often a challenge starts over.
+type ChallengePeriod string
+
+const (
+	ChallengeOnce    ChallengePeriod = "once"
+	ChallengeWeekly  ChallengePeriod = "weekly" // Weeks start on Monday
+	ChallengeMonthly ChallengePeriod = "monthly"
+)
+
+// Challenge is a goal members complete for a badge and points. Progress counts what the member
+// did within the current period and the campaign dates; a challenge is completed once per period.
+type Challenge struct {
+	ID           int             `json:"id"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	Active       bool            `json:"active"`
+	Goal         ChallengeGoal   `json:"goal"`
+	Target       float64         `json:"target"`
+	CategoryID   *int            `json:"category_id,omitempty"` // Counts only purchases from the category
+	Period       ChallengePeriod `json:"period"`
+	StartsAt     *string         `json:"starts_at,omitempty"`
+	EndsAt       *string         `json:"ends_at,omitempty"`
+	BadgeName    string          `json:"badge_name"`
+	BadgeIconURL string          `json:"badge_icon_url,omitempty"`
+	RewardPoints int             `json:"reward_points"`
+	CreatedAt    string          `json:"created_at"`
+	UpdatedAt    string          `json:"updated_at"`
+}
+
+// UserBadge is a badge a member was awarded for completing a challenge.
+// It keeps the badge's name and icon as awarded, even if the challenge changes or is deleted.
+type UserBadge struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"user_id"`
+	ChallengeID *int   `json:"challenge_id,omitempty"`
+	PeriodStart string `json:"period_start"`
+	Name        string `json:"name"`
+	IconURL     string `json:"icon_url,omitempty"`
+	Points      int    `json:"points"`
+	AwardedAt   string `json:"awarded_at"`
+}
@@ -0,0 +1,69 @@
+package domain
+
+// ConsentPurpose names a kind of processing the user may agree to, as required by 152-FZ and GDPR.
+type ConsentPurpose string
+
+const (
+	ConsentMarketingSMS       ConsentPurpose = "marketing_sms"
+	ConsentMarketingEmail     ConsentPurpose = "marketing_email"
+	ConsentPush               ConsentPurpose = "push"
+	ConsentPersonalizedOffers ConsentPurpose = "personalized_offers"
+)
+
+// ConsentPurposes lists every purpose in display order.
+var ConsentPurposes = []ConsentPurpose{
+	ConsentMarketingSMS,
+	ConsentMarketingEmail,
+	ConsentPush,
+	ConsentPersonalizedOffers,
+}
+
+// IsValid reports whether the purpose is one of the known purposes.
+func (p ConsentPurpose) IsValid() bool {
+	for _, purpose := range ConsentPurposes {
+		if purpose == p {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationChannel is a way a notification reaches the user.
+type NotificationChannel string
+
+const (
+	NotificationPush  NotificationChannel = "push" // Shown in the app
+	NotificationSMS   NotificationChannel = "sms"
+	NotificationEmail NotificationChannel = "email"
+)
+
+// channelConsents are the consents marketing notifications need to go out on each channel.
+var channelConsents = map[NotificationChannel]ConsentPurpose{
+	NotificationPush:  ConsentPush,
+	NotificationSMS:   ConsentMarketingSMS,
+	NotificationEmail: ConsentMarketingEmail,
+}
+
+// IsValid reports whether the channel is one of the known channels.
+func (c NotificationChannel) IsValid() bool {
+	_, ok := channelConsents[c]
+	return ok
+}
+
+// marketingNotifications maps marketing notification types to the consents they need besides the channel's own.
+// Types not listed here, such as order confirmations, are transactional and need no consent.
+var marketingNotifications = map[string][]ConsentPurpose{
+	"promotion":          nil,
+	"new_arrival":        nil,
+	"personalized_offer": {ConsentPersonalizedOffers},
+	"win_back":           nil,
+}
+
+// RequiredConsents returns the consents a notification of the given type needs to go out on the channel.
+func RequiredConsents(notificationType string, channel NotificationChannel) []ConsentPurpose {
+	extra, marketing := marketingNotifications[notificationType]
+	if !marketing {
+		return nil
+	}
+	return append([]ConsentPurpose{channelConsents[channel]}, extra...)
+}
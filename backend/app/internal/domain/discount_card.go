@@ -0,0 +1,31 @@
+package domain
+
+// DiscountCardOverride is a discount set by staff for a member in place of the discount of their tier,
+// such as for employees or to settle a complaint.
+type DiscountCardOverride struct {
+	UserID          int     `json:"user_id"`
+	DiscountPercent float64 `json:"discount_percent"`
+	Reason          string  `json:"reason"`
+	SetBy           *int    `json:"set_by,omitempty"` // Empty once that staff account is deleted
+	CreatedAt       string  `json:"created_at"`
+}
+
+// DiscountCardAuditAction is what was done to a member's discount override.
+type DiscountCardAuditAction string
+
+const (
+	DiscountCardOverrideSet     DiscountCardAuditAction = "set"
+	DiscountCardOverrideCleared DiscountCardAuditAction = "clear"
+)
+
+// DiscountCardAuditEntry records an override being set or removed, who did it and why.
+type DiscountCardAuditEntry struct {
+	ID              int                     `json:"id"`
+	UserID          int                     `json:"user_id"`
+	AdminID         *int                    `json:"admin_id,omitempty"`
+	Action          DiscountCardAuditAction `json:"action"`
+	DiscountPercent *float64                `json:"discount_percent,omitempty"` // New discount; empty when the override is removed
+	PreviousPercent *float64                `json:"previous_percent,omitempty"` // Override it replaced, if any
+	Reason          string                  `json:"reason"`
+	CreatedAt       string                  `json:"created_at"`
+}
@@ -0,0 +1,67 @@
+package domain
+
+// FraudRule names a pattern of loyalty abuse the fraud detector looks for.
+type FraudRule string
+
+const (
+	FraudSharedPhonePrefix   FraudRule = "shared_phone_prefix"   // Many recent sign-ups with the same phone number prefix
+	FraudSharedDevice        FraudRule = "shared_device"         // Many members signing in from the same device and address
+	FraudEarnRedeemCycle     FraudRule = "earn_redeem_cycle"     // Points redeemed again and again soon after they were earned
+	FraudReturnsAfterEarning FraudRule = "returns_after_earning" // Many orders cancelled after they earned points
+	FraudStaffAdjustments    FraudRule = "staff_adjustments"     // A staff member adjusting many points; the flag is on the staff member
+)
+
+// FraudFlagStatus is where a flag is in the review queue.
+type FraudFlagStatus string
+
+const (
+	FraudFlagOpen      FraudFlagStatus = "open"      // Waiting for review
+	FraudFlagDismissed FraudFlagStatus = "dismissed" // Reviewed and found harmless
+	FraudFlagConfirmed FraudFlagStatus = "confirmed" // Reviewed and found to be abuse
+)
+
+// FraudFlag is an anomaly found by a fraud rule, queued for staff to review.
+type FraudFlag struct {
+	ID         int             `json:"id"`
+	Rule       FraudRule       `json:"rule"`
+	UserID     int             `json:"user_id"`
+	Details    string          `json:"details"` // What the rule found, for the reviewer
+	Status     FraudFlagStatus `json:"status"`
+	ReviewedBy *int            `json:"reviewed_by,omitempty"`
+	ReviewNote string          `json:"review_note,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+	ReviewedAt *string         `json:"reviewed_at,omitempty"`
+}
+
+// FraudFinding is a user a fraud rule matched, before it is stored as a flag.
+type FraudFinding struct {
+	UserID  int
+	Details string
+}
+
+// PointFreeze stops a member from spending their points. They keep earning while their points are frozen.
+type PointFreeze struct {
+	UserID    int    `json:"user_id"`
+	Reason    string `json:"reason"`
+	FlagID    *int   `json:"flag_id,omitempty"`   // The fraud flag that led to the freeze, if any
+	FrozenBy  *int   `json:"frozen_by,omitempty"` // Empty once that staff account is deleted
+	CreatedAt string `json:"created_at"`
+}
+
+// PointFreezeAction is what was done to a member's points.
+type PointFreezeAction string
+
+const (
+	PointsFrozen   PointFreezeAction = "freeze"
+	PointsUnfrozen PointFreezeAction = "unfreeze"
+)
+
+// PointFreezeAuditEntry records points being frozen or unfrozen, who did it and why.
+type PointFreezeAuditEntry struct {
+	ID        int               `json:"id"`
+	UserID    int               `json:"user_id"`
+	AdminID   *int              `json:"admin_id,omitempty"`
+	Action    PointFreezeAction `json:"action"`
+	Reason    string            `json:"reason"`
+	CreatedAt string            `json:"created_at"`
+}
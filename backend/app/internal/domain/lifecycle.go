@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// LifecycleBonusKind is the occasion a lifecycle bonus is granted for.
+type LifecycleBonusKind string
+
+const (
+	LifecycleBirthday    LifecycleBonusKind = "birthday"
+	LifecycleAnniversary LifecycleBonusKind = "anniversary" // Of the day the member joined
+	LifecycleWinBack     LifecycleBonusKind = "win_back"    // After a long time without a purchase or a store visit
+)
+
+// LifecycleBonus is points granted to a member for a lifecycle occasion, once per period.
+type LifecycleBonus struct {
+	ID        int                `json:"id"`
+	UserID    int                `json:"user_id"`
+	Kind      LifecycleBonusKind `json:"kind"`
+	Period    string             `json:"period"` // The year for birthdays and anniversaries, the date of the last activity for win-backs
+	Points    int                `json:"points"`
+	GrantedAt string             `json:"granted_at"`
+}
+
+// LifecycleCandidate is a member due a lifecycle bonus that has not been granted yet.
+type LifecycleCandidate struct {
+	UserID   int
+	Username string
+	Period   string
+	Since    time.Time // When the member joined for anniversaries, their last activity for win-backs
+}
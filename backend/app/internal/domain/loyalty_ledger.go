@@ -0,0 +1,43 @@
+package domain
+
+import "strconv"
+
+// LedgerEntryKind classifies an entry of the loyalty ledger.
+type LedgerEntryKind string
+
+const (
+	LedgerEarn    LedgerEntryKind = "earn"
+	LedgerRedeem  LedgerEntryKind = "redeem"
+	LedgerExpire  LedgerEntryKind = "expire"
+	LedgerAdjust  LedgerEntryKind = "adjust"
+	LedgerReverse LedgerEntryKind = "reverse" // Undoes an earlier entry, posting against the same accounts
+)
+
+// Program accounts are the other side of every member posting, so the whole ledger always sums to zero.
+const (
+	ProgramAccountIssued      = "program:issued"
+	ProgramAccountRedeemed    = "program:redeemed"
+	ProgramAccountExpired     = "program:expired"
+	ProgramAccountAdjustments = "program:adjustments"
+)
+
+// ProgramAccount returns the account an entry of this kind is balanced against.
+// Reversals have none of their own; they post against the account of the entry they reverse.
+func (k LedgerEntryKind) ProgramAccount() string {
+	switch k {
+	case LedgerEarn:
+		return ProgramAccountIssued
+	case LedgerRedeem:
+		return ProgramAccountRedeemed
+	case LedgerExpire:
+		return ProgramAccountExpired
+	case LedgerAdjust:
+		return ProgramAccountAdjustments
+	}
+	return ""
+}
+
+// MemberAccount returns the ledger account holding a user's points.
+func MemberAccount(userID int) string {
+	return "member:" + strconv.Itoa(userID)
+}
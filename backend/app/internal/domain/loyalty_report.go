@@ -0,0 +1,45 @@
+package domain
+
+// TierLiability is the points held by the members of a tier. Members without a tier have a zero tier ID.
+type TierLiability struct {
+	TierID               int     `json:"tier_id,omitempty"`
+	TierName             string  `json:"tier_name"`
+	PointValueMultiplier float64 `json:"point_value_multiplier"`
+	Members              int     `json:"members"` // Members with a positive balance
+	Points               int     `json:"points"`
+}
+
+// PointsFlow is the points that moved in a month, by kind of ledger entry.
+type PointsFlow struct {
+	Month    string `json:"month"` // YYYY-MM
+	Earned   int    `json:"earned"`
+	Redeemed int    `json:"redeemed"`
+	Expired  int    `json:"expired"`
+	Adjusted int    `json:"adjusted"` // Net of credits and debits
+	Reversed int    `json:"reversed"` // Net; cancelled orders take earned points back and give redeemed ones back
+}
+
+// ExpiringPoints is the unspent points due to expire in a month.
+type ExpiringPoints struct {
+	Month   string `json:"month"` // YYYY-MM
+	Points  int    `json:"points"`
+	Members int    `json:"members"`
+}
+
+// TierSnapshot is how many members a tier had on a day.
+type TierSnapshot struct {
+	Date     string `json:"date"` // YYYY-MM-DD
+	TierID   *int   `json:"tier_id,omitempty"`
+	TierName string `json:"tier_name"` // Empty for members without a tier
+	Members  int    `json:"members"`
+}
+
+// ActiveMembers counts the members who did something in a month.
+type ActiveMembers struct {
+	Month      string `json:"month"`  // YYYY-MM
+	Active     int    `json:"active"` // Paid for an order, visited a store, or earned or redeemed points
+	Purchasing int    `json:"purchasing"`
+	Earning    int    `json:"earning"`
+	Redeeming  int    `json:"redeeming"`
+	NewMembers int    `json:"new_members"`
+}
@@ -1,19 +1,25 @@
 package domain
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
 
 type User struct {
-	ID                  int     `json:"id"`
-	Username            string  `json:"username"` // New field for username
-	PhoneNumber         string  `json:"phone_number"`
-	Email               string  `json:"email,omitempty"`     // New field for user email
-	PasswordHash        string  `json:"-"`                   // New field for storing hashed password, omit from JSON
-	SocialID            string  `json:"social_id,omitempty"` // For social logins
-	DiscountLevel       int     `json:"discount_level"`
-	ProgressToNextLevel float64 `json:"progress_to_next_level"`
-	QRCode              *string `json:"qr_code,omitempty"`
-	LoyaltyStatus       string  `json:"loyalty_status"` // New field for user loyalty status
-	CurrentPoints       int     `json:"current_points"`
+	ID              int     `json:"id"`
+	Username        string  `json:"username"` // New field for username
+	PhoneNumber     string  `json:"phone_number"`
+	Email           string  `json:"email,omitempty"`     // New field for user email
+	PasswordHash    string  `json:"-"`                   // New field for storing hashed password, omit from JSON
+	SocialID        string  `json:"social_id,omitempty"` // Legacy; linked social accounts live in UserIdentity
+	QRCode          *string `json:"qr_code,omitempty"`
+	LoyaltyStatus   string  `json:"loyalty_status"` // New field for user loyalty status
+	CurrentPoints   int     `json:"current_points"`
+	Role            Role    `json:"role"`
+	EmailVerifiedAt *string `json:"email_verified_at,omitempty"`
+	PhoneVerifiedAt *string `json:"phone_verified_at,omitempty"` // Set once a login code sent to the number was entered, or a provider confirmed it
+	BirthDate       *string `json:"birth_date,omitempty"`        // YYYY-MM-DD
 }
 
 // Define a custom type for context keys to avoid collisions.
@@ -22,6 +28,18 @@ type contextKey string
 // UserContextKey is the key used to store and retrieve the user ID from the context.
 const UserContextKey contextKey = "userID"
 
+// RoleContextKey is the key used to store and retrieve the user role from the context.
+const RoleContextKey contextKey = "role"
+
+// SessionContextKey is the key used to store and retrieve the session ID from the context.
+const SessionContextKey contextKey = "sessionID"
+
+// ServiceAccountContextKey is the key used to store and retrieve the service account ID of an API key request.
+const ServiceAccountContextKey contextKey = "serviceAccountID"
+
+// ScopesContextKey is the key used to store and retrieve the scopes of an API key request.
+const ScopesContextKey contextKey = "scopes"
+
 type Store struct {
 	ID       int    `json:"id"`
 	Name     string `json:"name"`
@@ -30,6 +48,15 @@ type Store struct {
 	Phone    string `json:"phone"`
 }
 
+// StoreVisit is a customer seen in a store, by an in-store purchase or a scan of their card.
+type StoreVisit struct {
+	ID        int    `json:"id"`
+	UserID    int    `json:"user_id"`
+	StoreID   int    `json:"store_id"`
+	Source    string `json:"source"` // "order" or "scan"
+	VisitedAt string `json:"visited_at"`
+}
+
 type Notification struct {
 	ID        int    `json:"id"`
 	UserID    int    `json:"user_id"`
@@ -54,27 +81,228 @@ type Product struct {
 	ImageURL    string  `json:"image_url,omitempty"`
 	CreatedAt   string  `json:"created_at"`
 	UpdatedAt   string  `json:"updated_at"`
+	// Until then only members whose tier has early access see and buy the product
+	EarlyAccessUntil *string `json:"early_access_until,omitempty"`
 }
 
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string       `json:"user_id"`
+	Role      Role         `json:"role,omitempty"`
+	SessionID string       `json:"sid,omitempty"`
+	Purpose   TokenPurpose `json:"purpose,omitempty"` // Empty for access tokens
+	Email     string       `json:"email,omitempty"`   // Address an email verification token was sent to
 	jwt.RegisteredClaims
 }
 
-type LoyaltyPoint struct {
+// TokenPurpose marks signed tokens that are sent to the user instead of used for authentication.
+type TokenPurpose string
+
+const (
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+)
+
+// JSONWebKey is a public key in RFC 7517 format.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA public exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve, e.g. "Ed25519"
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+type Session struct {
+	ID                       string  `json:"id"`
+	UserID                   int     `json:"user_id"`
+	RefreshTokenHash         string  `json:"-"`
+	PreviousRefreshTokenHash *string `json:"-"` // Hash of the token rotated out last, used to detect refresh token reuse
+	Device                   string  `json:"device"`
+	IPAddress                string  `json:"ip_address"`
+	CreatedAt                string  `json:"created_at"`
+	LastUsedAt               string  `json:"last_used_at"`
+	ExpiresAt                string  `json:"expires_at"`
+	RevokedAt                *string `json:"revoked_at,omitempty"`
+}
+
+// OTPCode is the one-time login code most recently sent to a phone number.
+type OTPCode struct {
+	PhoneNumber string
+	CodeHash    string
+	Attempts    int
+	ExpiresAt   time.Time
+	LastSentAt  time.Time
+}
+
+// UserIdentity links a user to an account at an external OpenID Connect provider.
+type UserIdentity struct {
 	ID        int    `json:"id"`
 	UserID    int    `json:"user_id"`
-	Points    int    `json:"points"`
-	Type      string `json:"type"` // e.g., "purchase", "referral", "bonus"
+	Provider  string `json:"provider"` // e.g., "google", "yandex", "vk"
+	Subject   string `json:"subject"`  // The provider's stable user identifier
+	Email     string `json:"email,omitempty"`
 	CreatedAt string `json:"created_at"`
 }
 
+// ExternalIdentity is what an OpenID Connect provider asserts about the user who logged in.
+type ExternalIdentity struct {
+	Subject             string
+	Name                string
+	Email               string
+	EmailVerified       bool
+	PhoneNumber         string
+	PhoneNumberVerified bool
+}
+
+// OAuthState is the server-side half of an authorization-code flow in progress.
+type OAuthState struct {
+	State        string
+	Provider     string
+	CodeVerifier string
+	Nonce        string
+	UserID       *int // Set when an authenticated user is linking a new identity
+	ExpiresAt    time.Time
+}
+
+// OneTimeToken tracks a purpose token by its JWT ID so it can be used only once.
+type OneTimeToken struct {
+	ID        string
+	UserID    int
+	Purpose   TokenPurpose
+	ExpiresAt time.Time
+}
+
+// ConsentText is one published version of the wording a user agrees to for a purpose.
+type ConsentText struct {
+	ID        int            `json:"id"`
+	Purpose   ConsentPurpose `json:"purpose"`
+	Version   int            `json:"version"`
+	Text      string         `json:"text"`
+	CreatedAt string         `json:"created_at"`
+}
+
+// ConsentRecord is an entry in the append-only log of consents given and withdrawn.
+// A user's current consent for a purpose is their latest record for it.
+type ConsentRecord struct {
+	ID          int            `json:"id"`
+	UserID      int            `json:"user_id"`
+	Purpose     ConsentPurpose `json:"purpose"`
+	Granted     bool           `json:"granted"`
+	TextVersion int            `json:"text_version"`
+	IPAddress   string         `json:"ip_address"`
+	UserAgent   string         `json:"user_agent"`
+	CreatedAt   string         `json:"created_at"`
+}
+
+// ServiceAccount is a non-human client, such as an in-store terminal or a partner system.
+type ServiceAccount struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   string    `json:"created_at"`
+	DisabledAt  *string   `json:"disabled_at,omitempty"`
+	Keys        []*APIKey `json:"keys,omitempty"`
+}
+
+// APIKey authenticates a service account. Only the hash of the key is stored;
+// the prefix identifies it to administrators.
+type APIKey struct {
+	ID               int        `json:"id"`
+	ServiceAccountID int        `json:"service_account_id"`
+	Prefix           string     `json:"prefix"`
+	KeyHash          string     `json:"-"`
+	Scopes           []APIScope `json:"scopes"`
+	CreatedAt        string     `json:"created_at"`
+	ExpiresAt        *string    `json:"expires_at,omitempty"`
+	RevokedAt        *string    `json:"revoked_at,omitempty"`
+	LastUsedAt       *string    `json:"last_used_at,omitempty"`
+	LastUsedIP       string     `json:"last_used_ip,omitempty"`
+}
+
+// LoyaltyPoint is an entry of the loyalty ledger. Entries are never updated or deleted;
+// a mistake is corrected by a reversing or adjusting entry.
+type LoyaltyPoint struct {
+	ID             int             `json:"id"`
+	UserID         int             `json:"user_id"`
+	Points         int             `json:"points"` // Change to the member's balance, negative for debits
+	Type           string          `json:"type"`   // e.g., "purchase", "referral", "bonus"
+	Kind           LedgerEntryKind `json:"kind"`
+	IdempotencyKey string          `json:"-"`
+	Reference      string          `json:"reference,omitempty"` // What the entry is for, e.g. "order:42"
+	ReversesID     *int            `json:"reverses_id,omitempty"`
+	BalanceAfter   int             `json:"balance_after"`
+	ExpiresAt      *string         `json:"expires_at,omitempty"` // Set on credits; nil if the points never expire
+	CreatedAt      string          `json:"created_at"`
+}
+
+// PointLot is the part of a credit not yet spent or expired.
+type PointLot struct {
+	EntryID   int     `json:"entry_id"`
+	UserID    int     `json:"user_id"`
+	Remaining int     `json:"remaining"`
+	ExpiresAt *string `json:"expires_at,omitempty"`
+}
+
+// LedgerMismatch is a member whose cached balances disagree with the ledger.
+type LedgerMismatch struct {
+	UserID          int  `json:"user_id"`
+	LedgerBalance   int  `json:"ledger_balance"`   // Sum of the member's entries
+	PostingsBalance int  `json:"postings_balance"` // Sum of the postings on the member's account
+	LoyaltyBalance  int  `json:"loyalty_balance"`  // user_loyalty.current_points
+	UserBalance     int  `json:"user_balance"`     // users.current_points
+	Repaired        bool `json:"repaired"`
+}
+
 type LoyaltyTier struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"` // e.g., "Bronze", "Silver", "Gold", "Platinum"
-	MinPoints   int    `json:"min_points"`
+	ID                   int          `json:"id"`
+	Name                 string       `json:"name"` // e.g., "Bronze", "Silver", "Gold", "Platinum"
+	MinPoints            int          `json:"min_points"`
+	Description          string       `json:"description"`
+	Benefits             TierBenefits `json:"benefits"`
+	PointValueMultiplier float64      `json:"point_value_multiplier"` // Scales what a point is worth when redeemed by members of this tier
+}
+
+// TierBenefits are the perks members of a tier get.
+type TierBenefits struct {
+	DiscountPercent  float64 `json:"discount_percent"`  // Taken off the order total at checkout
+	PointsMultiplier float64 `json:"points_multiplier"` // Scales the points earned on purchases
+	FreeAlterations  bool    `json:"free_alterations"`  // Waives the alteration fee at checkout
+	FreeShipping     bool    `json:"free_shipping"`     // Waives the shipping fee at checkout
+	EarlyAccess      bool    `json:"early_access"`      // To new collections and sales
+}
+
+// EarningRule awards points at checkout on top of the base rate. A rule fires for an order that meets
+// all of its conditions; unset conditions match any order. Category and product conditions pick
+// the lines of the order the multiplier applies to.
+type EarningRule struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"` // Shown to the customer in the points breakdown
+	Active      bool    `json:"active"`
+	CategoryID  *int    `json:"category_id,omitempty"`
+	ProductID   *int    `json:"product_id,omitempty"`
+	StoreID     *int    `json:"store_id,omitempty"`
+	TierID      *int    `json:"tier_id,omitempty"`
+	DaysOfWeek  []int   `json:"days_of_week"` // 0 is Sunday; empty means every day
+	StartsAt    *string `json:"starts_at,omitempty"`
+	EndsAt      *string `json:"ends_at,omitempty"`
+	MinBasket   float64 `json:"min_basket"`   // Order total before discounts
+	Multiplier  float64 `json:"multiplier"`   // Scales the base points of the matching lines, e.g. 2 for double points
+	BonusPoints int     `json:"bonus_points"` // Awarded once per order
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
+}
+
+// EarnedPoints is a line of an order's points breakdown.
+type EarnedPoints struct {
+	RuleID      *int   `json:"rule_id,omitempty"` // Empty for the base rate and the tier bonus
 	Description string `json:"description"`
-	Benefits    string `json:"benefits"` // e.g., JSON string or comma-separated list of benefits
+	Points      int    `json:"points"`
 }
 
 type LoyaltyActivity struct {
@@ -86,10 +314,11 @@ type LoyaltyActivity struct {
 }
 
 type UserLoyalty struct {
-	UserID         int    `json:"user_id"`
-	CurrentPoints  int    `json:"current_points"`
-	CurrentTierID  int    `json:"current_tier_id"`
-	LastActivityAt string `json:"last_activity_at"`
+	UserID         int     `json:"user_id"`
+	CurrentPoints  int     `json:"current_points"`
+	CurrentTierID  int     `json:"current_tier_id"`
+	LastActivityAt string  `json:"last_activity_at"`
+	TierGraceUntil *string `json:"tier_grace_until,omitempty"` // Set while the member keeps a tier they no longer qualify for
 }
 
 type Cart struct {
@@ -110,15 +339,24 @@ type CartItem struct {
 }
 
 type Order struct {
-	ID            int         `json:"id"`
-	UserID        string      `json:"user_id"`
-	OrderDate     string      `json:"order_date"`
-	TotalAmount   float64     `json:"total_amount"`
-	Status        string      `json:"status"`         // e.g., pending, completed, cancelled
-	PaymentStatus string      `json:"payment_status"` // e.g., unpaid, paid, refunded
-	CreatedAt     string      `json:"created_at"`
-	UpdatedAt     string      `json:"updated_at"`
-	Items         []OrderItem `json:"items"` // For embedding order items in the response
+	ID             int         `json:"id"`
+	UserID         string      `json:"user_id"`
+	OrderDate      string      `json:"order_date"`
+	TotalAmount    float64     `json:"total_amount"`
+	Status         string      `json:"status"`         // e.g., pending, completed, cancelled
+	PaymentStatus  string      `json:"payment_status"` // e.g., unpaid, paid, refunded
+	PointsRedeemed int         `json:"points_redeemed"`
+	TierDiscount   float64     `json:"tier_discount"`      // Part of TotalAmount taken off by the member's tier
+	PointsDiscount float64     `json:"points_discount"`    // Part of TotalAmount paid with points
+	ShippingFee    float64     `json:"shipping_fee"`       // Charged on top of TotalAmount for delivery
+	AlterationFee  float64     `json:"alteration_fee"`     // Charged on top of TotalAmount for altering items
+	StoreID        *int        `json:"store_id,omitempty"` // Set for purchases made in a store
+	PointsEarned   int         `json:"points_earned"`
+	CreatedAt      string      `json:"created_at"`
+	UpdatedAt      string      `json:"updated_at"`
+	Items          []OrderItem `json:"items"` // For embedding order items in the response
+	// Which earning rules awarded PointsEarned
+	PointsBreakdown []*EarnedPoints `json:"points_breakdown,omitempty"`
 }
 
 type OrderItem struct {
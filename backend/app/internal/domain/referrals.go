@@ -0,0 +1,28 @@
+package domain
+
+// ReferralStatus is where a referral is on its way to being rewarded.
+type ReferralStatus string
+
+const (
+	ReferralPending   ReferralStatus = "pending"   // The referee has not paid for an order yet
+	ReferralQualified ReferralStatus = "qualified" // The referee paid; the reward waits out the hold period for returns
+	ReferralRewarded  ReferralStatus = "rewarded"
+	ReferralRejected  ReferralStatus = "rejected" // Taken for a self-referral; never rewarded
+)
+
+// Referral is a member who registered with another member's invite code.
+type Referral struct {
+	ID              int            `json:"id"`
+	ReferrerID      int            `json:"referrer_id"`
+	RefereeID       int            `json:"referee_id"`
+	Status          ReferralStatus `json:"status"`
+	RejectionReason string         `json:"rejection_reason,omitempty"`
+	Device          string         `json:"-"` // The referee registered from
+	IPAddress       string         `json:"-"`
+	OrderID         *int           `json:"order_id,omitempty"` // The referee's first paid order
+	RewardAfter     *string        `json:"reward_after,omitempty"`
+	ReferrerPoints  int            `json:"referrer_points"`
+	RefereePoints   int            `json:"referee_points"`
+	RewardedAt      *string        `json:"rewarded_at,omitempty"`
+	CreatedAt       string         `json:"created_at"`
+}
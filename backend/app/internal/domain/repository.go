@@ -1,18 +1,25 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *User) error
-	GetUserByID(ctx context.Context, id int) (*User, error)
+	GetUserByID(ctx context.Context, id int) (*User, error) // Deleted accounts are not found
 	GetUserByPhoneNumber(ctx context.Context, phoneNumber string) (*User, error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error) // New method for authentication
+	GetUserByQRCode(ctx context.Context, qrCode string) (*User, error)
 	UpdateUser(ctx context.Context, user *User) error
-	UpdateUserDiscountCard(ctx context.Context, userID int, level int, progress float64) error
-	GetUserDiscountCard(ctx context.Context, userID int) (*User, error) // Can return a User with only discount card fields populated
+	UpdateUserProfile(ctx context.Context, user *User) error // Writes only the fields users edit themselves, leaving credentials, role, tier and card alone
+	UpdateUserRole(ctx context.Context, userID int, role Role) error
+	UpdateUserPassword(ctx context.Context, userID int, passwordHash string) error
+	MarkEmailVerified(ctx context.Context, userID int, email string) error       // No-op if the user's email has changed since
+	MarkPhoneVerified(ctx context.Context, userID int, phoneNumber string) error // No-op if the user's phone number has changed since
+	AnonymizeUser(ctx context.Context, userID int) error                         // Erases personal data but keeps the row for order and loyalty history
 
 	// Loyalty Program methods
-	CreateLoyaltyPoint(ctx context.Context, point *LoyaltyPoint) error
 	GetLoyaltyPointsByUserID(ctx context.Context, userID int) ([]*LoyaltyPoint, error)
 	GetLoyaltyTierByID(ctx context.Context, id int) (*LoyaltyTier, error)
 	GetLoyaltyTierByName(ctx context.Context, name string) (*LoyaltyTier, error)
@@ -23,12 +30,99 @@ type UserRepository interface {
 	CreateLoyaltyActivity(ctx context.Context, activity *LoyaltyActivity) error
 	GetLoyaltyActivitiesByUserID(ctx context.Context, userID int) ([]*LoyaltyActivity, error)
 	GetUserLoyalty(ctx context.Context, userID int) (*UserLoyalty, error)
-	UpdateUserLoyalty(ctx context.Context, userLoyalty *UserLoyalty) error
+	UpdateUserLoyalty(ctx context.Context, userLoyalty *UserLoyalty) error   // Never changes the balance, which only the ledger moves
+	GetUserLoyalties(ctx context.Context) ([]*UserLoyalty, error)            // Skips deleted accounts
+	UpdateUserTier(ctx context.Context, userID int, tier *LoyaltyTier) error // Also ends any grace period
+	SetTierGraceUntil(ctx context.Context, userID int, graceUntil *time.Time) error
+}
+
+type LoyaltyLedgerRepository interface {
+	// PostEntry appends the entry with its postings and moves the member's balances in one transaction,
+	// failing with "insufficient points" rather than taking the balance below zero.
+	// Credits open a lot, and debits consume lots soonest-expiring first. Reversing a debit puts its points
+	// back into the lots it consumed, so they keep their expiry.
+	// If an entry with the same idempotency key was posted before, nothing is posted, the stored entry
+	// is copied into entry and false is returned.
+	PostEntry(ctx context.Context, entry *LoyaltyPoint) (bool, error)
+	// ExpirePoints posts entry as the expiry of all the member's lots that have expired by now,
+	// setting its points. It returns false if there was nothing to expire or the key was already used.
+	ExpirePoints(ctx context.Context, entry *LoyaltyPoint) (bool, error)
+	GetUserIDsWithExpiredPoints(ctx context.Context) ([]int, error)
+	DateCreditsWithoutExpiry(ctx context.Context, lifetimeMonths int, notBefore time.Time) (int, error) // Counted from accrual but never before notBefore; returns how many were dated
+	GetPointLotsByUserID(ctx context.Context, userID int) ([]*PointLot, error)                          // Unspent lots, soonest-expiring first
+	GetUnwarnedExpiringLots(ctx context.Context, daysBefore int) ([]*PointLot, error)                   // Lots expiring within the days and not yet warned about that close to expiry, skipping deleted accounts
+	MarkLotsWarned(ctx context.Context, entryIDs []int, daysBefore int) error
+	GetEntryByIdempotencyKey(ctx context.Context, key string) (*LoyaltyPoint, error)
+	GetEntriesByUserID(ctx context.Context, userID, limit, offset int) ([]*LoyaltyPoint, int, error) // Newest first, with the total count
+	GetQualifyingPoints(ctx context.Context, userID int, since time.Time) (int, error)               // Points earned on purchases since the time, net of reversals; bonuses do not count
+	GetBalanceMismatches(ctx context.Context) ([]*LedgerMismatch, error)
+	GetUnbalancedEntryIDs(ctx context.Context) ([]int, error) // Entries whose postings do not sum to zero
+	RepairBalance(ctx context.Context, userID int) error      // Resets the cached balances to the sum of the member's entries
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *Session) error
+	GetSessionByID(ctx context.Context, id string) (*Session, error)
+	GetSessionByRefreshTokenHash(ctx context.Context, hash string) (*Session, error) // Matches the current or the previous refresh token
+	GetActiveSessionsByUserID(ctx context.Context, userID int) ([]*Session, error)
+	IsSessionActive(ctx context.Context, id string) (bool, error)
+	RotateRefreshToken(ctx context.Context, id, oldHash, newHash, ipAddress, expiresAt string) error
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userID int, exceptSessionID string) error
+}
+
+type OTPRepository interface {
+	SaveOTPCode(ctx context.Context, code *OTPCode) error // Replaces any earlier code for the same phone number
+	GetOTPCode(ctx context.Context, phoneNumber string) (*OTPCode, error)
+	// ClaimOTPAttempt counts an attempt at the unexpired code and returns it, or fails with "OTP code not found"
+	// if there is no such code or it has used up its attempts. Concurrent checks cannot exceed the limit.
+	ClaimOTPAttempt(ctx context.Context, phoneNumber string, maxAttempts int) (*OTPCode, error)
+	ConsumeOTPCode(ctx context.Context, phoneNumber, codeHash string) error // Fails with "OTP code not found" if the code was already used or replaced
+	DeleteOTPCode(ctx context.Context, phoneNumber string) error
+}
+
+type IdentityRepository interface {
+	CreateIdentity(ctx context.Context, identity *UserIdentity) error
+	GetIdentity(ctx context.Context, provider, subject string) (*UserIdentity, error)
+	GetIdentitiesByUserID(ctx context.Context, userID int) ([]*UserIdentity, error)
+	DeleteIdentity(ctx context.Context, userID int, provider string) error
+	SaveOAuthState(ctx context.Context, state *OAuthState) error
+	ConsumeOAuthState(ctx context.Context, state string) (*OAuthState, error) // Returns and deletes an unexpired state
+}
+
+type OneTimeTokenRepository interface {
+	CreateOneTimeToken(ctx context.Context, token *OneTimeToken) error
+	UseOneTimeToken(ctx context.Context, id string, purpose TokenPurpose) (*OneTimeToken, error) // Fails if the token is expired or already used
+	InvalidateOneTimeTokens(ctx context.Context, userID int, purpose TokenPurpose) error
+}
+
+type ConsentRepository interface {
+	CreateConsentText(ctx context.Context, text *ConsentText) error // Assigns the next version for the purpose
+	GetLatestConsentTexts(ctx context.Context) ([]*ConsentText, error)
+	GetConsentText(ctx context.Context, purpose ConsentPurpose, version int) (*ConsentText, error)
+	CreateConsentRecord(ctx context.Context, record *ConsentRecord) error
+	GetCurrentConsents(ctx context.Context, userID int) ([]*ConsentRecord, error)
+	GetConsentHistory(ctx context.Context, userID int) ([]*ConsentRecord, error)
+}
+
+type ServiceAccountRepository interface {
+	CreateServiceAccount(ctx context.Context, account *ServiceAccount) error
+	GetServiceAccountByID(ctx context.Context, id int) (*ServiceAccount, error)
+	GetServiceAccounts(ctx context.Context) ([]*ServiceAccount, error)
+	DisableServiceAccount(ctx context.Context, id int) error // Also revokes all of the account's keys
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeyByID(ctx context.Context, id int) (*APIKey, error)
+	GetActiveAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error) // Only unexpired, unrevoked keys of enabled accounts
+	GetAPIKeysByServiceAccountID(ctx context.Context, serviceAccountID int) ([]*APIKey, error)
+	ExpireAPIKey(ctx context.Context, id int, expiresAt time.Time) error // Never extends a key's existing expiry
+	RevokeAPIKey(ctx context.Context, id int) error
+	TouchAPIKey(ctx context.Context, id int, ipAddress string) error
 }
 
 type StoreRepository interface {
 	GetStores(ctx context.Context) ([]*Store, error)
 	GetStoreByID(ctx context.Context, id int) (*Store, error)
+	CreateStoreVisit(ctx context.Context, visit *StoreVisit) error
 }
 
 type NotificationRepository interface {
@@ -47,7 +141,7 @@ type CategoryRepository interface {
 type ProductRepository interface {
 	CreateProduct(ctx context.Context, product *Product) error
 	GetProductByID(ctx context.Context, id int) (*Product, error)
-	GetProducts(ctx context.Context, categoryID *string, minPrice *float64, maxPrice *float64, sortBy *string, sortOrder *string, limit, offset int) ([]*Product, error)
+	GetProducts(ctx context.Context, categoryID *string, minPrice *float64, maxPrice *float64, sortBy *string, sortOrder *string, limit, offset int, earlyAccess bool) ([]*Product, error) // Products still in early access are left out unless earlyAccess is set
 	UpdateProduct(ctx context.Context, product *Product) error
 	DeleteProduct(ctx context.Context, id int) error
 }
@@ -68,10 +162,131 @@ type CartItemRepository interface {
 }
 
 type OrderRepository interface {
-	CreateOrder(ctx context.Context, order *Order) error
+	CreateOrder(ctx context.Context, order *Order) error // Stores the order and its items in one transaction
 	GetOrderByID(ctx context.Context, orderID int) (*Order, error)
 	GetOrdersByUserID(ctx context.Context, userID string, paymentStatus *string) ([]*Order, error) // Added paymentStatus filter
 	UpdateOrder(ctx context.Context, order *Order) error
+	CancelOrder(ctx context.Context, orderID int) error // Fails if the order is already cancelled
+	// SetOrderPoints stores the points breakdown of the order and sets its points earned to the total
+	SetOrderPoints(ctx context.Context, orderID int, breakdown []*EarnedPoints) error
+	GetOrderPointsBreakdown(ctx context.Context, orderID int) ([]*EarnedPoints, error)
+	MarkOrderPointsPosted(ctx context.Context, orderID int) error
+	GetOrdersAwaitingPoints(ctx context.Context, placedBefore time.Time) ([]*Order, error) // Paid orders placed before the time whose points were never posted, oldest first
+}
+
+type ReferralRepository interface {
+	GetReferralCode(ctx context.Context, userID int) (string, error)
+	CreateReferralCode(ctx context.Context, userID int, code string) error // Fails with "referral code already exists" if the code is taken
+	GetUserIDByReferralCode(ctx context.Context, code string) (int, error)
+	CreateReferral(ctx context.Context, referral *Referral) error
+	GetReferralsByReferrerID(ctx context.Context, referrerID int) ([]*Referral, error) // Newest first
+	// SharesDevice reports whether the referee registered or signed in from a device and address
+	// the referrer, or another member they referred, used too
+	SharesDevice(ctx context.Context, referrerID, refereeID int, device, ipAddress string) (bool, error)
+	// QualifyReferral marks the referee's pending referral as qualified by the order.
+	// It returns false if the referee has no pending referral.
+	QualifyReferral(ctx context.Context, refereeID, orderID int, rewardAfter time.Time) (bool, error)
+	GetReferralsDueForReward(ctx context.Context) ([]*Referral, error) // Qualified referrals past their hold period
+	MarkReferralRewarded(ctx context.Context, referral *Referral) error
+	ResetReferral(ctx context.Context, referralID int) error // Back to pending, for the next paid order to qualify
+	RejectReferral(ctx context.Context, referralID int, reason string) error
+}
+
+type ChallengeRepository interface {
+	CreateChallenge(ctx context.Context, challenge *Challenge) error
+	GetChallenges(ctx context.Context) ([]*Challenge, error)
+	GetActiveChallenges(ctx context.Context, at time.Time) ([]*Challenge, error) // Active challenges whose campaign dates cover the time
+	UpdateChallenge(ctx context.Context, challenge *Challenge) error
+	DeleteChallenge(ctx context.Context, challengeID int) error
+	GetChallengeProgress(ctx context.Context, challenge *Challenge, userID int, from, to time.Time) (float64, error)
+	AwardBadge(ctx context.Context, badge *UserBadge) (bool, error) // Returns false if the badge was already awarded for the period
+	GetBadgesByUserID(ctx context.Context, userID int) ([]*UserBadge, error)
+}
+
+type DiscountCardRepository interface {
+	GetDiscountCardOverride(ctx context.Context, userID int) (*DiscountCardOverride, error)
+	SetDiscountCardOverride(ctx context.Context, override *DiscountCardOverride) error           // Replaces the member's override and records it in the audit trail
+	ClearDiscountCardOverride(ctx context.Context, userID int, adminID int, reason string) error // Removes the member's override and records it in the audit trail
+	GetDiscountCardAudit(ctx context.Context, userID int) ([]*DiscountCardAuditEntry, error)     // Newest first
+}
+
+type LoyaltyAdjustmentRepository interface {
+	// CreateAdjustment stores a pending adjustment with its "requested" event. If the idempotency key was
+	// used before, nothing is stored, the stored adjustment is copied into adjustment and false is returned.
+	// With a positive dailyLimit, it fails if the points of the requester's adjustments over the last 24 hours,
+	// rejected ones aside, would add up to more than that.
+	CreateAdjustment(ctx context.Context, adjustment *LoyaltyAdjustment, dailyLimit int) (bool, error)
+	GetAdjustmentByID(ctx context.Context, adjustmentID int) (*LoyaltyAdjustment, error)
+	GetAdjustments(ctx context.Context, userID int, status AdjustmentStatus) ([]*LoyaltyAdjustment, error) // Newest first; a zero user ID or empty status matches any
+	// ReviewAdjustment approves or rejects a pending adjustment with the adjustment's status, reviewer,
+	// note and ledger entry, and records the event. It fails with "loyalty adjustment is not pending"
+	// if the adjustment was reviewed already.
+	ReviewAdjustment(ctx context.Context, adjustment *LoyaltyAdjustment) error
+	GetAdjustmentEvents(ctx context.Context, adjustmentID int) ([]*LoyaltyAdjustmentEvent, error) // Oldest first
+}
+
+// LoyaltyReportRepository aggregates the loyalty program for reporting. Months run from from up to but excluding to,
+// and every month in between is returned, with zeros if nothing happened.
+type LoyaltyReportRepository interface {
+	GetPointsLiability(ctx context.Context) ([]*TierLiability, error) // Ledger balances by the members' current tier, lowest tier first
+	GetPointsFlow(ctx context.Context, from, to time.Time) ([]*PointsFlow, error)
+	GetExpiringPoints(ctx context.Context, until time.Time) ([]*ExpiringPoints, error) // By month of expiry, including lots past expiry not yet expired by the daily job
+	RecordTierSnapshot(ctx context.Context) error                                      // Records today's distribution, replacing one recorded earlier today
+	GetTierSnapshots(ctx context.Context, from, to time.Time) ([]*TierSnapshot, error) // The last snapshot of each month
+	GetActiveMembers(ctx context.Context, from, to time.Time) ([]*ActiveMembers, error)
+}
+
+// FraudRepository finds loyalty abuse and keeps the flags raised for it. The Find methods return one finding
+// per user a rule matches, looking only at what happened since the given time.
+type FraudRepository interface {
+	FindSharedPhonePrefixes(ctx context.Context, prefixLength, minAccounts int, since time.Time) ([]*FraudFinding, error) // Members who signed up since then
+	FindSharedDevices(ctx context.Context, minAccounts int, since time.Time) ([]*FraudFinding, error)                     // Members who signed in since then
+	FindEarnRedeemCycles(ctx context.Context, withinHours, minCycles int, since time.Time) ([]*FraudFinding, error)
+	FindReturnsAfterEarning(ctx context.Context, minReturns int, since time.Time) ([]*FraudFinding, error)
+	FindStaffAdjustments(ctx context.Context, minPoints int, since time.Time) ([]*FraudFinding, error) // Findings are on the staff members
+	// CreateFraudFlag stores an open flag. Nothing is stored and false is returned if the rule already has
+	// an open flag on the user, or one that was reviewed after reviewedSince.
+	CreateFraudFlag(ctx context.Context, flag *FraudFlag, reviewedSince time.Time) (bool, error)
+	GetFraudFlags(ctx context.Context, status FraudFlagStatus) ([]*FraudFlag, error) // Newest first; an empty status matches any
+	GetFraudFlagByID(ctx context.Context, flagID int) (*FraudFlag, error)
+	// ReviewFraudFlag confirms or dismisses an open flag with the flag's status, reviewer and note.
+	// It fails with "fraud flag is not open" if the flag was reviewed already.
+	ReviewFraudFlag(ctx context.Context, flag *FraudFlag) error
+}
+
+type PointFreezeRepository interface {
+	GetPointFreeze(ctx context.Context, userID int) (*PointFreeze, error)
+	FreezePoints(ctx context.Context, freeze *PointFreeze) error                           // Replaces the member's freeze, if any, and records it in the audit trail
+	UnfreezePoints(ctx context.Context, userID int, adminID int, reason string) error      // Removes the member's freeze and records it in the audit trail
+	GetPointFreezeAudit(ctx context.Context, userID int) ([]*PointFreezeAuditEntry, error) // Newest first
+}
+
+type LifecycleBonusRepository interface {
+	GetBirthdayCandidates(ctx context.Context, day time.Time) ([]*LifecycleCandidate, error)
+	GetAnniversaryCandidates(ctx context.Context, day time.Time) ([]*LifecycleCandidate, error)
+	GetWinBackCandidates(ctx context.Context, inactiveSince time.Time) ([]*LifecycleCandidate, error)
+	RecordLifecycleBonus(ctx context.Context, bonus *LifecycleBonus) (bool, error) // Returns false if the bonus was already granted for the period
+}
+
+type WalletPassRepository interface {
+	CreateWalletPass(ctx context.Context, pass *WalletPass) error // Keeps the member's existing pass, if any
+	GetWalletPassByUserID(ctx context.Context, userID int) (*WalletPass, error)
+	GetWalletPassBySerialNumber(ctx context.Context, serialNumber string) (*WalletPass, error)
+	GetStaleWalletPasses(ctx context.Context, codeWindow int64) ([]*WalletPass, error) // Passes showing other points, tier or code than now, with the new ones filled in
+	MarkWalletPassUpdated(ctx context.Context, pass *WalletPass) error
+	RegisterWalletDevice(ctx context.Context, deviceLibraryID, serialNumber, pushToken string) (bool, error) // Returns false if the device was already registered
+	UnregisterWalletDevice(ctx context.Context, deviceLibraryID, serialNumber string) error
+	GetWalletPassesByDevice(ctx context.Context, deviceLibraryID string, updatedSince *time.Time) ([]*WalletPass, error)
+	GetWalletPushTokens(ctx context.Context, serialNumber string) ([]string, error)
+	DeleteWalletPushToken(ctx context.Context, pushToken string) error
+}
+
+type EarningRuleRepository interface {
+	CreateEarningRule(ctx context.Context, rule *EarningRule) error
+	GetEarningRules(ctx context.Context) ([]*EarningRule, error)
+	GetActiveEarningRules(ctx context.Context, at time.Time) ([]*EarningRule, error) // Active rules whose campaign dates cover the time
+	UpdateEarningRule(ctx context.Context, rule *EarningRule) error
+	DeleteEarningRule(ctx context.Context, ruleID int) error
 }
 
 type OrderItemRepository interface {
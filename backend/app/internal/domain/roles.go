@@ -0,0 +1,82 @@
+package domain
+
+// Role identifies the kind of account a user holds.
+type Role string
+
+const (
+	RoleCustomer   Role = "customer"
+	RoleStoreStaff Role = "store_staff"
+	RoleManager    Role = "manager"
+	RoleAdmin      Role = "admin"
+)
+
+// Permission names a single action guarded by the authorization middleware.
+type Permission string
+
+const (
+	PermissionAccessAdmin       Permission = "admin:access"
+	PermissionViewUsers         Permission = "users:view"
+	PermissionManageRoles       Permission = "roles:manage"
+	PermissionSendNotifications Permission = "notifications:send"
+	PermissionManageLoyalty     Permission = "loyalty:manage"
+	PermissionScanCustomers     Permission = "customers:scan"
+	PermissionViewReports       Permission = "reports:view"
+	PermissionManageConsents    Permission = "consents:manage"
+	PermissionManageAPIKeys     Permission = "api_keys:manage"
+	PermissionManageOrders      Permission = "orders:manage"
+	PermissionAdjustPoints      Permission = "loyalty:adjust"  // Within the limit of the role
+	PermissionApproveAdjustment Permission = "loyalty:approve" // Adjustments requested by someone else
+	PermissionReviewFraud       Permission = "fraud:review"    // Fraud flags and point freezes
+)
+
+// rolePermissions maps each role to the permissions it is granted.
+var rolePermissions = map[Role][]Permission{
+	RoleCustomer: {},
+	RoleStoreStaff: {
+		PermissionAccessAdmin,
+		PermissionScanCustomers,
+		PermissionAdjustPoints,
+	},
+	RoleManager: {
+		PermissionAccessAdmin,
+		PermissionViewUsers,
+		PermissionSendNotifications,
+		PermissionManageLoyalty,
+		PermissionScanCustomers,
+		PermissionViewReports,
+		PermissionManageOrders,
+		PermissionAdjustPoints,
+		PermissionApproveAdjustment,
+	},
+	RoleAdmin: {
+		PermissionAccessAdmin,
+		PermissionViewUsers,
+		PermissionManageRoles,
+		PermissionSendNotifications,
+		PermissionManageLoyalty,
+		PermissionScanCustomers,
+		PermissionViewReports,
+		PermissionManageConsents,
+		PermissionManageAPIKeys,
+		PermissionManageOrders,
+		PermissionAdjustPoints,
+		PermissionApproveAdjustment,
+		PermissionReviewFraud,
+	},
+}
+
+// IsValid reports whether the role is one of the known roles.
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// HasPermission reports whether the role is granted the given permission.
+func (r Role) HasPermission(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
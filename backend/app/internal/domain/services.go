@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// TokenIssuer signs and verifies access tokens.
+type TokenIssuer interface {
+	Issue(claims *Claims) (string, error)
+	Parse(tokenString string) (*Claims, error)
+	JWKS() *JSONWebKeySet // Public verification keys; symmetric keys are never published
+}
+
+// SMSSender delivers text messages to phone numbers.
+type SMSSender interface {
+	Send(ctx context.Context, phoneNumber, message string) error
+}
+
+// MailSender delivers email messages.
+type MailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// AppleWallet signs Apple Wallet passes and asks devices to fetch them again, both with the pass type certificate.
+type AppleWallet interface {
+	PassTypeID() string
+	TeamID() string
+	SignManifest(manifest []byte) ([]byte, error) // Detached PKCS #7 signature of the pass's manifest.json
+	Push(ctx context.Context, pushToken string) error
+}
+
+// GoogleWallet issues "Save to Google Wallet" links and updates the loyalty cards already saved.
+type GoogleWallet interface {
+	IssuerID() string
+	ClassID() string
+	SaveURL(object *GoogleLoyaltyObject) (string, error)
+	UpdateLoyaltyObject(ctx context.Context, object *GoogleLoyaltyObject) error // A card the member has not saved yet is left alone
+}
+
+// OIDCProvider runs the OpenID Connect authorization-code flow against one external provider.
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error)
+}
+
+// RateLimitStore keeps throttling state: token buckets for request rates and failure counters for lockouts.
+// The operations map onto single Redis commands or scripts, so state can be shared between API instances.
+type RateLimitStore interface {
+	// Take removes a token from the bucket for key, which holds up to burst tokens refilled at rate per second.
+	// When the bucket is empty it reports how long until the next token.
+	Take(ctx context.Context, key string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error)
+	// RecordFailure counts a failure for key and returns the count within the window started by the first one.
+	RecordFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	ResetFailures(ctx context.Context, key string) error
+	Lock(ctx context.Context, key string, duration time.Duration) error
+	LockedFor(ctx context.Context, key string) (time.Duration, error) // Zero when key is not locked
+}
@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// WalletPass is a member's discount card in Apple Wallet and Google Wallet.
+// It keeps what the pass shows, so a change of points, tier or card code can be pushed to the wallets.
+type WalletPass struct {
+	UserID       int
+	SerialNumber string
+	AuthToken    string // Apple devices present it to the pass web service
+	Points       int
+	TierID       *int
+	CodeWindow   int64 // Day of the card code on the pass
+	UpdatedAt    time.Time
+}
+
+// GoogleLoyaltyObject is a loyalty card saved to Google Wallet, in the format of the Google Wallet API.
+type GoogleLoyaltyObject struct {
+	ID                     string               `json:"id"`
+	ClassID                string               `json:"classId"`
+	State                  string               `json:"state"`
+	AccountID              string               `json:"accountId"`
+	AccountName            string               `json:"accountName"`
+	LoyaltyPoints          *GoogleLoyaltyPoints `json:"loyaltyPoints,omitempty"`
+	SecondaryLoyaltyPoints *GoogleLoyaltyPoints `json:"secondaryLoyaltyPoints,omitempty"`
+	Barcode                *GoogleBarcode       `json:"barcode,omitempty"`
+}
+
+type GoogleLoyaltyPoints struct {
+	Label   string               `json:"label"`
+	Balance GoogleLoyaltyBalance `json:"balance"`
+}
+
+// GoogleLoyaltyBalance holds either a number or a text, such as the name of a tier.
+type GoogleLoyaltyBalance struct {
+	Int    *int   `json:"int,omitempty"`
+	String string `json:"string,omitempty"`
+}
+
+type GoogleBarcode struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
@@ -0,0 +1,274 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AppleWalletConfig describes the pass type certificate Apple Wallet passes are signed with.
+// Self-signed certificates are fine for development, though devices only accept passes signed by Apple's chain.
+type AppleWalletConfig struct {
+	PassTypeID   string
+	TeamID       string
+	CertFile     string // PEM certificate of the pass type
+	KeyFile      string // PEM private key of the certificate, RSA or ECDSA
+	WWDRCertFile string // Apple Worldwide Developer Relations intermediate certificate, PEM or DER
+	APNsURL      string // Defaults to Apple's production push service
+}
+
+// AppleWalletService signs passes with PKCS #7 and notifies devices through the Apple Push Notification service.
+type AppleWalletService struct {
+	cfg        AppleWalletConfig
+	cert       *x509.Certificate
+	wwdrCert   *x509.Certificate
+	signer     crypto.Signer
+	httpClient *http.Client
+}
+
+func NewAppleWalletService(cfg *AppleWalletConfig) (*AppleWalletService, error) {
+	if cfg.PassTypeID == "" || cfg.TeamID == "" {
+		return nil, fmt.Errorf("pass type ID and team ID are required")
+	}
+	keyPair, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pass type certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pass type certificate: %w", err)
+	}
+	signer, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported pass type certificate key")
+	}
+	wwdrCert, err := loadCertificate(cfg.WWDRCertFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load WWDR certificate: %w", err)
+	}
+
+	service := &AppleWalletService{cfg: *cfg, cert: cert, wwdrCert: wwdrCert, signer: signer}
+	if service.cfg.APNsURL == "" {
+		service.cfg.APNsURL = "https://api.push.apple.com"
+	}
+	service.httpClient = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{Certificates: []tls.Certificate{keyPair}},
+			ForceAttemptHTTP2: true, // APNs only speaks HTTP/2
+		},
+	}
+	return service, nil
+}
+
+func (s *AppleWalletService) PassTypeID() string {
+	return s.cfg.PassTypeID
+}
+
+func (s *AppleWalletService) TeamID() string {
+	return s.cfg.TeamID
+}
+
+// Push sends the empty notification that tells a device to ask the web service for updated passes.
+func (s *AppleWalletService) Push(ctx context.Context, pushToken string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.APNsURL+"/3/device/"+pushToken, strings.NewReader("{}"))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("apns-topic", s.cfg.PassTypeID)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send push notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusGone:
+		return fmt.Errorf("push token is no longer valid")
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("push notification rejected with status %d: %s", resp.StatusCode, body)
+	}
+}
+
+var (
+	oidData                   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256                 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256        = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// PKCS #7 structures (RFC 2315), just enough for a detached signature with one signer.
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type pkcs7DetachedContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      pkcs7DetachedContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7SignerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     pkcs7IssuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+}
+
+type pkcs7IssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type pkcs7Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// SignManifest returns the detached PKCS #7 signature of manifest.json that Apple Wallet expects in the signature file.
+func (s *AppleWalletService) SignManifest(manifest []byte) ([]byte, error) {
+	digest := sha256.Sum256(manifest)
+	attributes, err := pkcs7Attributes(map[string]interface{}{
+		oidAttributeContentType.String():   oidData,
+		oidAttributeSigningTime.String():   time.Now().UTC(),
+		oidAttributeMessageDigest.String(): digest[:],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The signature covers the attributes encoded as a SET, while the signer info carries them under an implicit [0] tag
+	attributesDigest := sha256.Sum256(append(asn1Header(asn1.ClassUniversal, asn1.TagSet, len(attributes)), attributes...))
+	signature, err := s.signer.Sign(rand.Reader, attributesDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign pass manifest: %w", err)
+	}
+
+	var encryptionAlgorithm pkix.AlgorithmIdentifier
+	switch s.signer.Public().(type) {
+	case *rsa.PublicKey:
+		encryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		encryptionAlgorithm = pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+	default:
+		return nil, fmt.Errorf("unsupported pass type certificate key")
+	}
+
+	digestAlgorithm := pkix.AlgorithmIdentifier{Algorithm: oidSHA256, Parameters: asn1.NullRawValue}
+	signedData := pkcs7SignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgorithm},
+		ContentInfo:      pkcs7DetachedContentInfo{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: append(append([]byte{}, s.cert.Raw...), s.wwdrCert.Raw...)},
+		SignerInfos: []pkcs7SignerInfo{{
+			Version:                   1,
+			IssuerAndSerialNumber:     pkcs7IssuerAndSerial{Issuer: asn1.RawValue{FullBytes: s.cert.RawIssuer}, SerialNumber: s.cert.SerialNumber},
+			DigestAlgorithm:           digestAlgorithm,
+			AuthenticatedAttributes:   asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attributes},
+			DigestEncryptionAlgorithm: encryptionAlgorithm,
+			EncryptedDigest:           signature,
+		}},
+	}
+	content, err := asn1.Marshal(signedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pass signature: %w", err)
+	}
+
+	signatureFile, err := asn1.Marshal(pkcs7ContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pass signature: %w", err)
+	}
+	return signatureFile, nil
+}
+
+// pkcs7Attributes encodes the attributes as the content of a DER SET OF, which must be sorted by encoding.
+func pkcs7Attributes(values map[string]interface{}) ([]byte, error) {
+	var encoded [][]byte
+	for oid, value := range values {
+		var attributeType asn1.ObjectIdentifier
+		for _, part := range strings.Split(oid, ".") {
+			var n int
+			fmt.Sscan(part, &n)
+			attributeType = append(attributeType, n)
+		}
+		valueBytes, err := asn1.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode signed attribute: %w", err)
+		}
+		attribute, err := asn1.Marshal(pkcs7Attribute{
+			Type:   attributeType,
+			Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: valueBytes},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode signed attribute: %w", err)
+		}
+		encoded = append(encoded, attribute)
+	}
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+	return bytes.Join(encoded, nil), nil
+}
+
+// asn1Header encodes the identifier and length octets of a DER value.
+func asn1Header(class, tag, length int) []byte {
+	header := []byte{byte(class<<6) | byte(tag)}
+	if tag == asn1.TagSet || tag == asn1.TagSequence || class == asn1.ClassContextSpecific {
+		header[0] |= 0x20 // Constructed
+	}
+	if length < 0x80 {
+		return append(header, byte(length))
+	}
+	var lengthBytes []byte
+	for n := length; n > 0; n >>= 8 {
+		lengthBytes = append([]byte{byte(n)}, lengthBytes...)
+	}
+	return append(append(header, 0x80|byte(len(lengthBytes))), lengthBytes...)
+}
+
+// loadCertificate reads a certificate stored as PEM or DER.
+func loadCertificate(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+	return x509.ParseCertificate(data)
+}
@@ -0,0 +1,157 @@
+package infrastructure
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert writes a self-signed pass type certificate and its key, as used in development.
+func writeSelfSignedCert(t *testing.T, key crypto.Signer) (certFile, keyFile string, der []byte) {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "pass.pem"), filepath.Join(dir, "pass.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, der
+}
+
+func TestAppleWalletSignManifest(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		key        crypto.Signer
+		wwdrAsDER  bool
+		wantScheme asn1.ObjectIdentifier
+	}{
+		{name: "RSA key", key: rsaKey, wantScheme: oidRSAEncryption},
+		{name: "ECDSA key with a DER intermediate", key: ecKey, wwdrAsDER: true, wantScheme: oidECDSAWithSHA256},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certFile, keyFile, certDER := writeSelfSignedCert(t, tt.key)
+			wwdrFile := certFile
+			if tt.wwdrAsDER {
+				wwdrFile = filepath.Join(t.TempDir(), "wwdr.cer")
+				if err := os.WriteFile(wwdrFile, certDER, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			service, err := NewAppleWalletService(&AppleWalletConfig{PassTypeID: "pass.test", TeamID: "TEAM", CertFile: certFile, KeyFile: keyFile, WWDRCertFile: wwdrFile})
+			if err != nil {
+				t.Fatalf("NewAppleWalletService() error = %v", err)
+			}
+
+			manifest := []byte(`{"pass.json":"0000"}`)
+			signatureFile, err := service.SignManifest(manifest)
+			if err != nil {
+				t.Fatalf("SignManifest() error = %v", err)
+			}
+
+			var contentInfo pkcs7ContentInfo
+			if _, err := asn1.Unmarshal(signatureFile, &contentInfo); err != nil {
+				t.Fatalf("signature is not a PKCS #7 content info: %v", err)
+			}
+			if !contentInfo.ContentType.Equal(oidSignedData) {
+				t.Fatalf("content type = %v, want signed data", contentInfo.ContentType)
+			}
+			var signedData pkcs7SignedData
+			if _, err := asn1.Unmarshal(contentInfo.Content.Bytes, &signedData); err != nil {
+				t.Fatalf("content is not signed data: %v", err)
+			}
+			if len(signedData.SignerInfos) != 1 {
+				t.Fatalf("got %d signers, want 1", len(signedData.SignerInfos))
+			}
+			if !bytes.Equal(signedData.Certificates.Bytes, append(append([]byte{}, certDER...), certDER...)) {
+				t.Errorf("signed data does not carry the pass type and WWDR certificates")
+			}
+
+			signer := signedData.SignerInfos[0]
+			if !signer.DigestEncryptionAlgorithm.Algorithm.Equal(tt.wantScheme) {
+				t.Errorf("signature algorithm = %v, want %v", signer.DigestEncryptionAlgorithm.Algorithm, tt.wantScheme)
+			}
+			attributes := signer.AuthenticatedAttributes.Bytes
+			manifestDigest := sha256.Sum256(manifest)
+			if !bytes.Contains(attributes, manifestDigest[:]) {
+				t.Errorf("signed attributes do not carry the manifest digest")
+			}
+
+			// The signature covers the attributes re-encoded as a SET
+			attributesDigest := sha256.Sum256(append(asn1Header(asn1.ClassUniversal, asn1.TagSet, len(attributes)), attributes...))
+			switch pub := tt.key.Public().(type) {
+			case *rsa.PublicKey:
+				err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, attributesDigest[:], signer.EncryptedDigest)
+			case *ecdsa.PublicKey:
+				if !ecdsa.VerifyASN1(pub, attributesDigest[:], signer.EncryptedDigest) {
+					err = os.ErrInvalid
+				}
+			}
+			if err != nil {
+				t.Errorf("signature does not verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestASN1Header(t *testing.T) {
+	tests := []struct {
+		name   string
+		class  int
+		tag    int
+		length int
+		want   []byte
+	}{
+		{name: "short SET", class: asn1.ClassUniversal, tag: asn1.TagSet, length: 0x45, want: []byte{0x31, 0x45}},
+		{name: "long SET", class: asn1.ClassUniversal, tag: asn1.TagSet, length: 0x1234, want: []byte{0x31, 0x82, 0x12, 0x34}},
+		{name: "boundary length", class: asn1.ClassUniversal, tag: asn1.TagSequence, length: 0x80, want: []byte{0x30, 0x81, 0x80}},
+		{name: "context-specific", class: asn1.ClassContextSpecific, tag: 0, length: 3, want: []byte{0xa0, 0x03}},
+		{name: "primitive", class: asn1.ClassUniversal, tag: asn1.TagOctetString, length: 32, want: []byte{0x04, 0x20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := asn1Header(tt.class, tt.tag, tt.length); !bytes.Equal(got, tt.want) {
+				t.Errorf("asn1Header() = % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type challengeRepository struct {
+	db *sql.DB
+}
+
+func NewChallengeRepository(db *sql.DB) domain.ChallengeRepository {
+	return &challengeRepository{db: db}
+}
+
+const challengeColumns = `id, name, description, active, goal, target, category_id, period, starts_at, ends_at, badge_name, badge_icon_url, reward_points, created_at, updated_at`
+
+func (r *challengeRepository) CreateChallenge(ctx context.Context, challenge *domain.Challenge) error {
+	query := `
+		INSERT INTO challenges (name, description, active, goal, target, category_id, period, starts_at, ends_at, badge_name, badge_icon_url, reward_points)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, created_at, updated_at
+	`
+	c := challenge
+	err := r.db.QueryRowContext(
+		ctx, query, c.Name, c.Description, c.Active, c.Goal, c.Target, c.CategoryID, c.Period, c.StartsAt, c.EndsAt, c.BadgeName, c.BadgeIconURL, c.RewardPoints,
+	).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return challengeError("create", err)
+	}
+	return nil
+}
+
+func (r *challengeRepository) GetChallenges(ctx context.Context) ([]*domain.Challenge, error) {
+	return r.queryChallenges(ctx, `SELECT `+challengeColumns+` FROM challenges ORDER BY id`)
+}
+
+func (r *challengeRepository) GetActiveChallenges(ctx context.Context, at time.Time) ([]*domain.Challenge, error) {
+	query := `
+		SELECT ` + challengeColumns + ` FROM challenges
+		WHERE active AND (starts_at IS NULL OR starts_at <= $1) AND (ends_at IS NULL OR ends_at > $1)
+		ORDER BY id
+	`
+	return r.queryChallenges(ctx, query, at)
+}
+
+func (r *challengeRepository) UpdateChallenge(ctx context.Context, challenge *domain.Challenge) error {
+	query := `
+		UPDATE challenges SET name = $2, description = $3, active = $4, goal = $5, target = $6, category_id = $7, period = $8,
+			starts_at = $9, ends_at = $10, badge_name = $11, badge_icon_url = $12, reward_points = $13, updated_at = NOW()
+		WHERE id = $1 RETURNING created_at, updated_at
+	`
+	c := challenge
+	err := r.db.QueryRowContext(
+		ctx, query, c.ID, c.Name, c.Description, c.Active, c.Goal, c.Target, c.CategoryID, c.Period, c.StartsAt, c.EndsAt, c.BadgeName, c.BadgeIconURL, c.RewardPoints,
+	).Scan(&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("challenge not found")
+		}
+		return challengeError("update", err)
+	}
+	return nil
+}
+
+func (r *challengeRepository) DeleteChallenge(ctx context.Context, challengeID int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM challenges WHERE id = $1`, challengeID)
+	if err != nil {
+		return fmt.Errorf("failed to delete challenge: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete challenge: %w", err)
+	} else if rows == 0 {
+		return fmt.Errorf("challenge not found")
+	}
+	return nil
+}
+
+func (r *challengeRepository) GetChallengeProgress(ctx context.Context, challenge *domain.Challenge, userID int, from, to time.Time) (float64, error) {
+	// Purchases count while the order stays paid, so a cancelled order takes its progress back
+	purchases := `
+		FROM orders o JOIN order_items oi ON oi.order_id = o.id JOIN products p ON p.id = oi.product_id
+		WHERE o.user_id = $1 AND o.payment_status = 'paid' AND o.order_date >= $2 AND o.order_date < $3
+			AND ($4::INT IS NULL OR p.category_id = $4)
+	`
+	visits := `FROM store_visits WHERE user_id = $1 AND visited_at >= $2 AND visited_at < $3`
+
+	var query string
+	args := []interface{}{userID, from, to}
+	switch challenge.Goal {
+	case domain.ChallengeGoalOrders:
+		query = `SELECT COUNT(DISTINCT o.id) ` + purchases
+		args = append(args, challenge.CategoryID)
+	case domain.ChallengeGoalItems:
+		query = `SELECT COALESCE(SUM(oi.quantity), 0) ` + purchases
+		args = append(args, challenge.CategoryID)
+	case domain.ChallengeGoalAmountSpent:
+		query = `SELECT COALESCE(SUM(oi.price * oi.quantity), 0) ` + purchases
+		args = append(args, challenge.CategoryID)
+	case domain.ChallengeGoalStoreVisits:
+		query = `SELECT COUNT(DISTINCT (store_id, visited_at::DATE)) ` + visits
+	case domain.ChallengeGoalDistinctStores:
+		query = `SELECT COUNT(DISTINCT store_id) ` + visits
+	default:
+		return 0, fmt.Errorf("unknown challenge goal %q", challenge.Goal)
+	}
+
+	var progress float64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&progress); err != nil {
+		return 0, fmt.Errorf("failed to get challenge progress: %w", err)
+	}
+	return progress, nil
+}
+
+func (r *challengeRepository) AwardBadge(ctx context.Context, badge *domain.UserBadge) (bool, error) {
+	query := `
+		INSERT INTO user_badges (user_id, challenge_id, period_start, name, icon_url, points)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, challenge_id, period_start) DO NOTHING
+		RETURNING id, awarded_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query, badge.UserID, badge.ChallengeID, badge.PeriodStart, badge.Name, badge.IconURL, badge.Points,
+	).Scan(&badge.ID, &badge.AwardedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to award badge: %w", err)
+	}
+	return true, nil
+}
+
+func (r *challengeRepository) GetBadgesByUserID(ctx context.Context, userID int) ([]*domain.UserBadge, error) {
+	query := `
+		SELECT id, user_id, challenge_id, period_start, name, icon_url, points, awarded_at
+		FROM user_badges WHERE user_id = $1 ORDER BY awarded_at DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get badges: %w", err)
+	}
+	defer rows.Close()
+
+	var badges []*domain.UserBadge
+	for rows.Next() {
+		b := &domain.UserBadge{}
+		if err := rows.Scan(&b.ID, &b.UserID, &b.ChallengeID, &b.PeriodStart, &b.Name, &b.IconURL, &b.Points, &b.AwardedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan badge: %w", err)
+		}
+		badges = append(badges, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return badges, nil
+}
+
+func (r *challengeRepository) queryChallenges(ctx context.Context, query string, args ...interface{}) ([]*domain.Challenge, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenges: %w", err)
+	}
+	defer rows.Close()
+
+	var challenges []*domain.Challenge
+	for rows.Next() {
+		c := &domain.Challenge{}
+		err := rows.Scan(
+			&c.ID, &c.Name, &c.Description, &c.Active, &c.Goal, &c.Target, &c.CategoryID, &c.Period,
+			&c.StartsAt, &c.EndsAt, &c.BadgeName, &c.BadgeIconURL, &c.RewardPoints, &c.CreatedAt, &c.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan challenge: %w", err)
+		}
+		challenges = append(challenges, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return challenges, nil
+}
+
+func challengeError(action string, err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+		return fmt.Errorf("category of the challenge does not exist")
+	}
+	return fmt.Errorf("failed to %s challenge: %w", action, err)
+}
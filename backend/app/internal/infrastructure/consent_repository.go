@@ -0,0 +1,119 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type consentRepository struct {
+	db *sql.DB
+}
+
+func NewConsentRepository(db *sql.DB) domain.ConsentRepository {
+	return &consentRepository{db: db}
+}
+
+func (r *consentRepository) CreateConsentText(ctx context.Context, text *domain.ConsentText) error {
+	query := `
+		INSERT INTO consent_texts (purpose, version, text)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2 FROM consent_texts WHERE purpose = $1
+		RETURNING id, version, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, text.Purpose, text.Text).Scan(&text.ID, &text.Version, &text.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create consent text: %w", err)
+	}
+	return nil
+}
+
+func (r *consentRepository) GetLatestConsentTexts(ctx context.Context) ([]*domain.ConsentText, error) {
+	query := `
+		SELECT DISTINCT ON (purpose) id, purpose, version, text, created_at
+		FROM consent_texts ORDER BY purpose, version DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consent texts: %w", err)
+	}
+	defer rows.Close()
+
+	var texts []*domain.ConsentText
+	for rows.Next() {
+		text := &domain.ConsentText{}
+		if err := rows.Scan(&text.ID, &text.Purpose, &text.Version, &text.Text, &text.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan consent text: %w", err)
+		}
+		texts = append(texts, text)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return texts, nil
+}
+
+func (r *consentRepository) GetConsentText(ctx context.Context, purpose domain.ConsentPurpose, version int) (*domain.ConsentText, error) {
+	query := `SELECT id, purpose, version, text, created_at FROM consent_texts WHERE purpose = $1 AND version = $2`
+	text := &domain.ConsentText{}
+	err := r.db.QueryRowContext(ctx, query, purpose, version).Scan(&text.ID, &text.Purpose, &text.Version, &text.Text, &text.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("consent text not found")
+		}
+		return nil, fmt.Errorf("failed to get consent text: %w", err)
+	}
+	return text, nil
+}
+
+func (r *consentRepository) CreateConsentRecord(ctx context.Context, record *domain.ConsentRecord) error {
+	query := `
+		INSERT INTO consent_records (user_id, purpose, granted, text_version, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, record.UserID, record.Purpose, record.Granted, record.TextVersion, record.IPAddress, record.UserAgent).Scan(&record.ID, &record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create consent record: %w", err)
+	}
+	return nil
+}
+
+func (r *consentRepository) GetCurrentConsents(ctx context.Context, userID int) ([]*domain.ConsentRecord, error) {
+	query := `
+		SELECT DISTINCT ON (purpose) id, user_id, purpose, granted, text_version, ip_address, user_agent, created_at
+		FROM consent_records WHERE user_id = $1 ORDER BY purpose, created_at DESC, id DESC
+	`
+	return r.queryConsentRecords(ctx, query, userID)
+}
+
+func (r *consentRepository) GetConsentHistory(ctx context.Context, userID int) ([]*domain.ConsentRecord, error) {
+	query := `
+		SELECT id, user_id, purpose, granted, text_version, ip_address, user_agent, created_at
+		FROM consent_records WHERE user_id = $1 ORDER BY created_at, id
+	`
+	return r.queryConsentRecords(ctx, query, userID)
+}
+
+func (r *consentRepository) queryConsentRecords(ctx context.Context, query string, args ...interface{}) ([]*domain.ConsentRecord, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consent records: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*domain.ConsentRecord
+	for rows.Next() {
+		record := &domain.ConsentRecord{}
+		if err := rows.Scan(&record.ID, &record.UserID, &record.Purpose, &record.Granted, &record.TextVersion, &record.IPAddress, &record.UserAgent, &record.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan consent record: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return records, nil
+}
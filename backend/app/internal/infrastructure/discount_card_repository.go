@@ -0,0 +1,144 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type discountCardRepository struct {
+	db *sql.DB
+}
+
+func NewDiscountCardRepository(db *sql.DB) domain.DiscountCardRepository {
+	return &discountCardRepository{db: db}
+}
+
+func (r *discountCardRepository) GetDiscountCardOverride(ctx context.Context, userID int) (*domain.DiscountCardOverride, error) {
+	override := &domain.DiscountCardOverride{}
+	query := `SELECT user_id, discount_percent, reason, set_by, created_at FROM discount_card_overrides WHERE user_id = $1`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&override.UserID, &override.DiscountPercent, &override.Reason, &override.SetBy, &override.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("discount card override not found")
+		}
+		return nil, fmt.Errorf("failed to get discount card override: %w", err)
+	}
+	return override, nil
+}
+
+func (r *discountCardRepository) SetDiscountCardOverride(ctx context.Context, override *domain.DiscountCardOverride) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	previous, err := lockDiscountCardOverride(ctx, tx, override.UserID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO discount_card_overrides (user_id, discount_percent, reason, set_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET discount_percent = EXCLUDED.discount_percent, reason = EXCLUDED.reason,
+			set_by = EXCLUDED.set_by, created_at = NOW()
+		RETURNING created_at
+	`
+	if err := tx.QueryRowContext(ctx, query, override.UserID, override.DiscountPercent, override.Reason, override.SetBy).Scan(&override.CreatedAt); err != nil {
+		return fmt.Errorf("failed to set discount card override: %w", err)
+	}
+	if err := recordDiscountCardAudit(ctx, tx, override.UserID, override.SetBy, domain.DiscountCardOverrideSet, &override.DiscountPercent, previous, override.Reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *discountCardRepository) ClearDiscountCardOverride(ctx context.Context, userID int, adminID int, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	previous, err := lockDiscountCardOverride(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if previous == nil {
+		return fmt.Errorf("discount card override not found")
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM discount_card_overrides WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear discount card override: %w", err)
+	}
+	if err := recordDiscountCardAudit(ctx, tx, userID, &adminID, domain.DiscountCardOverrideCleared, nil, previous, reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *discountCardRepository) GetDiscountCardAudit(ctx context.Context, userID int) ([]*domain.DiscountCardAuditEntry, error) {
+	query := `
+		SELECT id, user_id, admin_id, action, discount_percent, previous_percent, reason, created_at
+		FROM discount_card_audit WHERE user_id = $1 ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get discount card audit: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*domain.DiscountCardAuditEntry
+	for rows.Next() {
+		entry := &domain.DiscountCardAuditEntry{}
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.AdminID, &entry.Action, &entry.DiscountPercent, &entry.PreviousPercent, &entry.Reason, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan discount card audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return entries, nil
+}
+
+// lockDiscountCardOverride returns the member's current override discount, or nil if there is none,
+// and locks the member's row so concurrent changes are audited in the order they are made.
+func lockDiscountCardOverride(ctx context.Context, tx *sql.Tx, userID int) (*float64, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+	var previous float64
+	err := tx.QueryRowContext(ctx, `SELECT discount_percent FROM discount_card_overrides WHERE user_id = $1`, userID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get discount card override: %w", err)
+	}
+	return &previous, nil
+}
+
+func recordDiscountCardAudit(ctx context.Context, tx *sql.Tx, userID int, adminID *int, action domain.DiscountCardAuditAction, discountPercent, previousPercent *float64, reason string) error {
+	query := `
+		INSERT INTO discount_card_audit (user_id, admin_id, action, discount_percent, previous_percent, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := tx.ExecContext(ctx, query, userID, adminID, action, discountPercent, previousPercent, reason); err != nil {
+		return fmt.Errorf("failed to record discount card audit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type earningRuleRepository struct {
+	db *sql.DB
+}
+
+func NewEarningRuleRepository(db *sql.DB) domain.EarningRuleRepository {
+	return &earningRuleRepository{db: db}
+}
+
+const earningRuleColumns = `id, name, active, category_id, product_id, store_id, tier_id, days_of_week, starts_at, ends_at, min_basket, multiplier, bonus_points, created_at, updated_at`
+
+func (r *earningRuleRepository) CreateEarningRule(ctx context.Context, rule *domain.EarningRule) error {
+	query := `
+		INSERT INTO earning_rules (name, active, category_id, product_id, store_id, tier_id, days_of_week, starts_at, ends_at, min_basket, multiplier, bonus_points)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query, rule.Name, rule.Active, rule.CategoryID, rule.ProductID, rule.StoreID, rule.TierID, pq.Array(toInt64s(rule.DaysOfWeek)),
+		rule.StartsAt, rule.EndsAt, rule.MinBasket, rule.Multiplier, rule.BonusPoints,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return earningRuleError("create", err)
+	}
+	return nil
+}
+
+func (r *earningRuleRepository) GetEarningRules(ctx context.Context) ([]*domain.EarningRule, error) {
+	return r.queryEarningRules(ctx, `SELECT `+earningRuleColumns+` FROM earning_rules ORDER BY id`)
+}
+
+func (r *earningRuleRepository) GetActiveEarningRules(ctx context.Context, at time.Time) ([]*domain.EarningRule, error) {
+	query := `
+		SELECT ` + earningRuleColumns + ` FROM earning_rules
+		WHERE active AND (starts_at IS NULL OR starts_at <= $1) AND (ends_at IS NULL OR ends_at > $1)
+		ORDER BY id
+	`
+	return r.queryEarningRules(ctx, query, at)
+}
+
+func (r *earningRuleRepository) UpdateEarningRule(ctx context.Context, rule *domain.EarningRule) error {
+	query := `
+		UPDATE earning_rules SET name = $2, active = $3, category_id = $4, product_id = $5, store_id = $6, tier_id = $7, days_of_week = $8,
+			starts_at = $9, ends_at = $10, min_basket = $11, multiplier = $12, bonus_points = $13, updated_at = NOW()
+		WHERE id = $1 RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query, rule.ID, rule.Name, rule.Active, rule.CategoryID, rule.ProductID, rule.StoreID, rule.TierID, pq.Array(toInt64s(rule.DaysOfWeek)),
+		rule.StartsAt, rule.EndsAt, rule.MinBasket, rule.Multiplier, rule.BonusPoints,
+	).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("earning rule not found")
+		}
+		return earningRuleError("update", err)
+	}
+	return nil
+}
+
+func (r *earningRuleRepository) DeleteEarningRule(ctx context.Context, ruleID int) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM earning_rules WHERE id = $1`, ruleID)
+	if err != nil {
+		return fmt.Errorf("failed to delete earning rule: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete earning rule: %w", err)
+	} else if rows == 0 {
+		return fmt.Errorf("earning rule not found")
+	}
+	return nil
+}
+
+func (r *earningRuleRepository) queryEarningRules(ctx context.Context, query string, args ...interface{}) ([]*domain.EarningRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get earning rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []*domain.EarningRule
+	for rows.Next() {
+		rule := &domain.EarningRule{}
+		var daysOfWeek []int64
+		err := rows.Scan(
+			&rule.ID, &rule.Name, &rule.Active, &rule.CategoryID, &rule.ProductID, &rule.StoreID, &rule.TierID, pq.Array(&daysOfWeek),
+			&rule.StartsAt, &rule.EndsAt, &rule.MinBasket, &rule.Multiplier, &rule.BonusPoints, &rule.CreatedAt, &rule.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan earning rule: %w", err)
+		}
+		rule.DaysOfWeek = make([]int, len(daysOfWeek))
+		for i, day := range daysOfWeek {
+			rule.DaysOfWeek[i] = int(day)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return rules, nil
+}
+
+// earningRuleError explains a rule pointing at a category, product, store or tier that does not exist.
+func earningRuleError(action string, err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+		return fmt.Errorf("category, product, store or tier of the earning rule does not exist")
+	}
+	return fmt.Errorf("failed to %s earning rule: %w", action, err)
+}
+
+func toInt64s(values []int) []int64 {
+	result := make([]int64, len(values))
+	for i, v := range values {
+		result[i] = int64(v)
+	}
+	return result
+}
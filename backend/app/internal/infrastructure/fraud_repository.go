@@ -0,0 +1,195 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type fraudRepository struct {
+	db *sql.DB
+}
+
+func NewFraudRepository(db *sql.DB) domain.FraudRepository {
+	return &fraudRepository{db: db}
+}
+
+const fraudFlagColumns = `id, rule, user_id, details, status, reviewed_by, review_note, created_at, reviewed_at`
+
+func scanFraudFlag(row rowScanner) (*domain.FraudFlag, error) {
+	flag := &domain.FraudFlag{}
+	err := row.Scan(&flag.ID, &flag.Rule, &flag.UserID, &flag.Details, &flag.Status, &flag.ReviewedBy, &flag.ReviewNote,
+		&flag.CreatedAt, &flag.ReviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
+
+func (r *fraudRepository) FindSharedPhonePrefixes(ctx context.Context, prefixLength, minAccounts int, since time.Time) ([]*domain.FraudFinding, error) {
+	query := `
+		WITH recent AS (
+			SELECT id, LEFT(phone_number, $1) AS prefix FROM users
+			WHERE created_at >= $3 AND deleted_at IS NULL AND role = 'customer' AND LENGTH(phone_number) > $1
+		), shared AS (
+			SELECT prefix, COUNT(*) AS accounts FROM recent GROUP BY prefix HAVING COUNT(*) >= $2
+		)
+		SELECT r.id, FORMAT('%s accounts signed up since %s with phone numbers starting %s', s.accounts, TO_CHAR($3::TIMESTAMPTZ, 'YYYY-MM-DD'), s.prefix)
+		FROM recent r JOIN shared s ON s.prefix = r.prefix
+		ORDER BY r.id
+	`
+	return r.queryFindings(ctx, "shared phone prefixes", query, prefixLength, minAccounts, since)
+}
+
+func (r *fraudRepository) FindSharedDevices(ctx context.Context, minAccounts int, since time.Time) ([]*domain.FraudFinding, error) {
+	// A device is the user agent and address together; either alone is shared by too many people
+	query := `
+		WITH recent AS (
+			SELECT DISTINCT s.user_id, s.device, s.ip_address FROM sessions s
+			JOIN users u ON u.id = s.user_id AND u.deleted_at IS NULL AND u.role = 'customer'
+			WHERE s.created_at >= $2 AND s.device <> '' AND s.ip_address <> ''
+		), shared AS (
+			SELECT device, ip_address, COUNT(*) AS accounts FROM recent GROUP BY device, ip_address HAVING COUNT(*) >= $1
+		)
+		SELECT DISTINCT ON (r.user_id) r.user_id, FORMAT('%s accounts signed in from the same device at %s', s.accounts, s.ip_address)
+		FROM recent r JOIN shared s ON s.device = r.device AND s.ip_address = r.ip_address
+		ORDER BY r.user_id, s.accounts DESC
+	`
+	return r.queryFindings(ctx, "shared devices", query, minAccounts, since)
+}
+
+func (r *fraudRepository) FindEarnRedeemCycles(ctx context.Context, withinHours, minCycles int, since time.Time) ([]*domain.FraudFinding, error) {
+	// Points earned on the order they are redeemed on do not count, as both are posted at checkout
+	query := `
+		SELECT rd.user_id, FORMAT('%s redemptions within %s hours of earning points since %s', COUNT(*), $1::INT, TO_CHAR($3::TIMESTAMPTZ, 'YYYY-MM-DD'))
+		FROM loyalty_points rd
+		WHERE rd.kind = 'redeem' AND rd.created_at >= $3 AND EXISTS (
+			SELECT 1 FROM loyalty_points e
+			WHERE e.user_id = rd.user_id AND e.kind = 'earn' AND e.reference <> rd.reference
+				AND e.created_at <= rd.created_at AND e.created_at > rd.created_at - make_interval(hours => $1::INT)
+		)
+		GROUP BY rd.user_id
+		HAVING COUNT(*) >= $2
+		ORDER BY rd.user_id
+	`
+	return r.queryFindings(ctx, "earn and redeem cycles", query, withinHours, minCycles, since)
+}
+
+func (r *fraudRepository) FindReturnsAfterEarning(ctx context.Context, minReturns int, since time.Time) ([]*domain.FraudFinding, error) {
+	// Cancelling an order takes back the points it earned, but only as many as the member has not spent yet
+	query := `
+		SELECT user_id, FORMAT('%s orders that earned %s points cancelled since %s', COUNT(*), SUM(points_earned), TO_CHAR($2::TIMESTAMPTZ, 'YYYY-MM-DD'))
+		FROM orders
+		WHERE status = 'cancelled' AND points_earned > 0 AND updated_at >= $2
+		GROUP BY user_id
+		HAVING COUNT(*) >= $1
+		ORDER BY user_id
+	`
+	return r.queryFindings(ctx, "returns after earning", query, minReturns, since)
+}
+
+func (r *fraudRepository) FindStaffAdjustments(ctx context.Context, minPoints int, since time.Time) ([]*domain.FraudFinding, error) {
+	query := `
+		SELECT requested_by, FORMAT('%s points adjusted in %s adjustments since %s', SUM(ABS(points)), COUNT(*), TO_CHAR($2::TIMESTAMPTZ, 'YYYY-MM-DD'))
+		FROM loyalty_adjustments
+		WHERE requested_by IS NOT NULL AND status <> 'rejected' AND created_at >= $2
+		GROUP BY requested_by
+		HAVING SUM(ABS(points)) >= $1
+		ORDER BY requested_by
+	`
+	return r.queryFindings(ctx, "staff adjustments", query, minPoints, since)
+}
+
+// queryFindings runs a rule's query, which returns the user ID and details of each finding.
+func (r *fraudRepository) queryFindings(ctx context.Context, rule, query string, args ...interface{}) ([]*domain.FraudFinding, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", rule, err)
+	}
+	defer rows.Close()
+
+	var findings []*domain.FraudFinding
+	for rows.Next() {
+		finding := &domain.FraudFinding{}
+		if err := rows.Scan(&finding.UserID, &finding.Details); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", rule, err)
+		}
+		findings = append(findings, finding)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return findings, nil
+}
+
+func (r *fraudRepository) CreateFraudFlag(ctx context.Context, flag *domain.FraudFlag, reviewedSince time.Time) (bool, error) {
+	query := `
+		INSERT INTO fraud_flags (rule, user_id, details)
+		SELECT $1::VARCHAR, $2::INT, $3::TEXT
+		WHERE NOT EXISTS (SELECT 1 FROM fraud_flags WHERE rule = $1 AND user_id = $2 AND reviewed_at >= $4)
+		ON CONFLICT (rule, user_id) WHERE status = 'open' DO NOTHING
+		RETURNING ` + fraudFlagColumns
+	stored, err := scanFraudFlag(r.db.QueryRowContext(ctx, query, flag.Rule, flag.UserID, flag.Details, reviewedSince))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create fraud flag: %w", err)
+	}
+	*flag = *stored
+	return true, nil
+}
+
+func (r *fraudRepository) GetFraudFlags(ctx context.Context, status domain.FraudFlagStatus) ([]*domain.FraudFlag, error) {
+	query := `SELECT ` + fraudFlagColumns + ` FROM fraud_flags WHERE ($1 = '' OR status = $1) ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fraud flags: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []*domain.FraudFlag
+	for rows.Next() {
+		flag, err := scanFraudFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan fraud flag: %w", err)
+		}
+		flags = append(flags, flag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return flags, nil
+}
+
+func (r *fraudRepository) GetFraudFlagByID(ctx context.Context, flagID int) (*domain.FraudFlag, error) {
+	flag, err := scanFraudFlag(r.db.QueryRowContext(ctx, `SELECT `+fraudFlagColumns+` FROM fraud_flags WHERE id = $1`, flagID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("fraud flag not found")
+		}
+		return nil, fmt.Errorf("failed to get fraud flag: %w", err)
+	}
+	return flag, nil
+}
+
+func (r *fraudRepository) ReviewFraudFlag(ctx context.Context, flag *domain.FraudFlag) error {
+	query := `
+		UPDATE fraud_flags SET status = $2, reviewed_by = $3, review_note = $4, reviewed_at = NOW()
+		WHERE id = $1 AND status = 'open'
+		RETURNING reviewed_at
+	`
+	err := r.db.QueryRowContext(ctx, query, flag.ID, flag.Status, flag.ReviewedBy, flag.ReviewNote).Scan(&flag.ReviewedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("fraud flag is not open")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to review fraud flag: %w", err)
+	}
+	return nil
+}
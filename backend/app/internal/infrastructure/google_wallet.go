@@ -0,0 +1,201 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const (
+	googleWalletSaveURL   = "https://pay.google.com/gp/v/save/"
+	googleWalletAPIURL    = "https://walletobjects.googleapis.com/walletobjects/v1"
+	googleWalletScope     = "https://www.googleapis.com/auth/wallet_object.issuer"
+	googleDefaultTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// GoogleWalletConfig describes the Google Wallet issuer account and the loyalty class the cards belong to.
+// The class itself is created once in the Google Pay & Wallet Console.
+type GoogleWalletConfig struct {
+	IssuerID           string
+	ClassID            string   // Class suffix; the full class ID is prefixed with the issuer ID
+	ServiceAccountFile string   // JSON key of the service account allowed to manage the issuer's objects
+	Origins            []string // Sites allowed to show the "Save to Google Wallet" button
+}
+
+// GoogleWalletService signs save links with the service account key and updates saved cards through the Google Wallet API.
+type GoogleWalletService struct {
+	cfg         GoogleWalletConfig
+	clientEmail string
+	privateKey  *rsa.PrivateKey
+	tokenURL    string
+	httpClient  *http.Client
+
+	mu             sync.Mutex
+	accessToken    string
+	tokenExpiresAt time.Time
+}
+
+func NewGoogleWalletService(cfg *GoogleWalletConfig) (*GoogleWalletService, error) {
+	if cfg.IssuerID == "" || cfg.ClassID == "" {
+		return nil, fmt.Errorf("issuer ID and class ID are required")
+	}
+	data, err := os.ReadFile(cfg.ServiceAccountFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account key: %w", err)
+	}
+	var key struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("failed to parse service account key: %w", err)
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(key.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse service account private key: %w", err)
+	}
+
+	service := &GoogleWalletService{
+		cfg:         *cfg,
+		clientEmail: key.ClientEmail,
+		privateKey:  privateKey,
+		tokenURL:    key.TokenURI,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+	if service.tokenURL == "" {
+		service.tokenURL = googleDefaultTokenURL
+	}
+	return service, nil
+}
+
+func (s *GoogleWalletService) IssuerID() string {
+	return s.cfg.IssuerID
+}
+
+func (s *GoogleWalletService) ClassID() string {
+	return s.cfg.IssuerID + "." + s.cfg.ClassID
+}
+
+// SaveURL returns the "Save to Google Wallet" link for the card. The object travels inside the signed link,
+// so Google creates it when the member saves the card and nothing has to be created in advance.
+func (s *GoogleWalletService) SaveURL(object *domain.GoogleLoyaltyObject) (string, error) {
+	claims := jwt.MapClaims{
+		"iss":     s.clientEmail,
+		"aud":     "google",
+		"typ":     "savetowallet",
+		"iat":     time.Now().Unix(),
+		"payload": map[string]interface{}{"loyaltyObjects": []*domain.GoogleLoyaltyObject{object}},
+	}
+	if len(s.cfg.Origins) > 0 {
+		claims["origins"] = s.cfg.Origins
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign save link: %w", err)
+	}
+	return googleWalletSaveURL + token, nil
+}
+
+func (s *GoogleWalletService) UpdateLoyaltyObject(ctx context.Context, object *domain.GoogleLoyaltyObject) error {
+	accessToken, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(object)
+	if err != nil {
+		return fmt.Errorf("failed to encode loyalty object: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, googleWalletAPIURL+"/loyaltyObject/"+url.PathEscape(object.ID), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build loyalty object request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update loyalty object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Not found means the member has not saved the card yet; the save link will carry the current data
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to update loyalty object: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// token returns an OAuth access token of the service account, obtained with a signed JWT assertion and kept until it expires.
+func (s *GoogleWalletService) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accessToken != "" && time.Now().Before(s.tokenExpiresAt) {
+		return s.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.clientEmail,
+		"scope": googleWalletScope,
+		"aud":   s.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token assertion: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get access token: unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", fmt.Errorf("failed to parse access token: %w", err)
+	}
+
+	s.accessToken = tokenResp.AccessToken
+	// Renew a minute early so a token does not expire mid-request
+	s.tokenExpiresAt = now.Add(time.Duration(tokenResp.ExpiresIn)*time.Second - time.Minute)
+	return s.accessToken, nil
+}
@@ -0,0 +1,114 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type identityRepository struct {
+	db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) domain.IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	query := `INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING id, created_at`
+	err := r.db.QueryRowContext(ctx, query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(&identity.ID, &identity.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return fmt.Errorf("%s account is already linked", identity.Provider)
+		}
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+	return nil
+}
+
+func (r *identityRepository) GetIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	query := `SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at FROM user_identities WHERE provider = $1 AND subject = $2`
+	identity := &domain.UserIdentity{}
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("identity not found")
+		}
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (r *identityRepository) GetIdentitiesByUserID(ctx context.Context, userID int) ([]*domain.UserIdentity, error) {
+	query := `SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identities by user ID: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []*domain.UserIdentity
+	for rows.Next() {
+		identity := &domain.UserIdentity{}
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return identities, nil
+}
+
+func (r *identityRepository) DeleteIdentity(ctx context.Context, userID int, provider string) error {
+	query := `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`
+	result, err := r.db.ExecContext(ctx, query, userID, provider)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("identity not found")
+	}
+	return nil
+}
+
+func (r *identityRepository) SaveOAuthState(ctx context.Context, state *domain.OAuthState) error {
+	query := `INSERT INTO oauth_states (state, provider, code_verifier, nonce, user_id, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := r.db.ExecContext(ctx, query, state.State, state.Provider, state.CodeVerifier, state.Nonce, state.UserID, state.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save OAuth state: %w", err)
+	}
+	return nil
+}
+
+func (r *identityRepository) ConsumeOAuthState(ctx context.Context, state string) (*domain.OAuthState, error) {
+	// Expired states of abandoned logins are cleaned up on the way
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM oauth_states WHERE expires_at <= NOW()`); err != nil {
+		return nil, fmt.Errorf("failed to clean up OAuth states: %w", err)
+	}
+
+	query := `DELETE FROM oauth_states WHERE state = $1 RETURNING state, provider, code_verifier, nonce, user_id, expires_at`
+	oauthState := &domain.OAuthState{}
+	var userID sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query, state).Scan(&oauthState.State, &oauthState.Provider, &oauthState.CodeVerifier, &oauthState.Nonce, &userID, &oauthState.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("OAuth state not found")
+		}
+		return nil, fmt.Errorf("failed to consume OAuth state: %w", err)
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		oauthState.UserID = &id
+	}
+	return oauthState, nil
+}
@@ -0,0 +1,232 @@
+package infrastructure
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// JWTConfig describes the keys used to sign and verify access tokens.
+type JWTConfig struct {
+	Issuer         string
+	Algorithm      string // HS256, RS256 or EdDSA
+	KeyID          string
+	Secret         string // HS256 only
+	PrivateKeyFile string // PEM file for RS256 and EdDSA
+	// VerificationKeyFiles maps key IDs of retired signing keys to PEM files,
+	// so tokens they signed stay valid during a rotation.
+	VerificationKeyFiles map[string]string
+}
+
+type jwtKey struct {
+	id     string
+	method jwt.SigningMethod
+	verify interface{} // Key passed to jwt for verification
+	public crypto.PublicKey
+}
+
+// JWTIssuer signs tokens with one active key and verifies them against every configured key.
+type JWTIssuer struct {
+	issuer     string
+	signingKey *jwtKey
+	signWith   interface{}
+	keys       map[string]*jwtKey
+}
+
+// NewJWTIssuer loads the keys described by cfg.
+// With no key configured it falls back to an ephemeral EdDSA key, which is only suitable for local development.
+func NewJWTIssuer(cfg *JWTConfig) (*JWTIssuer, error) {
+	issuer := &JWTIssuer{issuer: cfg.Issuer, keys: make(map[string]*jwtKey)}
+
+	keyID := cfg.KeyID
+	if keyID == "" {
+		keyID = "default"
+	}
+
+	switch strings.ToUpper(cfg.Algorithm) {
+	case "HS256":
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("JWT secret is required for HS256")
+		}
+		secret := []byte(cfg.Secret)
+		issuer.signingKey = &jwtKey{id: keyID, method: jwt.SigningMethodHS256, verify: secret}
+		issuer.signWith = secret
+	case "RS256", "EDDSA":
+		if cfg.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("private key file is required for %s", cfg.Algorithm)
+		}
+		pemData, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+		key, private, err := parsePrivateKey(keyID, pemData)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(key.method.Alg(), cfg.Algorithm) {
+			return nil, fmt.Errorf("private key does not match algorithm %s", cfg.Algorithm)
+		}
+		issuer.signingKey = key
+		issuer.signWith = private
+	case "":
+		log.Println("WARNING: no JWT signing key configured, using an ephemeral EdDSA key")
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		issuer.signingKey = &jwtKey{id: keyID, method: jwt.SigningMethodEdDSA, verify: public, public: public}
+		issuer.signWith = private
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+	issuer.keys[keyID] = issuer.signingKey
+
+	for id, path := range cfg.VerificationKeyFiles {
+		if _, exists := issuer.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate JWT key ID %q", id)
+		}
+		pemData, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read verification key %q: %w", id, err)
+		}
+		key, err := parseVerificationKey(id, pemData)
+		if err != nil {
+			return nil, err
+		}
+		issuer.keys[id] = key
+	}
+
+	return issuer, nil
+}
+
+// ParseVerificationKeyFiles parses a "kid:path,kid:path" list of retired keys.
+func ParseVerificationKeyFiles(value string) (map[string]string, error) {
+	files := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, path, ok := strings.Cut(entry, ":")
+		if !ok || id == "" || path == "" {
+			return nil, fmt.Errorf("invalid verification key entry %q, expected kid:path", entry)
+		}
+		files[id] = path
+	}
+	return files, nil
+}
+
+func (i *JWTIssuer) Issue(claims *domain.Claims) (string, error) {
+	if i.issuer != "" {
+		claims.Issuer = i.issuer
+	}
+	token := jwt.NewWithClaims(i.signingKey.method, claims)
+	token.Header["kid"] = i.signingKey.id
+	tokenString, err := token.SignedString(i.signWith)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return tokenString, nil
+}
+
+func (i *JWTIssuer) Parse(tokenString string) (*domain.Claims, error) {
+	var options []jwt.ParserOption
+	if i.issuer != "" {
+		options = append(options, jwt.WithIssuer(i.issuer))
+	}
+
+	claims := &domain.Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := i.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		// Never let the token choose a different algorithm than the key was configured for
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.verify, nil
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return claims, nil
+}
+
+func (i *JWTIssuer) JWKS() *domain.JSONWebKeySet {
+	ids := make([]string, 0, len(i.keys))
+	for id := range i.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := &domain.JSONWebKeySet{Keys: []domain.JSONWebKey{}}
+	for _, id := range ids {
+		key := i.keys[id]
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, domain.JSONWebKey{
+				KeyType:   "RSA",
+				KeyID:     key.id,
+				Algorithm: key.method.Alg(),
+				Use:       "sig",
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, domain.JSONWebKey{
+				KeyType:   "OKP",
+				KeyID:     key.id,
+				Algorithm: key.method.Alg(),
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return set
+}
+
+// parsePrivateKey reads an RSA or Ed25519 private key in PEM format.
+func parsePrivateKey(id string, pemData []byte) (*jwtKey, crypto.Signer, error) {
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(pemData); err == nil {
+		return &jwtKey{id: id, method: jwt.SigningMethodRS256, verify: &private.PublicKey, public: &private.PublicKey}, private, nil
+	}
+	if private, err := jwt.ParseEdPrivateKeyFromPEM(pemData); err == nil {
+		signer := private.(ed25519.PrivateKey)
+		public := signer.Public().(ed25519.PublicKey)
+		return &jwtKey{id: id, method: jwt.SigningMethodEdDSA, verify: public, public: public}, signer, nil
+	}
+	return nil, nil, fmt.Errorf("key %q is neither an RSA nor an Ed25519 private key", id)
+}
+
+// parseVerificationKey reads an RSA or Ed25519 public key, or derives it from a private key.
+func parseVerificationKey(id string, pemData []byte) (*jwtKey, error) {
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(pemData); err == nil {
+		return &jwtKey{id: id, method: jwt.SigningMethodRS256, verify: public, public: public}, nil
+	}
+	if public, err := jwt.ParseEdPublicKeyFromPEM(pemData); err == nil {
+		edPublic := public.(ed25519.PublicKey)
+		return &jwtKey{id: id, method: jwt.SigningMethodEdDSA, verify: edPublic, public: edPublic}, nil
+	}
+	key, _, err := parsePrivateKey(id, pemData)
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
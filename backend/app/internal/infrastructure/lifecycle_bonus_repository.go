@@ -0,0 +1,115 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type lifecycleBonusRepository struct {
+	db *sql.DB
+}
+
+func NewLifecycleBonusRepository(db *sql.DB) domain.LifecycleBonusRepository {
+	return &lifecycleBonusRepository{db: db}
+}
+
+func (r *lifecycleBonusRepository) GetBirthdayCandidates(ctx context.Context, day time.Time) ([]*domain.LifecycleCandidate, error) {
+	// Members born on 29 February celebrate on the 28th in other years
+	query := `
+		SELECT u.id, u.username, u.birth_date, $2::VARCHAR FROM users u
+		WHERE u.deleted_at IS NULL AND u.birth_date IS NOT NULL
+			AND (
+				TO_CHAR(u.birth_date, 'MM-DD') = TO_CHAR($1::DATE, 'MM-DD')
+				OR (TO_CHAR(u.birth_date, 'MM-DD') = '02-29' AND TO_CHAR($1::DATE, 'MM-DD') = '02-28'
+					AND TO_CHAR($1::DATE + 1, 'MM-DD') = '03-01')
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM lifecycle_bonuses b WHERE b.user_id = u.id AND b.kind = 'birthday' AND b.period = $2::VARCHAR
+			)
+		ORDER BY u.id
+	`
+	return r.queryCandidates(ctx, query, day, day.Format("2006"))
+}
+
+func (r *lifecycleBonusRepository) GetAnniversaryCandidates(ctx context.Context, day time.Time) ([]*domain.LifecycleCandidate, error) {
+	query := `
+		SELECT u.id, u.username, u.created_at, $2::VARCHAR FROM users u
+		WHERE u.deleted_at IS NULL
+			AND EXTRACT(YEAR FROM u.created_at) < EXTRACT(YEAR FROM $1::DATE)
+			AND (
+				TO_CHAR(u.created_at, 'MM-DD') = TO_CHAR($1::DATE, 'MM-DD')
+				OR (TO_CHAR(u.created_at, 'MM-DD') = '02-29' AND TO_CHAR($1::DATE, 'MM-DD') = '02-28'
+					AND TO_CHAR($1::DATE + 1, 'MM-DD') = '03-01')
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM lifecycle_bonuses b WHERE b.user_id = u.id AND b.kind = 'anniversary' AND b.period = $2::VARCHAR
+			)
+		ORDER BY u.id
+	`
+	return r.queryCandidates(ctx, query, day, day.Format("2006"))
+}
+
+func (r *lifecycleBonusRepository) GetWinBackCandidates(ctx context.Context, inactiveSince time.Time) ([]*domain.LifecycleCandidate, error) {
+	// Activity is a paid order or a store visit; members who never came count from the day they joined.
+	// The period is the day of the last activity, so each spell of inactivity earns one win-back
+	query := `
+		SELECT id, username, last_activity, TO_CHAR(last_activity, 'YYYY-MM-DD') FROM (
+			SELECT u.id, u.username, GREATEST(
+				u.created_at,
+				(SELECT MAX(o.order_date) FROM orders o WHERE o.user_id = u.id AND o.payment_status = 'paid'),
+				(SELECT MAX(v.visited_at) FROM store_visits v WHERE v.user_id = u.id)
+			) AS last_activity
+			FROM users u WHERE u.deleted_at IS NULL
+		) members
+		WHERE last_activity < $1
+			AND NOT EXISTS (
+				SELECT 1 FROM lifecycle_bonuses b
+				WHERE b.user_id = members.id AND b.kind = 'win_back' AND b.period = TO_CHAR(last_activity, 'YYYY-MM-DD')
+			)
+		ORDER BY id
+	`
+	return r.queryCandidates(ctx, query, inactiveSince)
+}
+
+func (r *lifecycleBonusRepository) RecordLifecycleBonus(ctx context.Context, bonus *domain.LifecycleBonus) (bool, error) {
+	query := `
+		INSERT INTO lifecycle_bonuses (user_id, kind, period, points)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, kind, period) DO NOTHING
+		RETURNING id, granted_at
+	`
+	err := r.db.QueryRowContext(ctx, query, bonus.UserID, bonus.Kind, bonus.Period, bonus.Points).Scan(&bonus.ID, &bonus.GrantedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to record lifecycle bonus: %w", err)
+	}
+	return true, nil
+}
+
+func (r *lifecycleBonusRepository) queryCandidates(ctx context.Context, query string, args ...interface{}) ([]*domain.LifecycleCandidate, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lifecycle bonus candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []*domain.LifecycleCandidate
+	for rows.Next() {
+		c := &domain.LifecycleCandidate{}
+		if err := rows.Scan(&c.UserID, &c.Username, &c.Since, &c.Period); err != nil {
+			return nil, fmt.Errorf("failed to scan lifecycle bonus candidate: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return candidates, nil
+}
@@ -0,0 +1,214 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type loyaltyAdjustmentRepository struct {
+	db *sql.DB
+}
+
+func NewLoyaltyAdjustmentRepository(db *sql.DB) domain.LoyaltyAdjustmentRepository {
+	return &loyaltyAdjustmentRepository{db: db}
+}
+
+const loyaltyAdjustmentColumns = `id, user_id, points, reason_code, note, status, requested_by, reviewed_by, review_note, entry_id, idempotency_key, created_at, reviewed_at`
+
+func scanLoyaltyAdjustment(row rowScanner) (*domain.LoyaltyAdjustment, error) {
+	adjustment := &domain.LoyaltyAdjustment{}
+	err := row.Scan(&adjustment.ID, &adjustment.UserID, &adjustment.Points, &adjustment.ReasonCode, &adjustment.Note, &adjustment.Status,
+		&adjustment.RequestedBy, &adjustment.ReviewedBy, &adjustment.ReviewNote, &adjustment.EntryID, &adjustment.IdempotencyKey,
+		&adjustment.CreatedAt, &adjustment.ReviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+func (r *loyaltyAdjustmentRepository) CreateAdjustment(ctx context.Context, adjustment *domain.LoyaltyAdjustment, dailyLimit int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if dailyLimit > 0 && adjustment.RequestedBy != nil {
+		// Parallel requests by the same staff member wait here, so together they cannot overrun the limit
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, *adjustment.RequestedBy); err != nil {
+			return false, fmt.Errorf("failed to lock staff member: %w", err)
+		}
+	}
+
+	// A repeated request gets the adjustment stored the first time, even if the limit has been reached since
+	stored := func() (bool, error) {
+		row := tx.QueryRowContext(ctx, `SELECT `+loyaltyAdjustmentColumns+` FROM loyalty_adjustments WHERE idempotency_key = $1`, adjustment.IdempotencyKey)
+		existing, err := scanLoyaltyAdjustment(row)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get loyalty adjustment: %w", err)
+		}
+		*adjustment = *existing
+		return true, nil
+	}
+	if found, err := stored(); found || err != nil {
+		return false, err
+	}
+
+	if dailyLimit > 0 && adjustment.RequestedBy != nil {
+		var used int
+		query := `
+			SELECT COALESCE(SUM(ABS(points)), 0) FROM loyalty_adjustments
+			WHERE requested_by = $1 AND status <> 'rejected' AND created_at > NOW() - INTERVAL '24 hours'
+		`
+		if err := tx.QueryRowContext(ctx, query, *adjustment.RequestedBy).Scan(&used); err != nil {
+			return false, fmt.Errorf("failed to sum recent adjustments: %w", err)
+		}
+		points := adjustment.Points
+		if points < 0 {
+			points = -points
+		}
+		if used+points > dailyLimit {
+			return false, fmt.Errorf("adjustment exceeds your daily limit of %d points, %d used in the last 24 hours", dailyLimit, used)
+		}
+	}
+
+	query := `
+		INSERT INTO loyalty_adjustments (user_id, points, reason_code, note, status, requested_by, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (idempotency_key) DO NOTHING
+		RETURNING id, created_at
+	`
+	err = tx.QueryRowContext(ctx, query, adjustment.UserID, adjustment.Points, adjustment.ReasonCode, adjustment.Note,
+		domain.AdjustmentPending, adjustment.RequestedBy, adjustment.IdempotencyKey).Scan(&adjustment.ID, &adjustment.CreatedAt)
+	if err == sql.ErrNoRows {
+		// Stored by a parallel request with the same key since the check above
+		_, err := stored()
+		return false, err
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create loyalty adjustment: %w", err)
+	}
+	adjustment.Status = domain.AdjustmentPending
+
+	if err := recordAdjustmentEvent(ctx, tx, adjustment.ID, adjustment.RequestedBy, domain.AdjustmentRequested, adjustment.Note); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (r *loyaltyAdjustmentRepository) GetAdjustmentByID(ctx context.Context, adjustmentID int) (*domain.LoyaltyAdjustment, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+loyaltyAdjustmentColumns+` FROM loyalty_adjustments WHERE id = $1`, adjustmentID)
+	adjustment, err := scanLoyaltyAdjustment(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("loyalty adjustment not found")
+		}
+		return nil, fmt.Errorf("failed to get loyalty adjustment: %w", err)
+	}
+	return adjustment, nil
+}
+
+func (r *loyaltyAdjustmentRepository) GetAdjustments(ctx context.Context, userID int, status domain.AdjustmentStatus) ([]*domain.LoyaltyAdjustment, error) {
+	query := `
+		SELECT ` + loyaltyAdjustmentColumns + ` FROM loyalty_adjustments
+		WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loyalty adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	var adjustments []*domain.LoyaltyAdjustment
+	for rows.Next() {
+		adjustment, err := scanLoyaltyAdjustment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan loyalty adjustment: %w", err)
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return adjustments, nil
+}
+
+func (r *loyaltyAdjustmentRepository) ReviewAdjustment(ctx context.Context, adjustment *domain.LoyaltyAdjustment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE loyalty_adjustments SET status = $2, reviewed_by = $3, review_note = $4, entry_id = $5, reviewed_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING reviewed_at
+	`
+	err = tx.QueryRowContext(ctx, query, adjustment.ID, adjustment.Status, adjustment.ReviewedBy, adjustment.ReviewNote, adjustment.EntryID).Scan(&adjustment.ReviewedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("loyalty adjustment is not pending")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to review loyalty adjustment: %w", err)
+	}
+
+	action := domain.AdjustmentEventApproved
+	if adjustment.Status == domain.AdjustmentRejected {
+		action = domain.AdjustmentEventRejected
+	}
+	if err := recordAdjustmentEvent(ctx, tx, adjustment.ID, adjustment.ReviewedBy, action, adjustment.ReviewNote); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *loyaltyAdjustmentRepository) GetAdjustmentEvents(ctx context.Context, adjustmentID int) ([]*domain.LoyaltyAdjustmentEvent, error) {
+	query := `
+		SELECT id, adjustment_id, actor_id, action, note, created_at
+		FROM loyalty_adjustment_events WHERE adjustment_id = $1 ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, adjustmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get loyalty adjustment events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*domain.LoyaltyAdjustmentEvent
+	for rows.Next() {
+		event := &domain.LoyaltyAdjustmentEvent{}
+		if err := rows.Scan(&event.ID, &event.AdjustmentID, &event.ActorID, &event.Action, &event.Note, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan loyalty adjustment event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return events, nil
+}
+
+func recordAdjustmentEvent(ctx context.Context, tx *sql.Tx, adjustmentID int, actorID *int, action domain.AdjustmentEventAction, note string) error {
+	query := `INSERT INTO loyalty_adjustment_events (adjustment_id, actor_id, action, note) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, query, adjustmentID, actorID, action, note); err != nil {
+		return fmt.Errorf("failed to record loyalty adjustment event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,542 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type loyaltyLedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLoyaltyLedgerRepository(db *sql.DB) domain.LoyaltyLedgerRepository {
+	return &loyaltyLedgerRepository{db: db}
+}
+
+const loyaltyPointColumns = `id, user_id, points, type, kind, idempotency_key, reference, reverses_id, balance_after, expires_at, created_at`
+
+func (r *loyaltyLedgerRepository) PostEntry(ctx context.Context, entry *domain.LoyaltyPoint) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balance, posted, err := lockBalance(ctx, tx, entry)
+	if err != nil || posted {
+		return false, err
+	}
+	if err := insertEntry(ctx, tx, entry, balance); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (r *loyaltyLedgerRepository) ExpirePoints(ctx context.Context, entry *domain.LoyaltyPoint) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balance, posted, err := lockBalance(ctx, tx, entry)
+	if err != nil || posted {
+		return false, err
+	}
+
+	query := `
+		SELECT COALESCE(SUM(l.remaining), 0) FROM loyalty_point_lots l JOIN loyalty_points e ON e.id = l.entry_id
+		WHERE l.user_id = $1 AND l.remaining > 0 AND e.expires_at <= NOW()
+	`
+	var expired int
+	if err := tx.QueryRowContext(ctx, query, entry.UserID).Scan(&expired); err != nil {
+		return false, fmt.Errorf("failed to sum expired points: %w", err)
+	}
+	if expired == 0 {
+		return false, nil
+	}
+	// Expired lots are the soonest-expiring, so consuming this many takes exactly them
+	entry.Points = -expired
+	if err := insertEntry(ctx, tx, entry, balance); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// lockBalance locks the member's balance for the rest of the transaction and returns it.
+// If the entry's idempotency key was already used, the stored entry is copied into entry and posted is true.
+func lockBalance(ctx context.Context, tx *sql.Tx, entry *domain.LoyaltyPoint) (balance int, posted bool, err error) {
+	// Members enrolled before the ledger may have no balance row yet
+	if _, err := tx.ExecContext(ctx, `INSERT INTO user_loyalty (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, entry.UserID); err != nil {
+		return 0, false, fmt.Errorf("failed to create loyalty balance: %w", err)
+	}
+	// Locking the balance serializes every posting for the member, including retries with the same key
+	if err := tx.QueryRowContext(ctx, `SELECT current_points FROM user_loyalty WHERE user_id = $1 FOR UPDATE`, entry.UserID).Scan(&balance); err != nil {
+		return 0, false, fmt.Errorf("failed to lock loyalty balance: %w", err)
+	}
+
+	query := `SELECT ` + loyaltyPointColumns + ` FROM loyalty_points WHERE idempotency_key = $1`
+	existing, err := scanLoyaltyPoint(tx.QueryRowContext(ctx, query, entry.IdempotencyKey))
+	if err == nil {
+		*entry = *existing
+		return balance, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to get loyalty entry: %w", err)
+	}
+	return balance, false, nil
+}
+
+// insertEntry writes the entry, its postings and its lot, and moves the balances. The balance must be locked.
+func insertEntry(ctx context.Context, tx *sql.Tx, entry *domain.LoyaltyPoint, balance int) error {
+	if balance+entry.Points < 0 {
+		return fmt.Errorf("insufficient points")
+	}
+
+	counterAccount := entry.Kind.ProgramAccount()
+	if entry.Kind == domain.LedgerReverse {
+		if entry.ReversesID == nil {
+			return fmt.Errorf("reversal has no entry to reverse")
+		}
+		query := `SELECT p.account FROM loyalty_postings p JOIN loyalty_points e ON e.id = p.transaction_id WHERE e.id = $1 AND e.user_id = $2 AND p.account <> $3`
+		if err := tx.QueryRowContext(ctx, query, *entry.ReversesID, entry.UserID, domain.MemberAccount(entry.UserID)).Scan(&counterAccount); err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("loyalty entry not found")
+			}
+			return fmt.Errorf("failed to get reversed loyalty entry: %w", err)
+		}
+	}
+	if counterAccount == "" {
+		return fmt.Errorf("unknown loyalty entry kind %q", entry.Kind)
+	}
+
+	// Reversing a debit puts the points back into the lots it took them from, and the entry shows
+	// the soonest of their expiries. Debits posted before lots were tracked get a fresh lot instead.
+	var restores []lotTake
+	if entry.Points > 0 && entry.ReversesID != nil {
+		var expiresAt *string
+		var err error
+		if restores, expiresAt, err = consumedLots(ctx, tx, *entry.ReversesID, entry.Points); err != nil {
+			return err
+		}
+		if len(restores) > 0 {
+			entry.ExpiresAt = expiresAt
+		}
+	}
+
+	entry.BalanceAfter = balance + entry.Points
+	query := `
+		INSERT INTO loyalty_points (user_id, points, type, kind, idempotency_key, reference, reverses_id, balance_after, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at
+	`
+	err := tx.QueryRowContext(
+		ctx, query, entry.UserID, entry.Points, entry.Type, entry.Kind, entry.IdempotencyKey, entry.Reference, entry.ReversesID, entry.BalanceAfter, entry.ExpiresAt,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create loyalty entry: %w", err)
+	}
+
+	query = `INSERT INTO loyalty_postings (transaction_id, account, amount) VALUES ($1, $2, $3), ($1, $4, $5)`
+	if _, err := tx.ExecContext(ctx, query, entry.ID, domain.MemberAccount(entry.UserID), entry.Points, counterAccount, -entry.Points); err != nil {
+		return fmt.Errorf("failed to create loyalty postings: %w", err)
+	}
+
+	if entry.Points > 0 {
+		unrestored := entry.Points
+		for _, t := range restores {
+			// The lot may be close to expiring again, so it is warned about afresh
+			query = `UPDATE loyalty_point_lots SET remaining = remaining + $2, warned_days_before = NULL WHERE entry_id = $1`
+			if _, err := tx.ExecContext(ctx, query, t.entryID, t.points); err != nil {
+				return fmt.Errorf("failed to restore point lot: %w", err)
+			}
+			unrestored -= t.points
+		}
+		if unrestored > 0 {
+			query = `INSERT INTO loyalty_point_lots (entry_id, user_id, remaining) VALUES ($1, $2, $3)`
+			if _, err := tx.ExecContext(ctx, query, entry.ID, entry.UserID, unrestored); err != nil {
+				return fmt.Errorf("failed to create point lot: %w", err)
+			}
+		}
+	} else if err := consumeLots(ctx, tx, entry, -entry.Points); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE user_loyalty SET current_points = $2, last_activity_at = NOW() WHERE user_id = $1`, entry.UserID, entry.BalanceAfter); err != nil {
+		return fmt.Errorf("failed to update loyalty balance: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET current_points = $2 WHERE id = $1`, entry.UserID, entry.BalanceAfter); err != nil {
+		return fmt.Errorf("failed to update loyalty balance: %w", err)
+	}
+	return nil
+}
+
+// lotTake is a number of points taken from or put back into the lot of a credit entry.
+type lotTake struct{ entryID, points int }
+
+// consumeLots takes the debit entry's points from the member's lots, soonest-expiring first, and records
+// the lots it took them from. A reversal takes from the lot of the entry it reverses first, so taking
+// back earned points leaves the member's older points alone.
+func consumeLots(ctx context.Context, tx *sql.Tx, entry *domain.LoyaltyPoint, points int) error {
+	query := `
+		SELECT l.entry_id, l.remaining FROM loyalty_point_lots l JOIN loyalty_points e ON e.id = l.entry_id
+		WHERE l.user_id = $1 AND l.remaining > 0
+		ORDER BY COALESCE(l.entry_id = $2, FALSE) DESC, e.expires_at NULLS LAST, e.id
+	`
+	rows, err := tx.QueryContext(ctx, query, entry.UserID, entry.ReversesID)
+	if err != nil {
+		return fmt.Errorf("failed to get point lots: %w", err)
+	}
+	var lots []lotTake
+	for rows.Next() {
+		var lot lotTake
+		if err := rows.Scan(&lot.entryID, &lot.points); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan point lot: %w", err)
+		}
+		lots = append(lots, lot)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	for _, t := range takeFromLots(lots, points) {
+		if _, err := tx.ExecContext(ctx, `UPDATE loyalty_point_lots SET remaining = remaining - $2 WHERE entry_id = $1`, t.entryID, t.points); err != nil {
+			return fmt.Errorf("failed to consume point lot: %w", err)
+		}
+		query = `INSERT INTO loyalty_lot_consumptions (entry_id, lot_entry_id, points) VALUES ($1, $2, $3)`
+		if _, err := tx.ExecContext(ctx, query, entry.ID, t.entryID, t.points); err != nil {
+			return fmt.Errorf("failed to record point lot consumption: %w", err)
+		}
+	}
+	return nil
+}
+
+// consumedLots returns up to points of what the debit entry took from each lot, latest-expiring first,
+// and the soonest expiry among those lots.
+func consumedLots(ctx context.Context, tx *sql.Tx, debitID, points int) ([]lotTake, *string, error) {
+	query := `
+		SELECT c.lot_entry_id, c.points, e.expires_at FROM loyalty_lot_consumptions c JOIN loyalty_points e ON e.id = c.lot_entry_id
+		WHERE c.entry_id = $1
+		ORDER BY e.expires_at DESC NULLS FIRST, e.id DESC
+	`
+	rows, err := tx.QueryContext(ctx, query, debitID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get consumed point lots: %w", err)
+	}
+	defer rows.Close()
+
+	var consumed []lotTake
+	expiries := make(map[int]time.Time)
+	for rows.Next() {
+		var t lotTake
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&t.entryID, &t.points, &expiresAt); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan consumed point lot: %w", err)
+		}
+		consumed = append(consumed, t)
+		if expiresAt.Valid {
+			expiries[t.entryID] = expiresAt.Time
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	takes := takeFromLots(consumed, points)
+	return takes, soonestExpiry(takes, expiries), nil
+}
+
+// takeFromLots takes points from the lots in order, each giving up to what it holds, and returns what was taken.
+func takeFromLots(lots []lotTake, points int) []lotTake {
+	var takes []lotTake
+	for _, lot := range lots {
+		if points <= 0 {
+			break
+		}
+		n := min(lot.points, points)
+		takes = append(takes, lotTake{lot.entryID, n})
+		points -= n
+	}
+	return takes
+}
+
+// soonestExpiry returns the soonest expiry of the lots taken from, or nil if none of them expire.
+func soonestExpiry(takes []lotTake, expiries map[int]time.Time) *string {
+	var soonest *time.Time
+	for _, t := range takes {
+		if expiresAt, ok := expiries[t.entryID]; ok && (soonest == nil || expiresAt.Before(*soonest)) {
+			soonest = &expiresAt
+		}
+	}
+	if soonest == nil {
+		return nil
+	}
+	expiresAt := soonest.Format(time.RFC3339)
+	return &expiresAt
+}
+
+func (r *loyaltyLedgerRepository) GetEntryByIdempotencyKey(ctx context.Context, key string) (*domain.LoyaltyPoint, error) {
+	query := `SELECT ` + loyaltyPointColumns + ` FROM loyalty_points WHERE idempotency_key = $1`
+	entry, err := scanLoyaltyPoint(r.db.QueryRowContext(ctx, query, key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("loyalty entry not found")
+		}
+		return nil, fmt.Errorf("failed to get loyalty entry: %w", err)
+	}
+	return entry, nil
+}
+
+func (r *loyaltyLedgerRepository) GetEntriesByUserID(ctx context.Context, userID, limit, offset int) ([]*domain.LoyaltyPoint, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM loyalty_points WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count loyalty entries: %w", err)
+	}
+
+	query := `SELECT ` + loyaltyPointColumns + ` FROM loyalty_points WHERE user_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get loyalty entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*domain.LoyaltyPoint
+	for rows.Next() {
+		entry, err := scanLoyaltyPoint(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan loyalty entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("rows error: %w", err)
+	}
+	return entries, total, nil
+}
+
+func (r *loyaltyLedgerRepository) GetQualifyingPoints(ctx context.Context, userID int, since time.Time) (int, error) {
+	query := `
+		SELECT COALESCE(SUM(e.points), 0) FROM loyalty_points e
+		LEFT JOIN loyalty_points o ON o.id = e.reverses_id
+		WHERE e.user_id = $1 AND e.created_at > $2
+			AND COALESCE(o.kind, e.kind) = 'earn' AND COALESCE(o.type, e.type) = 'purchase'
+	`
+	var points int
+	if err := r.db.QueryRowContext(ctx, query, userID, since).Scan(&points); err != nil {
+		return 0, fmt.Errorf("failed to sum qualifying points: %w", err)
+	}
+	return points, nil
+}
+
+func (r *loyaltyLedgerRepository) GetBalanceMismatches(ctx context.Context) ([]*domain.LedgerMismatch, error) {
+	query := `
+		SELECT u.id, COALESCE(e.balance, 0), COALESCE(p.balance, 0), COALESCE(ul.current_points, 0), u.current_points
+		FROM users u
+		LEFT JOIN (SELECT user_id, SUM(points) AS balance FROM loyalty_points GROUP BY user_id) e ON e.user_id = u.id
+		LEFT JOIN (SELECT account, SUM(amount) AS balance FROM loyalty_postings WHERE account LIKE 'member:%' GROUP BY account) p ON p.account = 'member:' || u.id
+		LEFT JOIN user_loyalty ul ON ul.user_id = u.id
+		WHERE COALESCE(e.balance, 0) <> COALESCE(p.balance, 0)
+			OR COALESCE(e.balance, 0) <> COALESCE(ul.current_points, 0)
+			OR COALESCE(e.balance, 0) <> u.current_points
+		ORDER BY u.id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance mismatches: %w", err)
+	}
+	defer rows.Close()
+
+	var mismatches []*domain.LedgerMismatch
+	for rows.Next() {
+		m := &domain.LedgerMismatch{}
+		if err := rows.Scan(&m.UserID, &m.LedgerBalance, &m.PostingsBalance, &m.LoyaltyBalance, &m.UserBalance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance mismatch: %w", err)
+		}
+		mismatches = append(mismatches, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return mismatches, nil
+}
+
+func (r *loyaltyLedgerRepository) GetUnbalancedEntryIDs(ctx context.Context) ([]int, error) {
+	query := `
+		SELECT e.id FROM loyalty_points e
+		LEFT JOIN loyalty_postings p ON p.transaction_id = e.id
+		GROUP BY e.id
+		HAVING COUNT(p.id) = 0 OR SUM(p.amount) <> 0
+		ORDER BY e.id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unbalanced loyalty entries: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan loyalty entry ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *loyaltyLedgerRepository) RepairBalance(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO user_loyalty (user_id) VALUES ($1) ON CONFLICT (user_id) DO NOTHING`, userID); err != nil {
+		return fmt.Errorf("failed to create loyalty balance: %w", err)
+	}
+	// Taking the same lock as PostEntry keeps a posting from landing between the sum and the update
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM user_loyalty WHERE user_id = $1 FOR UPDATE`, userID); err != nil {
+		return fmt.Errorf("failed to lock loyalty balance: %w", err)
+	}
+	var balance int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(points), 0) FROM loyalty_points WHERE user_id = $1`, userID).Scan(&balance); err != nil {
+		return fmt.Errorf("failed to sum loyalty entries: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE user_loyalty SET current_points = $2 WHERE user_id = $1`, userID, balance); err != nil {
+		return fmt.Errorf("failed to repair loyalty balance: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET current_points = $2 WHERE id = $1`, userID, balance); err != nil {
+		return fmt.Errorf("failed to repair loyalty balance: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *loyaltyLedgerRepository) GetUserIDsWithExpiredPoints(ctx context.Context) ([]int, error) {
+	query := `
+		SELECT DISTINCT l.user_id FROM loyalty_point_lots l JOIN loyalty_points e ON e.id = l.entry_id
+		WHERE l.remaining > 0 AND e.expires_at <= NOW()
+		ORDER BY l.user_id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users with expired points: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan user ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return ids, nil
+}
+
+func (r *loyaltyLedgerRepository) GetPointLotsByUserID(ctx context.Context, userID int) ([]*domain.PointLot, error) {
+	query := `
+		SELECT l.entry_id, l.user_id, l.remaining, e.expires_at FROM loyalty_point_lots l JOIN loyalty_points e ON e.id = l.entry_id
+		WHERE l.user_id = $1 AND l.remaining > 0
+		ORDER BY e.expires_at NULLS LAST, e.id
+	`
+	return r.queryPointLots(ctx, query, userID)
+}
+
+func (r *loyaltyLedgerRepository) DateCreditsWithoutExpiry(ctx context.Context, lifetimeMonths int, notBefore time.Time) (int, error) {
+	query := `
+		UPDATE loyalty_points SET expires_at = GREATEST(created_at + make_interval(months => $1), $2)
+		WHERE points > 0 AND expires_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, lifetimeMonths, notBefore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to date credits without expiry: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return int(rowsAffected), nil
+}
+
+func (r *loyaltyLedgerRepository) GetUnwarnedExpiringLots(ctx context.Context, daysBefore int) ([]*domain.PointLot, error) {
+	query := `
+		SELECT l.entry_id, l.user_id, l.remaining, e.expires_at FROM loyalty_point_lots l JOIN loyalty_points e ON e.id = l.entry_id
+		JOIN users u ON u.id = l.user_id AND u.deleted_at IS NULL
+		WHERE l.remaining > 0 AND e.expires_at > NOW() AND e.expires_at <= NOW() + make_interval(days => $1)
+			AND (l.warned_days_before IS NULL OR l.warned_days_before > $1)
+		ORDER BY l.user_id, e.expires_at, e.id
+	`
+	return r.queryPointLots(ctx, query, daysBefore)
+}
+
+func (r *loyaltyLedgerRepository) MarkLotsWarned(ctx context.Context, entryIDs []int, daysBefore int) error {
+	query := `UPDATE loyalty_point_lots SET warned_days_before = $2 WHERE entry_id = ANY($1)`
+	if _, err := r.db.ExecContext(ctx, query, pq.Array(entryIDs), daysBefore); err != nil {
+		return fmt.Errorf("failed to mark point lots warned: %w", err)
+	}
+	return nil
+}
+
+func (r *loyaltyLedgerRepository) queryPointLots(ctx context.Context, query string, args ...interface{}) ([]*domain.PointLot, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point lots: %w", err)
+	}
+	defer rows.Close()
+
+	var lots []*domain.PointLot
+	for rows.Next() {
+		lot := &domain.PointLot{}
+		if err := rows.Scan(&lot.EntryID, &lot.UserID, &lot.Remaining, &lot.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan point lot: %w", err)
+		}
+		lots = append(lots, lot)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return lots, nil
+}
+
+func scanLoyaltyPoint(row rowScanner) (*domain.LoyaltyPoint, error) {
+	point := &domain.LoyaltyPoint{}
+	err := row.Scan(
+		&point.ID, &point.UserID, &point.Points, &point.Type, &point.Kind, &point.IdempotencyKey,
+		&point.Reference, &point.ReversesID, &point.BalanceAfter, &point.ExpiresAt, &point.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return point, nil
+}
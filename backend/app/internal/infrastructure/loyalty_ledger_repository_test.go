@@ -0,0 +1,72 @@
+package infrastructure
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTakeFromLots(t *testing.T) {
+	tests := []struct {
+		name   string
+		lots   []lotTake
+		points int
+		want   []lotTake
+	}{
+		{name: "first lot covers it", lots: []lotTake{{1, 100}, {2, 50}}, points: 40, want: []lotTake{{1, 40}}},
+		{name: "spills into the next lot", lots: []lotTake{{1, 100}, {2, 50}}, points: 120, want: []lotTake{{1, 100}, {2, 20}}},
+		{name: "takes whole lots exactly", lots: []lotTake{{1, 100}, {2, 50}}, points: 150, want: []lotTake{{1, 100}, {2, 50}}},
+		{name: "lots run out", lots: []lotTake{{1, 30}}, points: 50, want: []lotTake{{1, 30}}},
+		{name: "nothing to take", lots: []lotTake{{1, 30}}, points: 0, want: nil},
+		{name: "no lots", lots: nil, points: 10, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := takeFromLots(tt.lots, tt.points); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("takeFromLots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A reversed debit gives its points back to the lots it consumed, latest-expiring first, and the reversal
+// entry shows the soonest expiry among the lots that got points back.
+func TestRestoreConsumedLots(t *testing.T) {
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	june := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	expiries := map[int]time.Time{1: march, 2: june}
+	// Consumptions of a debit as consumedLots reads them: lot 3 never expires, then lot 2, then lot 1
+	consumed := []lotTake{{3, 20}, {2, 50}, {1, 30}}
+
+	tests := []struct {
+		name        string
+		points      int
+		wantTakes   []lotTake
+		wantExpires *string
+	}{
+		{name: "full reversal restores every lot", points: 100, wantTakes: []lotTake{{3, 20}, {2, 50}, {1, 30}}, wantExpires: strPtr("2026-03-01T00:00:00Z")},
+		{name: "partial reversal skips the soonest-expiring lot", points: 60, wantTakes: []lotTake{{3, 20}, {2, 40}}, wantExpires: strPtr("2026-06-01T00:00:00Z")},
+		{name: "lot without expiry", points: 10, wantTakes: []lotTake{{3, 10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			takes := takeFromLots(consumed, tt.points)
+			if !reflect.DeepEqual(takes, tt.wantTakes) {
+				t.Errorf("takeFromLots() = %v, want %v", takes, tt.wantTakes)
+			}
+			got := soonestExpiry(takes, expiries)
+			if (got == nil) != (tt.wantExpires == nil) || (got != nil && *got != *tt.wantExpires) {
+				t.Errorf("soonestExpiry() = %v, want %v", deref(got), deref(tt.wantExpires))
+			}
+		})
+	}
+}
+
+func strPtr(v string) *string { return &v }
+
+func deref(v *string) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return *v
+}
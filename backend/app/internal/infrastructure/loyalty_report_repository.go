@@ -0,0 +1,218 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type loyaltyReportRepository struct {
+	db *sql.DB
+}
+
+func NewLoyaltyReportRepository(db *sql.DB) domain.LoyaltyReportRepository {
+	return &loyaltyReportRepository{db: db}
+}
+
+// reportMonths is every month of a report, as a date series to join the figures of each month onto
+const reportMonths = `generate_series(DATE_TRUNC('month', $1::TIMESTAMPTZ), $2::TIMESTAMPTZ - INTERVAL '1 microsecond', INTERVAL '1 month')`
+
+func (r *loyaltyReportRepository) GetPointsLiability(ctx context.Context) ([]*domain.TierLiability, error) {
+	// The ledger, not the cached balances, is the record of what members hold; deleted accounts can no longer spend theirs
+	query := `
+		SELECT COALESCE(t.id, 0), COALESCE(t.name, ''), COALESCE(t.point_value_multiplier, 1), COUNT(*), SUM(b.balance)
+		FROM (SELECT user_id, SUM(points) AS balance FROM loyalty_points GROUP BY user_id HAVING SUM(points) > 0) b
+		JOIN users u ON u.id = b.user_id AND u.deleted_at IS NULL
+		LEFT JOIN user_loyalty ul ON ul.user_id = b.user_id
+		LEFT JOIN loyalty_tiers t ON t.id = ul.current_tier_id
+		GROUP BY t.id, t.name, t.point_value_multiplier, t.min_points
+		ORDER BY t.min_points NULLS FIRST
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get points liability: %w", err)
+	}
+	defer rows.Close()
+
+	var liabilities []*domain.TierLiability
+	for rows.Next() {
+		l := &domain.TierLiability{}
+		if err := rows.Scan(&l.TierID, &l.TierName, &l.PointValueMultiplier, &l.Members, &l.Points); err != nil {
+			return nil, fmt.Errorf("failed to scan points liability: %w", err)
+		}
+		liabilities = append(liabilities, l)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return liabilities, nil
+}
+
+func (r *loyaltyReportRepository) GetPointsFlow(ctx context.Context, from, to time.Time) ([]*domain.PointsFlow, error) {
+	query := `
+		SELECT TO_CHAR(m.month_start, 'YYYY-MM'),
+			COALESCE(SUM(e.points) FILTER (WHERE e.kind = 'earn'), 0),
+			COALESCE(-SUM(e.points) FILTER (WHERE e.kind = 'redeem'), 0),
+			COALESCE(-SUM(e.points) FILTER (WHERE e.kind = 'expire'), 0),
+			COALESCE(SUM(e.points) FILTER (WHERE e.kind = 'adjust'), 0),
+			COALESCE(SUM(e.points) FILTER (WHERE e.kind = 'reverse'), 0)
+		FROM ` + reportMonths + ` AS m(month_start)
+		LEFT JOIN loyalty_points e ON e.created_at >= m.month_start AND e.created_at < m.month_start + INTERVAL '1 month'
+		GROUP BY m.month_start
+		ORDER BY m.month_start
+	`
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get points flow: %w", err)
+	}
+	defer rows.Close()
+
+	var flows []*domain.PointsFlow
+	for rows.Next() {
+		f := &domain.PointsFlow{}
+		if err := rows.Scan(&f.Month, &f.Earned, &f.Redeemed, &f.Expired, &f.Adjusted, &f.Reversed); err != nil {
+			return nil, fmt.Errorf("failed to scan points flow: %w", err)
+		}
+		flows = append(flows, f)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return flows, nil
+}
+
+func (r *loyaltyReportRepository) GetExpiringPoints(ctx context.Context, until time.Time) ([]*domain.ExpiringPoints, error) {
+	query := `
+		SELECT TO_CHAR(e.expires_at, 'YYYY-MM'), SUM(l.remaining), COUNT(DISTINCT l.user_id)
+		FROM loyalty_point_lots l
+		JOIN loyalty_points e ON e.id = l.entry_id
+		WHERE l.remaining > 0 AND e.expires_at < $1
+		GROUP BY TO_CHAR(e.expires_at, 'YYYY-MM')
+		ORDER BY 1
+	`
+	rows, err := r.db.QueryContext(ctx, query, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expiring points: %w", err)
+	}
+	defer rows.Close()
+
+	var expiring []*domain.ExpiringPoints
+	for rows.Next() {
+		e := &domain.ExpiringPoints{}
+		if err := rows.Scan(&e.Month, &e.Points, &e.Members); err != nil {
+			return nil, fmt.Errorf("failed to scan expiring points: %w", err)
+		}
+		expiring = append(expiring, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return expiring, nil
+}
+
+func (r *loyaltyReportRepository) RecordTierSnapshot(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Replaced as a whole, so a tier that has lost all its members since the last run drops out
+	if _, err := tx.ExecContext(ctx, `DELETE FROM loyalty_tier_snapshots WHERE snapshot_date = CURRENT_DATE`); err != nil {
+		return fmt.Errorf("failed to record tier snapshot: %w", err)
+	}
+	query := `
+		INSERT INTO loyalty_tier_snapshots (snapshot_date, tier_id, tier_name, members)
+		SELECT CURRENT_DATE, t.id, COALESCE(t.name, ''), COUNT(*)
+		FROM user_loyalty ul
+		JOIN users u ON u.id = ul.user_id AND u.deleted_at IS NULL
+		LEFT JOIN loyalty_tiers t ON t.id = ul.current_tier_id
+		GROUP BY t.id, t.name
+	`
+	if _, err := tx.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to record tier snapshot: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *loyaltyReportRepository) GetTierSnapshots(ctx context.Context, from, to time.Time) ([]*domain.TierSnapshot, error) {
+	query := `
+		SELECT TO_CHAR(s.snapshot_date, 'YYYY-MM-DD'), s.tier_id, s.tier_name, s.members
+		FROM loyalty_tier_snapshots s
+		JOIN (
+			SELECT MAX(snapshot_date) AS snapshot_date FROM loyalty_tier_snapshots
+			WHERE snapshot_date >= $1::DATE AND snapshot_date < $2::DATE
+			GROUP BY DATE_TRUNC('month', snapshot_date)
+		) latest ON latest.snapshot_date = s.snapshot_date
+		LEFT JOIN loyalty_tiers t ON t.id = s.tier_id
+		ORDER BY s.snapshot_date, t.min_points NULLS FIRST, s.tier_name
+	`
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tier snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []*domain.TierSnapshot
+	for rows.Next() {
+		s := &domain.TierSnapshot{}
+		if err := rows.Scan(&s.Date, &s.TierID, &s.TierName, &s.Members); err != nil {
+			return nil, fmt.Errorf("failed to scan tier snapshot: %w", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return snapshots, nil
+}
+
+func (r *loyaltyReportRepository) GetActiveMembers(ctx context.Context, from, to time.Time) ([]*domain.ActiveMembers, error) {
+	query := `
+		WITH activity AS (
+			SELECT user_id, order_date AS at, 'purchase' AS kind FROM orders WHERE payment_status = 'paid'
+			UNION ALL
+			SELECT user_id, visited_at, 'visit' FROM store_visits
+			UNION ALL
+			SELECT user_id, created_at, kind FROM loyalty_points WHERE kind IN ('earn', 'redeem')
+		)
+		SELECT TO_CHAR(m.month_start, 'YYYY-MM'),
+			(SELECT COUNT(DISTINCT a.user_id) FROM activity a WHERE a.at >= m.month_start AND a.at < m.month_start + INTERVAL '1 month'),
+			(SELECT COUNT(DISTINCT a.user_id) FROM activity a WHERE a.kind = 'purchase' AND a.at >= m.month_start AND a.at < m.month_start + INTERVAL '1 month'),
+			(SELECT COUNT(DISTINCT a.user_id) FROM activity a WHERE a.kind = 'earn' AND a.at >= m.month_start AND a.at < m.month_start + INTERVAL '1 month'),
+			(SELECT COUNT(DISTINCT a.user_id) FROM activity a WHERE a.kind = 'redeem' AND a.at >= m.month_start AND a.at < m.month_start + INTERVAL '1 month'),
+			(SELECT COUNT(*) FROM users u WHERE u.created_at >= m.month_start AND u.created_at < m.month_start + INTERVAL '1 month')
+		FROM ` + reportMonths + ` AS m(month_start)
+		ORDER BY m.month_start
+	`
+	rows, err := r.db.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active members: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []*domain.ActiveMembers
+	for rows.Next() {
+		c := &domain.ActiveMembers{}
+		if err := rows.Scan(&c.Month, &c.Active, &c.Purchasing, &c.Earning, &c.Redeeming, &c.NewMembers); err != nil {
+			return nil, fmt.Errorf("failed to scan active members: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return counts, nil
+}
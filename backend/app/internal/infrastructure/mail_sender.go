@@ -0,0 +1,99 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SMTPConfig describes the mail server used for outgoing email.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // Leave empty for servers that accept mail without authentication
+	Password string
+	From     string
+}
+
+// SMTPMailSender delivers email through an SMTP server.
+type SMTPMailSender struct {
+	cfg SMTPConfig
+}
+
+func NewSMTPMailSender(cfg *SMTPConfig) *SMTPMailSender {
+	return &SMTPMailSender{cfg: *cfg}
+}
+
+func (s *SMTPMailSender) Send(ctx context.Context, to, subject, body string) error {
+	if strings.ContainsAny(to, "\r\n") {
+		return fmt.Errorf("invalid recipient address")
+	}
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	if err := smtp.SendMail(addr, auth, s.cfg.From, []string{to}, buildMessage(s.cfg.From, to, subject, body)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func buildMessage(from, to, subject, body string) []byte {
+	var msg strings.Builder
+	msg.WriteString("From: " + from + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return []byte(msg.String())
+}
+
+// MailMessage is an email kept by MemoryMailSender.
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+	SentAt  time.Time
+}
+
+// MemoryMailSender keeps outgoing email in memory instead of delivering it.
+// It is meant for tests and local development only.
+type MemoryMailSender struct {
+	mu       sync.Mutex
+	messages []MailMessage
+}
+
+func NewMemoryMailSender() *MemoryMailSender {
+	return &MemoryMailSender{}
+}
+
+func (s *MemoryMailSender) Send(ctx context.Context, to, subject, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, MailMessage{To: to, Subject: subject, Body: body, SentAt: time.Now()})
+	return nil
+}
+
+// Messages returns the email sent so far, oldest first.
+func (s *MemoryMailSender) Messages() []MailMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := make([]MailMessage, len(s.messages))
+	copy(messages, s.messages)
+	return messages
+}
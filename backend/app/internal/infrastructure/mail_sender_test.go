@@ -0,0 +1,39 @@
+package infrastructure
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryMailSender(t *testing.T) {
+	sender := NewMemoryMailSender()
+	if got := sender.Messages(); len(got) != 0 {
+		t.Fatalf("Messages() = %v, want none", got)
+	}
+
+	sent := []MailMessage{
+		{To: "a@example.com", Subject: "Confirm your email", Body: "Link: /verify-email?token=1"},
+		{To: "b@example.com", Subject: "Reset your password", Body: "Link: /reset-password?token=2"},
+	}
+	for _, m := range sent {
+		if err := sender.Send(context.Background(), m.To, m.Subject, m.Body); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	got := sender.Messages()
+	if len(got) != len(sent) {
+		t.Fatalf("Messages() returned %d messages, want %d", len(got), len(sent))
+	}
+	for i, m := range got {
+		if m.To != sent[i].To || m.Subject != sent[i].Subject || m.Body != sent[i].Body || m.SentAt.IsZero() {
+			t.Errorf("Messages()[%d] = %+v, want %+v with a send time", i, m, sent[i])
+		}
+	}
+
+	// The returned slice is a copy
+	got[0].To = "changed@example.com"
+	if sender.Messages()[0].To != "a@example.com" {
+		t.Errorf("changing the result of Messages() changed the stored message")
+	}
+}
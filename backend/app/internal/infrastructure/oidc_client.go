@@ -0,0 +1,341 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// OIDCProviderConfig describes one external OpenID Connect provider.
+// Endpoints left empty are discovered from the issuer's /.well-known/openid-configuration.
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	JWKSURL      string
+	Scopes       []string
+	// TrustEmail marks providers that only ever return confirmed addresses
+	// but do not send an email_verified claim.
+	TrustEmail bool
+}
+
+// OIDCClient runs the authorization-code flow with PKCE against a single provider.
+type OIDCClient struct {
+	cfg        OIDCProviderConfig
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	discovered    bool
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+func NewOIDCClient(cfg *OIDCProviderConfig) *OIDCClient {
+	return &OIDCClient{cfg: *cfg, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *OIDCClient) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	if err := c.discover(ctx); err != nil {
+		return "", err
+	}
+
+	scopes := c.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.cfg.ClientID)
+	params.Set("redirect_uri", c.cfg.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(c.cfg.AuthURL, "?") {
+		separator = "&"
+	}
+	return c.cfg.AuthURL + separator + params.Encode(), nil
+}
+
+func (c *OIDCClient) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*domain.ExternalIdentity, error) {
+	if err := c.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.cfg.RedirectURL)
+	form.Set("client_id", c.cfg.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if c.cfg.ClientSecret != "" {
+		form.Set("client_secret", c.cfg.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		IDToken     string `json:"id_token"`
+	}
+	if err := c.doJSON(req, &tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if tokenResp.IDToken != "" && c.cfg.JWKSURL != "" {
+		claims, err = c.verifyIDToken(ctx, tokenResp.IDToken, nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Fill in what the ID token left out, or everything if the provider issues none
+	if c.cfg.UserInfoURL != "" && tokenResp.AccessToken != "" && (claims == nil || claims["email"] == nil || claims["phone_number"] == nil) {
+		userInfo, err := c.fetchUserInfo(ctx, tokenResp.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if claims == nil {
+			claims = userInfo
+		} else if claimString(userInfo, "sub", "id") == claimString(claims, "sub") {
+			for k, v := range userInfo {
+				if _, ok := claims[k]; !ok {
+					claims[k] = v
+				}
+			}
+		}
+	}
+	if claims == nil {
+		return nil, fmt.Errorf("provider %s returned neither an ID token nor user info", c.cfg.Name)
+	}
+
+	identity := &domain.ExternalIdentity{
+		Subject:             claimString(claims, "sub", "id", "user_id"),
+		Name:                claimString(claims, "name", "real_name", "display_name"),
+		Email:               claimString(claims, "email", "default_email"),
+		EmailVerified:       claimBool(claims, "email_verified") || c.cfg.TrustEmail,
+		PhoneNumber:         claimString(claims, "phone_number", "phone"),
+		PhoneNumberVerified: claimBool(claims, "phone_number_verified"),
+	}
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("provider %s did not return a subject", c.cfg.Name)
+	}
+	return identity, nil
+}
+
+// discover fills in endpoints missing from the configuration from the issuer's discovery document.
+func (c *OIDCClient) discover(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.discovered || (c.cfg.AuthURL != "" && c.cfg.TokenURL != "") {
+		c.discovered = true
+		return nil
+	}
+	if c.cfg.Issuer == "" {
+		return fmt.Errorf("provider %s has neither an issuer nor endpoints configured", c.cfg.Name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.cfg.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return fmt.Errorf("failed to build discovery request: %w", err)
+	}
+	var doc struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := c.doJSON(req, &doc); err != nil {
+		return fmt.Errorf("failed to discover provider %s: %w", c.cfg.Name, err)
+	}
+
+	if c.cfg.AuthURL == "" {
+		c.cfg.AuthURL = doc.AuthorizationEndpoint
+	}
+	if c.cfg.TokenURL == "" {
+		c.cfg.TokenURL = doc.TokenEndpoint
+	}
+	if c.cfg.UserInfoURL == "" {
+		c.cfg.UserInfoURL = doc.UserInfoEndpoint
+	}
+	if c.cfg.JWKSURL == "" {
+		c.cfg.JWKSURL = doc.JWKSURI
+	}
+	c.discovered = true
+	return nil
+}
+
+func (c *OIDCClient) verifyIDToken(ctx context.Context, idToken, nonce string) (map[string]interface{}, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	}
+	if c.cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(c.cfg.Issuer))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.signingKey(ctx, kid)
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("invalid ID token: nonce mismatch")
+	}
+	return claims, nil
+}
+
+// signingKey returns the provider key with the given ID, refetching the key set at most once a minute.
+func (c *OIDCClient) signingKey(ctx context.Context, kid string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(c.keysFetchedAt) < time.Minute {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+	var set struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := c.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		switch {
+		case k.KeyType == "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[k.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.KeyType == "EC" && k.Curve == "P-256":
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[k.KeyID] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	c.keys = keys
+	c.keysFetchedAt = time.Now()
+
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (c *OIDCClient) fetchUserInfo(ctx context.Context, accessToken string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.cfg.UserInfoURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build user info request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var userInfo map[string]interface{}
+	if err := c.doJSON(req, &userInfo); err != nil {
+		return nil, fmt.Errorf("failed to fetch user info: %w", err)
+	}
+	return userInfo, nil
+}
+
+func (c *OIDCClient) doJSON(req *http.Request, v interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// claimString returns the first of the named claims that is present, converting numeric IDs to strings.
+func claimString(claims map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		switch v := claims[name].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return fmt.Sprintf("%.0f", v)
+		case map[string]interface{}:
+			// Yandex nests the phone number as {"id": ..., "number": "..."}
+			if number, ok := v["number"].(string); ok && number != "" {
+				return number
+			}
+		}
+	}
+	return ""
+}
+
+// claimBool reads a boolean claim that some providers send as a string.
+func claimBool(claims map[string]interface{}, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// testProvider is an OpenID Connect provider serving discovery, a token endpoint and its key set.
+type testProvider struct {
+	server  *httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	idToken string // Returned by the token endpoint
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{rsaKey: rsaKey, ecKey: ecKey}
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"id_token": p.idToken})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X.FillBytes(make([]byte, 32))), "y": encode(ecKey.Y.FillBytes(make([]byte, 32)))},
+		}})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *testProvider) client() *OIDCClient {
+	return NewOIDCClient(&OIDCProviderConfig{Name: "test", Issuer: p.server.URL, ClientID: "client", RedirectURL: "https://app.example/callback"})
+}
+
+func (p *testProvider) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   "client",
+		"sub":   "user-1",
+		"nonce": "nonce",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+	}
+}
+
+func (p *testProvider) sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestOIDCClientVerifyIDToken(t *testing.T) {
+	p := newTestProvider(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	with := func(changes map[string]interface{}) jwt.MapClaims {
+		claims := p.claims()
+		for k, v := range changes {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "RS256", token: p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, p.claims())},
+		{name: "ES256", token: p.sign(t, jwt.SigningMethodES256, "ec", p.ecKey, p.claims())},
+		{name: "audience among several", token: p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, with(map[string]interface{}{"aud": []string{"other", "client"}}))},
+		{name: "wrong nonce", token: p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, with(map[string]interface{}{"nonce": "replayed"})), wantErr: "nonce mismatch"},
+		{name: "no nonce", token: p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, with(map[string]interface{}{"nonce": nil})), wantErr: "nonce mismatch"},
+		{name: "another client", token: p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, with(map[string]interface{}{"aud": "other"})), wantErr: "invalid ID token"},
+		{name: "another issuer", token: p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, with(map[string]interface{}{"iss": "https://evil.example"})), wantErr: "invalid ID token"},
+		{name: "expired", token: p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, with(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})), wantErr: "token is expired"},
+		{name: "no expiry", token: p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, with(map[string]interface{}{"exp": nil})), wantErr: "invalid ID token"},
+		{name: "signed by another key", token: p.sign(t, jwt.SigningMethodRS256, "rsa", otherKey, p.claims()), wantErr: "invalid ID token"},
+		{name: "unknown key", token: p.sign(t, jwt.SigningMethodRS256, "rotated", p.rsaKey, p.claims()), wantErr: `unknown signing key "rotated"`},
+		{name: "symmetric algorithm", token: p.sign(t, jwt.SigningMethodHS256, "rsa", []byte("client-secret"), p.claims()), wantErr: "invalid ID token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := p.client()
+			if err := c.discover(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			claims, err := c.verifyIDToken(context.Background(), tt.token, "nonce")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("verifyIDToken() error = %v", err)
+				}
+				if claims["sub"] != "user-1" {
+					t.Errorf("verifyIDToken() sub = %v, want user-1", claims["sub"])
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("verifyIDToken() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOIDCClientExchange(t *testing.T) {
+	p := newTestProvider(t)
+	with := func(changes map[string]interface{}) jwt.MapClaims {
+		claims := p.claims()
+		for k, v := range changes {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name       string
+		claims     jwt.MapClaims
+		trustEmail bool
+		want       domain.ExternalIdentity
+	}{
+		{
+			name: "verified email and phone",
+			claims: with(map[string]interface{}{
+				"email": "a@example.com", "email_verified": true, "phone_number": "+7 900 000-00-00", "phone_number_verified": true, "name": "Ann",
+			}),
+			want: domain.ExternalIdentity{Subject: "user-1", Name: "Ann", Email: "a@example.com", EmailVerified: true, PhoneNumber: "+7 900 000-00-00", PhoneNumberVerified: true},
+		},
+		{
+			name:   "unverified email",
+			claims: with(map[string]interface{}{"email": "a@example.com", "email_verified": false, "phone_number": "79000000000"}),
+			want:   domain.ExternalIdentity{Subject: "user-1", Email: "a@example.com", PhoneNumber: "79000000000"},
+		},
+		{
+			name:   "verification sent as a string",
+			claims: with(map[string]interface{}{"email": "a@example.com", "email_verified": "true", "phone_number": "79000000000"}),
+			want:   domain.ExternalIdentity{Subject: "user-1", Email: "a@example.com", EmailVerified: true, PhoneNumber: "79000000000"},
+		},
+		{
+			name:       "provider trusted to send only confirmed addresses",
+			claims:     with(map[string]interface{}{"email": "a@example.com", "phone_number": "79000000000"}),
+			trustEmail: true,
+			want:       domain.ExternalIdentity{Subject: "user-1", Email: "a@example.com", EmailVerified: true, PhoneNumber: "79000000000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p.idToken = p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, tt.claims)
+			c := p.client()
+			c.cfg.TrustEmail = tt.trustEmail
+
+			identity, err := c.Exchange(context.Background(), "code", "verifier", "nonce")
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if *identity != tt.want {
+				t.Errorf("Exchange() = %+v, want %+v", *identity, tt.want)
+			}
+		})
+	}
+
+	t.Run("ID token for another login", func(t *testing.T) {
+		p.idToken = p.sign(t, jwt.SigningMethodRS256, "rsa", p.rsaKey, p.claims())
+		if _, err := p.client().Exchange(context.Background(), "code", "verifier", "other-nonce"); err == nil {
+			t.Fatal("Exchange() accepted an ID token with another nonce")
+		}
+	})
+}
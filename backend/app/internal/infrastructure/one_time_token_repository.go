@@ -0,0 +1,52 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type oneTimeTokenRepository struct {
+	db *sql.DB
+}
+
+func NewOneTimeTokenRepository(db *sql.DB) domain.OneTimeTokenRepository {
+	return &oneTimeTokenRepository{db: db}
+}
+
+func (r *oneTimeTokenRepository) CreateOneTimeToken(ctx context.Context, token *domain.OneTimeToken) error {
+	query := `INSERT INTO one_time_tokens (id, user_id, purpose, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.ExecContext(ctx, query, token.ID, token.UserID, token.Purpose, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create one-time token: %w", err)
+	}
+	return nil
+}
+
+func (r *oneTimeTokenRepository) UseOneTimeToken(ctx context.Context, id string, purpose domain.TokenPurpose) (*domain.OneTimeToken, error) {
+	query := `
+		UPDATE one_time_tokens SET used_at = NOW()
+		WHERE id = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, purpose, expires_at
+	`
+	token := &domain.OneTimeToken{}
+	err := r.db.QueryRowContext(ctx, query, id, purpose).Scan(&token.ID, &token.UserID, &token.Purpose, &token.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("one-time token not found")
+		}
+		return nil, fmt.Errorf("failed to use one-time token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *oneTimeTokenRepository) InvalidateOneTimeTokens(ctx context.Context, userID int, purpose domain.TokenPurpose) error {
+	query := `UPDATE one_time_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID, purpose)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate one-time tokens: %w", err)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/mkbagandov/kingsman/backend/app/internal/domain" // Update with your actual project path
 )
@@ -16,31 +17,50 @@ func NewOrderRepository(db *sql.DB) domain.OrderRepository {
 	return &orderRepository{db: db}
 }
 
+const orderColumns = `id, user_id, order_date, total_amount, status, payment_status, points_redeemed, tier_discount, points_discount, shipping_fee, alteration_fee, store_id, points_earned, created_at, updated_at`
+
 func (r *orderRepository) CreateOrder(ctx context.Context, order *domain.Order) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO orders (user_id, total_amount, status, payment_status, order_date, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+		INSERT INTO orders (user_id, total_amount, status, payment_status, points_redeemed, tier_discount, points_discount, shipping_fee, alteration_fee, store_id, order_date, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id
 	`
-	err := r.db.QueryRowContext(
-		ctx, query, order.UserID, order.TotalAmount, order.Status, order.PaymentStatus, order.OrderDate, order.CreatedAt, order.UpdatedAt,
+	err = tx.QueryRowContext(
+		ctx, query, order.UserID, order.TotalAmount, order.Status, order.PaymentStatus, order.PointsRedeemed, order.TierDiscount, order.PointsDiscount, order.ShippingFee, order.AlterationFee, order.StoreID, order.OrderDate, order.CreatedAt, order.UpdatedAt,
 	).Scan(&order.ID)
-
 	if err != nil {
 		return fmt.Errorf("failed to create order: %w", err)
 	}
+
+	query = `
+		INSERT INTO order_items (order_id, product_id, quantity, price, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id
+	`
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.OrderID = order.ID
+		if err := tx.QueryRowContext(ctx, query, item.OrderID, item.ProductID, item.Quantity, item.Price, item.CreatedAt, item.UpdatedAt).Scan(&item.ID); err != nil {
+			return fmt.Errorf("failed to create order item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
 func (r *orderRepository) GetOrderByID(ctx context.Context, orderID int) (*domain.Order, error) {
 	query := `
-		SELECT id, user_id, order_date, total_amount, status, payment_status, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders WHERE id = $1
 	`
-	order := &domain.Order{}
-	err := r.db.QueryRowContext(
-		ctx, query, orderID,
-	).Scan(&order.ID, &order.UserID, &order.OrderDate, &order.TotalAmount, &order.Status, &order.PaymentStatus, &order.CreatedAt, &order.UpdatedAt)
-
+	order, err := scanOrder(r.db.QueryRowContext(ctx, query, orderID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("order not found")
@@ -52,7 +72,7 @@ func (r *orderRepository) GetOrderByID(ctx context.Context, orderID int) (*domai
 
 func (r *orderRepository) GetOrdersByUserID(ctx context.Context, userID string, paymentStatus *string) ([]*domain.Order, error) {
 	baseQuery := `
-		SELECT id, user_id, order_date, total_amount, status, payment_status, created_at, updated_at
+		SELECT ` + orderColumns + `
 		FROM orders WHERE user_id = $1
 	`
 	args := []interface{}{userID}
@@ -72,8 +92,7 @@ func (r *orderRepository) GetOrdersByUserID(ctx context.Context, userID string,
 
 	var orders []*domain.Order
 	for rows.Next() {
-		order := &domain.Order{}
-		err := rows.Scan(&order.ID, &order.UserID, &order.OrderDate, &order.TotalAmount, &order.Status, &order.PaymentStatus, &order.CreatedAt, &order.UpdatedAt)
+		order, err := scanOrder(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
@@ -105,3 +124,128 @@ func (r *orderRepository) UpdateOrder(ctx context.Context, order *domain.Order)
 	}
 	return nil
 }
+
+func (r *orderRepository) CancelOrder(ctx context.Context, orderID int) error {
+	query := `
+		UPDATE orders
+		SET status = 'cancelled', payment_status = CASE WHEN payment_status = 'paid' THEN 'refunded' ELSE payment_status END, updated_at = NOW()
+		WHERE id = $1 AND status <> 'cancelled'
+	`
+	result, err := r.db.ExecContext(ctx, query, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("order not found or already cancelled")
+	}
+	return nil
+}
+
+func (r *orderRepository) SetOrderPoints(ctx context.Context, orderID int, breakdown []*domain.EarnedPoints) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM order_points_breakdown WHERE order_id = $1`, orderID); err != nil {
+		return fmt.Errorf("failed to clear order points breakdown: %w", err)
+	}
+	total := 0
+	for _, line := range breakdown {
+		query := `INSERT INTO order_points_breakdown (order_id, rule_id, description, points) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.ExecContext(ctx, query, orderID, line.RuleID, line.Description, line.Points); err != nil {
+			return fmt.Errorf("failed to create order points breakdown: %w", err)
+		}
+		total += line.Points
+	}
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET points_earned = $1, updated_at = NOW() WHERE id = $2`, total, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to set order points: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("order not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) GetOrderPointsBreakdown(ctx context.Context, orderID int) ([]*domain.EarnedPoints, error) {
+	query := `SELECT rule_id, description, points FROM order_points_breakdown WHERE order_id = $1 ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order points breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	var breakdown []*domain.EarnedPoints
+	for rows.Next() {
+		line := &domain.EarnedPoints{}
+		if err := rows.Scan(&line.RuleID, &line.Description, &line.Points); err != nil {
+			return nil, fmt.Errorf("failed to scan order points breakdown: %w", err)
+		}
+		breakdown = append(breakdown, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return breakdown, nil
+}
+
+func (r *orderRepository) MarkOrderPointsPosted(ctx context.Context, orderID int) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE orders SET points_posted_at = NOW() WHERE id = $1`, orderID); err != nil {
+		return fmt.Errorf("failed to mark order points posted: %w", err)
+	}
+	return nil
+}
+
+func (r *orderRepository) GetOrdersAwaitingPoints(ctx context.Context, placedBefore time.Time) ([]*domain.Order, error) {
+	query := `
+		SELECT ` + orderColumns + `
+		FROM orders WHERE points_posted_at IS NULL AND payment_status = 'paid' AND status <> 'cancelled' AND created_at < $1
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, placedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get orders awaiting points: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []*domain.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return orders, nil
+}
+
+func scanOrder(row rowScanner) (*domain.Order, error) {
+	order := &domain.Order{}
+	err := row.Scan(
+		&order.ID, &order.UserID, &order.OrderDate, &order.TotalAmount, &order.Status, &order.PaymentStatus, &order.PointsRedeemed,
+		&order.TierDiscount, &order.PointsDiscount, &order.ShippingFee, &order.AlterationFee, &order.StoreID, &order.PointsEarned, &order.CreatedAt, &order.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,94 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type otpRepository struct {
+	db *sql.DB
+}
+
+func NewOTPRepository(db *sql.DB) domain.OTPRepository {
+	return &otpRepository{db: db}
+}
+
+func (r *otpRepository) SaveOTPCode(ctx context.Context, code *domain.OTPCode) error {
+	query := `
+		INSERT INTO otp_codes (phone_number, code_hash, attempts, expires_at, last_sent_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (phone_number) DO UPDATE SET code_hash = EXCLUDED.code_hash, attempts = EXCLUDED.attempts,
+			expires_at = EXCLUDED.expires_at, last_sent_at = EXCLUDED.last_sent_at
+	`
+	_, err := r.db.ExecContext(ctx, query, code.PhoneNumber, code.CodeHash, code.Attempts, code.ExpiresAt, code.LastSentAt)
+	if err != nil {
+		return fmt.Errorf("failed to save OTP code: %w", err)
+	}
+	return nil
+}
+
+const otpCodeColumns = `phone_number, code_hash, attempts, expires_at, last_sent_at`
+
+func scanOTPCode(row rowScanner) (*domain.OTPCode, error) {
+	code := &domain.OTPCode{}
+	if err := row.Scan(&code.PhoneNumber, &code.CodeHash, &code.Attempts, &code.ExpiresAt, &code.LastSentAt); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+func (r *otpRepository) GetOTPCode(ctx context.Context, phoneNumber string) (*domain.OTPCode, error) {
+	query := `SELECT ` + otpCodeColumns + ` FROM otp_codes WHERE phone_number = $1`
+	code, err := scanOTPCode(r.db.QueryRowContext(ctx, query, phoneNumber))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("OTP code not found")
+		}
+		return nil, fmt.Errorf("failed to get OTP code: %w", err)
+	}
+	return code, nil
+}
+
+func (r *otpRepository) ClaimOTPAttempt(ctx context.Context, phoneNumber string, maxAttempts int) (*domain.OTPCode, error) {
+	// The row lock taken by the update makes concurrent checks count one after another
+	query := `
+		UPDATE otp_codes SET attempts = attempts + 1
+		WHERE phone_number = $1 AND attempts < $2 AND expires_at > NOW()
+		RETURNING ` + otpCodeColumns
+	code, err := scanOTPCode(r.db.QueryRowContext(ctx, query, phoneNumber, maxAttempts))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("OTP code not found")
+		}
+		return nil, fmt.Errorf("failed to claim OTP attempt: %w", err)
+	}
+	return code, nil
+}
+
+func (r *otpRepository) ConsumeOTPCode(ctx context.Context, phoneNumber, codeHash string) error {
+	query := `DELETE FROM otp_codes WHERE phone_number = $1 AND code_hash = $2`
+	result, err := r.db.ExecContext(ctx, query, phoneNumber, codeHash)
+	if err != nil {
+		return fmt.Errorf("failed to consume OTP code: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("OTP code not found")
+	}
+	return nil
+}
+
+func (r *otpRepository) DeleteOTPCode(ctx context.Context, phoneNumber string) error {
+	query := `DELETE FROM otp_codes WHERE phone_number = $1`
+	_, err := r.db.ExecContext(ctx, query, phoneNumber)
+	if err != nil {
+		return fmt.Errorf("failed to delete OTP code: %w", err)
+	}
+	return nil
+}
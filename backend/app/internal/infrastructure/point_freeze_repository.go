@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type pointFreezeRepository struct {
+	db *sql.DB
+}
+
+func NewPointFreezeRepository(db *sql.DB) domain.PointFreezeRepository {
+	return &pointFreezeRepository{db: db}
+}
+
+func (r *pointFreezeRepository) GetPointFreeze(ctx context.Context, userID int) (*domain.PointFreeze, error) {
+	freeze := &domain.PointFreeze{}
+	query := `SELECT user_id, reason, flag_id, frozen_by, created_at FROM loyalty_point_freezes WHERE user_id = $1`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&freeze.UserID, &freeze.Reason, &freeze.FlagID, &freeze.FrozenBy, &freeze.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("point freeze not found")
+		}
+		return nil, fmt.Errorf("failed to get point freeze: %w", err)
+	}
+	return freeze, nil
+}
+
+func (r *pointFreezeRepository) FreezePoints(ctx context.Context, freeze *domain.PointFreeze) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locks the member so concurrent changes are audited in the order they are made
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, freeze.UserID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	query := `
+		INSERT INTO loyalty_point_freezes (user_id, reason, flag_id, frozen_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET reason = EXCLUDED.reason, flag_id = EXCLUDED.flag_id,
+			frozen_by = EXCLUDED.frozen_by, created_at = NOW()
+		RETURNING created_at
+	`
+	if err := tx.QueryRowContext(ctx, query, freeze.UserID, freeze.Reason, freeze.FlagID, freeze.FrozenBy).Scan(&freeze.CreatedAt); err != nil {
+		return fmt.Errorf("failed to freeze points: %w", err)
+	}
+	if err := recordPointFreezeAudit(ctx, tx, freeze.UserID, freeze.FrozenBy, domain.PointsFrozen, freeze.Reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *pointFreezeRepository) UnfreezePoints(ctx context.Context, userID int, adminID int, reason string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM loyalty_point_freezes WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unfreeze points: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("point freeze not found")
+	}
+	if err := recordPointFreezeAudit(ctx, tx, userID, &adminID, domain.PointsUnfrozen, reason); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *pointFreezeRepository) GetPointFreezeAudit(ctx context.Context, userID int) ([]*domain.PointFreezeAuditEntry, error) {
+	query := `
+		SELECT id, user_id, admin_id, action, reason, created_at
+		FROM loyalty_point_freeze_audit WHERE user_id = $1 ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get point freeze audit: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*domain.PointFreezeAuditEntry
+	for rows.Next() {
+		entry := &domain.PointFreezeAuditEntry{}
+		if err := rows.Scan(&entry.ID, &entry.UserID, &entry.AdminID, &entry.Action, &entry.Reason, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan point freeze audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return entries, nil
+}
+
+func recordPointFreezeAudit(ctx context.Context, tx *sql.Tx, userID int, adminID *int, action domain.PointFreezeAction, reason string) error {
+	query := `INSERT INTO loyalty_point_freeze_audit (user_id, admin_id, action, reason) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, query, userID, adminID, action, reason); err != nil {
+		return fmt.Errorf("failed to record point freeze audit: %w", err)
+	}
+	return nil
+}
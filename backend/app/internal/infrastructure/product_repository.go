@@ -19,10 +19,10 @@ func NewPostgreSQLProductRepository(db *sql.DB) *PostgreSQLProductRepository {
 }
 
 func (r *PostgreSQLProductRepository) CreateProduct(ctx context.Context, product *domain.Product) error {
-	query := `INSERT INTO products (id, name, description, category_id, price, quantity, image_url, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	query := `INSERT INTO products (id, name, description, category_id, price, quantity, image_url, early_access_until, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
 	product.CreatedAt = time.Now().Format(time.RFC3339)
 	product.UpdatedAt = time.Now().Format(time.RFC3339)
-	err := r.db.QueryRowContext(ctx, query, product.ID, product.Name, product.Description, product.CategoryID, product.Price, product.Quantity, product.ImageURL, product.CreatedAt, product.UpdatedAt).Scan(&product.ID)
+	err := r.db.QueryRowContext(ctx, query, product.ID, product.Name, product.Description, product.CategoryID, product.Price, product.Quantity, product.ImageURL, product.EarlyAccessUntil, product.CreatedAt, product.UpdatedAt).Scan(&product.ID)
 	if err != nil {
 		return fmt.Errorf("failed to create product: %w", err)
 	}
@@ -31,8 +31,8 @@ func (r *PostgreSQLProductRepository) CreateProduct(ctx context.Context, product
 
 func (r *PostgreSQLProductRepository) GetProductByID(ctx context.Context, id int) (*domain.Product, error) {
 	product := &domain.Product{}
-	query := `SELECT id, name, description, category_id, price, quantity, image_url, created_at, updated_at FROM products WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&product.ID, &product.Name, &product.Description, &product.CategoryID, &product.Price, &product.Quantity, &product.ImageURL, &product.CreatedAt, &product.UpdatedAt)
+	query := `SELECT id, name, description, category_id, price, quantity, image_url, created_at, updated_at, early_access_until FROM products WHERE id = $1`
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&product.ID, &product.Name, &product.Description, &product.CategoryID, &product.Price, &product.Quantity, &product.ImageURL, &product.CreatedAt, &product.UpdatedAt, &product.EarlyAccessUntil)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("product not found")
@@ -42,8 +42,8 @@ func (r *PostgreSQLProductRepository) GetProductByID(ctx context.Context, id int
 	return product, nil
 }
 
-func (r *PostgreSQLProductRepository) GetProducts(ctx context.Context, categoryID *string, minPrice *float64, maxPrice *float64, sortBy *string, sortOrder *string, limit, offset int) ([]*domain.Product, error) {
-	baseQuery := `SELECT id, name, description, category_id, price, quantity, image_url, created_at, updated_at FROM products`
+func (r *PostgreSQLProductRepository) GetProducts(ctx context.Context, categoryID *string, minPrice *float64, maxPrice *float64, sortBy *string, sortOrder *string, limit, offset int, earlyAccess bool) ([]*domain.Product, error) {
+	baseQuery := `SELECT id, name, description, category_id, price, quantity, image_url, created_at, updated_at, early_access_until FROM products`
 	conditions := []string{`1=1`}
 	args := []interface{}{}
 	argCounter := 1
@@ -63,6 +63,9 @@ func (r *PostgreSQLProductRepository) GetProducts(ctx context.Context, categoryI
 		args = append(args, *maxPrice)
 		argCounter++
 	}
+	if !earlyAccess {
+		conditions = append(conditions, `(early_access_until IS NULL OR early_access_until <= NOW())`)
+	}
 
 	whereClause := " WHERE " + strings.Join(conditions, " AND ")
 
@@ -88,7 +91,7 @@ func (r *PostgreSQLProductRepository) GetProducts(ctx context.Context, categoryI
 	var products []*domain.Product
 	for rows.Next() {
 		product := &domain.Product{}
-		if err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.CategoryID, &product.Price, &product.Quantity, &product.ImageURL, &product.CreatedAt, &product.UpdatedAt); err != nil {
+		if err := rows.Scan(&product.ID, &product.Name, &product.Description, &product.CategoryID, &product.Price, &product.Quantity, &product.ImageURL, &product.CreatedAt, &product.UpdatedAt, &product.EarlyAccessUntil); err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
 		products = append(products, product)
@@ -103,8 +106,8 @@ func (r *PostgreSQLProductRepository) GetProducts(ctx context.Context, categoryI
 
 func (r *PostgreSQLProductRepository) UpdateProduct(ctx context.Context, product *domain.Product) error {
 	product.UpdatedAt = time.Now().Format(time.RFC3339)
-	query := `UPDATE products SET name = $2, description = $3, category_id = $4, price = $5, quantity = $6, image_url = $7, updated_at = $8 WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, product.ID, product.Name, product.Description, product.CategoryID, product.Price, product.Quantity, product.ImageURL, product.UpdatedAt)
+	query := `UPDATE products SET name = $2, description = $3, category_id = $4, price = $5, quantity = $6, image_url = $7, early_access_until = $8, updated_at = $9 WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, product.ID, product.Name, product.Description, product.CategoryID, product.Price, product.Quantity, product.ImageURL, product.EarlyAccessUntil, product.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to update product: %w", err)
 	}
@@ -0,0 +1,143 @@
+package infrastructure
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimitSweepInterval is how often idle entries are dropped from MemoryRateLimitStore.
+const rateLimitSweepInterval = time.Minute
+
+type tokenBucket struct {
+	tokens    float64
+	rate      float64
+	burst     int
+	updatedAt time.Time
+}
+
+// refill adds the tokens accumulated since the bucket was last touched.
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.burst), b.tokens+now.Sub(b.updatedAt).Seconds()*b.rate)
+	b.updatedAt = now
+}
+
+type failureCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+// MemoryRateLimitStore keeps throttling state in process memory.
+// It suits a single API instance; several instances need a shared store.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	failures  map[string]*failureCounter
+	locks     map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		failures:  make(map[string]*failureCounter),
+		locks:     make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, rate float64, burst int) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.rate, b.burst = rate, burst
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
+
+func (s *MemoryRateLimitStore) RecordFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	f, ok := s.failures[key]
+	if !ok || !now.Before(f.expiresAt) {
+		f = &failureCounter{expiresAt: now.Add(window)}
+		s.failures[key] = f
+	}
+	f.count++
+	return f.count, nil
+}
+
+func (s *MemoryRateLimitStore) ResetFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+func (s *MemoryRateLimitStore) Lock(ctx context.Context, key string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.locks[key] = time.Now().Add(duration)
+	return nil
+}
+
+func (s *MemoryRateLimitStore) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, ok := s.locks[key]
+	if !ok {
+		return 0, nil
+	}
+	remaining := time.Until(until)
+	if remaining <= 0 {
+		delete(s.locks, key)
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+// sweep drops full buckets and expired counters and locks, so memory stays bounded by recent clients.
+// The caller must hold s.mu.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.burst) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, f := range s.failures {
+		if !now.Before(f.expiresAt) {
+			delete(s.failures, key)
+		}
+	}
+	for key, until := range s.locks {
+		if !now.Before(until) {
+			delete(s.locks, key)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type referralRepository struct {
+	db *sql.DB
+}
+
+func NewReferralRepository(db *sql.DB) domain.ReferralRepository {
+	return &referralRepository{db: db}
+}
+
+const referralColumns = `id, referrer_id, referee_id, status, COALESCE(rejection_reason, ''), device, ip_address, order_id, reward_after, referrer_points, referee_points, rewarded_at, created_at`
+
+func (r *referralRepository) GetReferralCode(ctx context.Context, userID int) (string, error) {
+	var code string
+	err := r.db.QueryRowContext(ctx, `SELECT code FROM referral_codes WHERE user_id = $1`, userID).Scan(&code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("referral code not found")
+		}
+		return "", fmt.Errorf("failed to get referral code: %w", err)
+	}
+	return code, nil
+}
+
+func (r *referralRepository) CreateReferralCode(ctx context.Context, userID int, code string) error {
+	query := `INSERT INTO referral_codes (user_id, code) VALUES ($1, $2) ON CONFLICT (user_id) DO NOTHING`
+	if _, err := r.db.ExecContext(ctx, query, userID, code); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return fmt.Errorf("referral code already exists")
+		}
+		return fmt.Errorf("failed to create referral code: %w", err)
+	}
+	return nil
+}
+
+func (r *referralRepository) GetUserIDByReferralCode(ctx context.Context, code string) (int, error) {
+	var userID int
+	query := `SELECT c.user_id FROM referral_codes c JOIN users u ON u.id = c.user_id WHERE c.code = $1 AND u.deleted_at IS NULL`
+	if err := r.db.QueryRowContext(ctx, query, code).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("referral code not found")
+		}
+		return 0, fmt.Errorf("failed to get referral code: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *referralRepository) CreateReferral(ctx context.Context, referral *domain.Referral) error {
+	query := `
+		INSERT INTO referrals (referrer_id, referee_id, status, rejection_reason, device, ip_address)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6) RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query, referral.ReferrerID, referral.RefereeID, referral.Status, referral.RejectionReason, referral.Device, referral.IPAddress,
+	).Scan(&referral.ID, &referral.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return fmt.Errorf("user was already referred")
+		}
+		return fmt.Errorf("failed to create referral: %w", err)
+	}
+	return nil
+}
+
+func (r *referralRepository) GetReferralsByReferrerID(ctx context.Context, referrerID int) ([]*domain.Referral, error) {
+	query := `SELECT ` + referralColumns + ` FROM referrals WHERE referrer_id = $1 ORDER BY created_at DESC, id DESC`
+	return r.queryReferrals(ctx, query, referrerID)
+}
+
+func (r *referralRepository) SharesDevice(ctx context.Context, referrerID, refereeID int, device, ipAddress string) (bool, error) {
+	// A device is the user agent and address together; either alone is shared by too many people
+	query := `
+		SELECT
+			($3 <> '' AND $4 <> '' AND EXISTS (
+				SELECT 1 FROM sessions WHERE user_id = $1 AND device = $3 AND ip_address = $4
+			))
+			OR ($3 <> '' AND $4 <> '' AND EXISTS (
+				SELECT 1 FROM referrals WHERE referrer_id = $1 AND referee_id <> $2 AND device = $3 AND ip_address = $4
+			))
+			OR EXISTS (
+				SELECT 1 FROM sessions a JOIN sessions b ON b.device = a.device AND b.ip_address = a.ip_address
+				WHERE a.user_id = $1 AND b.user_id = $2 AND a.device <> '' AND a.ip_address <> ''
+			)
+	`
+	var shared bool
+	if err := r.db.QueryRowContext(ctx, query, referrerID, refereeID, device, ipAddress).Scan(&shared); err != nil {
+		return false, fmt.Errorf("failed to check referral device: %w", err)
+	}
+	return shared, nil
+}
+
+func (r *referralRepository) QualifyReferral(ctx context.Context, refereeID, orderID int, rewardAfter time.Time) (bool, error) {
+	query := `UPDATE referrals SET status = 'qualified', order_id = $2, reward_after = $3 WHERE referee_id = $1 AND status = 'pending'`
+	result, err := r.db.ExecContext(ctx, query, refereeID, orderID, rewardAfter)
+	if err != nil {
+		return false, fmt.Errorf("failed to qualify referral: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to qualify referral: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func (r *referralRepository) GetReferralsDueForReward(ctx context.Context) ([]*domain.Referral, error) {
+	query := `SELECT ` + referralColumns + ` FROM referrals WHERE status = 'qualified' AND reward_after <= NOW() ORDER BY reward_after`
+	return r.queryReferrals(ctx, query)
+}
+
+func (r *referralRepository) MarkReferralRewarded(ctx context.Context, referral *domain.Referral) error {
+	query := `
+		UPDATE referrals SET status = 'rewarded', referrer_points = $2, referee_points = $3, rewarded_at = NOW()
+		WHERE id = $1 AND status = 'qualified'
+	`
+	if _, err := r.db.ExecContext(ctx, query, referral.ID, referral.ReferrerPoints, referral.RefereePoints); err != nil {
+		return fmt.Errorf("failed to mark referral rewarded: %w", err)
+	}
+	return nil
+}
+
+func (r *referralRepository) ResetReferral(ctx context.Context, referralID int) error {
+	query := `UPDATE referrals SET status = 'pending', order_id = NULL, reward_after = NULL WHERE id = $1 AND status = 'qualified'`
+	if _, err := r.db.ExecContext(ctx, query, referralID); err != nil {
+		return fmt.Errorf("failed to reset referral: %w", err)
+	}
+	return nil
+}
+
+func (r *referralRepository) RejectReferral(ctx context.Context, referralID int, reason string) error {
+	query := `UPDATE referrals SET status = 'rejected', rejection_reason = $2 WHERE id = $1 AND status <> 'rewarded'`
+	if _, err := r.db.ExecContext(ctx, query, referralID, reason); err != nil {
+		return fmt.Errorf("failed to reject referral: %w", err)
+	}
+	return nil
+}
+
+func (r *referralRepository) queryReferrals(ctx context.Context, query string, args ...interface{}) ([]*domain.Referral, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get referrals: %w", err)
+	}
+	defer rows.Close()
+
+	var referrals []*domain.Referral
+	for rows.Next() {
+		referral := &domain.Referral{}
+		err := rows.Scan(
+			&referral.ID, &referral.ReferrerID, &referral.RefereeID, &referral.Status, &referral.RejectionReason, &referral.Device, &referral.IPAddress,
+			&referral.OrderID, &referral.RewardAfter, &referral.ReferrerPoints, &referral.RefereePoints, &referral.RewardedAt, &referral.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan referral: %w", err)
+		}
+		referrals = append(referrals, referral)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return referrals, nil
+}
@@ -0,0 +1,219 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type serviceAccountRepository struct {
+	db *sql.DB
+}
+
+func NewServiceAccountRepository(db *sql.DB) domain.ServiceAccountRepository {
+	return &serviceAccountRepository{db: db}
+}
+
+const apiKeyColumns = `id, service_account_id, prefix, key_hash, scopes, created_at, expires_at, revoked_at, last_used_at, last_used_ip`
+
+func (r *serviceAccountRepository) CreateServiceAccount(ctx context.Context, account *domain.ServiceAccount) error {
+	query := `INSERT INTO service_accounts (name, description) VALUES ($1, $2) RETURNING id, created_at`
+	if err := r.db.QueryRowContext(ctx, query, account.Name, account.Description).Scan(&account.ID, &account.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create service account: %w", err)
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) GetServiceAccountByID(ctx context.Context, id int) (*domain.ServiceAccount, error) {
+	query := `SELECT id, name, description, created_at, disabled_at FROM service_accounts WHERE id = $1`
+	account := &domain.ServiceAccount{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&account.ID, &account.Name, &account.Description, &account.CreatedAt, &account.DisabledAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("service account not found")
+		}
+		return nil, fmt.Errorf("failed to get service account: %w", err)
+	}
+	return account, nil
+}
+
+func (r *serviceAccountRepository) GetServiceAccounts(ctx context.Context) ([]*domain.ServiceAccount, error) {
+	query := `SELECT id, name, description, created_at, disabled_at FROM service_accounts ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get service accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []*domain.ServiceAccount
+	for rows.Next() {
+		account := &domain.ServiceAccount{}
+		if err := rows.Scan(&account.ID, &account.Name, &account.Description, &account.CreatedAt, &account.DisabledAt); err != nil {
+			return nil, fmt.Errorf("failed to scan service account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return accounts, nil
+}
+
+func (r *serviceAccountRepository) DisableServiceAccount(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE service_accounts SET disabled_at = NOW() WHERE id = $1 AND disabled_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to disable service account: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to disable service account: %w", err)
+	} else if rows == 0 {
+		return fmt.Errorf("service account not found")
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE service_account_id = $1 AND revoked_at IS NULL`, id); err != nil {
+		return fmt.Errorf("failed to revoke api keys: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	query := `
+		INSERT INTO api_keys (service_account_id, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query, key.ServiceAccountID, key.Prefix, key.KeyHash, pq.Array(scopesToStrings(key.Scopes)), key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) GetAPIKeyByID(ctx context.Context, id int) (*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key not found")
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *serviceAccountRepository) GetActiveAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	query := `
+		SELECT k.id, k.service_account_id, k.prefix, k.key_hash, k.scopes, k.created_at, k.expires_at, k.revoked_at, k.last_used_at, k.last_used_ip
+		FROM api_keys k JOIN service_accounts a ON a.id = k.service_account_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW()) AND a.disabled_at IS NULL
+	`
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("api key not found")
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *serviceAccountRepository) GetAPIKeysByServiceAccountID(ctx context.Context, serviceAccountID int) ([]*domain.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE service_account_id = $1 ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, serviceAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*domain.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *serviceAccountRepository) ExpireAPIKey(ctx context.Context, id int, expiresAt time.Time) error {
+	query := `UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, $2), $2) WHERE id = $1 AND revoked_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, id, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to expire api key: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to expire api key: %w", err)
+	} else if rows == 0 {
+		return fmt.Errorf("api key not found")
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) RevokeAPIKey(ctx context.Context, id int) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	} else if rows == 0 {
+		return fmt.Errorf("api key not found")
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) TouchAPIKey(ctx context.Context, id int, ipAddress string) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = NOW(), last_used_ip = $2 WHERE id = $1`, id, ipAddress); err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+	return nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row rowScanner) (*domain.APIKey, error) {
+	key := &domain.APIKey{}
+	var scopes []string
+	err := row.Scan(
+		&key.ID, &key.ServiceAccountID, &key.Prefix, &key.KeyHash, pq.Array(&scopes), &key.CreatedAt,
+		&key.ExpiresAt, &key.RevokedAt, &key.LastUsedAt, &key.LastUsedIP,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		key.Scopes = append(key.Scopes, domain.APIScope(scope))
+	}
+	return key, nil
+}
+
+func scopesToStrings(scopes []domain.APIScope) []string {
+	values := make([]string, len(scopes))
+	for i, scope := range scopes {
+		values[i] = string(scope)
+	}
+	return values
+}
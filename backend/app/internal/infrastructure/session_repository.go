@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) domain.SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
+	query := `
+		INSERT INTO sessions (id, user_id, refresh_token_hash, device, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at, last_used_at
+	`
+	err := r.db.QueryRowContext(
+		ctx, query, session.ID, session.UserID, session.RefreshTokenHash, session.Device, session.IPAddress, session.ExpiresAt,
+	).Scan(&session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) GetSessionByID(ctx context.Context, id string) (*domain.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_hash, previous_refresh_token_hash, device, ip_address, created_at, last_used_at, expires_at, revoked_at
+		FROM sessions WHERE id = $1
+	`
+	session := &domain.Session{}
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&session.ID, &session.UserID, &session.RefreshTokenHash, &session.PreviousRefreshTokenHash, &session.Device,
+		&session.IPAddress, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session by ID: %w", err)
+	}
+	return session, nil
+}
+
+func (r *sessionRepository) GetSessionByRefreshTokenHash(ctx context.Context, hash string) (*domain.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_hash, previous_refresh_token_hash, device, ip_address, created_at, last_used_at, expires_at, revoked_at
+		FROM sessions WHERE refresh_token_hash = $1 OR previous_refresh_token_hash = $1
+	`
+	session := &domain.Session{}
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&session.ID, &session.UserID, &session.RefreshTokenHash, &session.PreviousRefreshTokenHash, &session.Device,
+		&session.IPAddress, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.RevokedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session by refresh token: %w", err)
+	}
+	return session, nil
+}
+
+func (r *sessionRepository) GetActiveSessionsByUserID(ctx context.Context, userID int) ([]*domain.Session, error) {
+	query := `
+		SELECT id, user_id, refresh_token_hash, previous_refresh_token_hash, device, ip_address, created_at, last_used_at, expires_at, revoked_at
+		FROM sessions WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions by user ID: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*domain.Session
+	for rows.Next() {
+		session := &domain.Session{}
+		err := rows.Scan(
+			&session.ID, &session.UserID, &session.RefreshTokenHash, &session.PreviousRefreshTokenHash, &session.Device,
+			&session.IPAddress, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &session.RevokedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) IsSessionActive(ctx context.Context, id string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM sessions WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW())`
+	var active bool
+	if err := r.db.QueryRowContext(ctx, query, id).Scan(&active); err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return active, nil
+}
+
+func (r *sessionRepository) RotateRefreshToken(ctx context.Context, id, oldHash, newHash, ipAddress, expiresAt string) error {
+	query := `
+		UPDATE sessions
+		SET previous_refresh_token_hash = refresh_token_hash, refresh_token_hash = $3, ip_address = $4, expires_at = $5, last_used_at = NOW()
+		WHERE id = $1 AND refresh_token_hash = $2 AND revoked_at IS NULL
+	`
+	result, err := r.db.ExecContext(ctx, query, id, oldHash, newHash, ipAddress, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("session not found")
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeSession(ctx context.Context, id string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+func (r *sessionRepository) RevokeUserSessions(ctx context.Context, userID int, exceptSessionID string) error {
+	query := `UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND id::text <> $2 AND revoked_at IS NULL`
+	_, err := r.db.ExecContext(ctx, query, userID, exceptSessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke user sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogSMSSender writes outgoing messages to the application log instead of delivering them.
+// It is meant for local development only.
+type LogSMSSender struct{}
+
+func NewLogSMSSender() *LogSMSSender {
+	return &LogSMSSender{}
+}
+
+func (s *LogSMSSender) Send(ctx context.Context, phoneNumber, message string) error {
+	log.Printf("SMS to %s: %s", phoneNumber, message)
+	return nil
+}
+
+// FileSMSSender appends outgoing messages to a file, one line per message.
+// It is meant for local development and manual testing.
+type FileSMSSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSMSSender(path string) *FileSMSSender {
+	return &FileSMSSender{path: path}
+}
+
+func (s *FileSMSSender) Send(ctx context.Context, phoneNumber, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open SMS outbox: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phoneNumber, message); err != nil {
+		return fmt.Errorf("failed to write SMS: %w", err)
+	}
+	return nil
+}
@@ -52,3 +52,11 @@ func (r *PostgreSQLStoreRepository) GetStoreByID(ctx context.Context, id int) (*
 	}
 	return store, nil
 }
+
+func (r *PostgreSQLStoreRepository) CreateStoreVisit(ctx context.Context, visit *domain.StoreVisit) error {
+	query := `INSERT INTO store_visits (user_id, store_id, source) VALUES ($1, $2, $3) RETURNING id, visited_at`
+	if err := r.db.QueryRowContext(ctx, query, visit.UserID, visit.StoreID, visit.Source).Scan(&visit.ID, &visit.VisitedAt); err != nil {
+		return fmt.Errorf("failed to create store visit: %w", err)
+	}
+	return nil
+}
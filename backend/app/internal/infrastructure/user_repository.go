@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
@@ -18,9 +19,23 @@ func NewPostgreSQLUserRepository(db *sql.DB) *PostgreSQLUserRepository {
 	return &PostgreSQLUserRepository{db: db}
 }
 
+const userColumns = `id, username, COALESCE(phone_number, ''), COALESCE(email, ''), COALESCE(password_hash, ''), COALESCE(social_id, ''), qr_code, loyalty_status, current_points, role, email_verified_at, phone_verified_at, TO_CHAR(birth_date, 'YYYY-MM-DD')`
+
+func scanUser(row rowScanner) (*domain.User, error) {
+	user := &domain.User{}
+	err := row.Scan(&user.ID, &user.Username, &user.PhoneNumber, &user.Email, &user.PasswordHash, &user.SocialID, &user.QRCode, &user.LoyaltyStatus, &user.CurrentPoints, &user.Role, &user.EmailVerifiedAt, &user.PhoneVerifiedAt, &user.BirthDate)
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
 func (r *PostgreSQLUserRepository) CreateUser(ctx context.Context, user *domain.User) error {
-	query := `INSERT INTO users (username, phone_number, email, password_hash, social_id, discount_level, progress_to_next_level, qr_code, loyalty_status, current_points) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`
-	err := r.db.QueryRowContext(ctx, query, user.Username, user.PhoneNumber, user.Email, user.PasswordHash, user.SocialID, user.DiscountLevel, user.ProgressToNextLevel, user.QRCode, user.LoyaltyStatus, user.CurrentPoints).Scan(&user.ID)
+	if user.Role == "" {
+		user.Role = domain.RoleCustomer
+	}
+	query := `INSERT INTO users (username, phone_number, email, password_hash, social_id, qr_code, loyalty_status, current_points, role, email_verified_at, phone_verified_at, birth_date) VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12) RETURNING id`
+	err := r.db.QueryRowContext(ctx, query, user.Username, user.PhoneNumber, user.Email, user.PasswordHash, user.SocialID, user.QRCode, user.LoyaltyStatus, user.CurrentPoints, user.Role, user.EmailVerifiedAt, user.PhoneVerifiedAt, user.BirthDate).Scan(&user.ID)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
 			// Check if the unique violation is for phone_number or email
@@ -36,9 +51,8 @@ func (r *PostgreSQLUserRepository) CreateUser(ctx context.Context, user *domain.
 }
 
 func (r *PostgreSQLUserRepository) GetUserByID(ctx context.Context, id int) (*domain.User, error) {
-	user := &domain.User{}
-	query := `SELECT id, username, phone_number, email, password_hash, social_id, discount_level, progress_to_next_level, qr_code, loyalty_status, current_points FROM users WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Username, &user.PhoneNumber, &user.Email, &user.PasswordHash, &user.SocialID, &user.DiscountLevel, &user.ProgressToNextLevel, &user.QRCode, &user.LoyaltyStatus, &user.CurrentPoints)
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1 AND deleted_at IS NULL`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -51,9 +65,8 @@ func (r *PostgreSQLUserRepository) GetUserByID(ctx context.Context, id int) (*do
 func (r *PostgreSQLUserRepository) GetUserByPhoneNumber(ctx context.Context, phoneNumber string) (*domain.User, error) {
 	fmt.Println(phoneNumber)
 
-	user := &domain.User{}
-	query := `SELECT id, username, phone_number, email, password_hash, social_id, discount_level, progress_to_next_level, qr_code, loyalty_status, current_points FROM users WHERE phone_number = $1`
-	err := r.db.QueryRowContext(ctx, query, phoneNumber).Scan(&user.ID, &user.Username, &user.PhoneNumber, &user.Email, &user.PasswordHash, &user.SocialID, &user.DiscountLevel, &user.ProgressToNextLevel, &user.QRCode, &user.LoyaltyStatus, &user.CurrentPoints)
+	query := `SELECT ` + userColumns + ` FROM users WHERE phone_number = $1`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, phoneNumber))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -64,9 +77,8 @@ func (r *PostgreSQLUserRepository) GetUserByPhoneNumber(ctx context.Context, pho
 }
 
 func (r *PostgreSQLUserRepository) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
-	user := &domain.User{}
-	query := `SELECT id, username, phone_number, email, password_hash, social_id, discount_level, progress_to_next_level, qr_code, loyalty_status, current_points FROM users WHERE email = $1`
-	err := r.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Username, &user.PhoneNumber, &user.Email, &user.PasswordHash, &user.SocialID, &user.DiscountLevel, &user.ProgressToNextLevel, &user.QRCode, &user.LoyaltyStatus, &user.CurrentPoints)
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, email))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -76,50 +88,148 @@ func (r *PostgreSQLUserRepository) GetUserByEmail(ctx context.Context, email str
 	return user, nil
 }
 
+func (r *PostgreSQLUserRepository) GetUserByQRCode(ctx context.Context, qrCode string) (*domain.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE qr_code = $1`
+	user, err := scanUser(r.db.QueryRowContext(ctx, query, qrCode))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user by QR code: %w", err)
+	}
+	return user, nil
+}
+
 func (r *PostgreSQLUserRepository) UpdateUser(ctx context.Context, user *domain.User) error {
-	query := `UPDATE users SET username = $2, phone_number = $3, email = $4, password_hash = $5, social_id = $6, discount_level = $7, progress_to_next_level = $8, qr_code = $9, loyalty_status = $10, current_points = $11 WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, user.ID, user.Username, user.PhoneNumber, user.Email, user.PasswordHash, user.SocialID, user.DiscountLevel, user.ProgressToNextLevel, user.QRCode, user.LoyaltyStatus, user.CurrentPoints)
+	query := `UPDATE users SET username = $2, phone_number = NULLIF($3, ''), email = NULLIF($4, ''), password_hash = NULLIF($5, ''), social_id = NULLIF($6, ''), qr_code = $7, loyalty_status = $8, email_verified_at = $9, phone_verified_at = $10, birth_date = $11 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.Username, user.PhoneNumber, user.Email, user.PasswordHash, user.SocialID, user.QRCode, user.LoyaltyStatus, user.EmailVerifiedAt, user.PhoneVerifiedAt, user.BirthDate)
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 	return nil
 }
 
-func (r *PostgreSQLUserRepository) UpdateUserDiscountCard(ctx context.Context, userID int, level int, progress float64) error {
-	query := `UPDATE users SET discount_level = $2, progress_to_next_level = $3 WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, userID, level, progress)
+func (r *PostgreSQLUserRepository) UpdateUserProfile(ctx context.Context, user *domain.User) error {
+	query := `UPDATE users SET username = $2, phone_number = NULLIF($3, ''), phone_verified_at = $4, email = NULLIF($5, ''), email_verified_at = $6, birth_date = $7 WHERE id = $1 AND deleted_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, user.ID, user.Username, user.PhoneNumber, user.PhoneVerifiedAt, user.Email, user.EmailVerifiedAt, user.BirthDate)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			if strings.Contains(pqErr.Detail, "phone_number") {
+				return fmt.Errorf("user with phone number %s already exists", user.PhoneNumber)
+			} else if strings.Contains(pqErr.Detail, "email") {
+				return fmt.Errorf("user with email %s already exists", user.Email)
+			}
+		}
+		return fmt.Errorf("failed to update user profile: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("failed to update user discount card: %w", err)
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
 	}
 	return nil
 }
 
-func (r *PostgreSQLUserRepository) GetUserDiscountCard(ctx context.Context, userID int) (*domain.User, error) {
-	user := &domain.User{}
-	query := `SELECT discount_level, progress_to_next_level, qr_code FROM users WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&user.DiscountLevel, &user.ProgressToNextLevel, &user.QRCode)
+func (r *PostgreSQLUserRepository) UpdateUserRole(ctx context.Context, userID int, role domain.Role) error {
+	query := `UPDATE users SET role = $2 WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, userID, role)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
-		}
-		return nil, fmt.Errorf("failed to get user discount card: %w", err)
+		return fmt.Errorf("failed to update user role: %w", err)
 	}
-	return user, nil
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
 }
 
-// Loyalty Program Implementations
+func (r *PostgreSQLUserRepository) UpdateUserPassword(ctx context.Context, userID int, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2 WHERE id = $1`
+	result, err := r.db.ExecContext(ctx, query, userID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to update user password: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+	return nil
+}
+
+func (r *PostgreSQLUserRepository) MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW()) WHERE id = $1 AND email = $2`
+	_, err := r.db.ExecContext(ctx, query, userID, email)
+	if err != nil {
+		return fmt.Errorf("failed to mark email verified: %w", err)
+	}
+	return nil
+}
 
-func (r *PostgreSQLUserRepository) CreateLoyaltyPoint(ctx context.Context, point *domain.LoyaltyPoint) error {
-	query := `INSERT INTO loyalty_points (user_id, points, type) VALUES ($1, $2, $3) RETURNING id, created_at`
-	err := r.db.QueryRowContext(ctx, query, point.UserID, point.Points, point.Type).Scan(&point.ID, &point.CreatedAt)
+func (r *PostgreSQLUserRepository) MarkPhoneVerified(ctx context.Context, userID int, phoneNumber string) error {
+	query := `UPDATE users SET phone_verified_at = COALESCE(phone_verified_at, NOW()) WHERE id = $1 AND phone_number = $2`
+	_, err := r.db.ExecContext(ctx, query, userID, phoneNumber)
 	if err != nil {
-		return fmt.Errorf("failed to create loyalty point: %w", err)
+		return fmt.Errorf("failed to mark phone verified: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgreSQLUserRepository) AnonymizeUser(ctx context.Context, userID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var phoneNumber sql.NullString
+	query := `SELECT phone_number FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, userID).Scan(&phoneNumber); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	statements := []string{
+		`UPDATE users SET username = 'Deleted user', phone_number = NULL, email = NULL, email_verified_at = NULL, phone_verified_at = NULL, birth_date = NULL, password_hash = NULL, social_id = NULL, qr_code = NULL, deleted_at = NOW() WHERE id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+		`DELETE FROM notifications WHERE user_id = $1`,
+		`UPDATE one_time_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`,
+		`UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`,
+		`DELETE FROM referral_codes WHERE user_id = $1`,
+		`UPDATE referrals SET device = '', ip_address = '' WHERE referee_id = $1`,
+		`DELETE FROM wallet_passes WHERE user_id = $1`,
+		`DELETE FROM discount_card_overrides WHERE user_id = $1`,
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt, userID); err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+	}
+	if phoneNumber.Valid {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM otp_codes WHERE phone_number = $1`, phoneNumber.String); err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 	return nil
 }
 
+// Loyalty Program Implementations
+
 func (r *PostgreSQLUserRepository) GetLoyaltyPointsByUserID(ctx context.Context, userID int) ([]*domain.LoyaltyPoint, error) {
-	query := `SELECT id, user_id, points, type, created_at FROM loyalty_points WHERE user_id = $1 ORDER BY created_at DESC`
+	query := `SELECT ` + loyaltyPointColumns + ` FROM loyalty_points WHERE user_id = $1 ORDER BY id DESC`
 	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get loyalty points by user ID: %w", err)
@@ -128,8 +238,8 @@ func (r *PostgreSQLUserRepository) GetLoyaltyPointsByUserID(ctx context.Context,
 
 	var points []*domain.LoyaltyPoint
 	for rows.Next() {
-		point := &domain.LoyaltyPoint{}
-		if err := rows.Scan(&point.ID, &point.UserID, &point.Points, &point.Type, &point.CreatedAt); err != nil {
+		point, err := scanLoyaltyPoint(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan loyalty point: %w", err)
 		}
 		points = append(points, point)
@@ -139,8 +249,8 @@ func (r *PostgreSQLUserRepository) GetLoyaltyPointsByUserID(ctx context.Context,
 
 func (r *PostgreSQLUserRepository) GetLoyaltyTierByID(ctx context.Context, id int) (*domain.LoyaltyTier, error) {
 	tier := &domain.LoyaltyTier{}
-	query := `SELECT id, name, min_points, description, benefits FROM loyalty_tiers WHERE id = $1`
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&tier.ID, &tier.Name, &tier.MinPoints, &tier.Description, &tier.Benefits)
+	query := `SELECT ` + loyaltyTierColumns + ` FROM loyalty_tiers WHERE id = $1`
+	err := scanLoyaltyTier(r.db.QueryRowContext(ctx, query, id), tier)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("loyalty tier not found")
@@ -152,8 +262,8 @@ func (r *PostgreSQLUserRepository) GetLoyaltyTierByID(ctx context.Context, id in
 
 func (r *PostgreSQLUserRepository) GetLoyaltyTierByName(ctx context.Context, name string) (*domain.LoyaltyTier, error) {
 	tier := &domain.LoyaltyTier{}
-	query := `SELECT id, name, min_points, description, benefits FROM loyalty_tiers WHERE name = $1`
-	err := r.db.QueryRowContext(ctx, query, name).Scan(&tier.ID, &tier.Name, &tier.MinPoints, &tier.Description, &tier.Benefits)
+	query := `SELECT ` + loyaltyTierColumns + ` FROM loyalty_tiers WHERE name = $1`
+	err := scanLoyaltyTier(r.db.QueryRowContext(ctx, query, name), tier)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("loyalty tier not found")
@@ -164,7 +274,7 @@ func (r *PostgreSQLUserRepository) GetLoyaltyTierByName(ctx context.Context, nam
 }
 
 func (r *PostgreSQLUserRepository) GetAllLoyaltyTiers(ctx context.Context) ([]*domain.LoyaltyTier, error) {
-	query := `SELECT id, name, min_points, description, benefits FROM loyalty_tiers ORDER BY min_points ASC`
+	query := `SELECT ` + loyaltyTierColumns + ` FROM loyalty_tiers ORDER BY min_points ASC`
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all loyalty tiers: %w", err)
@@ -174,7 +284,7 @@ func (r *PostgreSQLUserRepository) GetAllLoyaltyTiers(ctx context.Context) ([]*d
 	var tiers []*domain.LoyaltyTier
 	for rows.Next() {
 		tier := &domain.LoyaltyTier{}
-		if err := rows.Scan(&tier.ID, &tier.Name, &tier.MinPoints, &tier.Description, &tier.Benefits); err != nil {
+		if err := scanLoyaltyTier(rows, tier); err != nil {
 			return nil, fmt.Errorf("failed to scan loyalty tier: %w", err)
 		}
 		tiers = append(tiers, tier)
@@ -183,29 +293,76 @@ func (r *PostgreSQLUserRepository) GetAllLoyaltyTiers(ctx context.Context) ([]*d
 }
 
 func (r *PostgreSQLUserRepository) CreateLoyaltyTier(ctx context.Context, tier *domain.LoyaltyTier) error {
-	query := `INSERT INTO loyalty_tiers (name, min_points, description, benefits) VALUES ($1, $2, $3, $4) RETURNING id`
-	err := r.db.QueryRowContext(ctx, query, tier.Name, tier.MinPoints, tier.Description, tier.Benefits).Scan(&tier.ID)
+	query := `
+		INSERT INTO loyalty_tiers (name, min_points, description, point_value_multiplier, discount_percent, points_multiplier, free_alterations, free_shipping, early_access)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id
+	`
+	b := tier.Benefits
+	err := r.db.QueryRowContext(
+		ctx, query, tier.Name, tier.MinPoints, tier.Description, tier.PointValueMultiplier,
+		b.DiscountPercent, b.PointsMultiplier, b.FreeAlterations, b.FreeShipping, b.EarlyAccess,
+	).Scan(&tier.ID)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return fmt.Errorf("loyalty tier with this name already exists")
+		}
 		return fmt.Errorf("failed to create loyalty tier: %w", err)
 	}
 	return nil
 }
 
 func (r *PostgreSQLUserRepository) UpdateLoyaltyTier(ctx context.Context, tier *domain.LoyaltyTier) error {
-	query := `UPDATE loyalty_tiers SET name = $2, min_points = $3, description = $4, benefits = $5 WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, tier.ID, tier.Name, tier.MinPoints, tier.Description, tier.Benefits)
+	query := `
+		UPDATE loyalty_tiers SET name = $2, min_points = $3, description = $4, point_value_multiplier = $5, discount_percent = $6,
+			points_multiplier = $7, free_alterations = $8, free_shipping = $9, early_access = $10
+		WHERE id = $1
+	`
+	b := tier.Benefits
+	result, err := r.db.ExecContext(
+		ctx, query, tier.ID, tier.Name, tier.MinPoints, tier.Description, tier.PointValueMultiplier,
+		b.DiscountPercent, b.PointsMultiplier, b.FreeAlterations, b.FreeShipping, b.EarlyAccess,
+	)
 	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			return fmt.Errorf("loyalty tier with this name already exists")
+		}
+		return fmt.Errorf("failed to update loyalty tier: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
 		return fmt.Errorf("failed to update loyalty tier: %w", err)
+	} else if rows == 0 {
+		return fmt.Errorf("loyalty tier not found")
 	}
 	return nil
 }
 
 func (r *PostgreSQLUserRepository) DeleteLoyaltyTier(ctx context.Context, id int) error {
-	query := `DELETE FROM loyalty_tiers WHERE id = $1`
-	_, err := r.db.ExecContext(ctx, query, id)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var members int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM user_loyalty WHERE current_tier_id = $1`, id).Scan(&members); err != nil {
+		return fmt.Errorf("failed to count tier members: %w", err)
+	}
+	if members > 0 {
+		return fmt.Errorf("loyalty tier has %d members", members)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM loyalty_tiers WHERE id = $1`, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete loyalty tier: %w", err)
 	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete loyalty tier: %w", err)
+	} else if rows == 0 {
+		return fmt.Errorf("loyalty tier not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 	return nil
 }
 
@@ -239,8 +396,8 @@ func (r *PostgreSQLUserRepository) GetLoyaltyActivitiesByUserID(ctx context.Cont
 
 func (r *PostgreSQLUserRepository) GetUserLoyalty(ctx context.Context, userID int) (*domain.UserLoyalty, error) {
 	userLoyalty := &domain.UserLoyalty{}
-	query := `SELECT user_id, current_points, current_tier_id, last_activity_at FROM user_loyalty WHERE user_id = $1`
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(&userLoyalty.UserID, &userLoyalty.CurrentPoints, &userLoyalty.CurrentTierID, &userLoyalty.LastActivityAt)
+	query := `SELECT user_id, current_points, COALESCE(current_tier_id, 0), last_activity_at, tier_grace_until FROM user_loyalty WHERE user_id = $1`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&userLoyalty.UserID, &userLoyalty.CurrentPoints, &userLoyalty.CurrentTierID, &userLoyalty.LastActivityAt, &userLoyalty.TierGraceUntil)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user loyalty not found")
@@ -251,10 +408,74 @@ func (r *PostgreSQLUserRepository) GetUserLoyalty(ctx context.Context, userID in
 }
 
 func (r *PostgreSQLUserRepository) UpdateUserLoyalty(ctx context.Context, userLoyalty *domain.UserLoyalty) error {
-	query := `INSERT INTO user_loyalty (user_id, current_points, current_tier_id, last_activity_at) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id) DO UPDATE SET current_points = EXCLUDED.current_points, current_tier_id = EXCLUDED.current_tier_id, last_activity_at = EXCLUDED.last_activity_at`
-	_, err := r.db.ExecContext(ctx, query, userLoyalty.UserID, userLoyalty.CurrentPoints, userLoyalty.CurrentTierID, userLoyalty.LastActivityAt)
+	query := `INSERT INTO user_loyalty (user_id, current_tier_id, last_activity_at) VALUES ($1, NULLIF($2, 0), $3) ON CONFLICT (user_id) DO UPDATE SET current_tier_id = EXCLUDED.current_tier_id, last_activity_at = EXCLUDED.last_activity_at`
+	_, err := r.db.ExecContext(ctx, query, userLoyalty.UserID, userLoyalty.CurrentTierID, userLoyalty.LastActivityAt)
 	if err != nil {
 		return fmt.Errorf("failed to update user loyalty: %w", err)
 	}
 	return nil
 }
+
+func (r *PostgreSQLUserRepository) GetUserLoyalties(ctx context.Context) ([]*domain.UserLoyalty, error) {
+	query := `
+		SELECT ul.user_id, ul.current_points, COALESCE(ul.current_tier_id, 0), ul.last_activity_at, ul.tier_grace_until
+		FROM user_loyalty ul JOIN users u ON u.id = ul.user_id AND u.deleted_at IS NULL
+		ORDER BY ul.user_id
+	`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user loyalties: %w", err)
+	}
+	defer rows.Close()
+
+	var loyalties []*domain.UserLoyalty
+	for rows.Next() {
+		userLoyalty := &domain.UserLoyalty{}
+		if err := rows.Scan(&userLoyalty.UserID, &userLoyalty.CurrentPoints, &userLoyalty.CurrentTierID, &userLoyalty.LastActivityAt, &userLoyalty.TierGraceUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan user loyalty: %w", err)
+		}
+		loyalties = append(loyalties, userLoyalty)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return loyalties, nil
+}
+
+func (r *PostgreSQLUserRepository) UpdateUserTier(ctx context.Context, userID int, tier *domain.LoyaltyTier) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE user_loyalty SET current_tier_id = $2, tier_grace_until = NULL WHERE user_id = $1`, userID, tier.ID); err != nil {
+		return fmt.Errorf("failed to update user tier: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET loyalty_status = $2 WHERE id = $1`, userID, tier.Name); err != nil {
+		return fmt.Errorf("failed to update user loyalty status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgreSQLUserRepository) SetTierGraceUntil(ctx context.Context, userID int, graceUntil *time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE user_loyalty SET tier_grace_until = $2 WHERE user_id = $1`, userID, graceUntil); err != nil {
+		return fmt.Errorf("failed to update tier grace period: %w", err)
+	}
+	return nil
+}
+
+const loyaltyTierColumns = `id, name, min_points, COALESCE(description, ''), point_value_multiplier, discount_percent, points_multiplier, free_alterations, free_shipping, early_access`
+
+func scanLoyaltyTier(row rowScanner, tier *domain.LoyaltyTier) error {
+	b := &tier.Benefits
+	return row.Scan(
+		&tier.ID, &tier.Name, &tier.MinPoints, &tier.Description, &tier.PointValueMultiplier,
+		&b.DiscountPercent, &b.PointsMultiplier, &b.FreeAlterations, &b.FreeShipping, &b.EarlyAccess,
+	)
+}
@@ -0,0 +1,172 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type walletPassRepository struct {
+	db *sql.DB
+}
+
+func NewWalletPassRepository(db *sql.DB) domain.WalletPassRepository {
+	return &walletPassRepository{db: db}
+}
+
+const walletPassColumns = `user_id, serial_number, auth_token, points, tier_id, code_window, updated_at`
+
+func scanWalletPass(row rowScanner) (*domain.WalletPass, error) {
+	pass := &domain.WalletPass{}
+	err := row.Scan(&pass.UserID, &pass.SerialNumber, &pass.AuthToken, &pass.Points, &pass.TierID, &pass.CodeWindow, &pass.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return pass, nil
+}
+
+func (r *walletPassRepository) CreateWalletPass(ctx context.Context, pass *domain.WalletPass) error {
+	query := `
+		INSERT INTO wallet_passes (user_id, serial_number, auth_token, points, tier_id, code_window)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO NOTHING
+	`
+	_, err := r.db.ExecContext(ctx, query, pass.UserID, pass.SerialNumber, pass.AuthToken, pass.Points, pass.TierID, pass.CodeWindow)
+	if err != nil {
+		return fmt.Errorf("failed to create wallet pass: %w", err)
+	}
+	return nil
+}
+
+func (r *walletPassRepository) GetWalletPassByUserID(ctx context.Context, userID int) (*domain.WalletPass, error) {
+	return r.getWalletPass(ctx, `SELECT `+walletPassColumns+` FROM wallet_passes WHERE user_id = $1`, userID)
+}
+
+func (r *walletPassRepository) GetWalletPassBySerialNumber(ctx context.Context, serialNumber string) (*domain.WalletPass, error) {
+	return r.getWalletPass(ctx, `SELECT `+walletPassColumns+` FROM wallet_passes WHERE serial_number = $1`, serialNumber)
+}
+
+func (r *walletPassRepository) GetStaleWalletPasses(ctx context.Context, codeWindow int64) ([]*domain.WalletPass, error) {
+	query := `
+		SELECT p.user_id, p.serial_number, p.auth_token, u.current_points, l.current_tier_id, $1::BIGINT, p.updated_at
+		FROM wallet_passes p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN user_loyalty l ON l.user_id = p.user_id
+		WHERE u.deleted_at IS NULL
+			AND (p.points <> u.current_points OR p.tier_id IS DISTINCT FROM l.current_tier_id OR p.code_window <> $1::BIGINT)
+		ORDER BY p.user_id
+	`
+	return r.queryWalletPasses(ctx, query, codeWindow)
+}
+
+func (r *walletPassRepository) MarkWalletPassUpdated(ctx context.Context, pass *domain.WalletPass) error {
+	query := `
+		UPDATE wallet_passes SET points = $2, tier_id = $3, code_window = $4, updated_at = NOW()
+		WHERE user_id = $1 RETURNING updated_at
+	`
+	if err := r.db.QueryRowContext(ctx, query, pass.UserID, pass.Points, pass.TierID, pass.CodeWindow).Scan(&pass.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("wallet pass not found")
+		}
+		return fmt.Errorf("failed to update wallet pass: %w", err)
+	}
+	return nil
+}
+
+func (r *walletPassRepository) RegisterWalletDevice(ctx context.Context, deviceLibraryID, serialNumber, pushToken string) (bool, error) {
+	// xmax is zero only for a freshly inserted row, which tells a new registration from a renewed one
+	query := `
+		INSERT INTO wallet_pass_devices (device_library_id, serial_number, push_token)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (device_library_id, serial_number) DO UPDATE SET push_token = EXCLUDED.push_token
+		RETURNING xmax = 0
+	`
+	var created bool
+	if err := r.db.QueryRowContext(ctx, query, deviceLibraryID, serialNumber, pushToken).Scan(&created); err != nil {
+		return false, fmt.Errorf("failed to register wallet device: %w", err)
+	}
+	return created, nil
+}
+
+func (r *walletPassRepository) UnregisterWalletDevice(ctx context.Context, deviceLibraryID, serialNumber string) error {
+	query := `DELETE FROM wallet_pass_devices WHERE device_library_id = $1 AND serial_number = $2`
+	if _, err := r.db.ExecContext(ctx, query, deviceLibraryID, serialNumber); err != nil {
+		return fmt.Errorf("failed to unregister wallet device: %w", err)
+	}
+	return nil
+}
+
+func (r *walletPassRepository) GetWalletPassesByDevice(ctx context.Context, deviceLibraryID string, updatedSince *time.Time) ([]*domain.WalletPass, error) {
+	query := `
+		SELECT p.user_id, p.serial_number, p.auth_token, p.points, p.tier_id, p.code_window, p.updated_at
+		FROM wallet_passes p JOIN wallet_pass_devices d ON d.serial_number = p.serial_number
+		WHERE d.device_library_id = $1 AND ($2::TIMESTAMPTZ IS NULL OR p.updated_at > $2)
+		ORDER BY p.updated_at
+	`
+	return r.queryWalletPasses(ctx, query, deviceLibraryID, updatedSince)
+}
+
+func (r *walletPassRepository) GetWalletPushTokens(ctx context.Context, serialNumber string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT push_token FROM wallet_pass_devices WHERE serial_number = $1`, serialNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet push tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var pushTokens []string
+	for rows.Next() {
+		var pushToken string
+		if err := rows.Scan(&pushToken); err != nil {
+			return nil, fmt.Errorf("failed to scan wallet push token: %w", err)
+		}
+		pushTokens = append(pushTokens, pushToken)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return pushTokens, nil
+}
+
+func (r *walletPassRepository) DeleteWalletPushToken(ctx context.Context, pushToken string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM wallet_pass_devices WHERE push_token = $1`, pushToken); err != nil {
+		return fmt.Errorf("failed to delete wallet push token: %w", err)
+	}
+	return nil
+}
+
+func (r *walletPassRepository) getWalletPass(ctx context.Context, query string, arg interface{}) (*domain.WalletPass, error) {
+	pass, err := scanWalletPass(r.db.QueryRowContext(ctx, query, arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("wallet pass not found")
+		}
+		return nil, fmt.Errorf("failed to get wallet pass: %w", err)
+	}
+	return pass, nil
+}
+
+func (r *walletPassRepository) queryWalletPasses(ctx context.Context, query string, args ...interface{}) ([]*domain.WalletPass, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wallet passes: %w", err)
+	}
+	defer rows.Close()
+
+	var passes []*domain.WalletPass
+	for rows.Next() {
+		pass, err := scanWalletPass(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wallet pass: %w", err)
+		}
+		passes = append(passes, pass)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return passes, nil
+}
@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// AdjustmentPolicy sets how many points staff may add or take by hand.
+type AdjustmentPolicy struct {
+	RoleLimits        map[domain.Role]int // Largest adjustment each role may request or approve; roles not listed may do neither
+	RoleDailyLimits   map[domain.Role]int // Most points each role may request in total over 24 hours, so large credits cannot be split to skip approval; zero means no cap
+	ApprovalThreshold int                 // Adjustments larger than this wait for a second staff member to approve them
+}
+
+// AdjustmentUseCase handles points added to or taken from members by staff, such as goodwill credits and corrections.
+type AdjustmentUseCase struct {
+	adjustmentRepo domain.LoyaltyAdjustmentRepository
+	userRepo       domain.UserRepository
+	loyaltyUseCase *LoyaltyUseCase
+	policy         AdjustmentPolicy
+}
+
+func NewAdjustmentUseCase(adjustmentRepo domain.LoyaltyAdjustmentRepository, userRepo domain.UserRepository, loyaltyUseCase *LoyaltyUseCase, policy AdjustmentPolicy) *AdjustmentUseCase {
+	return &AdjustmentUseCase{
+		adjustmentRepo: adjustmentRepo,
+		userRepo:       userRepo,
+		loyaltyUseCase: loyaltyUseCase,
+		policy:         policy,
+	}
+}
+
+// Staff is the staff member making or reviewing an adjustment.
+type Staff struct {
+	ID   int
+	Role domain.Role
+}
+
+type CreateAdjustmentRequest struct {
+	Points     int                     `json:"points"`
+	ReasonCode domain.AdjustmentReason `json:"reason_code"`
+	Note       string                  `json:"note"`
+}
+
+type ReviewAdjustmentRequest struct {
+	Note string `json:"note"`
+}
+
+// AdjustmentResponse is an adjustment with every step taken on it.
+type AdjustmentResponse struct {
+	*domain.LoyaltyAdjustment
+	Events []*domain.LoyaltyAdjustmentEvent `json:"events"`
+}
+
+type GetAdjustmentsResponse struct {
+	Adjustments []*domain.LoyaltyAdjustment `json:"adjustments"`
+}
+
+// CreateAdjustment adds points to or takes points from the member. Adjustments within the approval threshold
+// are posted straight away; larger ones wait for a second staff member. Staff cannot adjust their own points.
+// A request repeated with the same idempotency key returns the adjustment made the first time.
+func (uc *AdjustmentUseCase) CreateAdjustment(ctx context.Context, userID int, staff Staff, req *CreateAdjustmentRequest, idempotencyKey string) (*AdjustmentResponse, error) {
+	note := strings.TrimSpace(req.Note)
+	switch {
+	case userID == staff.ID:
+		return nil, fmt.Errorf("staff cannot adjust their own points")
+	case req.Points == 0:
+		return nil, fmt.Errorf("points must not be zero")
+	case !req.ReasonCode.IsValid():
+		return nil, fmt.Errorf("invalid reason_code: %s", req.ReasonCode)
+	case req.ReasonCode == domain.AdjustmentOther && note == "":
+		return nil, fmt.Errorf("note is required for reason_code other")
+	}
+	if err := uc.checkLimit(staff, req.Points); err != nil {
+		return nil, err
+	}
+	if _, err := uc.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	adjustment := &domain.LoyaltyAdjustment{
+		UserID:         userID,
+		Points:         req.Points,
+		ReasonCode:     req.ReasonCode,
+		Note:           note,
+		RequestedBy:    &staff.ID,
+		IdempotencyKey: fmt.Sprintf("staff:%d:%s", staff.ID, idempotencyKey),
+	}
+	if _, err := uc.adjustmentRepo.CreateAdjustment(ctx, adjustment, uc.policy.RoleDailyLimits[staff.Role]); err != nil {
+		return nil, err
+	}
+
+	// Also finishes a repeated request whose adjustment was stored but not yet posted
+	if adjustment.Status == domain.AdjustmentPending && abs(adjustment.Points) <= uc.policy.ApprovalThreshold {
+		if err := uc.approve(ctx, adjustment, staff.ID, "Within the approval threshold"); err != nil {
+			if err.Error() != "insufficient points" {
+				return nil, err
+			}
+			adjustment.Status = domain.AdjustmentRejected
+			adjustment.ReviewedBy = &staff.ID
+			adjustment.ReviewNote = "The member does not have enough points"
+			if err := uc.adjustmentRepo.ReviewAdjustment(ctx, adjustment); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return uc.GetAdjustment(ctx, adjustment.ID)
+}
+
+// GetAdjustment returns the adjustment with every step taken on it.
+func (uc *AdjustmentUseCase) GetAdjustment(ctx context.Context, adjustmentID int) (*AdjustmentResponse, error) {
+	adjustment, err := uc.adjustmentRepo.GetAdjustmentByID(ctx, adjustmentID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := uc.adjustmentRepo.GetAdjustmentEvents(ctx, adjustmentID)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []*domain.LoyaltyAdjustmentEvent{}
+	}
+	return &AdjustmentResponse{LoyaltyAdjustment: adjustment, Events: events}, nil
+}
+
+// GetAdjustments returns the adjustments of a member, or of everyone for a zero user ID, optionally only those with the status.
+func (uc *AdjustmentUseCase) GetAdjustments(ctx context.Context, userID int, status domain.AdjustmentStatus) (*GetAdjustmentsResponse, error) {
+	switch status {
+	case "", domain.AdjustmentPending, domain.AdjustmentApproved, domain.AdjustmentRejected:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", status)
+	}
+	adjustments, err := uc.adjustmentRepo.GetAdjustments(ctx, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	if adjustments == nil {
+		adjustments = []*domain.LoyaltyAdjustment{}
+	}
+	return &GetAdjustmentsResponse{Adjustments: adjustments}, nil
+}
+
+// ApproveAdjustment posts a pending adjustment to the ledger. It must be approved by someone other than
+// the staff member who requested it, within the approver's own limit.
+func (uc *AdjustmentUseCase) ApproveAdjustment(ctx context.Context, adjustmentID int, staff Staff, req *ReviewAdjustmentRequest) (*AdjustmentResponse, error) {
+	adjustment, err := uc.pendingAdjustment(ctx, adjustmentID, staff)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.approve(ctx, adjustment, staff.ID, strings.TrimSpace(req.Note)); err != nil {
+		return nil, err
+	}
+	return uc.GetAdjustment(ctx, adjustmentID)
+}
+
+// RejectAdjustment turns down a pending adjustment; nothing is posted.
+func (uc *AdjustmentUseCase) RejectAdjustment(ctx context.Context, adjustmentID int, staff Staff, req *ReviewAdjustmentRequest) (*AdjustmentResponse, error) {
+	note := strings.TrimSpace(req.Note)
+	if note == "" {
+		return nil, fmt.Errorf("note is required")
+	}
+	adjustment, err := uc.pendingAdjustment(ctx, adjustmentID, staff)
+	if err != nil {
+		return nil, err
+	}
+	adjustment.Status = domain.AdjustmentRejected
+	adjustment.ReviewedBy = &staff.ID
+	adjustment.ReviewNote = note
+	if err := uc.adjustmentRepo.ReviewAdjustment(ctx, adjustment); err != nil {
+		return nil, err
+	}
+	return uc.GetAdjustment(ctx, adjustmentID)
+}
+
+// pendingAdjustment returns an adjustment the staff member may review.
+func (uc *AdjustmentUseCase) pendingAdjustment(ctx context.Context, adjustmentID int, staff Staff) (*domain.LoyaltyAdjustment, error) {
+	adjustment, err := uc.adjustmentRepo.GetAdjustmentByID(ctx, adjustmentID)
+	if err != nil {
+		return nil, err
+	}
+	if adjustment.Status != domain.AdjustmentPending {
+		return nil, fmt.Errorf("loyalty adjustment is not pending")
+	}
+	if adjustment.RequestedBy != nil && *adjustment.RequestedBy == staff.ID {
+		return nil, fmt.Errorf("adjustments must be reviewed by someone other than the requester")
+	}
+	if adjustment.UserID == staff.ID {
+		return nil, fmt.Errorf("staff cannot review adjustments to their own points")
+	}
+	if err := uc.checkLimit(staff, adjustment.Points); err != nil {
+		return nil, err
+	}
+	return adjustment, nil
+}
+
+// approve posts the adjustment to the ledger and marks it approved. The entry is posted first, once per
+// adjustment however often this is retried; if the adjustment was rejected in the meantime, the entry is reversed.
+func (uc *AdjustmentUseCase) approve(ctx context.Context, adjustment *domain.LoyaltyAdjustment, reviewerID int, note string) error {
+	entry := &domain.LoyaltyPoint{
+		UserID:         adjustment.UserID,
+		Points:         adjustment.Points,
+		Type:           string(adjustment.ReasonCode),
+		Kind:           domain.LedgerAdjust,
+		IdempotencyKey: fmt.Sprintf("adjustment:%d", adjustment.ID),
+		Reference:      fmt.Sprintf("adjustment:%d", adjustment.ID),
+	}
+	if _, err := uc.loyaltyUseCase.postEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	adjustment.Status = domain.AdjustmentApproved
+	adjustment.ReviewedBy = &reviewerID
+	adjustment.ReviewNote = note
+	adjustment.EntryID = &entry.ID
+	err := uc.adjustmentRepo.ReviewAdjustment(ctx, adjustment)
+	if err == nil || err.Error() != "loyalty adjustment is not pending" {
+		return err
+	}
+
+	// Someone else reviewed it first; an approval has already posted this same entry
+	current, getErr := uc.adjustmentRepo.GetAdjustmentByID(ctx, adjustment.ID)
+	if getErr != nil {
+		return getErr
+	}
+	if current.Status == domain.AdjustmentRejected {
+		if err := uc.loyaltyUseCase.reverseEntry(ctx, entry, "adjustment_reversal"); err != nil {
+			log.Printf("Failed to reverse the entry of rejected adjustment %d: %v", adjustment.ID, err)
+			return err
+		}
+	}
+	return err
+}
+
+// checkLimit fails if the adjustment is larger than the staff member's role may handle.
+func (uc *AdjustmentUseCase) checkLimit(staff Staff, points int) error {
+	limit, ok := uc.policy.RoleLimits[staff.Role]
+	if !ok || limit <= 0 {
+		return fmt.Errorf("adjustment exceeds your limit of 0 points")
+	}
+	if abs(points) > limit {
+		return fmt.Errorf("adjustment exceeds your limit of %d points", limit)
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
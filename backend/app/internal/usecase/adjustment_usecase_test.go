@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type fakeAdjustmentRepo struct {
+	domain.LoyaltyAdjustmentRepository
+	adjustments   map[int]*domain.LoyaltyAdjustment
+	dailyLimit    int  // Passed with the last adjustment created
+	rejectedFirst bool // Someone else rejects the adjustment just before it is reviewed
+}
+
+func (r *fakeAdjustmentRepo) CreateAdjustment(ctx context.Context, adjustment *domain.LoyaltyAdjustment, dailyLimit int) (bool, error) {
+	r.dailyLimit = dailyLimit
+	for _, stored := range r.adjustments {
+		if stored.IdempotencyKey == adjustment.IdempotencyKey {
+			*adjustment = *stored
+			return false, nil
+		}
+	}
+	adjustment.ID = len(r.adjustments) + 1
+	adjustment.Status = domain.AdjustmentPending
+	stored := *adjustment
+	r.adjustments[adjustment.ID] = &stored
+	return true, nil
+}
+
+func (r *fakeAdjustmentRepo) GetAdjustmentByID(ctx context.Context, adjustmentID int) (*domain.LoyaltyAdjustment, error) {
+	if stored, ok := r.adjustments[adjustmentID]; ok {
+		adjustment := *stored
+		return &adjustment, nil
+	}
+	return nil, fmt.Errorf("loyalty adjustment not found")
+}
+
+func (r *fakeAdjustmentRepo) ReviewAdjustment(ctx context.Context, adjustment *domain.LoyaltyAdjustment) error {
+	if r.rejectedFirst {
+		r.adjustments[adjustment.ID].Status = domain.AdjustmentRejected
+	}
+	if r.adjustments[adjustment.ID].Status != domain.AdjustmentPending {
+		return fmt.Errorf("loyalty adjustment is not pending")
+	}
+	stored := *adjustment
+	r.adjustments[adjustment.ID] = &stored
+	return nil
+}
+
+func (r *fakeAdjustmentRepo) GetAdjustmentEvents(ctx context.Context, adjustmentID int) ([]*domain.LoyaltyAdjustmentEvent, error) {
+	return nil, nil
+}
+
+const adjustedMemberID = 1
+
+// newTestAdjustmentUseCase returns an adjustment use case for a member holding 100 points. Store staff may
+// adjust up to 500 points and 1000 a day, managers up to 5000; anything over 200 waits for approval.
+func newTestAdjustmentUseCase(adjustments map[int]*domain.LoyaltyAdjustment) (*AdjustmentUseCase, *fakeAdjustmentRepo, *fakeUserRepo) {
+	users := &fakeUserRepo{loyalties: map[int]*domain.UserLoyalty{adjustedMemberID: {UserID: adjustedMemberID, CurrentPoints: 100}}}
+	loyaltyUseCase, _ := newTestLoyaltyUseCase(users, nil)
+	if adjustments == nil {
+		adjustments = map[int]*domain.LoyaltyAdjustment{}
+	}
+	repo := &fakeAdjustmentRepo{adjustments: adjustments}
+	policy := AdjustmentPolicy{
+		RoleLimits:        map[domain.Role]int{domain.RoleStoreStaff: 500, domain.RoleManager: 5000},
+		RoleDailyLimits:   map[domain.Role]int{domain.RoleStoreStaff: 1000},
+		ApprovalThreshold: 200,
+	}
+	return NewAdjustmentUseCase(repo, &fakeMemberUserRepo{users}, loyaltyUseCase, policy), repo, users
+}
+
+func TestCreateAdjustment(t *testing.T) {
+	clerk := Staff{ID: 9, Role: domain.RoleStoreStaff}
+	manager := Staff{ID: 8, Role: domain.RoleManager}
+	tests := []struct {
+		name           string
+		userID         int
+		staff          Staff
+		req            CreateAdjustmentRequest
+		calls          int
+		wantStatus     domain.AdjustmentStatus
+		wantBalance    int
+		wantDailyLimit int
+		wantErr        string
+	}{
+		{
+			name: "within the threshold posted at once", userID: adjustedMemberID, staff: clerk,
+			req: CreateAdjustmentRequest{Points: 150, ReasonCode: domain.AdjustmentGoodwill}, calls: 1,
+			wantStatus: domain.AdjustmentApproved, wantBalance: 250, wantDailyLimit: 1000,
+		},
+		{
+			name: "repeated request posts once", userID: adjustedMemberID, staff: clerk,
+			req: CreateAdjustmentRequest{Points: 150, ReasonCode: domain.AdjustmentGoodwill}, calls: 3,
+			wantStatus: domain.AdjustmentApproved, wantBalance: 250, wantDailyLimit: 1000,
+		},
+		{
+			name: "debit within the threshold", userID: adjustedMemberID, staff: clerk,
+			req: CreateAdjustmentRequest{Points: -60, ReasonCode: domain.AdjustmentCorrection}, calls: 1,
+			wantStatus: domain.AdjustmentApproved, wantBalance: 40, wantDailyLimit: 1000,
+		},
+		{
+			name: "debit beyond the balance rejected", userID: adjustedMemberID, staff: clerk,
+			req: CreateAdjustmentRequest{Points: -150, ReasonCode: domain.AdjustmentCorrection}, calls: 1,
+			wantStatus: domain.AdjustmentRejected, wantBalance: 100, wantDailyLimit: 1000,
+		},
+		{
+			name: "over the threshold waits for approval", userID: adjustedMemberID, staff: manager,
+			req: CreateAdjustmentRequest{Points: 1000, ReasonCode: domain.AdjustmentMissedPurchase}, calls: 1,
+			wantStatus: domain.AdjustmentPending, wantBalance: 100,
+		},
+		{
+			name: "own points", userID: clerk.ID, staff: clerk,
+			req:     CreateAdjustmentRequest{Points: 50, ReasonCode: domain.AdjustmentGoodwill},
+			wantErr: "staff cannot adjust their own points",
+		},
+		{
+			name: "over the role limit", userID: adjustedMemberID, staff: clerk,
+			req:     CreateAdjustmentRequest{Points: 600, ReasonCode: domain.AdjustmentGoodwill},
+			wantErr: "adjustment exceeds your limit of 500 points",
+		},
+		{
+			name: "debit over the role limit", userID: adjustedMemberID, staff: clerk,
+			req:     CreateAdjustmentRequest{Points: -600, ReasonCode: domain.AdjustmentCorrection},
+			wantErr: "adjustment exceeds your limit of 500 points",
+		},
+		{
+			name: "role without a limit", userID: adjustedMemberID, staff: Staff{ID: 7, Role: domain.RoleCustomer},
+			req:     CreateAdjustmentRequest{Points: 10, ReasonCode: domain.AdjustmentGoodwill},
+			wantErr: "adjustment exceeds your limit of 0 points",
+		},
+		{
+			name: "zero points", userID: adjustedMemberID, staff: clerk,
+			req:     CreateAdjustmentRequest{ReasonCode: domain.AdjustmentGoodwill},
+			wantErr: "points must not be zero",
+		},
+		{
+			name: "unknown reason", userID: adjustedMemberID, staff: clerk,
+			req:     CreateAdjustmentRequest{Points: 50, ReasonCode: "gift"},
+			wantErr: "invalid reason_code: gift",
+		},
+		{
+			name: "other reason without a note", userID: adjustedMemberID, staff: clerk,
+			req:     CreateAdjustmentRequest{Points: 50, ReasonCode: domain.AdjustmentOther, Note: " "},
+			wantErr: "note is required for reason_code other",
+		},
+		{
+			name: "unknown member", userID: 2, staff: clerk,
+			req:     CreateAdjustmentRequest{Points: 50, ReasonCode: domain.AdjustmentGoodwill},
+			wantErr: "user not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, repo, users := newTestAdjustmentUseCase(nil)
+
+			var resp *AdjustmentResponse
+			var err error
+			for i := 0; i < max(tt.calls, 1); i++ {
+				if resp, err = uc.CreateAdjustment(context.Background(), tt.userID, tt.staff, &tt.req, "key"); err != nil {
+					break
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("CreateAdjustment() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(repo.adjustments) != 0 {
+					t.Errorf("stored %d adjustments, want none", len(repo.adjustments))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateAdjustment() error = %v", err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", resp.Status, tt.wantStatus)
+			}
+			if got := users.loyalties[adjustedMemberID].CurrentPoints; got != tt.wantBalance {
+				t.Errorf("balance = %d, want %d", got, tt.wantBalance)
+			}
+			if repo.dailyLimit != tt.wantDailyLimit {
+				t.Errorf("daily limit = %d, want %d", repo.dailyLimit, tt.wantDailyLimit)
+			}
+			if len(repo.adjustments) != 1 {
+				t.Errorf("stored %d adjustments, want 1", len(repo.adjustments))
+			}
+		})
+	}
+}
+
+func TestReviewAdjustment(t *testing.T) {
+	const requesterID = 9
+	reviewer := Staff{ID: 8, Role: domain.RoleManager}
+	tests := []struct {
+		name          string
+		reject        bool
+		staff         Staff
+		status        domain.AdjustmentStatus
+		note          string
+		rejectedFirst bool
+		wantStatus    domain.AdjustmentStatus
+		wantBalance   int
+		wantErr       string
+	}{
+		{name: "approved", staff: reviewer, status: domain.AdjustmentPending, wantStatus: domain.AdjustmentApproved, wantBalance: 1100},
+		{name: "rejected", reject: true, staff: reviewer, status: domain.AdjustmentPending, note: "Not eligible", wantStatus: domain.AdjustmentRejected, wantBalance: 100},
+		{name: "rejected without a note", reject: true, staff: reviewer, status: domain.AdjustmentPending, wantErr: "note is required"},
+		{
+			name: "approved by the requester", staff: Staff{ID: requesterID, Role: domain.RoleManager}, status: domain.AdjustmentPending,
+			wantErr: "adjustments must be reviewed by someone other than the requester",
+		},
+		{
+			name: "approved by the member", staff: Staff{ID: adjustedMemberID, Role: domain.RoleManager}, status: domain.AdjustmentPending,
+			wantErr: "staff cannot review adjustments to their own points",
+		},
+		{
+			name: "over the approver's limit", staff: Staff{ID: 7, Role: domain.RoleStoreStaff}, status: domain.AdjustmentPending,
+			wantErr: "adjustment exceeds your limit of 500 points",
+		},
+		{name: "already reviewed", staff: reviewer, status: domain.AdjustmentApproved, wantErr: "loyalty adjustment is not pending"},
+		{
+			name: "rejected while being approved", staff: reviewer, status: domain.AdjustmentPending, rejectedFirst: true,
+			wantStatus: domain.AdjustmentRejected, wantBalance: 100, wantErr: "loyalty adjustment is not pending",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requestedBy := requesterID
+			uc, repo, users := newTestAdjustmentUseCase(map[int]*domain.LoyaltyAdjustment{1: {
+				ID: 1, UserID: adjustedMemberID, Points: 1000, ReasonCode: domain.AdjustmentMissedPurchase, Status: tt.status, RequestedBy: &requestedBy,
+			}})
+			repo.rejectedFirst = tt.rejectedFirst
+
+			req := &ReviewAdjustmentRequest{Note: tt.note}
+			var resp *AdjustmentResponse
+			var err error
+			if tt.reject {
+				resp, err = uc.RejectAdjustment(context.Background(), 1, tt.staff, req)
+			} else {
+				resp, err = uc.ApproveAdjustment(context.Background(), 1, tt.staff, req)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("review error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("review error = %v", err)
+			}
+			if tt.wantStatus != "" {
+				if resp != nil && resp.Status != tt.wantStatus {
+					t.Errorf("response status = %q, want %q", resp.Status, tt.wantStatus)
+				}
+				if got := repo.adjustments[1].Status; got != tt.wantStatus {
+					t.Errorf("stored status = %q, want %q", got, tt.wantStatus)
+				}
+				if got := users.loyalties[adjustedMemberID].CurrentPoints; got != tt.wantBalance {
+					t.Errorf("balance = %d, want %d", got, tt.wantBalance)
+				}
+			}
+		})
+	}
+}
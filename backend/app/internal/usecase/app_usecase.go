@@ -3,10 +3,12 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"log"
+	"math"
 	"strconv" // Added for string to int conversion
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5" // Added for JWT token generation
 	"github.com/google/uuid"
 	"github.com/mkbagandov/kingsman/backend/app/internal/domain" // Update with your actual project path
 	qrcode "github.com/skip2/go-qrcode"                          // Added QR code library
@@ -14,29 +16,63 @@ import (
 )
 
 type UserUseCase struct {
-	userRepo domain.UserRepository
+	userRepo            domain.UserRepository
+	otpRepo             domain.OTPRepository
+	sessionUseCase      *SessionUseCase
+	verificationUseCase *VerificationUseCase
+	loginThrottle       *LoginThrottle
+	referralUseCase     *ReferralUseCase
+	cardCodeSigner      *CardCodeSigner
 }
 
-func NewUserUseCase(userRepo domain.UserRepository) *UserUseCase {
-	return &UserUseCase{userRepo: userRepo}
+func NewUserUseCase(userRepo domain.UserRepository, otpRepo domain.OTPRepository, sessionUseCase *SessionUseCase, verificationUseCase *VerificationUseCase, loginThrottle *LoginThrottle, referralUseCase *ReferralUseCase, cardCodeSigner *CardCodeSigner) *UserUseCase {
+	return &UserUseCase{userRepo: userRepo, otpRepo: otpRepo, sessionUseCase: sessionUseCase, verificationUseCase: verificationUseCase, loginThrottle: loginThrottle, referralUseCase: referralUseCase, cardCodeSigner: cardCodeSigner}
 }
 
 // LoyaltyUseCase handles loyalty program related business logic.
 type LoyaltyUseCase struct {
-	userRepo domain.UserRepository // Reusing UserRepository for loyalty data
+	userRepo            domain.UserRepository // Reusing UserRepository for loyalty data
+	ledgerRepo          domain.LoyaltyLedgerRepository
+	discountCardRepo    domain.DiscountCardRepository
+	pointFreezeRepo     domain.PointFreezeRepository
+	notificationUseCase *NotificationUseCase
+	redemptionPolicy    RedemptionPolicy
+	expiryPolicy        ExpiryPolicy
+	tierPolicy          TierPolicy
+}
+
+// RedemptionPolicy sets how much points are worth when spent at checkout.
+type RedemptionPolicy struct {
+	PointValue    float64 // Money a point is worth, before the member's tier multiplier
+	MaxOrderShare float64 // Largest fraction of an order that can be paid with points
+}
+
+// ExpiryPolicy sets how long accrued points last.
+type ExpiryPolicy struct {
+	PointsLifetimeMonths int // Counted from accrual; zero keeps points forever
 }
 
 // NewLoyaltyUseCase creates a new LoyaltyUseCase.
-func NewLoyaltyUseCase(userRepo domain.UserRepository) *LoyaltyUseCase {
-	return &LoyaltyUseCase{userRepo: userRepo}
+func NewLoyaltyUseCase(userRepo domain.UserRepository, ledgerRepo domain.LoyaltyLedgerRepository, discountCardRepo domain.DiscountCardRepository, pointFreezeRepo domain.PointFreezeRepository, notificationUseCase *NotificationUseCase, redemptionPolicy RedemptionPolicy, expiryPolicy ExpiryPolicy, tierPolicy TierPolicy) *LoyaltyUseCase {
+	return &LoyaltyUseCase{
+		userRepo:            userRepo,
+		ledgerRepo:          ledgerRepo,
+		discountCardRepo:    discountCardRepo,
+		pointFreezeRepo:     pointFreezeRepo,
+		notificationUseCase: notificationUseCase,
+		redemptionPolicy:    redemptionPolicy,
+		expiryPolicy:        expiryPolicy,
+		tierPolicy:          tierPolicy,
+	}
 }
 
 type RegisterUserRequest struct {
-	Username    string `json:"username"`
-	PhoneNumber string `json:"phoneNumber"`
-	Email       string `json:"email"`
-	Password    string `json:"password"`
-	SocialID    string `json:"social_id,omitempty"`
+	Username     string `json:"username"`
+	PhoneNumber  string `json:"phoneNumber"`
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+	ReferralCode string `json:"referral_code,omitempty"` // Invite code of the member who referred the user
+	BirthDate    string `json:"birth_date,omitempty"`    // YYYY-MM-DD, for the birthday bonus
 }
 
 type RegisterUserResponse struct {
@@ -49,10 +85,19 @@ type LoginUserRequest struct {
 }
 
 type LoginUserResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    string `json:"expires_at"`
 }
 
-func (uc *UserUseCase) RegisterUser(ctx context.Context, req *RegisterUserRequest) (*RegisterUserResponse, error) {
+// RegisterUser creates a customer account, crediting the referral to the member whose invite code was used.
+func (uc *UserUseCase) RegisterUser(ctx context.Context, req *RegisterUserRequest, meta *SessionMetadata) (*RegisterUserResponse, error) {
+	phoneNumber, err := normalizePhoneNumber(req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	req.PhoneNumber = phoneNumber
+
 	// Check if user already exists by phone number
 	fmt.Println(req)
 	existingUser, err := uc.userRepo.GetUserByPhoneNumber(ctx, req.PhoneNumber)
@@ -77,6 +122,20 @@ func (uc *UserUseCase) RegisterUser(ctx context.Context, req *RegisterUserReques
 		return nil, fmt.Errorf("user with email %s already exists", req.Email)
 	}
 
+	var birthDate *string
+	if req.BirthDate != "" {
+		if birthDate, err = parseBirthDate(req.BirthDate); err != nil {
+			return nil, err
+		}
+	}
+
+	referrerID := 0
+	if req.ReferralCode != "" {
+		if referrerID, err = uc.referralUseCase.FindReferrer(ctx, req.ReferralCode); err != nil {
+			return nil, err
+		}
+	}
+
 	// Hash the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -88,87 +147,107 @@ func (uc *UserUseCase) RegisterUser(ctx context.Context, req *RegisterUserReques
 	qrCodeString := uuid.New().String()
 	fmt.Println(req)
 	user := &domain.User{
-		Username:            req.Username,
-		PhoneNumber:         req.PhoneNumber,
-		Email:               req.Email,
-		PasswordHash:        string(hashedPassword),
-		SocialID:            req.SocialID,
-		DiscountLevel:       0,
-		ProgressToNextLevel: 0.0,
-		QRCode:              &qrCodeString,
-		LoyaltyStatus:       "Bronze",
-		CurrentPoints:       0,
+		Username:      req.Username,
+		PhoneNumber:   req.PhoneNumber,
+		Email:         req.Email,
+		PasswordHash:  string(hashedPassword),
+		QRCode:        &qrCodeString,
+		LoyaltyStatus: "Bronze",
+		CurrentPoints: 0,
+		Role:          domain.RoleCustomer,
+		BirthDate:     birthDate,
+	}
+
+	if err := uc.createUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if referrerID != 0 {
+		if err := uc.referralUseCase.AttributeReferral(ctx, referrerID, user.ID, meta); err != nil {
+			log.Printf("Failed to record referral of user %d by user %d: %v", user.ID, referrerID, err)
+		}
+	}
+
+	if user.Email != "" {
+		uc.verificationUseCase.trySendEmailVerification(ctx, user)
 	}
 
+	return &RegisterUserResponse{UserID: strconv.Itoa(user.ID)}, nil
+}
+
+// createUser stores a new user and enrolls them in the loyalty program.
+func (uc *UserUseCase) createUser(ctx context.Context, user *domain.User) error {
 	if err := uc.userRepo.CreateUser(ctx, user); err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return fmt.Errorf("failed to create user: %w", err)
 	}
 
 	// Initialize user loyalty entry
 	userLoyalty := &domain.UserLoyalty{
 		UserID:         user.ID,
-		CurrentPoints:  0,
 		CurrentTierID:  1, // Will be updated when tiers are defined and assigned
 		LastActivityAt: time.Now().Format(time.RFC3339),
 	}
 	if err := uc.userRepo.UpdateUserLoyalty(ctx, userLoyalty); err != nil {
-		return nil, fmt.Errorf("failed to initialize user loyalty: %w", err)
+		return fmt.Errorf("failed to initialize user loyalty: %w", err)
 	}
-
-	return &RegisterUserResponse{UserID: strconv.Itoa(user.ID)}, nil
+	return nil
 }
 
-// LoginUser authenticates a user and generates a JWT token.
-func (uc *UserUseCase) LoginUser(ctx context.Context, req *LoginUserRequest) (*LoginUserResponse, error) {
+// LoginUser authenticates a user and opens a new session for them.
+func (uc *UserUseCase) LoginUser(ctx context.Context, req *LoginUserRequest, meta *SessionMetadata) (*LoginUserResponse, error) {
+	ipAddress := ""
+	if meta != nil {
+		ipAddress = meta.IPAddress
+	}
+	if err := uc.loginThrottle.Check(ctx, req.Email, ipAddress); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := uc.userRepo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
+		uc.loginThrottle.Failure(ctx, req.Email, ipAddress)
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
 	// Compare password hash
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		uc.loginThrottle.Failure(ctx, req.Email, ipAddress)
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
-	// Generate JWT token
-	expirationTime := time.Now().Add(24 * time.Hour)
-	claims := &domain.Claims{
-		UserID: strconv.Itoa(user.ID),
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
-	}
-
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte("YOUR_SECRET_KEY")) // TODO: Use a secure secret key from config
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
-	}
-
-	return &LoginUserResponse{Token: tokenString}, nil
+	uc.loginThrottle.Success(ctx, req.Email)
+	return uc.sessionUseCase.StartSession(ctx, user, meta)
 }
 
 type GetUserProfileResponse struct {
-	ID                  string                    `json:"id"`
-	PhoneNumber         string                    `json:"phone_number"`
-	Username            string                    `json:"username"`
-	Email               string                    `json:"email"`
-	DiscountLevel       int                       `json:"discount_level"`
-	ProgressToNextLevel float64                   `json:"progress_to_next_level"`
-	QRCode              *string                   `json:"qr_code"` // Changed to *string to match domain.User
-	LoyaltyStatus       string                    `json:"loyalty_status"`
-	CurrentPoints       int                       `json:"current_points"`
-	CurrentTier         *LoyaltyTierResponse      `json:"current_tier,omitempty"`
-	LoyaltyActivities   []*domain.LoyaltyActivity `json:"loyalty_activities,omitempty"`
+	ID                string                    `json:"id"`
+	PhoneNumber       string                    `json:"phone_number"`
+	Username          string                    `json:"username"`
+	Email             string                    `json:"email"`
+	EmailVerified     bool                      `json:"email_verified"`
+	LoyaltyStatus     string                    `json:"loyalty_status"`
+	CurrentPoints     int                       `json:"current_points"`
+	Role              domain.Role               `json:"role,omitempty"`
+	CurrentTier       *LoyaltyTierResponse      `json:"current_tier,omitempty"`
+	PointsExpiring    []*PointsExpiry           `json:"points_expiring,omitempty"` // Soonest first
+	PointsFrozen      bool                      `json:"points_frozen,omitempty"`   // Set while staff stop the member from spending points
+	LoyaltyActivities []*domain.LoyaltyActivity `json:"loyalty_activities,omitempty"`
+}
+
+// PointsExpiry is how many of the user's points expire on a date.
+type PointsExpiry struct {
+	Date   string `json:"date"`
+	Points int    `json:"points"`
 }
 
 type LoyaltyTierResponse struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	MinPoints   int    `json:"min_points"`
-	Description string `json:"description,omitempty"`
-	Benefits    string `json:"benefits,omitempty"`
+	ID                   int                 `json:"id"`
+	Name                 string              `json:"name"`
+	MinPoints            int                 `json:"min_points"`
+	Description          string              `json:"description,omitempty"`
+	Benefits             domain.TierBenefits `json:"benefits"`
+	PointValueMultiplier float64             `json:"point_value_multiplier"`
 }
 
 func (uc *UserUseCase) GetUserProfile(ctx context.Context, userID string) (*GetUserProfileResponse, error) {
@@ -197,13 +276,7 @@ func (uc *UserUseCase) GetUserProfile(ctx context.Context, userID string) (*GetU
 			return nil, fmt.Errorf("failed to get loyalty tier: %w", err)
 		}
 		if tier != nil {
-			currentTier = &LoyaltyTierResponse{
-				ID:          tier.ID,
-				Name:        tier.Name,
-				MinPoints:   tier.MinPoints,
-				Description: tier.Description,
-				Benefits:    tier.Benefits,
-			}
+			currentTier = tierResponse(tier)
 		}
 	}
 
@@ -214,27 +287,83 @@ func (uc *UserUseCase) GetUserProfile(ctx context.Context, userID string) (*GetU
 	}
 
 	response := &GetUserProfileResponse{
-		ID:                  strconv.Itoa(user.ID),
-		Username:            user.Username,
-		PhoneNumber:         user.PhoneNumber,
-		Email:               user.Email,
-		DiscountLevel:       user.DiscountLevel,
-		ProgressToNextLevel: user.ProgressToNextLevel,
-		QRCode:              user.QRCode,
-		LoyaltyStatus:       user.LoyaltyStatus,
-		CurrentPoints:       user.CurrentPoints,
-		CurrentTier:         currentTier,
-		LoyaltyActivities:   activities,
+		ID:                strconv.Itoa(user.ID),
+		Username:          user.Username,
+		PhoneNumber:       user.PhoneNumber,
+		Email:             user.Email,
+		EmailVerified:     user.EmailVerifiedAt != nil,
+		LoyaltyStatus:     user.LoyaltyStatus,
+		CurrentPoints:     user.CurrentPoints,
+		Role:              user.Role,
+		CurrentTier:       currentTier,
+		LoyaltyActivities: activities,
 	}
 
 	return response, nil
 }
 
+type UpdateUserRoleRequest struct {
+	Role domain.Role `json:"role"`
+}
+
+// UpdateUserRole assigns a new role to a user. Access tokens carry the role, so a change
+// revokes the user's sessions and takes effect at once; the user has to sign in again.
+func (uc *UserUseCase) UpdateUserRole(ctx context.Context, userID string, req *UpdateUserRoleRequest) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+	if !req.Role.IsValid() {
+		return fmt.Errorf("unknown role %q", req.Role)
+	}
+	user, err := uc.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Role == req.Role {
+		return nil
+	}
+	if err := uc.userRepo.UpdateUserRole(ctx, id, req.Role); err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	return uc.sessionUseCase.RevokeOtherSessions(ctx, userID, "")
+}
+
+type SetBirthDateRequest struct {
+	BirthDate string `json:"birth_date"` // YYYY-MM-DD
+}
+
+// SetBirthDate lets staff correct a user's birth date, which users cannot change themselves once set.
+func (uc *UserUseCase) SetBirthDate(ctx context.Context, userID string, req *SetBirthDateRequest) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+	birthDate, err := parseBirthDate(req.BirthDate)
+	if err != nil {
+		return err
+	}
+	user, err := uc.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.BirthDate = birthDate
+	if err := uc.userRepo.UpdateUserProfile(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user birth date: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserProfileRequest changes the fields that are set and leaves empty ones as they are.
+// A new phone number must be confirmed with a code requested through /users/otp/request.
+// The birth date can only be added; once set, only staff can change it.
 type UpdateUserProfileRequest struct {
-	ID          string `json:"id"`
+	ID          string `json:"-"`
 	Username    string `json:"username"`
 	PhoneNumber string `json:"phone_number"`
+	PhoneCode   string `json:"phone_code,omitempty"`
 	Email       string `json:"email"`
+	BirthDate   string `json:"birth_date"` // YYYY-MM-DD
 }
 
 type UpdateUserProfileResponse struct {
@@ -252,82 +381,209 @@ func (uc *UserUseCase) UpdateUserProfile(ctx context.Context, req *UpdateUserPro
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
 
-	user.Username = req.Username
-	user.PhoneNumber = req.PhoneNumber
-	user.Email = req.Email
+	if username := strings.TrimSpace(req.Username); username != "" {
+		user.Username = username
+	}
 
-	if err := uc.userRepo.UpdateUser(ctx, user); err != nil {
-		return nil, fmt.Errorf("failed to update user profile: %w", err)
+	if req.PhoneNumber != "" {
+		phoneNumber, err := normalizePhoneNumber(req.PhoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		if phoneNumber != user.PhoneNumber {
+			existingUser, err := uc.userRepo.GetUserByPhoneNumber(ctx, phoneNumber)
+			if err != nil && err.Error() != "user not found" {
+				return nil, fmt.Errorf("failed to check existing user: %w", err)
+			}
+			if existingUser != nil {
+				return nil, fmt.Errorf("user with phone number %s already exists", phoneNumber)
+			}
+			if req.PhoneCode == "" {
+				return nil, fmt.Errorf("phone_code is required to change the phone number")
+			}
+			if err := checkOTPCode(ctx, uc.otpRepo, phoneNumber, req.PhoneCode); err != nil {
+				return nil, err
+			}
+			verifiedAt := time.Now().Format(time.RFC3339)
+			user.PhoneNumber = phoneNumber
+			user.PhoneVerifiedAt = &verifiedAt
+		}
+	}
+
+	if req.BirthDate != "" {
+		birthDate, err := parseBirthDate(req.BirthDate)
+		if err != nil {
+			return nil, err
+		}
+		// Otherwise the birthday bonus could be collected again by moving the date
+		if user.BirthDate != nil && *user.BirthDate != *birthDate {
+			return nil, fmt.Errorf("birth_date is already set, contact support to change it")
+		}
+		user.BirthDate = birthDate
+	}
+
+	emailChanged := false
+	if email := strings.TrimSpace(req.Email); email != "" && !strings.EqualFold(email, user.Email) {
+		existingUser, err := uc.userRepo.GetUserByEmail(ctx, email)
+		if err != nil && err.Error() != "user not found" {
+			return nil, fmt.Errorf("failed to check existing user by email: %w", err)
+		}
+		if existingUser != nil {
+			return nil, fmt.Errorf("user with email %s already exists", email)
+		}
+		user.Email = email
+		user.EmailVerifiedAt = nil
+		emailChanged = true
+	}
+
+	if err := uc.userRepo.UpdateUserProfile(ctx, user); err != nil {
+		return nil, err
 	}
 
+	if emailChanged {
+		if err := uc.verificationUseCase.emailChanged(ctx, user); err != nil {
+			return nil, err
+		}
+		return &UpdateUserProfileResponse{Message: "User profile updated successfully, check your inbox to confirm the new email"}, nil
+	}
 	return &UpdateUserProfileResponse{Message: "User profile updated successfully"}, nil
 }
 
-// GetUserQRCode retrieves the QR code string for a user.
-func (uc *UserUseCase) GetUserQRCode(ctx context.Context, userID string) (string, error) {
-	// Convert userID string to int for repository call
-	id, err := strconv.Atoi(userID)
+// parseBirthDate checks a birth date given as YYYY-MM-DD.
+func parseBirthDate(value string) (*string, error) {
+	birthDate, err := time.Parse("2006-01-02", strings.TrimSpace(value))
 	if err != nil {
-		return "", fmt.Errorf("invalid userID format: %w", err)
+		return nil, fmt.Errorf("birth_date must be a date in the YYYY-MM-DD format")
 	}
-	user, err := uc.userRepo.GetUserByID(ctx, id)
+	if birthDate.After(time.Now()) || birthDate.Year() < 1900 {
+		return nil, fmt.Errorf("birth_date is out of range")
+	}
+	formatted := birthDate.Format("2006-01-02")
+	return &formatted, nil
+}
+
+// recentSignInWindow is how long after signing in a user without a password may change account security settings.
+const recentSignInWindow = 10 * time.Minute
+
+// reauthenticatePasswordless confirms that a request on an account without a password comes from its owner,
+// by a login code sent to the account's phone number or, failing that, by a sign-in within recentSignInWindow.
+func (uc *UserUseCase) reauthenticatePasswordless(ctx context.Context, user *domain.User, sessionID, phoneCode string) error {
+	if phoneCode != "" {
+		if user.PhoneNumber == "" {
+			return fmt.Errorf("account has no phone number, sign in again instead")
+		}
+		return checkOTPCode(ctx, uc.otpRepo, user.PhoneNumber, phoneCode)
+	}
+	recent, err := uc.sessionUseCase.signedInSince(ctx, user.ID, sessionID, time.Now().Add(-recentSignInWindow))
 	if err != nil {
-		return "", fmt.Errorf("failed to get user QR code: %w", err)
+		return err
 	}
-	if user.QRCode == nil {
-		return "", fmt.Errorf("QR code not found for user %s", userID)
+	if !recent {
+		return fmt.Errorf("sign in again or confirm with phone_code to continue")
 	}
-	return *user.QRCode, nil
+	return nil
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	PhoneCode       string `json:"phone_code,omitempty"` // Instead of the current password, for users without one
 }
 
-// GenerateQRCodeImage generates a QR code image (PNG) for a given user's QR code string.
-func (uc *UserUseCase) GenerateQRCodeImage(ctx context.Context, userID string) ([]byte, error) {
-	qrCodeString, err := uc.GetUserQRCode(ctx, userID)
+// ChangePassword sets a new password and logs out every other session.
+// Users who signed up by SMS code or a social provider have no password yet and set one by confirming
+// a code sent to their phone number, or shortly after signing in.
+func (uc *UserUseCase) ChangePassword(ctx context.Context, userID, currentSessionID string, req *ChangePasswordRequest) error {
+	id, err := strconv.Atoi(userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get QR code string: %w", err)
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+	if err := validatePassword(req.NewPassword); err != nil {
+		return err
 	}
 
-	p, err := qrcode.Encode(qrCodeString, qrcode.Medium, 256)
+	user, err := uc.userRepo.GetUserByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate QR code image: %w", err)
+		return err
+	}
+	if user.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+			return fmt.Errorf("current password is incorrect")
+		}
+	} else if err := uc.reauthenticatePasswordless(ctx, user, currentSessionID, req.PhoneCode); err != nil {
+		return err
 	}
-	return p, nil
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := uc.userRepo.UpdateUserPassword(ctx, id, string(hashedPassword)); err != nil {
+		return err
+	}
+	return uc.sessionUseCase.RevokeOtherSessions(ctx, userID, currentSessionID)
 }
 
-// GetUserDiscountCard retrieves the discount card information for a user.
-func (uc *UserUseCase) GetUserDiscountCard(ctx context.Context, userID string) (*GetUserProfileResponse, error) {
-	// Convert userID string to int for repository call
+type DeleteAccountRequest struct {
+	Password  string `json:"password"`
+	PhoneCode string `json:"phone_code,omitempty"` // Instead of the password, for users without one
+}
+
+// DeleteAccount erases the user's personal data and ends all sessions.
+// Orders and loyalty history are kept, no longer linked to anything that identifies the person.
+// Users without a password confirm the same way as when setting one.
+func (uc *UserUseCase) DeleteAccount(ctx context.Context, userID, currentSessionID string, req *DeleteAccountRequest) error {
 	id, err := strconv.Atoi(userID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid userID format: %w", err)
+		return fmt.Errorf("invalid userID format: %w", err)
 	}
-	user, err := uc.userRepo.GetUserDiscountCard(ctx, id)
+
+	user, err := uc.userRepo.GetUserByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user discount card: %w", err)
+		return err
+	}
+	if user.PasswordHash != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+			return fmt.Errorf("password is incorrect")
+		}
+	} else if err := uc.reauthenticatePasswordless(ctx, user, currentSessionID, req.PhoneCode); err != nil {
+		return err
 	}
 
-	return &GetUserProfileResponse{
-			ID:                  strconv.Itoa(user.ID),
-			Username:            user.Username,
-			PhoneNumber:         user.PhoneNumber,
-			DiscountLevel:       user.DiscountLevel,
-			ProgressToNextLevel: user.ProgressToNextLevel,
-			QRCode:              user.QRCode,
-		},
-		nil
+	return uc.userRepo.AnonymizeUser(ctx, id)
 }
 
-// UpdateUserDiscountCard updates the discount card level and progress for a user.
-func (uc *UserUseCase) UpdateUserDiscountCard(ctx context.Context, userID string, level int, progress float64) error {
+// GetUserQRCode returns the user's current card code and when it stops being shown.
+// The code rotates every window, so the card seed in users.qr_code never leaves the server.
+func (uc *UserUseCase) GetUserQRCode(ctx context.Context, userID string) (string, time.Time, error) {
 	// Convert userID string to int for repository call
 	id, err := strconv.Atoi(userID)
 	if err != nil {
-		return fmt.Errorf("invalid userID format: %w", err)
+		return "", time.Time{}, fmt.Errorf("invalid userID format: %w", err)
 	}
-	if err := uc.userRepo.UpdateUserDiscountCard(ctx, id, level, progress); err != nil {
-		return fmt.Errorf("failed to update user discount card: %w", err)
+	user, err := uc.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to get user QR code: %w", err)
 	}
-	return nil
+	if user.QRCode == nil {
+		return "", time.Time{}, fmt.Errorf("QR code not found for user %s", userID)
+	}
+	code, expiresAt := uc.cardCodeSigner.Sign(user.ID, *user.QRCode, time.Now())
+	return code, expiresAt, nil
+}
+
+// GenerateQRCodeImage generates a QR code image (PNG) of the user's current card code.
+func (uc *UserUseCase) GenerateQRCodeImage(ctx context.Context, userID string) ([]byte, time.Time, error) {
+	qrCodeString, expiresAt, err := uc.GetUserQRCode(ctx, userID)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to get QR code string: %w", err)
+	}
+
+	p, err := qrcode.Encode(qrCodeString, qrcode.Medium, 256)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to generate QR code image: %w", err)
+	}
+	return p, expiresAt, nil
 }
 
 type StoreUseCase struct {
@@ -394,13 +650,15 @@ func (uc *CategoryUseCase) GetCategories(ctx context.Context) (*GetCategoriesRes
 // New Product Use Case
 type ProductUseCase struct {
 	productRepo domain.ProductRepository
+	userRepo    domain.UserRepository
 }
 
-func NewProductUseCase(productRepo domain.ProductRepository) *ProductUseCase {
-	return &ProductUseCase{productRepo: productRepo}
+func NewProductUseCase(productRepo domain.ProductRepository, userRepo domain.UserRepository) *ProductUseCase {
+	return &ProductUseCase{productRepo: productRepo, userRepo: userRepo}
 }
 
 type GetProductCatalogRequest struct {
+	UserID     string   `json:"user_id,omitempty"` // Empty for terminals
 	CategoryID *string  `json:"category_id,omitempty"`
 	MinPrice   *float64 `json:"min_price,omitempty"`
 	MaxPrice   *float64 `json:"max_price,omitempty"`
@@ -415,7 +673,11 @@ type GetProductCatalogResponse struct {
 }
 
 func (uc *ProductUseCase) GetProductCatalog(ctx context.Context, req *GetProductCatalogRequest) (*GetProductCatalogResponse, error) {
-	products, err := uc.productRepo.GetProducts(ctx, req.CategoryID, req.MinPrice, req.MaxPrice, req.SortBy, req.SortOrder, req.Limit, req.Offset)
+	earlyAccess, err := uc.seesEarlyAccess(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	products, err := uc.productRepo.GetProducts(ctx, req.CategoryID, req.MinPrice, req.MaxPrice, req.SortBy, req.SortOrder, req.Limit, req.Offset, earlyAccess)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product catalog: %w", err)
 	}
@@ -427,7 +689,8 @@ type GetProductByIDResponse struct {
 	Product *domain.Product `json:"product"`
 }
 
-func (uc *ProductUseCase) GetProductByID(ctx context.Context, productID string) (*GetProductByIDResponse, error) {
+// GetProductByID returns a product, hiding one still in early access from members without it.
+func (uc *ProductUseCase) GetProductByID(ctx context.Context, userID, productID string) (*GetProductByIDResponse, error) {
 	// Convert productID string to int for repository call
 	id, err := strconv.Atoi(productID)
 	if err != nil {
@@ -437,16 +700,59 @@ func (uc *ProductUseCase) GetProductByID(ctx context.Context, productID string)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product by ID: %w", err)
 	}
+	if inEarlyAccess(product.EarlyAccessUntil, time.Now()) {
+		earlyAccess, err := uc.seesEarlyAccess(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !earlyAccess {
+			return nil, fmt.Errorf("failed to get product by ID: product not found")
+		}
+	}
 
 	return &GetProductByIDResponse{Product: product}, nil
 }
 
+// seesEarlyAccess reports whether the requester sees products before their release: members whose tier
+// has early access, and terminals, which sell to members in the store.
+func (uc *ProductUseCase) seesEarlyAccess(ctx context.Context, userID string) (bool, error) {
+	if userID == "" {
+		return true, nil
+	}
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return false, fmt.Errorf("invalid userID format: %w", err)
+	}
+	return hasEarlyAccess(ctx, uc.userRepo, id)
+}
+
 type NotificationUseCase struct {
 	notificationRepo domain.NotificationRepository
+	consentRepo      domain.ConsentRepository
+	userRepo         domain.UserRepository
+	smsSender        domain.SMSSender
+	mailSender       domain.MailSender
+}
+
+func NewNotificationUseCase(notificationRepo domain.NotificationRepository, consentRepo domain.ConsentRepository, userRepo domain.UserRepository, smsSender domain.SMSSender, mailSender domain.MailSender) *NotificationUseCase {
+	return &NotificationUseCase{notificationRepo: notificationRepo, consentRepo: consentRepo, userRepo: userRepo, smsSender: smsSender, mailSender: mailSender}
+}
+
+// CheckoutPolicy prices the services a customer can add to an online order.
+type CheckoutPolicy struct {
+	ShippingFee   float64 // Charged for delivering an order instead of collecting it in a store
+	AlterationFee float64 // Charged for each item altered to fit
 }
 
-func NewNotificationUseCase(notificationRepo domain.NotificationRepository) *NotificationUseCase {
-	return &NotificationUseCase{notificationRepo: notificationRepo}
+// serviceFees returns what the services cost a member, waiving those their tier includes.
+func (p CheckoutPolicy) serviceFees(services OrderServices, benefits domain.TierBenefits) (shipping, alterations float64) {
+	if services.Delivery && !benefits.FreeShipping {
+		shipping = p.ShippingFee
+	}
+	if !benefits.FreeAlterations {
+		alterations = p.AlterationFee * float64(services.Alterations)
+	}
+	return shipping, alterations
 }
 
 type CartUseCase struct {
@@ -456,8 +762,13 @@ type CartUseCase struct {
 	orderRepo           domain.OrderRepository
 	orderItemRepo       domain.OrderItemRepository
 	loyaltyUseCase      *LoyaltyUseCase
+	earningRuleUseCase  *EarningRuleUseCase
+	referralUseCase     *ReferralUseCase
+	challengeUseCase    *ChallengeUseCase
 	notificationUseCase *NotificationUseCase
 	userRepo            domain.UserRepository
+	cardCodeSigner      *CardCodeSigner
+	checkoutPolicy      CheckoutPolicy
 }
 
 func NewCartUseCase(
@@ -467,8 +778,13 @@ func NewCartUseCase(
 	orderRepo domain.OrderRepository,
 	orderItemRepo domain.OrderItemRepository,
 	loyaltyUseCase *LoyaltyUseCase,
+	earningRuleUseCase *EarningRuleUseCase,
+	referralUseCase *ReferralUseCase,
+	challengeUseCase *ChallengeUseCase,
 	notificationUseCase *NotificationUseCase,
 	userRepo domain.UserRepository,
+	cardCodeSigner *CardCodeSigner,
+	checkoutPolicy CheckoutPolicy,
 ) *CartUseCase {
 	return &CartUseCase{
 		cartRepo:            cartRepo,
@@ -477,8 +793,13 @@ func NewCartUseCase(
 		orderRepo:           orderRepo,
 		orderItemRepo:       orderItemRepo,
 		loyaltyUseCase:      loyaltyUseCase,
+		earningRuleUseCase:  earningRuleUseCase,
+		referralUseCase:     referralUseCase,
+		challengeUseCase:    challengeUseCase,
 		notificationUseCase: notificationUseCase,
 		userRepo:            userRepo,
+		cardCodeSigner:      cardCodeSigner,
+		checkoutPolicy:      checkoutPolicy,
 	}
 }
 
@@ -523,6 +844,20 @@ func (uc *CartUseCase) AddItemToCart(ctx context.Context, req *AddItemToCartRequ
 	if err != nil || product == nil {
 		return nil, fmt.Errorf("product with ID %s not found: %w", req.ProductID, err)
 	}
+	// Products still in early access are hidden from members without it
+	if inEarlyAccess(product.EarlyAccessUntil, time.Now()) {
+		userIDInt, err := strconv.Atoi(req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid userID format: %w", err)
+		}
+		earlyAccess, err := hasEarlyAccess(ctx, uc.userRepo, userIDInt)
+		if err != nil {
+			return nil, err
+		}
+		if !earlyAccess {
+			return nil, fmt.Errorf("product with ID %s not found", req.ProductID)
+		}
+	}
 
 	if req.Quantity <= 0 {
 		return nil, fmt.Errorf("quantity must be greater than 0")
@@ -623,6 +958,19 @@ func (uc *CartUseCase) RemoveCartItem(ctx context.Context, req *RemoveCartItemRe
 type GetCartResponse struct {
 	Cart      *domain.Cart       `json:"cart"`
 	CartItems []*domain.CartItem `json:"cart_items"`
+	Totals    *CartTotals        `json:"totals"`
+}
+
+// CartTotals is what the cart costs at current prices, with the member's discount taken off as it will be at checkout.
+// Points the member may redeem are not included. The fees are what delivery and altering an item would add
+// to the total for this member, nothing if their tier includes the service.
+type CartTotals struct {
+	Subtotal        float64 `json:"subtotal"`
+	DiscountPercent float64 `json:"discount_percent"`
+	Discount        float64 `json:"discount"`
+	Total           float64 `json:"total"`
+	ShippingFee     float64 `json:"shipping_fee"`
+	AlterationFee   float64 `json:"alteration_fee"` // Per item altered
 }
 
 func (uc *CartUseCase) GetUserCart(ctx context.Context, userID string) (*GetCartResponse, error) {
@@ -636,7 +984,42 @@ func (uc *CartUseCase) GetUserCart(ctx context.Context, userID string) (*GetCart
 		return nil, fmt.Errorf("failed to get cart items for user: %w", err)
 	}
 
-	return &GetCartResponse{Cart: cart, CartItems: cartItems}, nil
+	totals, err := uc.cartTotals(ctx, userID, cartItems)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetCartResponse{Cart: cart, CartItems: cartItems, Totals: totals}, nil
+}
+
+func (uc *CartUseCase) cartTotals(ctx context.Context, userID string, cartItems []*domain.CartItem) (*CartTotals, error) {
+	userIDInt, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	totals := &CartTotals{}
+	for _, item := range cartItems {
+		productID, err := strconv.Atoi(item.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid product ID format: %w", err)
+		}
+		product, err := uc.productRepo.GetProductByID(ctx, productID)
+		if err != nil || product == nil {
+			return nil, fmt.Errorf("product with ID %s not found: %w", item.ProductID, err)
+		}
+		totals.Subtotal += product.Price * float64(item.Quantity)
+	}
+
+	benefits, err := uc.loyaltyUseCase.GetMemberBenefits(ctx, userIDInt)
+	if err != nil {
+		return nil, err
+	}
+	totals.DiscountPercent = benefits.DiscountPercent
+	totals.Discount = memberDiscount(totals.Subtotal, benefits.DiscountPercent)
+	totals.Total = totals.Subtotal - totals.Discount
+	totals.ShippingFee, totals.AlterationFee = uc.checkoutPolicy.serviceFees(OrderServices{Delivery: true, Alterations: 1}, benefits)
+	return totals, nil
 }
 
 func (uc *CartUseCase) ClearCart(ctx context.Context, userID string) error {
@@ -654,12 +1037,22 @@ func (uc *CartUseCase) ClearCart(ctx context.Context, userID string) error {
 }
 
 type PlaceOrderRequest struct {
-	UserID string `json:"user_id"`
+	UserID         string `json:"user_id"`
+	PointsToRedeem int    `json:"points_to_redeem,omitempty"`
+	OrderServices
+}
+
+// OrderServices are the paid services a customer can add to an online order.
+type OrderServices struct {
+	Delivery    bool `json:"delivery,omitempty"`    // Ship the order instead of collecting it in a store
+	Alterations int  `json:"alterations,omitempty"` // Number of items to alter to fit
 }
 
 type PlaceOrderResponse struct {
-	OrderID int    `json:"order_id"`
-	Message string `json:"message"`
+	OrderID         int                    `json:"order_id"`
+	Message         string                 `json:"message"`
+	PointsEarned    int                    `json:"points_earned"`
+	PointsBreakdown []*domain.EarnedPoints `json:"points_breakdown"`
 }
 
 func (uc *CartUseCase) PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (*PlaceOrderResponse, error) {
@@ -679,107 +1072,330 @@ func (uc *CartUseCase) PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (
 		return nil, fmt.Errorf("cart is empty")
 	}
 
-	// Calculate total amount
-	var totalAmount float64
+	lines := make([]OrderLine, 0, len(cartItems))
 	for _, item := range cartItems {
-		productIDInt, err := strconv.Atoi(item.ProductID)
+		lines = append(lines, OrderLine{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+	order, err := uc.createPaidOrder(ctx, req.UserID, lines, req.PointsToRedeem, nil, req.OrderServices)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mark cart as paid
+	cart.IsPaid = true
+	if err := uc.cartRepo.UpdateCart(ctx, cart); err != nil {
+		return nil, fmt.Errorf("failed to mark cart as paid: %w", err)
+	}
+
+	// Clear cart items (or delete the cart itself if preferred)
+	// For simplicity, we'll delete the cart items for now
+	for _, item := range cartItems {
+		if err := uc.cartItemRepo.DeleteCartItem(ctx, item.ID); err != nil {
+			return nil, fmt.Errorf("failed to delete cart item after order: %w", err)
+		}
+	}
+
+	// The order is paid by now; points that could not be posted are retried by AccrueUnpostedOrderPoints
+	if err := uc.completeOrder(ctx, req.UserID, order); err != nil {
+		log.Printf("Failed to post the points of order %d, they will be retried: %v", order.ID, err)
+	}
+
+	return &PlaceOrderResponse{
+		OrderID:         order.ID,
+		Message:         "Order placed successfully and cart marked as paid",
+		PointsEarned:    order.PointsEarned,
+		PointsBreakdown: order.PointsBreakdown,
+	}, nil
+}
+
+// OrderLine is a product and quantity in an order that does not come from the customer's cart.
+type OrderLine struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type RecordStoreOrderRequest struct {
+	UserID         string      `json:"user_id"`
+	Items          []OrderLine `json:"items"`
+	PointsToRedeem int         `json:"points_to_redeem,omitempty"`
+	CardCode       string      `json:"card_code,omitempty"` // Code on the customer's discount card, required to spend their points
+	StoreID        *int        `json:"store_id,omitempty"`  // Store the purchase was made in, for store earning rules
+}
+
+// RecordStoreOrder records a purchase paid at an in-store terminal for a customer, who earns points for it as for an online order.
+func (uc *CartUseCase) RecordStoreOrder(ctx context.Context, req *RecordStoreOrderRequest) (*PlaceOrderResponse, error) {
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("order has no items")
+	}
+	userID, err := strconv.Atoi(req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+	if _, err := uc.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	// Points are only spent for a customer at the till, who shows their card for it
+	if req.PointsToRedeem != 0 {
+		if req.CardCode == "" {
+			return nil, fmt.Errorf("card_code is required to redeem points")
+		}
+		member, err := memberByCardCode(ctx, uc.userRepo, uc.cardCodeSigner, req.CardCode)
 		if err != nil {
-			return nil, fmt.Errorf("invalid product ID format: %w", err)
+			return nil, err
 		}
-		product, err := uc.productRepo.GetProductByID(ctx, productIDInt)
-		if err != nil || product == nil {
-			return nil, fmt.Errorf("product with ID %s not found: %w", item.ProductID, err)
+		if member.ID != userID {
+			return nil, fmt.Errorf("invalid card code")
 		}
-		totalAmount += product.Price * float64(item.Quantity)
 	}
 
-	// Create order
-	order := &domain.Order{
-		UserID:        req.UserID,
-		OrderDate:     time.Now().Format(time.RFC3339),
-		TotalAmount:   totalAmount,
-		Status:        "completed", // Assuming successful payment
-		PaymentStatus: "paid",
-		CreatedAt:     time.Now().Format(time.RFC3339),
-		UpdatedAt:     time.Now().Format(time.RFC3339),
+	order, err := uc.createPaidOrder(ctx, req.UserID, req.Items, req.PointsToRedeem, req.StoreID, OrderServices{})
+	if err != nil {
+		return nil, err
 	}
-	if err := uc.orderRepo.CreateOrder(ctx, order); err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+	if err := uc.completeOrder(ctx, req.UserID, order); err != nil {
+		log.Printf("Failed to post the points of order %d, they will be retried: %v", order.ID, err)
 	}
 
-	// Move cart items to order items
-	for _, item := range cartItems {
-		productIDInt, err := strconv.Atoi(item.ProductID)
+	return &PlaceOrderResponse{
+		OrderID:         order.ID,
+		Message:         "Store order recorded successfully",
+		PointsEarned:    order.PointsEarned,
+		PointsBreakdown: order.PointsBreakdown,
+	}, nil
+}
+
+// createPaidOrder creates a paid order with the given lines, priced at the current product prices.
+// Points to redeem are taken from the customer's balance and paid towards the order.
+// The store is nil for online orders, which can only hold products released to the customer and pay for their services.
+func (uc *CartUseCase) createPaidOrder(ctx context.Context, userID string, lines []OrderLine, pointsToRedeem int, storeID *int, services OrderServices) (*domain.Order, error) {
+	userIDInt, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	// Calculate total amount
+	var totalAmount float64
+	var quantity int
+	products := make([]*domain.Product, len(lines))
+	for i, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity must be positive")
+		}
+		productIDInt, err := strconv.Atoi(line.ProductID)
 		if err != nil {
 			return nil, fmt.Errorf("invalid product ID format: %w", err)
 		}
 		product, err := uc.productRepo.GetProductByID(ctx, productIDInt)
 		if err != nil || product == nil {
-			return nil, fmt.Errorf("product with ID %s not found: %w", item.ProductID, err)
+			return nil, fmt.Errorf("product with ID %s not found: %w", line.ProductID, err)
 		}
+		products[i] = product
+		totalAmount += product.Price * float64(line.Quantity)
+		quantity += line.Quantity
+	}
+	if services.Alterations < 0 || services.Alterations > quantity {
+		return nil, fmt.Errorf("alterations must be between 0 and the number of items")
+	}
 
-		orderItem := &domain.OrderItem{
-			OrderID:   order.ID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     product.Price, // Store current product price at time of order
+	benefits, err := uc.loyaltyUseCase.GetMemberBenefits(ctx, userIDInt)
+	if err != nil {
+		return nil, err
+	}
+	if storeID == nil && !benefits.EarlyAccess {
+		for i, product := range products {
+			if inEarlyAccess(product.EarlyAccessUntil, time.Now()) {
+				return nil, fmt.Errorf("product with ID %s not found", lines[i].ProductID)
+			}
+		}
+	}
+	tierDiscount := memberDiscount(totalAmount, benefits.DiscountPercent)
+	shippingFee, alterationFee := uc.checkoutPolicy.serviceFees(services, benefits)
+
+	var pointsDiscount float64
+	if pointsToRedeem != 0 {
+		if pointsDiscount, err = uc.loyaltyUseCase.QuoteRedemption(ctx, userIDInt, pointsToRedeem, totalAmount-tierDiscount); err != nil {
+			return nil, err
+		}
+	}
+
+	// Create order
+	order := &domain.Order{
+		UserID:         userID,
+		OrderDate:      time.Now().Format(time.RFC3339),
+		TotalAmount:    totalAmount,
+		Status:         "completed", // Assuming successful payment
+		PaymentStatus:  "paid",
+		PointsRedeemed: pointsToRedeem,
+		TierDiscount:   tierDiscount,
+		PointsDiscount: pointsDiscount,
+		ShippingFee:    shippingFee,
+		AlterationFee:  alterationFee,
+		StoreID:        storeID,
+		CreatedAt:      time.Now().Format(time.RFC3339),
+		UpdatedAt:      time.Now().Format(time.RFC3339),
+	}
+	for i, line := range lines {
+		order.Items = append(order.Items, domain.OrderItem{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+			Price:     products[i].Price, // Store current product price at time of order
 			CreatedAt: time.Now().Format(time.RFC3339),
 			UpdatedAt: time.Now().Format(time.RFC3339),
-		}
-		if err := uc.orderItemRepo.CreateOrderItem(ctx, orderItem); err != nil {
-			return nil, fmt.Errorf("failed to create order item: %w", err)
+		})
+	}
+	// The order and its items are written together, so a failure leaves nothing to clean up
+	if err := uc.orderRepo.CreateOrder(ctx, order); err != nil {
+		return nil, err
+	}
+
+	// The balance is checked again as the points are taken, so two checkouts cannot spend it twice
+	if pointsToRedeem != 0 {
+		if err := uc.loyaltyUseCase.RedeemPoints(ctx, userIDInt, pointsToRedeem, order.ID); err != nil {
+			if cancelErr := uc.orderRepo.CancelOrder(ctx, order.ID); cancelErr != nil {
+				log.Printf("Failed to cancel order %d after its points could not be redeemed: %v", order.ID, cancelErr)
+			}
+			return nil, err
 		}
 	}
 
-	// Mark cart as paid
-	cart.IsPaid = true
-	if err := uc.cartRepo.UpdateCart(ctx, cart); err != nil {
-		return nil, fmt.Errorf("failed to mark cart as paid: %w", err)
+	return order, nil
+}
+
+// orderPointsRetryDelay is how old a paid order must be before AccrueUnpostedOrderPoints takes it up,
+// leaving the request that placed it time to post its points.
+const orderPointsRetryDelay = 5 * time.Minute
+
+// completeOrder accrues the points of a paid order and confirms it to the customer.
+// The points are posted first, once per order however often this is retried, and the order is then
+// marked as settled; the confirmation goes last and its failure is only logged.
+func (uc *CartUseCase) completeOrder(ctx context.Context, userID string, order *domain.Order) error {
+	userIDInt, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
 	}
 
-	// Clear cart items (or delete the cart itself if preferred)
-	// For simplicity, we'll delete the cart items for now
-	for _, item := range cartItems {
-		if err := uc.cartItemRepo.DeleteCartItem(ctx, item.ID); err != nil {
-			return nil, fmt.Errorf("failed to delete cart item after order: %w", err)
+	// Accrue loyalty points by the earning rules, keeping the breakdown on the order
+	breakdown, err := uc.earningRuleUseCase.CalculateOrderPoints(ctx, order)
+	if err != nil {
+		return fmt.Errorf("failed to calculate loyalty points: %w", err)
+	}
+	if err := uc.orderRepo.SetOrderPoints(ctx, order.ID, breakdown); err != nil {
+		return err
+	}
+	order.PointsBreakdown = breakdown
+	order.PointsEarned = 0
+	for _, line := range breakdown {
+		order.PointsEarned += line.Points
+	}
+	if order.PointsEarned > 0 {
+		if err := uc.loyaltyUseCase.AddLoyaltyPoints(ctx, userIDInt, order.PointsEarned, "purchase", fmt.Sprintf("order:%d", order.ID), fmt.Sprintf("order:%d:earn", order.ID)); err != nil {
+			return fmt.Errorf("failed to add loyalty points: %w", err)
 		}
 	}
+	if err := uc.orderRepo.MarkOrderPointsPosted(ctx, order.ID); err != nil {
+		return err
+	}
+
+	// A referred customer's first paid order qualifies their referral
+	if err := uc.referralUseCase.QualifyReferral(ctx, userIDInt, order.ID); err != nil {
+		log.Printf("Failed to qualify referral of user %d: %v", userIDInt, err)
+	}
 
-	// Send notification to user
-	userIDInt, err := strconv.Atoi(req.UserID)
+	// A purchase in a store is also a visit to it
+	if order.StoreID != nil {
+		err = uc.challengeUseCase.RecordStoreVisit(ctx, userIDInt, *order.StoreID, "order")
+	} else {
+		err = uc.challengeUseCase.RecordOrder(ctx, userIDInt)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("invalid userID format for notification: %w", err)
+		log.Printf("Failed to update challenges of user %d: %v", userIDInt, err)
 	}
 
 	notificationReq := &SendNotificationRequest{
-		UserID:  req.UserID,
+		UserID:  userID,
 		Type:    "purchase_confirmation",
 		Title:   "Заказ успешно оплачен!",
-		Message: fmt.Sprintf("Ваш заказ #%d на сумму $%.2f успешно оплачен и принят в обработку.", order.ID, totalAmount),
+		Message: fmt.Sprintf("Ваш заказ #%d на сумму $%.2f успешно оплачен и принят в обработку.", order.ID, order.TotalAmount-order.TierDiscount-order.PointsDiscount+order.ShippingFee+order.AlterationFee),
 	}
 	if err := uc.notificationUseCase.SendNotification(ctx, notificationReq); err != nil {
-		return nil, fmt.Errorf("failed to send purchase confirmation notification: %w", err)
+		log.Printf("Failed to send purchase confirmation for order %d: %v", order.ID, err)
 	}
 
-	// Accrue loyalty points (e.g., 1 point per $10 spent)
-	pointsToAccrue := int(totalAmount / 10)
-	if pointsToAccrue > 0 {
-		if err := uc.loyaltyUseCase.AddLoyaltyPoints(ctx, userIDInt, pointsToAccrue, "purchase"); err != nil {
-			return nil, fmt.Errorf("failed to add loyalty points: %w", err)
+	return nil
+}
+
+// AccrueUnpostedOrderPoints completes paid orders whose points were not posted when they were placed,
+// for instance because the process stopped in between.
+func (uc *CartUseCase) AccrueUnpostedOrderPoints(ctx context.Context) error {
+	orders, err := uc.orderRepo.GetOrdersAwaitingPoints(ctx, time.Now().Add(-orderPointsRetryDelay))
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		items, err := uc.orderItemRepo.GetOrderItemsByOrderID(ctx, order.ID)
+		if err != nil {
+			log.Printf("Failed to get items of order %d: %v", order.ID, err)
+			continue
+		}
+		for _, item := range items {
+			order.Items = append(order.Items, *item)
+		}
+		if err := uc.completeOrder(ctx, order.UserID, order); err != nil {
+			log.Printf("Failed to post the points of order %d: %v", order.ID, err)
 		}
 	}
-
-	return &PlaceOrderResponse{OrderID: order.ID, Message: "Order placed successfully and cart marked as paid"}, nil
+	return nil
 }
 
 type OrderUseCase struct {
-	orderRepo     domain.OrderRepository
-	orderItemRepo domain.OrderItemRepository
-	productRepo   domain.ProductRepository // To fetch product details for order items
+	orderRepo      domain.OrderRepository
+	orderItemRepo  domain.OrderItemRepository
+	productRepo    domain.ProductRepository // To fetch product details for order items
+	loyaltyUseCase *LoyaltyUseCase
+}
+
+func NewOrderUseCase(orderRepo domain.OrderRepository, orderItemRepo domain.OrderItemRepository, productRepo domain.ProductRepository, loyaltyUseCase *LoyaltyUseCase) *OrderUseCase {
+	return &OrderUseCase{orderRepo: orderRepo, orderItemRepo: orderItemRepo, productRepo: productRepo, loyaltyUseCase: loyaltyUseCase}
 }
 
-func NewOrderUseCase(orderRepo domain.OrderRepository, orderItemRepo domain.OrderItemRepository, productRepo domain.ProductRepository) *OrderUseCase {
-	return &OrderUseCase{orderRepo: orderRepo, orderItemRepo: orderItemRepo, productRepo: productRepo}
+// CancelOrderResponse is a cancelled order and what became of the points it earned.
+type CancelOrderResponse struct {
+	*domain.Order
+	PointsReversed    int `json:"points_reversed"`    // Points the order earned that were taken back
+	PointsUnrecovered int `json:"points_unrecovered"` // Points the order earned that the customer had already spent
+}
+
+// CancelOrder cancels an order, gives back any points the customer paid with and takes back the points it earned.
+func (uc *OrderUseCase) CancelOrder(ctx context.Context, orderID int) (*CancelOrderResponse, error) {
+	order, err := uc.orderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	// Cancelling again retries giving and taking the points back, each of which happens at most once
+	if err := uc.orderRepo.CancelOrder(ctx, orderID); err != nil && order.Status != "cancelled" {
+		return nil, err
+	}
+
+	// The refund comes first, so the points paid with can cover the points earned
+	if order.PointsRedeemed > 0 {
+		if err := uc.loyaltyUseCase.RestorePoints(ctx, orderID); err != nil {
+			return nil, fmt.Errorf("failed to restore redeemed points: %w", err)
+		}
+	}
+	resp := &CancelOrderResponse{}
+	if order.PointsEarned > 0 {
+		if resp.PointsReversed, resp.PointsUnrecovered, err = uc.loyaltyUseCase.ReverseOrderPoints(ctx, orderID); err != nil {
+			return nil, fmt.Errorf("failed to reverse earned points: %w", err)
+		}
+		if resp.PointsUnrecovered > 0 {
+			log.Printf("Order %d was cancelled after its customer spent %d of the points it earned", orderID, resp.PointsUnrecovered)
+		}
+	}
+
+	if resp.Order, err = uc.orderRepo.GetOrderByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
 type GetOrdersResponse struct {
@@ -822,16 +1438,24 @@ func (uc *OrderUseCase) GetOrdersByUserID(ctx context.Context, userID string) (*
 			plainOrderItems = append(plainOrderItems, *item)
 		}
 		order.Items = plainOrderItems // Assign the converted slice
+
+		if order.PointsBreakdown, err = uc.orderRepo.GetOrderPointsBreakdown(ctx, order.ID); err != nil {
+			return nil, err
+		}
 	}
 
 	return &GetOrdersResponse{Orders: orders}, nil
 }
 
+// SendNotificationRequest sends a notification on each of its channels, or only in the app if none are given.
 type SendNotificationRequest struct {
-	UserID  string `json:"user_id"`
-	Type    string `json:"type"`
-	Title   string `json:"title"`
-	Message string `json:"message"`
+	UserID   string                       `json:"user_id"`
+	Type     string                       `json:"type"`
+	Title    string                       `json:"title"`
+	Message  string                       `json:"message"`
+	Channels []domain.NotificationChannel `json:"channels,omitempty"`
+	// Release of a promotion; until then it only goes to members whose tier has early access
+	EarlyAccessUntil *string `json:"early_access_until,omitempty"`
 }
 
 func (uc *NotificationUseCase) SendNotification(ctx context.Context, req *SendNotificationRequest) error {
@@ -841,16 +1465,85 @@ func (uc *NotificationUseCase) SendNotification(ctx context.Context, req *SendNo
 		return fmt.Errorf("invalid UserID format for notification: %w", err)
 	}
 
-	notification := &domain.Notification{
-		UserID:    userID,
-		Type:      req.Type,
-		Title:     req.Title,
-		Message:   req.Message,
-		CreatedAt: time.Now().Format(time.RFC3339),
+	channels := req.Channels
+	if len(channels) == 0 {
+		channels = []domain.NotificationChannel{domain.NotificationPush}
+	}
+	var required []domain.ConsentPurpose
+	for _, channel := range channels {
+		if !channel.IsValid() {
+			return fmt.Errorf("unknown notification channel %q", channel)
+		}
+		required = append(required, domain.RequiredConsents(req.Type, channel)...)
 	}
 
-	if err := uc.notificationRepo.CreateNotification(ctx, notification); err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+	if req.EarlyAccessUntil != nil {
+		if _, err := time.Parse(time.RFC3339, *req.EarlyAccessUntil); err != nil {
+			return fmt.Errorf("invalid early_access_until format, use RFC 3339")
+		}
+		if inEarlyAccess(req.EarlyAccessUntil, time.Now()) {
+			earlyAccess, err := hasEarlyAccess(ctx, uc.userRepo, userID)
+			if err != nil {
+				return err
+			}
+			if !earlyAccess {
+				return &EarlyAccessError{Until: *req.EarlyAccessUntil}
+			}
+		}
+	}
+
+	// Marketing notifications need the user's consent for every channel; transactional ones are always sent
+	if len(required) > 0 {
+		consents, err := uc.consentRepo.GetCurrentConsents(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to check consents: %w", err)
+		}
+		if missing := missingConsent(consents, required); missing != "" {
+			return &ConsentRequiredError{Purpose: missing}
+		}
+	}
+
+	// Check that the user can be reached on every channel before sending on any
+	var user *domain.User
+	for _, channel := range channels {
+		if channel == domain.NotificationPush {
+			continue
+		}
+		if user == nil {
+			if user, err = uc.userRepo.GetUserByID(ctx, userID); err != nil {
+				return err
+			}
+		}
+		if channel == domain.NotificationSMS && user.PhoneNumber == "" {
+			return fmt.Errorf("user has no phone number")
+		}
+		if channel == domain.NotificationEmail && (user.Email == "" || user.EmailVerifiedAt == nil) {
+			return fmt.Errorf("user has no confirmed email")
+		}
+	}
+
+	for _, channel := range channels {
+		switch channel {
+		case domain.NotificationPush:
+			notification := &domain.Notification{
+				UserID:    userID,
+				Type:      req.Type,
+				Title:     req.Title,
+				Message:   req.Message,
+				CreatedAt: time.Now().Format(time.RFC3339),
+			}
+			if err := uc.notificationRepo.CreateNotification(ctx, notification); err != nil {
+				return fmt.Errorf("failed to send notification: %w", err)
+			}
+		case domain.NotificationSMS:
+			if err := uc.smsSender.Send(ctx, user.PhoneNumber, req.Message); err != nil {
+				return fmt.Errorf("failed to send notification by SMS: %w", err)
+			}
+		case domain.NotificationEmail:
+			if err := uc.mailSender.Send(ctx, user.Email, req.Title, req.Message); err != nil {
+				return fmt.Errorf("failed to send notification by email: %w", err)
+			}
+		}
 	}
 	return nil
 }
@@ -873,80 +1566,148 @@ func (uc *NotificationUseCase) GetNotifications(ctx context.Context, userID stri
 	return &GetNotificationsResponse{Notifications: notifications}, nil
 }
 
-// AddLoyaltyPoints adds loyalty points to a user and updates their tier if necessary.
-func (uc *LoyaltyUseCase) AddLoyaltyPoints(ctx context.Context, userID int, points int, pointType string) error {
-	// Create loyalty point record
-	point := &domain.LoyaltyPoint{
-		UserID: userID,
-		Points: points,
-		Type:   pointType,
+// AddLoyaltyPoints adds loyalty points to a user and upgrades their tier if they now qualify for a higher one.
+// Points are added once per idempotency key, however many times the call is repeated.
+func (uc *LoyaltyUseCase) AddLoyaltyPoints(ctx context.Context, userID int, points int, pointType, reference, idempotencyKey string) error {
+	if points <= 0 {
+		return fmt.Errorf("points must be positive")
+	}
+
+	entry := &domain.LoyaltyPoint{
+		UserID:         userID,
+		Points:         points,
+		Type:           pointType,
+		Kind:           domain.LedgerEarn,
+		IdempotencyKey: idempotencyKey,
+		Reference:      reference,
 	}
-	if err := uc.userRepo.CreateLoyaltyPoint(ctx, point); err != nil {
+	posted, err := uc.postEntry(ctx, entry)
+	if err != nil {
 		return fmt.Errorf("failed to create loyalty point: %w", err)
 	}
-
-	// Get user loyalty and update current points
-	userLoyalty, err := uc.userRepo.GetUserLoyalty(ctx, userID)
-	if err != nil && err.Error() == "user loyalty not found" {
-		// Initialize if not found
-		userLoyalty = &domain.UserLoyalty{
-			UserID:         userID,
-			CurrentPoints:  points,
-			LastActivityAt: time.Now().Format(time.RFC3339),
-		}
-	} else if err != nil {
-		return fmt.Errorf("failed to get user loyalty: %w", err)
-	} else {
-		userLoyalty.CurrentPoints += points
-		userLoyalty.LastActivityAt = time.Now().Format(time.RFC3339)
+	if !posted {
+		return nil
 	}
 
-	// Determine new tier
-	tiers, err := uc.userRepo.GetAllLoyaltyTiers(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to get all loyalty tiers: %w", err)
+	// Earning can only lift the tier; downgrades wait for the nightly evaluation
+	return uc.evaluateTier(ctx, userID, false)
+}
+
+// QuoteRedemption returns how much the points are worth towards an order of the given total.
+// Members of higher tiers get more per point, and only part of an order can be paid with points.
+func (uc *LoyaltyUseCase) QuoteRedemption(ctx context.Context, userID int, points int, orderTotal float64) (float64, error) {
+	if points < 0 {
+		return 0, fmt.Errorf("points to redeem cannot be negative")
+	}
+	if points == 0 {
+		return 0, nil
+	}
+	if err := uc.checkPointsNotFrozen(ctx, userID); err != nil {
+		return 0, err
 	}
 
-	var newTier *domain.LoyaltyTier
-	for _, tier := range tiers {
-		if userLoyalty.CurrentPoints >= tier.MinPoints {
-			newTier = tier
-		} else {
-			break // Tiers are sorted by min_points, so we can stop.
+	userLoyalty, err := uc.userRepo.GetUserLoyalty(ctx, userID)
+	if err != nil {
+		if err.Error() == "user loyalty not found" {
+			return 0, fmt.Errorf("insufficient points")
 		}
+		return 0, fmt.Errorf("failed to get user loyalty: %w", err)
+	}
+	if userLoyalty.CurrentPoints < points {
+		return 0, fmt.Errorf("insufficient points: %d available", userLoyalty.CurrentPoints)
 	}
 
-	if newTier != nil && (userLoyalty.CurrentTierID == 0 || userLoyalty.CurrentTierID != newTier.ID) {
-		userLoyalty.CurrentTierID = newTier.ID
-		// Update user's loyalty status in the main user table as well
-		user, err := uc.userRepo.GetUserByID(ctx, userID)
-		if err != nil {
-			return fmt.Errorf("failed to get user for tier update: %w", err)
-		}
-		user.LoyaltyStatus = newTier.Name
-		user.CurrentPoints = userLoyalty.CurrentPoints
-		if err := uc.userRepo.UpdateUser(ctx, user); err != nil {
-			return fmt.Errorf("failed to update user loyalty status: %w", err)
-		}
-		// Add loyalty activity for tier upgrade
-		activity := &domain.LoyaltyActivity{
-			UserID:      userID,
-			Type:        "tier_upgrade",
-			Description: fmt.Sprintf("Upgraded to %s tier", newTier.Name),
+	multiplier := 1.0
+	if userLoyalty.CurrentTierID != 0 {
+		tier, err := uc.userRepo.GetLoyaltyTierByID(ctx, userLoyalty.CurrentTierID)
+		if err != nil && err.Error() != "loyalty tier not found" {
+			return 0, fmt.Errorf("failed to get loyalty tier: %w", err)
 		}
-		if err := uc.userRepo.CreateLoyaltyActivity(ctx, activity); err != nil {
-			return fmt.Errorf("failed to create tier upgrade activity: %w", err)
+		if tier != nil && tier.PointValueMultiplier > 0 {
+			multiplier = tier.PointValueMultiplier
 		}
 	}
 
-	if err := uc.userRepo.UpdateUserLoyalty(ctx, userLoyalty); err != nil {
-		return fmt.Errorf("failed to update user loyalty: %w", err)
+	pointValue := uc.redemptionPolicy.PointValue * multiplier
+	if pointValue <= 0 {
+		return 0, fmt.Errorf("points cannot be redeemed")
 	}
+	maxDiscount := orderTotal * uc.redemptionPolicy.MaxOrderShare
+	discount := math.Round(float64(points)*pointValue*100) / 100
+	if discount > maxDiscount {
+		return 0, fmt.Errorf("at most %d points can be redeemed on this order", int(maxDiscount/pointValue))
+	}
+	return discount, nil
+}
 
+// RedeemPoints takes points from the user's balance to pay for an order.
+// Unlike earning, spending points never changes the user's tier.
+func (uc *LoyaltyUseCase) RedeemPoints(ctx context.Context, userID int, points int, orderID int) error {
+	if err := uc.checkPointsNotFrozen(ctx, userID); err != nil {
+		return err
+	}
+	entry := &domain.LoyaltyPoint{
+		UserID:         userID,
+		Points:         -points,
+		Type:           "redemption",
+		Kind:           domain.LedgerRedeem,
+		IdempotencyKey: fmt.Sprintf("order:%d:redeem", orderID),
+		Reference:      fmt.Sprintf("order:%d", orderID),
+	}
+	if _, err := uc.postEntry(ctx, entry); err != nil {
+		return err
+	}
 	return nil
 }
 
-// AddLoyaltyActivity records a loyalty-related activity for a user.
+// RestorePoints reverses the redemption made for an order that was cancelled.
+func (uc *LoyaltyUseCase) RestorePoints(ctx context.Context, orderID int) error {
+	redemption, err := uc.ledgerRepo.GetEntryByIdempotencyKey(ctx, fmt.Sprintf("order:%d:redeem", orderID))
+	if err != nil {
+		return err
+	}
+	return uc.reverseEntry(ctx, redemption, "redemption_refund")
+}
+
+// ReverseOrderPoints takes back the points earned by an order that was cancelled. Points the customer has
+// already spent cannot be taken back: as many as their balance covers are, and the rest is returned as unrecovered.
+// The points are reversed once; calling again reports the first reversal.
+func (uc *LoyaltyUseCase) ReverseOrderPoints(ctx context.Context, orderID int) (reversed, unrecovered int, err error) {
+	earned, err := uc.ledgerRepo.GetEntryByIdempotencyKey(ctx, fmt.Sprintf("order:%d:earn", orderID))
+	if err != nil {
+		if err.Error() == "loyalty entry not found" {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	previous, err := uc.ledgerRepo.GetEntryByIdempotencyKey(ctx, fmt.Sprintf("reverse:%d", earned.ID))
+	if err == nil {
+		return -previous.Points, earned.Points + previous.Points, nil
+	}
+	if err.Error() != "loyalty entry not found" {
+		return 0, 0, err
+	}
+
+	balance := 0
+	userLoyalty, err := uc.userRepo.GetUserLoyalty(ctx, earned.UserID)
+	if err != nil && err.Error() != "user loyalty not found" {
+		return 0, 0, fmt.Errorf("failed to get user loyalty: %w", err)
+	}
+	if userLoyalty != nil {
+		balance = userLoyalty.CurrentPoints
+	}
+	reversed = min(earned.Points, max(balance, 0))
+	if reversed == 0 {
+		return 0, earned.Points, nil
+	}
+	// A purchase made meanwhile can still spend the balance first; the error is left for the caller to retry
+	if err := uc.reversePoints(ctx, earned, reversed, "purchase_reversal"); err != nil {
+		return 0, 0, err
+	}
+	return reversed, earned.Points - reversed, nil
+}
+
+// AddLoyaltyActivity records a loyalty-related activity for a user. Activities are written by the program itself, never by clients.
 func (uc *LoyaltyUseCase) AddLoyaltyActivity(ctx context.Context, userID int, activityType, description string) error {
 	activity := &domain.LoyaltyActivity{
 		UserID:      userID,
@@ -978,31 +1739,34 @@ func (uc *LoyaltyUseCase) GetUserLoyaltyProfile(ctx context.Context, userID int)
 			return nil, fmt.Errorf("failed to get loyalty tier: %w", err)
 		}
 		if tier != nil {
-			currentTier = &LoyaltyTierResponse{
-				ID:          tier.ID,
-				Name:        tier.Name,
-				MinPoints:   tier.MinPoints,
-				Description: tier.Description,
-				Benefits:    tier.Benefits,
-			}
+			currentTier = tierResponse(tier)
 		}
 	}
 
+	pointsExpiring, err := uc.getExpirySchedule(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	activities, err := uc.userRepo.GetLoyaltyActivitiesByUserID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get loyalty activities: %w", err)
 	}
 
+	freeze, err := uc.pointFreeze(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	response := &GetUserProfileResponse{
-		ID:                  strconv.Itoa(user.ID),
-		PhoneNumber:         user.PhoneNumber,
-		DiscountLevel:       user.DiscountLevel,
-		ProgressToNextLevel: user.ProgressToNextLevel,
-		QRCode:              user.QRCode,
-		LoyaltyStatus:       user.LoyaltyStatus,
-		CurrentPoints:       user.CurrentPoints,
-		CurrentTier:         currentTier,
-		LoyaltyActivities:   activities,
+		ID:                strconv.Itoa(user.ID),
+		PhoneNumber:       user.PhoneNumber,
+		LoyaltyStatus:     user.LoyaltyStatus,
+		CurrentPoints:     user.CurrentPoints,
+		CurrentTier:       currentTier,
+		PointsExpiring:    pointsExpiring,
+		PointsFrozen:      freeze != nil,
+		LoyaltyActivities: activities,
 	}
 
 	return response, nil
@@ -1017,13 +1781,7 @@ func (uc *LoyaltyUseCase) GetLoyaltyTiers(ctx context.Context) ([]*LoyaltyTierRe
 
 	var tierResponses []*LoyaltyTierResponse
 	for _, tier := range tiers {
-		tierResponses = append(tierResponses, &LoyaltyTierResponse{
-			ID:          tier.ID,
-			Name:        tier.Name,
-			MinPoints:   tier.MinPoints,
-			Description: tier.Description,
-			Benefits:    tier.Benefits,
-		})
+		tierResponses = append(tierResponses, tierResponse(tier))
 	}
 	return tierResponses, nil
 }
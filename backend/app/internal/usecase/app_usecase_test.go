@@ -0,0 +1,235 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// fakeLedgerRepo keeps entries in memory and moves the balances held by the user repository. Like the
+// real ledger, it posts an idempotency key once and refuses to take a balance below zero.
+type fakeLedgerRepo struct {
+	domain.LoyaltyLedgerRepository
+	users   *fakeUserRepo
+	entries []*domain.LoyaltyPoint
+}
+
+func (r *fakeLedgerRepo) PostEntry(ctx context.Context, entry *domain.LoyaltyPoint) (bool, error) {
+	if stored, err := r.GetEntryByIdempotencyKey(ctx, entry.IdempotencyKey); err == nil {
+		*entry = *stored
+		return false, nil
+	}
+	loyalty, ok := r.users.loyalties[entry.UserID]
+	if !ok {
+		loyalty = &domain.UserLoyalty{UserID: entry.UserID}
+		r.users.loyalties[entry.UserID] = loyalty
+	}
+	if loyalty.CurrentPoints+entry.Points < 0 {
+		return false, fmt.Errorf("insufficient points")
+	}
+	loyalty.CurrentPoints += entry.Points
+	entry.ID = len(r.entries) + 1
+	entry.BalanceAfter = loyalty.CurrentPoints
+	stored := *entry
+	r.entries = append(r.entries, &stored)
+	return true, nil
+}
+
+func (r *fakeLedgerRepo) GetEntryByIdempotencyKey(ctx context.Context, key string) (*domain.LoyaltyPoint, error) {
+	for _, entry := range r.entries {
+		if entry.IdempotencyKey == key {
+			stored := *entry
+			return &stored, nil
+		}
+	}
+	return nil, fmt.Errorf("loyalty entry not found")
+}
+
+type fakePointFreezeRepo struct {
+	domain.PointFreezeRepository
+	frozen map[int]bool
+}
+
+func (r *fakePointFreezeRepo) GetPointFreeze(ctx context.Context, userID int) (*domain.PointFreeze, error) {
+	if r.frozen[userID] {
+		return &domain.PointFreeze{UserID: userID}, nil
+	}
+	return nil, fmt.Errorf("point freeze not found")
+}
+
+// newTestLoyaltyUseCase returns a loyalty use case over in-memory fakes, with a point worth 0.1
+// and at most half of an order payable with points.
+func newTestLoyaltyUseCase(users *fakeUserRepo, frozen map[int]bool) (*LoyaltyUseCase, *fakeLedgerRepo) {
+	if users.loyalties == nil {
+		users.loyalties = map[int]*domain.UserLoyalty{}
+	}
+	ledger := &fakeLedgerRepo{users: users}
+	policy := RedemptionPolicy{PointValue: 0.1, MaxOrderShare: 0.5}
+	uc := NewLoyaltyUseCase(users, ledger, nil, &fakePointFreezeRepo{frozen: frozen}, nil, policy, ExpiryPolicy{}, TierPolicy{})
+	return uc, ledger
+}
+
+func TestQuoteRedemption(t *testing.T) {
+	const (
+		memberID = 1
+		goldID   = 2 // Points are worth half as much again
+		frozenID = 3
+		guestID  = 4 // Never earned anything
+		goldTier = 10
+	)
+	users := &fakeUserRepo{
+		loyalties: map[int]*domain.UserLoyalty{
+			memberID: {UserID: memberID, CurrentPoints: 1000},
+			goldID:   {UserID: goldID, CurrentPoints: 1000, CurrentTierID: goldTier},
+			frozenID: {UserID: frozenID, CurrentPoints: 1000},
+		},
+		tiers: map[int]*domain.LoyaltyTier{goldTier: {ID: goldTier, Name: "Gold", PointValueMultiplier: 1.5}},
+	}
+	uc, _ := newTestLoyaltyUseCase(users, map[int]bool{frozenID: true})
+
+	tests := []struct {
+		name       string
+		userID     int
+		points     int
+		orderTotal float64
+		want       float64
+		wantErr    string
+	}{
+		{name: "nothing to redeem", userID: guestID, points: 0, orderTotal: 100},
+		{name: "negative points", userID: memberID, points: -10, orderTotal: 100, wantErr: "points to redeem cannot be negative"},
+		{name: "base point value", userID: memberID, points: 500, orderTotal: 200, want: 50},
+		{name: "tier multiplier", userID: goldID, points: 500, orderTotal: 200, want: 75},
+		{name: "up to the order share", userID: memberID, points: 400, orderTotal: 80, want: 40},
+		{name: "over the order share", userID: memberID, points: 500, orderTotal: 80, wantErr: "at most 400 points can be redeemed on this order"},
+		{name: "more than the balance", userID: memberID, points: 1500, orderTotal: 1000, wantErr: "insufficient points: 1000 available"},
+		{name: "no balance", userID: guestID, points: 10, orderTotal: 100, wantErr: "insufficient points"},
+		{name: "frozen points", userID: frozenID, points: 10, orderTotal: 100, wantErr: "loyalty points are frozen"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := uc.QuoteRedemption(context.Background(), tt.userID, tt.points, tt.orderTotal)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("QuoteRedemption() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QuoteRedemption() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("QuoteRedemption() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestorePoints(t *testing.T) {
+	const (
+		userID  = 1
+		orderID = 7
+	)
+	tests := []struct {
+		name         string
+		redeemed     int
+		restoreCalls int
+		wantBalance  int
+		wantErr      string
+	}{
+		{name: "redemption refunded", redeemed: 150, restoreCalls: 1, wantBalance: 500},
+		{name: "refunded once however often retried", redeemed: 150, restoreCalls: 3, wantBalance: 500},
+		{name: "order paid without points", restoreCalls: 1, wantBalance: 500, wantErr: "loyalty entry not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserRepo{loyalties: map[int]*domain.UserLoyalty{userID: {UserID: userID, CurrentPoints: 500}}}
+			uc, ledger := newTestLoyaltyUseCase(users, nil)
+			ctx := context.Background()
+			if tt.redeemed > 0 {
+				if err := uc.RedeemPoints(ctx, userID, tt.redeemed, orderID); err != nil {
+					t.Fatalf("RedeemPoints() error = %v", err)
+				}
+			}
+
+			var err error
+			for i := 0; i < tt.restoreCalls; i++ {
+				err = uc.RestorePoints(ctx, orderID)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("RestorePoints() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("RestorePoints() error = %v", err)
+			}
+			if got := users.loyalties[userID].CurrentPoints; got != tt.wantBalance {
+				t.Errorf("balance = %d, want %d", got, tt.wantBalance)
+			}
+			if tt.redeemed > 0 {
+				redemption, err := ledger.GetEntryByIdempotencyKey(ctx, fmt.Sprintf("order:%d:redeem", orderID))
+				if err != nil {
+					t.Fatalf("redemption not posted: %v", err)
+				}
+				refund, err := ledger.GetEntryByIdempotencyKey(ctx, fmt.Sprintf("reverse:%d", redemption.ID))
+				if err != nil {
+					t.Fatalf("refund not posted: %v", err)
+				}
+				if refund.Points != tt.redeemed || refund.Type != "redemption_refund" || refund.Kind != domain.LedgerReverse {
+					t.Errorf("refund = %+v, want %d points of redemption_refund", refund, tt.redeemed)
+				}
+			}
+		})
+	}
+}
+
+func TestReverseOrderPoints(t *testing.T) {
+	const (
+		userID  = 1
+		orderID = 7
+	)
+	tests := []struct {
+		name            string
+		earned          int
+		balance         int // When the order is cancelled
+		calls           int
+		wantReversed    int
+		wantUnrecovered int
+		wantBalance     int
+	}{
+		{name: "order earned nothing", balance: 300, calls: 1, wantBalance: 300},
+		{name: "balance covers the points", earned: 100, balance: 300, calls: 1, wantReversed: 100, wantBalance: 200},
+		{name: "some points already spent", earned: 100, balance: 40, calls: 1, wantReversed: 40, wantUnrecovered: 60},
+		{name: "all points already spent", earned: 100, balance: 0, calls: 1, wantUnrecovered: 100},
+		{name: "repeat reports the first reversal", earned: 100, balance: 40, calls: 2, wantReversed: 40, wantUnrecovered: 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserRepo{}
+			uc, ledger := newTestLoyaltyUseCase(users, nil)
+			ctx := context.Background()
+			if tt.earned > 0 {
+				earn := &domain.LoyaltyPoint{UserID: userID, Points: tt.earned, Type: "purchase", Kind: domain.LedgerEarn, IdempotencyKey: fmt.Sprintf("order:%d:earn", orderID)}
+				if _, err := ledger.PostEntry(ctx, earn); err != nil {
+					t.Fatalf("PostEntry() error = %v", err)
+				}
+			}
+			users.loyalties[userID] = &domain.UserLoyalty{UserID: userID, CurrentPoints: tt.balance}
+
+			var reversed, unrecovered int
+			var err error
+			for i := 0; i < tt.calls; i++ {
+				if reversed, unrecovered, err = uc.ReverseOrderPoints(ctx, orderID); err != nil {
+					t.Fatalf("ReverseOrderPoints() error = %v", err)
+				}
+			}
+			if reversed != tt.wantReversed || unrecovered != tt.wantUnrecovered {
+				t.Errorf("ReverseOrderPoints() = %d, %d, want %d, %d", reversed, unrecovered, tt.wantReversed, tt.wantUnrecovered)
+			}
+			if got := users.loyalties[userID].CurrentPoints; got != tt.wantBalance {
+				t.Errorf("balance = %d, want %d", got, tt.wantBalance)
+			}
+		})
+	}
+}
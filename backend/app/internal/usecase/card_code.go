@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cardCodePrefix       = "KM1"
+	walletCodePrefix     = "KMW1"    // Codes printed on wallet passes, which cannot be redrawn every few seconds
+	walletCodePeriod     = time.Hour // Window of a wallet code; passes are pushed a new one each hour
+	cardCodeMACLength    = 16        // Bytes of the HMAC kept in the code, so the QR stays small
+	cardCodeWindowsTaken = 1         // Neighbouring windows accepted on either side, for clock skew and a slow scan
+)
+
+// CardCodeSigner issues the rotating codes shown on a member's discount card. A code names the member
+// and a time window and is signed with the server secret and the member's card seed (users.qr_code),
+// so it cannot be forged and a screenshot of it stops working within a couple of windows.
+type CardCodeSigner struct {
+	secret []byte
+	period time.Duration
+}
+
+func NewCardCodeSigner(secret string, period time.Duration) *CardCodeSigner {
+	return &CardCodeSigner{secret: []byte(secret), period: period}
+}
+
+// Sign returns the card code of the member for the window containing at, and when that window ends.
+func (s *CardCodeSigner) Sign(userID int, seed string, at time.Time) (string, time.Time) {
+	return s.sign(cardCodePrefix, s.period, userID, seed, at)
+}
+
+// SignWallet returns the code printed on the member's wallet pass. It lives for an hour rather than seconds,
+// since a pass only changes when the server pushes an update.
+func (s *CardCodeSigner) SignWallet(userID int, seed string, at time.Time) (string, time.Time) {
+	return s.sign(walletCodePrefix, walletCodePeriod, userID, seed, at)
+}
+
+// WalletCodeWindow returns the window of the wallet code issued at the given time.
+func (s *CardCodeSigner) WalletCodeWindow(at time.Time) int64 {
+	return at.Unix() / int64(walletCodePeriod.Seconds())
+}
+
+func (s *CardCodeSigner) sign(prefix string, period time.Duration, userID int, seed string, at time.Time) (string, time.Time) {
+	window := at.Unix() / int64(period.Seconds())
+	code := fmt.Sprintf("%s.%d.%d.%s", prefix, userID, window, s.mac(prefix, userID, window, seed))
+	return code, time.Unix((window+1)*int64(period.Seconds()), 0)
+}
+
+// ParseUserID returns the member a card code claims to belong to. The claim means nothing until Verify accepts the code.
+func (s *CardCodeSigner) ParseUserID(code string) (int, error) {
+	_, userID, _, _, err := parseCardCode(code)
+	return userID, err
+}
+
+// Verify checks that the code, from the app or from a wallet pass, was signed for the member with the given seed
+// in a window close to now.
+func (s *CardCodeSigner) Verify(code string, seed string, now time.Time) error {
+	prefix, userID, window, mac, err := parseCardCode(code)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(mac), []byte(s.mac(prefix, userID, window, seed))) {
+		return fmt.Errorf("invalid card code")
+	}
+
+	if prefix == walletCodePrefix {
+		// The previous window covers a pass whose update has not reached the device yet. Skew is small next
+		// to an hour, so no later window is taken, which would keep a screenshot working for another hour.
+		current := now.Unix() / int64(walletCodePeriod.Seconds())
+		if window < current-cardCodeWindowsTaken || window > current {
+			return fmt.Errorf("card code has expired")
+		}
+		return nil
+	}
+	current := now.Unix() / int64(s.period.Seconds())
+	if window < current-cardCodeWindowsTaken || window > current+cardCodeWindowsTaken {
+		return fmt.Errorf("card code has expired")
+	}
+	return nil
+}
+
+// mac covers the prefix too, so a short-lived code cannot be passed off as a day-long one.
+func (s *CardCodeSigner) mac(prefix string, userID int, window int64, seed string) string {
+	h := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(h, "%s.%d.%d.%s", prefix, userID, window, seed)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:cardCodeMACLength])
+}
+
+func parseCardCode(code string) (string, int, int64, string, error) {
+	parts := strings.Split(strings.TrimSpace(code), ".")
+	if len(parts) != 4 || (parts[0] != cardCodePrefix && parts[0] != walletCodePrefix) {
+		return "", 0, 0, "", fmt.Errorf("invalid card code")
+	}
+	userID, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, 0, "", fmt.Errorf("invalid card code")
+	}
+	window, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, 0, "", fmt.Errorf("invalid card code")
+	}
+	return parts[0], userID, window, parts[3], nil
+}
@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCardCodeSignerVerify(t *testing.T) {
+	const period = 30 * time.Second
+	signer := NewCardCodeSigner("test-secret", period)
+	// A few seconds into both an app window and a wallet window
+	issuedAt := time.Unix(1_700_000_000/3600*3600+5, 0)
+
+	appCode, _ := signer.Sign(42, "seed", issuedAt)
+	walletCode, _ := signer.SignWallet(42, "seed", issuedAt)
+
+	tests := []struct {
+		name    string
+		code    string
+		seed    string
+		now     time.Time
+		wantErr string
+	}{
+		{name: "app code in its window", code: appCode, seed: "seed", now: issuedAt},
+		{name: "app code in the next window", code: appCode, seed: "seed", now: issuedAt.Add(period)},
+		{name: "app code two windows later", code: appCode, seed: "seed", now: issuedAt.Add(2 * period), wantErr: "card code has expired"},
+		{name: "app code from the next window", code: appCode, seed: "seed", now: issuedAt.Add(-period)},
+		{name: "app code from two windows ahead", code: appCode, seed: "seed", now: issuedAt.Add(-2 * period), wantErr: "card code has expired"},
+		{name: "wallet code in its window", code: walletCode, seed: "seed", now: issuedAt.Add(30 * time.Minute)},
+		{name: "wallet code in the next window", code: walletCode, seed: "seed", now: issuedAt.Add(walletCodePeriod)},
+		{name: "wallet code two windows later", code: walletCode, seed: "seed", now: issuedAt.Add(2 * walletCodePeriod), wantErr: "card code has expired"},
+		{name: "wallet code from the next window", code: walletCode, seed: "seed", now: issuedAt.Add(-walletCodePeriod), wantErr: "card code has expired"},
+		{name: "wrong seed", code: appCode, seed: "other", now: issuedAt, wantErr: "invalid card code"},
+		{name: "other member", code: strings.Replace(appCode, ".42.", ".43.", 1), seed: "seed", now: issuedAt, wantErr: "invalid card code"},
+		{name: "wallet code passed off as app code", code: strings.Replace(walletCode, walletCodePrefix+".", cardCodePrefix+".", 1), seed: "seed", now: issuedAt, wantErr: "invalid card code"},
+		{name: "unknown prefix", code: strings.Replace(appCode, cardCodePrefix+".", "KM0.", 1), seed: "seed", now: issuedAt, wantErr: "invalid card code"},
+		{name: "static code", code: "4f1c2d3e-0000-0000-0000-000000000000", seed: "seed", now: issuedAt, wantErr: "invalid card code"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signer.Verify(tt.code, tt.seed, tt.now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Verify() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("Verify() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCardCodeSignerSign(t *testing.T) {
+	signer := NewCardCodeSigner("test-secret", 30*time.Second)
+	at := time.Unix(1_700_000_010, 0)
+
+	code, expiresAt := signer.Sign(7, "seed", at)
+	if want := time.Unix(1_700_000_010/30*30+30, 0); !expiresAt.Equal(want) {
+		t.Errorf("Sign() expires at %v, want %v", expiresAt, want)
+	}
+	if again, _ := signer.Sign(7, "seed", at.Add(10*time.Second)); again != code {
+		t.Errorf("Sign() within the window = %q, want %q", again, code)
+	}
+	if next, _ := signer.Sign(7, "seed", expiresAt); next == code {
+		t.Errorf("Sign() in the next window returned the same code")
+	}
+	if other, _ := NewCardCodeSigner("other-secret", 30*time.Second).Sign(7, "seed", at); other == code {
+		t.Errorf("Sign() with another secret returned the same code")
+	}
+
+	userID, err := signer.ParseUserID(code)
+	if err != nil || userID != 7 {
+		t.Errorf("ParseUserID() = %d, %v, want 7", userID, err)
+	}
+}
@@ -0,0 +1,341 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// ChallengeUseCase runs the challenges members complete for badges and points.
+// Progress is counted from orders and store visits whenever the member places an order or visits a store.
+type ChallengeUseCase struct {
+	challengeRepo       domain.ChallengeRepository
+	storeRepo           domain.StoreRepository
+	loyaltyUseCase      *LoyaltyUseCase
+	notificationUseCase *NotificationUseCase
+}
+
+func NewChallengeUseCase(challengeRepo domain.ChallengeRepository, storeRepo domain.StoreRepository, loyaltyUseCase *LoyaltyUseCase, notificationUseCase *NotificationUseCase) *ChallengeUseCase {
+	return &ChallengeUseCase{challengeRepo: challengeRepo, storeRepo: storeRepo, loyaltyUseCase: loyaltyUseCase, notificationUseCase: notificationUseCase}
+}
+
+// ChallengeRequest defines a challenge. Active defaults to true and the period to once.
+type ChallengeRequest struct {
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	Active       *bool                  `json:"active,omitempty"`
+	Goal         domain.ChallengeGoal   `json:"goal"`
+	Target       float64                `json:"target"`
+	CategoryID   *int                   `json:"category_id,omitempty"`
+	Period       domain.ChallengePeriod `json:"period"`
+	StartsAt     *string                `json:"starts_at,omitempty"` // RFC 3339
+	EndsAt       *string                `json:"ends_at,omitempty"`
+	BadgeName    string                 `json:"badge_name"`
+	BadgeIconURL string                 `json:"badge_icon_url,omitempty"`
+	RewardPoints int                    `json:"reward_points"`
+}
+
+type GetChallengesResponse struct {
+	Challenges []*domain.Challenge `json:"challenges"`
+}
+
+// ChallengeProgress is how far a member is with a challenge in its current period.
+type ChallengeProgress struct {
+	Challenge    *domain.Challenge `json:"challenge"`
+	Progress     float64           `json:"progress"`
+	Completed    bool              `json:"completed"`
+	PeriodEndsAt *string           `json:"period_ends_at,omitempty"` // Empty for a challenge without an end
+}
+
+type GetUserChallengesResponse struct {
+	Challenges []*ChallengeProgress `json:"challenges"`
+	Badges     []*domain.UserBadge  `json:"badges"`
+}
+
+func (uc *ChallengeUseCase) GetChallenges(ctx context.Context) (*GetChallengesResponse, error) {
+	challenges, err := uc.challengeRepo.GetChallenges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if challenges == nil {
+		challenges = []*domain.Challenge{}
+	}
+	return &GetChallengesResponse{Challenges: challenges}, nil
+}
+
+func (uc *ChallengeUseCase) CreateChallenge(ctx context.Context, req *ChallengeRequest) (*domain.Challenge, error) {
+	challenge, err := challengeFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.challengeRepo.CreateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// UpdateChallenge changes a challenge. Badges already awarded are kept.
+func (uc *ChallengeUseCase) UpdateChallenge(ctx context.Context, challengeID int, req *ChallengeRequest) (*domain.Challenge, error) {
+	challenge, err := challengeFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	challenge.ID = challengeID
+	if err := uc.challengeRepo.UpdateChallenge(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// DeleteChallenge removes a challenge. Badges already awarded are kept.
+func (uc *ChallengeUseCase) DeleteChallenge(ctx context.Context, challengeID int) error {
+	return uc.challengeRepo.DeleteChallenge(ctx, challengeID)
+}
+
+// GetUserChallenges returns the user's progress with every running challenge and the badges they have earned.
+func (uc *ChallengeUseCase) GetUserChallenges(ctx context.Context, userID int) (*GetUserChallengesResponse, error) {
+	now := time.Now()
+	challenges, err := uc.challengeRepo.GetActiveChallenges(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	badges, err := uc.challengeRepo.GetBadgesByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	awarded := awardedPeriods(badges)
+
+	resp := &GetUserChallengesResponse{Challenges: []*ChallengeProgress{}, Badges: badges}
+	if resp.Badges == nil {
+		resp.Badges = []*domain.UserBadge{}
+	}
+	for _, challenge := range challenges {
+		from, to := challengeWindow(challenge, now)
+		progress, err := uc.challengeRepo.GetChallengeProgress(ctx, challenge, userID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		item := &ChallengeProgress{
+			Challenge: challenge,
+			Progress:  progress,
+			Completed: awarded[badgeKey(challenge.ID, from)],
+		}
+		if !to.Equal(noChallengeEnd) {
+			periodEnd := to.Format(time.RFC3339)
+			item.PeriodEndsAt = &periodEnd
+		}
+		resp.Challenges = append(resp.Challenges, item)
+	}
+	return resp, nil
+}
+
+// RecordOrder moves the challenges of a customer who has paid for an order.
+func (uc *ChallengeUseCase) RecordOrder(ctx context.Context, userID int) error {
+	return uc.evaluateChallenges(ctx, userID)
+}
+
+// RecordStoreVisit records that a customer was in a store and moves their challenges.
+func (uc *ChallengeUseCase) RecordStoreVisit(ctx context.Context, userID, storeID int, source string) error {
+	visit := &domain.StoreVisit{UserID: userID, StoreID: storeID, Source: source}
+	if err := uc.storeRepo.CreateStoreVisit(ctx, visit); err != nil {
+		return err
+	}
+	return uc.evaluateChallenges(ctx, userID)
+}
+
+// evaluateChallenges awards the member every challenge they have reached the target of in its current period.
+func (uc *ChallengeUseCase) evaluateChallenges(ctx context.Context, userID int) error {
+	now := time.Now()
+	challenges, err := uc.challengeRepo.GetActiveChallenges(ctx, now)
+	if err != nil {
+		return err
+	}
+	if len(challenges) == 0 {
+		return nil
+	}
+	badges, err := uc.challengeRepo.GetBadgesByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	awarded := awardedPeriods(badges)
+
+	for _, challenge := range challenges {
+		from, to := challengeWindow(challenge, now)
+		if awarded[badgeKey(challenge.ID, from)] {
+			continue
+		}
+		progress, err := uc.challengeRepo.GetChallengeProgress(ctx, challenge, userID, from, to)
+		if err != nil {
+			return err
+		}
+		if progress < challenge.Target {
+			continue
+		}
+		if err := uc.completeChallenge(ctx, userID, challenge, from); err != nil {
+			log.Printf("Failed to complete challenge %d for user %d: %v", challenge.ID, userID, err)
+		}
+	}
+	return nil
+}
+
+// completeChallenge gives the member the challenge's points and badge for the period.
+// The points are posted first and only once, so a failed award is safely retried by the next event.
+func (uc *ChallengeUseCase) completeChallenge(ctx context.Context, userID int, challenge *domain.Challenge, periodStart time.Time) error {
+	if challenge.RewardPoints > 0 {
+		reference := fmt.Sprintf("challenge:%d", challenge.ID)
+		key := fmt.Sprintf("challenge:%d:%d:%d", challenge.ID, userID, periodStart.Unix())
+		if err := uc.loyaltyUseCase.AddLoyaltyPoints(ctx, userID, challenge.RewardPoints, "challenge", reference, key); err != nil {
+			return err
+		}
+	}
+
+	badge := &domain.UserBadge{
+		UserID:      userID,
+		ChallengeID: &challenge.ID,
+		PeriodStart: periodStart.Format(time.RFC3339),
+		Name:        challenge.BadgeName,
+		IconURL:     challenge.BadgeIconURL,
+		Points:      challenge.RewardPoints,
+	}
+	awarded, err := uc.challengeRepo.AwardBadge(ctx, badge)
+	if err != nil || !awarded {
+		return err
+	}
+
+	if err := uc.loyaltyUseCase.AddLoyaltyActivity(ctx, userID, "challenge_completed", fmt.Sprintf("Completed the %s challenge", challenge.Name)); err != nil {
+		log.Printf("Failed to record completed challenge %d of user %d: %v", challenge.ID, userID, err)
+	}
+	if err := uc.loyaltyUseCase.AddLoyaltyActivity(ctx, userID, "badge_earned", fmt.Sprintf("Earned the %s badge", challenge.BadgeName)); err != nil {
+		log.Printf("Failed to record badge of challenge %d of user %d: %v", challenge.ID, userID, err)
+	}
+
+	message := fmt.Sprintf("Вы выполнили задание «%s» и получили значок «%s».", challenge.Name, challenge.BadgeName)
+	if challenge.RewardPoints > 0 {
+		message += fmt.Sprintf(" Начислено %d баллов.", challenge.RewardPoints)
+	}
+	notificationReq := &SendNotificationRequest{
+		UserID:  strconv.Itoa(userID),
+		Type:    "challenge_completed",
+		Title:   "Задание выполнено!",
+		Message: message,
+	}
+	if err := uc.notificationUseCase.SendNotification(ctx, notificationReq); err != nil {
+		log.Printf("Failed to notify user %d about completed challenge: %v", userID, err)
+	}
+	return nil
+}
+
+// noChallengeEnd bounds the window of a one-off challenge without an end date.
+var noChallengeEnd = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// challengeWindow returns the period of the challenge that contains t, cut to the challenge's campaign dates.
+func challengeWindow(challenge *domain.Challenge, t time.Time) (time.Time, time.Time) {
+	from, to := time.Unix(0, 0), noChallengeEnd
+	switch challenge.Period {
+	case domain.ChallengeWeekly:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		from = time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+		to = from.AddDate(0, 0, 7)
+	case domain.ChallengeMonthly:
+		from = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		to = from.AddDate(0, 1, 0)
+	}
+
+	if challenge.StartsAt != nil {
+		if startsAt, err := time.Parse(time.RFC3339Nano, *challenge.StartsAt); err == nil && startsAt.After(from) {
+			from = startsAt
+		}
+	}
+	if challenge.EndsAt != nil {
+		if endsAt, err := time.Parse(time.RFC3339Nano, *challenge.EndsAt); err == nil && endsAt.Before(to) {
+			to = endsAt
+		}
+	}
+	return from, to
+}
+
+func awardedPeriods(badges []*domain.UserBadge) map[string]bool {
+	awarded := make(map[string]bool, len(badges))
+	for _, badge := range badges {
+		if badge.ChallengeID == nil {
+			continue
+		}
+		if periodStart, err := time.Parse(time.RFC3339Nano, badge.PeriodStart); err == nil {
+			awarded[badgeKey(*badge.ChallengeID, periodStart)] = true
+		}
+	}
+	return awarded
+}
+
+func badgeKey(challengeID int, periodStart time.Time) string {
+	return fmt.Sprintf("%d:%d", challengeID, periodStart.Unix())
+}
+
+func challengeFromRequest(req *ChallengeRequest) (*domain.Challenge, error) {
+	challenge := &domain.Challenge{
+		Name:         strings.TrimSpace(req.Name),
+		Description:  strings.TrimSpace(req.Description),
+		Active:       req.Active == nil || *req.Active,
+		Goal:         req.Goal,
+		Target:       req.Target,
+		CategoryID:   req.CategoryID,
+		Period:       req.Period,
+		StartsAt:     req.StartsAt,
+		EndsAt:       req.EndsAt,
+		BadgeName:    strings.TrimSpace(req.BadgeName),
+		BadgeIconURL: strings.TrimSpace(req.BadgeIconURL),
+		RewardPoints: req.RewardPoints,
+	}
+	if challenge.Period == "" {
+		challenge.Period = domain.ChallengeOnce
+	}
+	if challenge.BadgeName == "" {
+		challenge.BadgeName = challenge.Name
+	}
+
+	switch challenge.Goal {
+	case domain.ChallengeGoalOrders, domain.ChallengeGoalItems, domain.ChallengeGoalAmountSpent:
+	case domain.ChallengeGoalStoreVisits, domain.ChallengeGoalDistinctStores:
+		if challenge.CategoryID != nil {
+			return nil, fmt.Errorf("category_id only applies to purchase goals")
+		}
+	default:
+		return nil, fmt.Errorf("goal must be one of orders, items, amount_spent, store_visits, distinct_stores")
+	}
+	switch challenge.Period {
+	case domain.ChallengeOnce, domain.ChallengeWeekly, domain.ChallengeMonthly:
+	default:
+		return nil, fmt.Errorf("period must be one of once, weekly, monthly")
+	}
+
+	switch {
+	case challenge.Name == "":
+		return nil, fmt.Errorf("name is required")
+	case challenge.Target <= 0:
+		return nil, fmt.Errorf("target must be positive")
+	case challenge.RewardPoints < 0:
+		return nil, fmt.Errorf("reward_points cannot be negative")
+	}
+
+	var startsAt, endsAt time.Time
+	var err error
+	if challenge.StartsAt != nil {
+		if startsAt, err = time.Parse(time.RFC3339, *challenge.StartsAt); err != nil {
+			return nil, fmt.Errorf("starts_at must be an RFC 3339 time")
+		}
+	}
+	if challenge.EndsAt != nil {
+		if endsAt, err = time.Parse(time.RFC3339, *challenge.EndsAt); err != nil {
+			return nil, fmt.Errorf("ends_at must be an RFC 3339 time")
+		}
+		if challenge.StartsAt != nil && !endsAt.After(startsAt) {
+			return nil, fmt.Errorf("ends_at must be after starts_at")
+		}
+	}
+	return challenge, nil
+}
@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+func TestChallengeWindow(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	timestamp := func(t time.Time) *string {
+		s := t.Format(time.RFC3339)
+		return &s
+	}
+	// A Wednesday afternoon
+	now := time.Date(2024, time.May, 15, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		challenge *domain.Challenge
+		at        time.Time
+		wantFrom  time.Time
+		wantTo    time.Time
+	}{
+		{
+			name:      "weekly starts on Monday",
+			challenge: &domain.Challenge{Period: domain.ChallengeWeekly},
+			at:        now,
+			wantFrom:  date(2024, time.May, 13),
+			wantTo:    date(2024, time.May, 20),
+		},
+		{
+			name:      "weekly on a Sunday belongs to the week before",
+			challenge: &domain.Challenge{Period: domain.ChallengeWeekly},
+			at:        time.Date(2024, time.May, 19, 23, 0, 0, 0, time.UTC),
+			wantFrom:  date(2024, time.May, 13),
+			wantTo:    date(2024, time.May, 20),
+		},
+		{
+			name:      "weekly across a month boundary",
+			challenge: &domain.Challenge{Period: domain.ChallengeWeekly},
+			at:        date(2024, time.June, 1),
+			wantFrom:  date(2024, time.May, 27),
+			wantTo:    date(2024, time.June, 3),
+		},
+		{
+			name:      "monthly",
+			challenge: &domain.Challenge{Period: domain.ChallengeMonthly},
+			at:        now,
+			wantFrom:  date(2024, time.May, 1),
+			wantTo:    date(2024, time.June, 1),
+		},
+		{
+			name:      "monthly in December",
+			challenge: &domain.Challenge{Period: domain.ChallengeMonthly},
+			at:        date(2024, time.December, 31),
+			wantFrom:  date(2024, time.December, 1),
+			wantTo:    date(2025, time.January, 1),
+		},
+		{
+			name:      "once without dates",
+			challenge: &domain.Challenge{Period: domain.ChallengeOnce},
+			at:        now,
+			wantFrom:  time.Unix(0, 0),
+			wantTo:    noChallengeEnd,
+		},
+		{
+			name: "once within campaign dates",
+			challenge: &domain.Challenge{
+				Period:   domain.ChallengeOnce,
+				StartsAt: timestamp(date(2024, time.May, 1)),
+				EndsAt:   timestamp(date(2024, time.May, 31)),
+			},
+			at:       now,
+			wantFrom: date(2024, time.May, 1),
+			wantTo:   date(2024, time.May, 31),
+		},
+		{
+			name: "weekly cut to a campaign starting midweek",
+			challenge: &domain.Challenge{
+				Period:   domain.ChallengeWeekly,
+				StartsAt: timestamp(date(2024, time.May, 15)),
+			},
+			at:       now,
+			wantFrom: date(2024, time.May, 15),
+			wantTo:   date(2024, time.May, 20),
+		},
+		{
+			name: "monthly cut to a campaign ending mid-month",
+			challenge: &domain.Challenge{
+				Period: domain.ChallengeMonthly,
+				EndsAt: timestamp(date(2024, time.May, 20)),
+			},
+			at:       now,
+			wantFrom: date(2024, time.May, 1),
+			wantTo:   date(2024, time.May, 20),
+		},
+		{
+			name: "campaign dates outside the period are ignored",
+			challenge: &domain.Challenge{
+				Period:   domain.ChallengeWeekly,
+				StartsAt: timestamp(date(2024, time.January, 1)),
+				EndsAt:   timestamp(date(2024, time.December, 31)),
+			},
+			at:       now,
+			wantFrom: date(2024, time.May, 13),
+			wantTo:   date(2024, time.May, 20),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := challengeWindow(tt.challenge, tt.at)
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("challengeWindow() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
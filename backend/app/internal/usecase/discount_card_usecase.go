@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// DiscountCardResponse is the member's discount card. It is derived from their tier and the points
+// they earned in the qualification window, so it always agrees with the tier engine.
+type DiscountCardResponse struct {
+	DiscountLevel       int                  `json:"discount_level"`         // Position of the member's tier, from 1 for the lowest; 0 without a tier
+	ProgressToNextLevel float64              `json:"progress_to_next_level"` // Percent of the way from the tier's min_points to the next tier's; 100 at the top tier
+	PointsToNextLevel   int                  `json:"points_to_next_level"`
+	QualifyingPoints    int                  `json:"qualifying_points"`
+	DiscountPercent     float64              `json:"discount_percent"` // Taken off orders at checkout
+	Overridden          bool                 `json:"overridden"`       // Set when staff gave the member a discount other than their tier's
+	Tier                *LoyaltyTierResponse `json:"tier,omitempty"`
+	NextTier            *LoyaltyTierResponse `json:"next_tier,omitempty"`
+}
+
+// AdminDiscountCardResponse is a member's discount card as staff see it, with the override and its history.
+type AdminDiscountCardResponse struct {
+	Card     *DiscountCardResponse            `json:"card"`
+	Override *domain.DiscountCardOverride     `json:"override,omitempty"`
+	Audit    []*domain.DiscountCardAuditEntry `json:"audit"`
+}
+
+// DiscountOverrideRequest sets a member's discount in place of their tier's.
+type DiscountOverrideRequest struct {
+	DiscountPercent *float64 `json:"discount_percent"`
+	Reason          string   `json:"reason"`
+}
+
+// DiscountOverrideClearRequest gives the member their tier's discount back.
+type DiscountOverrideClearRequest struct {
+	Reason string `json:"reason"`
+}
+
+// GetDiscountCard returns the member's discount card.
+func (uc *LoyaltyUseCase) GetDiscountCard(ctx context.Context, userID int) (*DiscountCardResponse, error) {
+	tiers, err := uc.userRepo.GetAllLoyaltyTiers(ctx) // Sorted by min_points
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all loyalty tiers: %w", err)
+	}
+	tier, err := uc.memberTier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().AddDate(0, -uc.tierPolicy.QualificationMonths, 0)
+	qualifyingPoints, err := uc.ledgerRepo.GetQualifyingPoints(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	card := &DiscountCardResponse{QualifyingPoints: qualifyingPoints}
+	current, base := -1, 0
+	for i, t := range tiers {
+		if tier != nil && t.ID == tier.ID {
+			current, base = i, t.MinPoints
+		}
+	}
+	if current >= 0 {
+		card.DiscountLevel = current + 1
+		card.Tier = tierResponse(tier)
+		card.DiscountPercent = tier.Benefits.DiscountPercent
+	}
+
+	if current+1 < len(tiers) {
+		next := tiers[current+1]
+		card.NextTier = tierResponse(next)
+		card.PointsToNextLevel = max(next.MinPoints-qualifyingPoints, 0)
+		// A member in their grace period can be below their own tier's threshold; they show no progress
+		if span := next.MinPoints - base; span > 0 {
+			progress := float64(qualifyingPoints-base) / float64(span) * 100
+			card.ProgressToNextLevel = math.Round(math.Min(math.Max(progress, 0), 100)*10) / 10
+		} else {
+			card.ProgressToNextLevel = 100
+		}
+	} else if current >= 0 {
+		card.ProgressToNextLevel = 100
+	}
+
+	override, err := uc.discountOverride(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if override != nil {
+		card.DiscountPercent = override.DiscountPercent
+		card.Overridden = true
+	}
+	return card, nil
+}
+
+// GetAdminDiscountCard returns the member's discount card with the override set by staff and every change made to it.
+func (uc *LoyaltyUseCase) GetAdminDiscountCard(ctx context.Context, userID int) (*AdminDiscountCardResponse, error) {
+	if _, err := uc.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	card, err := uc.GetDiscountCard(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	override, err := uc.discountOverride(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := uc.discountCardRepo.GetDiscountCardAudit(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if audit == nil {
+		audit = []*domain.DiscountCardAuditEntry{}
+	}
+	return &AdminDiscountCardResponse{Card: card, Override: override, Audit: audit}, nil
+}
+
+// SetDiscountOverride gives the member a discount in place of their tier's until it is cleared.
+// The change, who made it and why are kept in the audit trail.
+func (uc *LoyaltyUseCase) SetDiscountOverride(ctx context.Context, userID, adminID int, req *DiscountOverrideRequest) (*AdminDiscountCardResponse, error) {
+	reason := strings.TrimSpace(req.Reason)
+	switch {
+	case req.DiscountPercent == nil:
+		return nil, fmt.Errorf("discount_percent is required")
+	case *req.DiscountPercent < 0 || *req.DiscountPercent > 100:
+		return nil, fmt.Errorf("discount_percent must be between 0 and 100")
+	case reason == "":
+		return nil, fmt.Errorf("reason is required")
+	}
+	if _, err := uc.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	override := &domain.DiscountCardOverride{
+		UserID:          userID,
+		DiscountPercent: math.Round(*req.DiscountPercent*100) / 100,
+		Reason:          reason,
+		SetBy:           &adminID,
+	}
+	if err := uc.discountCardRepo.SetDiscountCardOverride(ctx, override); err != nil {
+		return nil, err
+	}
+	return uc.GetAdminDiscountCard(ctx, userID)
+}
+
+// ClearDiscountOverride gives the member their tier's discount back, recording the change in the audit trail.
+func (uc *LoyaltyUseCase) ClearDiscountOverride(ctx context.Context, userID, adminID int, req *DiscountOverrideClearRequest) error {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	return uc.discountCardRepo.ClearDiscountCardOverride(ctx, userID, adminID, reason)
+}
+
+// discountOverride returns the discount staff set for the member, or nil if there is none.
+func (uc *LoyaltyUseCase) discountOverride(ctx context.Context, userID int) (*domain.DiscountCardOverride, error) {
+	override, err := uc.discountCardRepo.GetDiscountCardOverride(ctx, userID)
+	if err != nil {
+		if err.Error() == "discount card override not found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return override, nil
+}
+
+// memberDiscount is the part of an amount taken off by a discount percent, rounded to cents.
+func memberDiscount(amount, discountPercent float64) float64 {
+	return math.Round(amount*discountPercent) / 100
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type fakeDiscountCardRepo struct {
+	domain.DiscountCardRepository
+	overrides map[int]*domain.DiscountCardOverride
+}
+
+func (r *fakeDiscountCardRepo) GetDiscountCardOverride(ctx context.Context, userID int) (*domain.DiscountCardOverride, error) {
+	if override, ok := r.overrides[userID]; ok {
+		return override, nil
+	}
+	return nil, fmt.Errorf("discount card override not found")
+}
+
+func (r *fakeDiscountCardRepo) SetDiscountCardOverride(ctx context.Context, override *domain.DiscountCardOverride) error {
+	r.overrides[override.UserID] = override
+	return nil
+}
+
+func (r *fakeDiscountCardRepo) GetDiscountCardAudit(ctx context.Context, userID int) ([]*domain.DiscountCardAuditEntry, error) {
+	return nil, nil
+}
+
+// fakeMemberUserRepo finds every member of the user repository it wraps.
+type fakeMemberUserRepo struct {
+	*fakeUserRepo
+}
+
+func (r *fakeMemberUserRepo) GetUserByID(ctx context.Context, id int) (*domain.User, error) {
+	if _, ok := r.loyalties[id]; ok {
+		return &domain.User{}, nil
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func newTestDiscountCardUseCase(tierID, qualifyingPoints int, overrides map[int]*domain.DiscountCardOverride) *LoyaltyUseCase {
+	users := &fakeMemberUserRepo{&fakeUserRepo{
+		loyalties: map[int]*domain.UserLoyalty{1: {UserID: 1, CurrentTierID: tierID}},
+		tiers: map[int]*domain.LoyaltyTier{
+			1: {ID: 1, Name: "Bronze", MinPoints: 0, Benefits: domain.TierBenefits{DiscountPercent: 5}},
+			2: {ID: 2, Name: "Silver", MinPoints: 1000, Benefits: domain.TierBenefits{DiscountPercent: 10}},
+			3: {ID: 3, Name: "Gold", MinPoints: 5000, Benefits: domain.TierBenefits{DiscountPercent: 15}},
+		},
+	}}
+	if overrides == nil {
+		overrides = map[int]*domain.DiscountCardOverride{}
+	}
+	ledger := &fakeQualifyingLedgerRepo{points: qualifyingPoints}
+	return NewLoyaltyUseCase(users, ledger, &fakeDiscountCardRepo{overrides: overrides}, nil, nil, RedemptionPolicy{}, ExpiryPolicy{}, TierPolicy{QualificationMonths: 12})
+}
+
+func TestGetDiscountCard(t *testing.T) {
+	tests := []struct {
+		name             string
+		tierID           int
+		qualifyingPoints int
+		overrides        map[int]*domain.DiscountCardOverride
+		wantLevel        int
+		wantProgress     float64
+		wantToNext       int
+		wantNextTier     string
+		wantDiscount     float64
+		wantOverridden   bool
+	}{
+		{name: "entry tier", tierID: 1, qualifyingPoints: 250, wantLevel: 1, wantProgress: 25, wantToNext: 750, wantNextTier: "Silver", wantDiscount: 5},
+		{name: "progress rounded to a tenth", tierID: 1, qualifyingPoints: 333, wantLevel: 1, wantProgress: 33.3, wantToNext: 667, wantNextTier: "Silver", wantDiscount: 5},
+		{name: "middle tier", tierID: 2, qualifyingPoints: 3000, wantLevel: 2, wantProgress: 50, wantToNext: 2000, wantNextTier: "Gold", wantDiscount: 10},
+		{name: "below the threshold in the grace period", tierID: 2, qualifyingPoints: 400, wantLevel: 2, wantProgress: 0, wantToNext: 4600, wantNextTier: "Gold", wantDiscount: 10},
+		{name: "top tier", tierID: 3, qualifyingPoints: 7000, wantLevel: 3, wantProgress: 100, wantDiscount: 15},
+		{
+			name: "override replaces the tier discount", tierID: 2, qualifyingPoints: 1000,
+			overrides: map[int]*domain.DiscountCardOverride{1: {UserID: 1, DiscountPercent: 30}},
+			wantLevel: 2, wantProgress: 0, wantToNext: 4000, wantNextTier: "Gold", wantDiscount: 30, wantOverridden: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newTestDiscountCardUseCase(tt.tierID, tt.qualifyingPoints, tt.overrides)
+
+			card, err := uc.GetDiscountCard(context.Background(), 1)
+			if err != nil {
+				t.Fatalf("GetDiscountCard() error = %v", err)
+			}
+			if card.DiscountLevel != tt.wantLevel || card.ProgressToNextLevel != tt.wantProgress || card.PointsToNextLevel != tt.wantToNext {
+				t.Errorf("level, progress, points to next = %d, %v, %d, want %d, %v, %d",
+					card.DiscountLevel, card.ProgressToNextLevel, card.PointsToNextLevel, tt.wantLevel, tt.wantProgress, tt.wantToNext)
+			}
+			var nextTier string
+			if card.NextTier != nil {
+				nextTier = card.NextTier.Name
+			}
+			if nextTier != tt.wantNextTier {
+				t.Errorf("next tier = %q, want %q", nextTier, tt.wantNextTier)
+			}
+			if card.DiscountPercent != tt.wantDiscount || card.Overridden != tt.wantOverridden {
+				t.Errorf("discount, overridden = %v, %v, want %v, %v", card.DiscountPercent, card.Overridden, tt.wantDiscount, tt.wantOverridden)
+			}
+		})
+	}
+}
+
+func TestSetDiscountOverride(t *testing.T) {
+	floatPtr := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name         string
+		userID       int
+		req          DiscountOverrideRequest
+		wantDiscount float64
+		wantReason   string
+		wantErr      string
+	}{
+		{name: "override set", userID: 1, req: DiscountOverrideRequest{DiscountPercent: floatPtr(25), Reason: " Wedding party "}, wantDiscount: 25, wantReason: "Wedding party"},
+		{name: "rounded to cents", userID: 1, req: DiscountOverrideRequest{DiscountPercent: floatPtr(12.345), Reason: "VIP"}, wantDiscount: 12.35, wantReason: "VIP"},
+		{name: "no discount", userID: 1, req: DiscountOverrideRequest{Reason: "VIP"}, wantErr: "discount_percent is required"},
+		{name: "negative discount", userID: 1, req: DiscountOverrideRequest{DiscountPercent: floatPtr(-1), Reason: "VIP"}, wantErr: "discount_percent must be between 0 and 100"},
+		{name: "discount above 100", userID: 1, req: DiscountOverrideRequest{DiscountPercent: floatPtr(101), Reason: "VIP"}, wantErr: "discount_percent must be between 0 and 100"},
+		{name: "no reason", userID: 1, req: DiscountOverrideRequest{DiscountPercent: floatPtr(25), Reason: "  "}, wantErr: "reason is required"},
+		{name: "unknown member", userID: 2, req: DiscountOverrideRequest{DiscountPercent: floatPtr(25), Reason: "VIP"}, wantErr: "user not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := newTestDiscountCardUseCase(2, 1000, nil)
+
+			resp, err := uc.SetDiscountOverride(context.Background(), tt.userID, 9, &tt.req)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("SetDiscountOverride() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetDiscountOverride() error = %v", err)
+			}
+			if resp.Card.DiscountPercent != tt.wantDiscount || !resp.Card.Overridden {
+				t.Errorf("card discount, overridden = %v, %v, want %v, true", resp.Card.DiscountPercent, resp.Card.Overridden, tt.wantDiscount)
+			}
+			if resp.Override == nil || resp.Override.SetBy == nil || *resp.Override.SetBy != 9 || resp.Override.Reason != tt.wantReason {
+				t.Errorf("override = %+v, want one set by 9 for %q", resp.Override, tt.wantReason)
+			}
+		})
+	}
+}
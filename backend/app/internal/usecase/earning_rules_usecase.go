@@ -0,0 +1,290 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// EarningPolicy sets the base rate points are earned at.
+type EarningPolicy struct {
+	AmountPerPoint float64 // Money paid for one base point
+}
+
+// EarningRuleUseCase manages the earning rules and works out the points an order earns.
+// An order earns base points on the amount paid, after discounts and points. The member's tier
+// multiplier and every rule that fires then add to the base points; they stack rather than compound.
+type EarningRuleUseCase struct {
+	ruleRepo       domain.EarningRuleRepository
+	productRepo    domain.ProductRepository
+	loyaltyUseCase *LoyaltyUseCase
+	policy         EarningPolicy
+}
+
+func NewEarningRuleUseCase(ruleRepo domain.EarningRuleRepository, productRepo domain.ProductRepository, loyaltyUseCase *LoyaltyUseCase, policy EarningPolicy) *EarningRuleUseCase {
+	return &EarningRuleUseCase{ruleRepo: ruleRepo, productRepo: productRepo, loyaltyUseCase: loyaltyUseCase, policy: policy}
+}
+
+// EarningRuleRequest defines an earning rule. Active defaults to true and the multiplier to 1.
+type EarningRuleRequest struct {
+	Name        string  `json:"name"`
+	Active      *bool   `json:"active,omitempty"`
+	CategoryID  *int    `json:"category_id,omitempty"`
+	ProductID   *int    `json:"product_id,omitempty"`
+	StoreID     *int    `json:"store_id,omitempty"`
+	TierID      *int    `json:"tier_id,omitempty"`
+	DaysOfWeek  []int   `json:"days_of_week,omitempty"`
+	StartsAt    *string `json:"starts_at,omitempty"` // RFC 3339
+	EndsAt      *string `json:"ends_at,omitempty"`
+	MinBasket   float64 `json:"min_basket"`
+	Multiplier  float64 `json:"multiplier"`
+	BonusPoints int     `json:"bonus_points"`
+}
+
+type GetEarningRulesResponse struct {
+	Rules []*domain.EarningRule `json:"rules"`
+}
+
+func (uc *EarningRuleUseCase) GetEarningRules(ctx context.Context) (*GetEarningRulesResponse, error) {
+	rules, err := uc.ruleRepo.GetEarningRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []*domain.EarningRule{}
+	}
+	return &GetEarningRulesResponse{Rules: rules}, nil
+}
+
+// GetMemberOffers returns the earning rules a member shopping in the store can use today.
+func (uc *EarningRuleUseCase) GetMemberOffers(ctx context.Context, userID, storeID int) ([]*domain.EarningRule, error) {
+	now := time.Now()
+	rules, err := uc.ruleRepo.GetActiveEarningRules(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	tier, err := uc.loyaltyUseCase.memberTier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tierID := 0
+	if tier != nil {
+		tierID = tier.ID
+	}
+
+	offers := []*domain.EarningRule{}
+	for _, rule := range rules {
+		if ruleAppliesToMember(rule, &storeID, tierID, now) {
+			offers = append(offers, rule)
+		}
+	}
+	return offers, nil
+}
+
+// CreateEarningRule adds a rule. It applies from the next order.
+func (uc *EarningRuleUseCase) CreateEarningRule(ctx context.Context, req *EarningRuleRequest) (*domain.EarningRule, error) {
+	rule, err := ruleFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.ruleRepo.CreateEarningRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateEarningRule changes a rule. Orders already placed keep the points they earned.
+func (uc *EarningRuleUseCase) UpdateEarningRule(ctx context.Context, ruleID int, req *EarningRuleRequest) (*domain.EarningRule, error) {
+	rule, err := ruleFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	rule.ID = ruleID
+	if err := uc.ruleRepo.UpdateEarningRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteEarningRule removes a rule. Breakdowns of past orders keep its name.
+func (uc *EarningRuleUseCase) DeleteEarningRule(ctx context.Context, ruleID int) error {
+	return uc.ruleRepo.DeleteEarningRule(ctx, ruleID)
+}
+
+// earningLine is an order line as the earning rules see it.
+type earningLine struct {
+	productID  int
+	categoryID int
+	basePoints float64 // Unrounded, so that rules on several lines do not lose points to rounding
+}
+
+// CalculateOrderPoints works out the points the order earns, line by line of the breakdown shown to the customer.
+// The order must carry its items.
+func (uc *EarningRuleUseCase) CalculateOrderPoints(ctx context.Context, order *domain.Order) ([]*domain.EarnedPoints, error) {
+	paid := order.TotalAmount - order.TierDiscount - order.PointsDiscount
+	if paid <= 0 || order.TotalAmount <= 0 || uc.policy.AmountPerPoint <= 0 {
+		return nil, nil
+	}
+	userID, err := strconv.Atoi(order.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	// Discounts and the part paid with points are spread over the lines by their value
+	share := paid / order.TotalAmount
+	lines := make([]earningLine, len(order.Items))
+	var base float64
+	for i, item := range order.Items {
+		productID, err := strconv.Atoi(item.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid product ID format: %w", err)
+		}
+		product, err := uc.productRepo.GetProductByID(ctx, productID)
+		if err != nil || product == nil {
+			return nil, fmt.Errorf("product with ID %s not found: %w", item.ProductID, err)
+		}
+		lines[i] = earningLine{
+			productID:  productID,
+			categoryID: product.CategoryID,
+			basePoints: item.Price * float64(item.Quantity) * share / uc.policy.AmountPerPoint,
+		}
+		base += lines[i].basePoints
+	}
+
+	breakdown := []*domain.EarnedPoints{{Description: "Base points", Points: int(base)}}
+
+	tier, err := uc.loyaltyUseCase.memberTier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tierID := 0
+	if tier != nil {
+		tierID = tier.ID
+		if m := tier.Benefits.PointsMultiplier; m != 1 {
+			breakdown = append(breakdown, &domain.EarnedPoints{
+				Description: fmt.Sprintf("%s tier ×%g", tier.Name, m),
+				Points:      int(base * (m - 1)),
+			})
+		}
+	}
+
+	now := time.Now()
+	rules, err := uc.ruleRepo.GetActiveEarningRules(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if !ruleMatchesOrder(rule, order, tierID, now) {
+			continue
+		}
+		var matched float64
+		matches := false
+		for _, line := range lines {
+			if (rule.CategoryID != nil && *rule.CategoryID != line.categoryID) || (rule.ProductID != nil && *rule.ProductID != line.productID) {
+				continue
+			}
+			matched += line.basePoints
+			matches = true
+		}
+		if !matches {
+			continue
+		}
+		if points := int(matched*(rule.Multiplier-1)) + rule.BonusPoints; points > 0 {
+			breakdown = append(breakdown, &domain.EarnedPoints{RuleID: &rule.ID, Description: rule.Name, Points: points})
+		}
+	}
+
+	earned := breakdown[:0]
+	for _, line := range breakdown {
+		if line.Points != 0 {
+			earned = append(earned, line)
+		}
+	}
+	return earned, nil
+}
+
+// ruleMatchesOrder checks the conditions of the rule that concern the order as a whole.
+func ruleMatchesOrder(rule *domain.EarningRule, order *domain.Order, tierID int, now time.Time) bool {
+	return order.TotalAmount >= rule.MinBasket && ruleAppliesToMember(rule, order.StoreID, tierID, now)
+}
+
+// ruleAppliesToMember reports whether the rule is on for a member of the tier buying in the store, whatever they buy.
+func ruleAppliesToMember(rule *domain.EarningRule, storeID *int, tierID int, now time.Time) bool {
+	if rule.StoreID != nil && (storeID == nil || *storeID != *rule.StoreID) {
+		return false
+	}
+	if rule.TierID != nil && *rule.TierID != tierID {
+		return false
+	}
+	if len(rule.DaysOfWeek) == 0 {
+		return true
+	}
+	for _, day := range rule.DaysOfWeek {
+		if time.Weekday(day) == now.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleFromRequest(req *EarningRuleRequest) (*domain.EarningRule, error) {
+	rule := &domain.EarningRule{
+		Name:        strings.TrimSpace(req.Name),
+		Active:      req.Active == nil || *req.Active,
+		CategoryID:  req.CategoryID,
+		ProductID:   req.ProductID,
+		StoreID:     req.StoreID,
+		TierID:      req.TierID,
+		DaysOfWeek:  req.DaysOfWeek,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		MinBasket:   req.MinBasket,
+		Multiplier:  req.Multiplier,
+		BonusPoints: req.BonusPoints,
+	}
+	if rule.Multiplier == 0 {
+		rule.Multiplier = 1
+	}
+	if rule.DaysOfWeek == nil {
+		rule.DaysOfWeek = []int{}
+	}
+
+	switch {
+	case rule.Name == "":
+		return nil, fmt.Errorf("name is required")
+	case rule.MinBasket < 0:
+		return nil, fmt.Errorf("min_basket cannot be negative")
+	case rule.Multiplier < 1 || rule.Multiplier > 10:
+		return nil, fmt.Errorf("multiplier must be between 1 and 10")
+	case rule.BonusPoints < 0:
+		return nil, fmt.Errorf("bonus_points cannot be negative")
+	case rule.Multiplier == 1 && rule.BonusPoints == 0:
+		return nil, fmt.Errorf("rule must set a multiplier above 1 or bonus_points")
+	}
+	for _, day := range rule.DaysOfWeek {
+		if day < 0 || day > 6 {
+			return nil, fmt.Errorf("days_of_week must be between 0 (Sunday) and 6 (Saturday)")
+		}
+	}
+
+	var startsAt, endsAt time.Time
+	var err error
+	if rule.StartsAt != nil {
+		if startsAt, err = time.Parse(time.RFC3339, *rule.StartsAt); err != nil {
+			return nil, fmt.Errorf("starts_at must be an RFC 3339 time")
+		}
+	}
+	if rule.EndsAt != nil {
+		if endsAt, err = time.Parse(time.RFC3339, *rule.EndsAt); err != nil {
+			return nil, fmt.Errorf("ends_at must be an RFC 3339 time")
+		}
+		if rule.StartsAt != nil && !endsAt.After(startsAt) {
+			return nil, fmt.Errorf("ends_at must be after starts_at")
+		}
+	}
+	return rule, nil
+}
@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// The fakes embed their interface, so a call to anything they do not implement panics.
+
+type fakeEarningRuleRepo struct {
+	domain.EarningRuleRepository
+	rules []*domain.EarningRule
+}
+
+func (r *fakeEarningRuleRepo) GetActiveEarningRules(ctx context.Context, at time.Time) ([]*domain.EarningRule, error) {
+	return r.rules, nil
+}
+
+type fakeProductRepo struct {
+	domain.ProductRepository
+	products map[int]*domain.Product
+}
+
+func (r *fakeProductRepo) GetProductByID(ctx context.Context, id int) (*domain.Product, error) {
+	if product, ok := r.products[id]; ok {
+		return product, nil
+	}
+	return nil, fmt.Errorf("product not found")
+}
+
+type fakeUserRepo struct {
+	domain.UserRepository
+	loyalties map[int]*domain.UserLoyalty
+	tiers     map[int]*domain.LoyaltyTier
+}
+
+func (r *fakeUserRepo) GetUserLoyalty(ctx context.Context, userID int) (*domain.UserLoyalty, error) {
+	if loyalty, ok := r.loyalties[userID]; ok {
+		return loyalty, nil
+	}
+	return nil, fmt.Errorf("user loyalty not found")
+}
+
+func (r *fakeUserRepo) GetLoyaltyTierByID(ctx context.Context, id int) (*domain.LoyaltyTier, error) {
+	if tier, ok := r.tiers[id]; ok {
+		return tier, nil
+	}
+	return nil, fmt.Errorf("loyalty tier not found")
+}
+
+func TestCalculateOrderPoints(t *testing.T) {
+	const (
+		memberID = 1 // Gold, earning half as much again
+		guestID  = 2 // No tier
+		goldID   = 3
+	)
+	intPtr := func(v int) *int { return &v }
+	today := int(time.Now().Weekday())
+
+	userRepo := &fakeUserRepo{
+		loyalties: map[int]*domain.UserLoyalty{memberID: {UserID: memberID, CurrentTierID: goldID}},
+		tiers:     map[int]*domain.LoyaltyTier{goldID: {ID: goldID, Name: "Gold", Benefits: domain.TierBenefits{PointsMultiplier: 1.5}}},
+	}
+	productRepo := &fakeProductRepo{products: map[int]*domain.Product{
+		1: {ID: 1, CategoryID: 10, Price: 100},
+		2: {ID: 2, CategoryID: 20, Price: 50},
+	}}
+	// 200 of suits and 50 of accessories earn 20 and 5 base points at 10 per point
+	order := func(userID int, storeID *int, tierDiscount, pointsDiscount float64) *domain.Order {
+		return &domain.Order{
+			UserID:         fmt.Sprint(userID),
+			TotalAmount:    250,
+			TierDiscount:   tierDiscount,
+			PointsDiscount: pointsDiscount,
+			StoreID:        storeID,
+			Items: []domain.OrderItem{
+				{ProductID: "1", Quantity: 2, Price: 100},
+				{ProductID: "2", Quantity: 1, Price: 50},
+			},
+		}
+	}
+
+	type line struct {
+		Description string
+		Points      int
+	}
+	tests := []struct {
+		name  string
+		rules []*domain.EarningRule
+		order *domain.Order
+		want  []line
+	}{
+		{
+			name:  "base points only",
+			order: order(guestID, nil, 0, 0),
+			want:  []line{{"Base points", 25}},
+		},
+		{
+			name:  "tier multiplier",
+			order: order(memberID, nil, 0, 0),
+			want:  []line{{"Base points", 25}, {"Gold tier ×1.5", 12}},
+		},
+		{
+			name:  "discounts and points are not earned on",
+			order: order(guestID, nil, 25, 25),
+			want:  []line{{"Base points", 20}},
+		},
+		{
+			name:  "order paid in full with points",
+			order: order(guestID, nil, 0, 250),
+			want:  nil,
+		},
+		{
+			name:  "category rule multiplies its lines",
+			rules: []*domain.EarningRule{{ID: 1, Name: "Double suits", CategoryID: intPtr(10), Multiplier: 2}},
+			order: order(guestID, nil, 0, 0),
+			want:  []line{{"Base points", 25}, {"Double suits", 20}},
+		},
+		{
+			name:  "product rule with a bonus",
+			rules: []*domain.EarningRule{{ID: 1, Name: "Tie bonus", ProductID: intPtr(2), Multiplier: 3, BonusPoints: 15}},
+			order: order(guestID, nil, 0, 0),
+			want:  []line{{"Base points", 25}, {"Tie bonus", 25}},
+		},
+		{
+			name:  "rule for a category not in the order",
+			rules: []*domain.EarningRule{{ID: 1, Name: "Shoes", CategoryID: intPtr(30), Multiplier: 2, BonusPoints: 100}},
+			order: order(guestID, nil, 0, 0),
+			want:  []line{{"Base points", 25}},
+		},
+		{
+			name:  "basket below the minimum",
+			rules: []*domain.EarningRule{{ID: 1, Name: "Big basket", MinBasket: 300, Multiplier: 1, BonusPoints: 50}},
+			order: order(guestID, nil, 0, 0),
+			want:  []line{{"Base points", 25}},
+		},
+		{
+			name:  "basket minimum counts the total before discounts",
+			rules: []*domain.EarningRule{{ID: 1, Name: "Big basket", MinBasket: 250, Multiplier: 1, BonusPoints: 50}},
+			order: order(guestID, nil, 50, 0),
+			want:  []line{{"Base points", 20}, {"Big basket", 50}},
+		},
+		{
+			name: "store rule",
+			rules: []*domain.EarningRule{
+				{ID: 1, Name: "Flagship", StoreID: intPtr(1), Multiplier: 1, BonusPoints: 10},
+				{ID: 2, Name: "Outlet", StoreID: intPtr(2), Multiplier: 1, BonusPoints: 10},
+			},
+			order: order(guestID, intPtr(1), 0, 0),
+			want:  []line{{"Base points", 25}, {"Flagship", 10}},
+		},
+		{
+			name:  "store rule on an online order",
+			rules: []*domain.EarningRule{{ID: 1, Name: "Flagship", StoreID: intPtr(1), Multiplier: 1, BonusPoints: 10}},
+			order: order(guestID, nil, 0, 0),
+			want:  []line{{"Base points", 25}},
+		},
+		{
+			name:  "tier rule for a member of the tier",
+			rules: []*domain.EarningRule{{ID: 1, Name: "Gold week", TierID: intPtr(goldID), Multiplier: 2}},
+			order: order(memberID, nil, 0, 0),
+			want:  []line{{"Base points", 25}, {"Gold tier ×1.5", 12}, {"Gold week", 25}},
+		},
+		{
+			name:  "tier rule for someone else",
+			rules: []*domain.EarningRule{{ID: 1, Name: "Gold week", TierID: intPtr(goldID), Multiplier: 2}},
+			order: order(guestID, nil, 0, 0),
+			want:  []line{{"Base points", 25}},
+		},
+		{
+			name: "days of the week",
+			rules: []*domain.EarningRule{
+				{ID: 1, Name: "Today", DaysOfWeek: []int{today}, Multiplier: 1, BonusPoints: 5},
+				{ID: 2, Name: "Tomorrow", DaysOfWeek: []int{(today + 1) % 7}, Multiplier: 1, BonusPoints: 5},
+			},
+			order: order(guestID, nil, 0, 0),
+			want:  []line{{"Base points", 25}, {"Today", 5}},
+		},
+		{
+			name: "rules stack rather than compound",
+			rules: []*domain.EarningRule{
+				{ID: 1, Name: "Double everything", Multiplier: 2},
+				{ID: 2, Name: "Double suits", CategoryID: intPtr(10), Multiplier: 2},
+			},
+			order: order(memberID, nil, 0, 0),
+			want:  []line{{"Base points", 25}, {"Gold tier ×1.5", 12}, {"Double everything", 25}, {"Double suits", 20}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := NewEarningRuleUseCase(&fakeEarningRuleRepo{rules: tt.rules}, productRepo, &LoyaltyUseCase{userRepo: userRepo}, EarningPolicy{AmountPerPoint: 10})
+			breakdown, err := uc.CalculateOrderPoints(context.Background(), tt.order)
+			if err != nil {
+				t.Fatalf("CalculateOrderPoints() error = %v", err)
+			}
+			var got []line
+			for _, earned := range breakdown {
+				got = append(got, line{earned.Description, earned.Points})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CalculateOrderPoints() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleFromRequest(t *testing.T) {
+	strPtr := func(v string) *string { return &v }
+	inactive := false
+
+	tests := []struct {
+		name    string
+		req     EarningRuleRequest
+		want    *domain.EarningRule
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			req:  EarningRuleRequest{Name: "  Bonus  ", BonusPoints: 50},
+			want: &domain.EarningRule{Name: "Bonus", Active: true, DaysOfWeek: []int{}, Multiplier: 1, BonusPoints: 50},
+		},
+		{
+			name: "inactive with a multiplier and dates",
+			req: EarningRuleRequest{Name: "Weekend", Active: &inactive, DaysOfWeek: []int{0, 6}, Multiplier: 2,
+				StartsAt: strPtr("2024-05-01T00:00:00Z"), EndsAt: strPtr("2024-06-01T00:00:00Z")},
+			want: &domain.EarningRule{Name: "Weekend", DaysOfWeek: []int{0, 6}, Multiplier: 2,
+				StartsAt: strPtr("2024-05-01T00:00:00Z"), EndsAt: strPtr("2024-06-01T00:00:00Z")},
+		},
+		{name: "no name", req: EarningRuleRequest{Name: " ", BonusPoints: 50}, wantErr: "name is required"},
+		{name: "negative basket", req: EarningRuleRequest{Name: "R", MinBasket: -1, BonusPoints: 50}, wantErr: "min_basket cannot be negative"},
+		{name: "multiplier below 1", req: EarningRuleRequest{Name: "R", Multiplier: 0.5}, wantErr: "multiplier must be between 1 and 10"},
+		{name: "multiplier above 10", req: EarningRuleRequest{Name: "R", Multiplier: 11}, wantErr: "multiplier must be between 1 and 10"},
+		{name: "negative bonus", req: EarningRuleRequest{Name: "R", Multiplier: 2, BonusPoints: -1}, wantErr: "bonus_points cannot be negative"},
+		{name: "rule that awards nothing", req: EarningRuleRequest{Name: "R"}, wantErr: "rule must set a multiplier above 1 or bonus_points"},
+		{name: "unknown day", req: EarningRuleRequest{Name: "R", BonusPoints: 5, DaysOfWeek: []int{7}}, wantErr: "days_of_week must be between 0 (Sunday) and 6 (Saturday)"},
+		{name: "bad start", req: EarningRuleRequest{Name: "R", BonusPoints: 5, StartsAt: strPtr("2024-05-01")}, wantErr: "starts_at must be an RFC 3339 time"},
+		{name: "bad end", req: EarningRuleRequest{Name: "R", BonusPoints: 5, EndsAt: strPtr("tomorrow")}, wantErr: "ends_at must be an RFC 3339 time"},
+		{
+			name:    "end before start",
+			req:     EarningRuleRequest{Name: "R", BonusPoints: 5, StartsAt: strPtr("2024-06-01T00:00:00Z"), EndsAt: strPtr("2024-06-01T00:00:00Z")},
+			wantErr: "ends_at must be after starts_at",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ruleFromRequest(&tt.req)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ruleFromRequest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ruleFromRequest() error = %v", err)
+			}
+			if !reflect.DeepEqual(rule, tt.want) {
+				t.Errorf("ruleFromRequest() = %+v, want %+v", rule, tt.want)
+			}
+		})
+	}
+}
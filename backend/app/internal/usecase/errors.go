@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// RetryAfterError is returned when a request is refused until some time has passed.
+type RetryAfterError struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Message
+}
+
+// ConsentRequiredError is returned when an action needs a consent the user has not given.
+type ConsentRequiredError struct {
+	Purpose domain.ConsentPurpose
+}
+
+func (e *ConsentRequiredError) Error() string {
+	return fmt.Sprintf("user has not consented to %s", e.Purpose)
+}
+
+// EarlyAccessError is returned when a promotion is sent before its release to a member without early access.
+type EarlyAccessError struct {
+	Until string
+}
+
+func (e *EarlyAccessError) Error() string {
+	return fmt.Sprintf("promotion is only for members with early access until %s", e.Until)
+}
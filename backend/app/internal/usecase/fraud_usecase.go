@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// FraudPolicy sets when the fraud rules flag a user. A rule with a zero threshold is turned off.
+type FraudPolicy struct {
+	LookbackDays           int // How far back the rules look; a reviewed flag is not raised again for as long
+	SignupWindowHours      int // Sign-ups this recent are compared by phone number prefix
+	PhonePrefixLength      int
+	AccountsPerPhonePrefix int
+	AccountsPerDevice      int
+	EarnRedeemHours        int // A redemption this soon after earning points counts as a cycle
+	EarnRedeemCycles       int
+	ReturnsAfterEarning    int // Cancelled orders that had earned points
+	StaffAdjustmentPoints  int // Points a staff member adjusts in total, in either direction
+}
+
+// FraudUseCase flags loyalty abuse by rule and lets staff review the flags.
+type FraudUseCase struct {
+	fraudRepo      domain.FraudRepository
+	loyaltyUseCase *LoyaltyUseCase
+	policy         FraudPolicy
+}
+
+func NewFraudUseCase(fraudRepo domain.FraudRepository, loyaltyUseCase *LoyaltyUseCase, policy FraudPolicy) *FraudUseCase {
+	return &FraudUseCase{fraudRepo: fraudRepo, loyaltyUseCase: loyaltyUseCase, policy: policy}
+}
+
+// ReviewFraudFlagRequest confirms or dismisses a flag. Confirming can also freeze the flagged user's points.
+type ReviewFraudFlagRequest struct {
+	Note         string `json:"note"`
+	FreezePoints bool   `json:"freeze_points"` // Only when confirming
+}
+
+type GetFraudFlagsResponse struct {
+	Flags []*domain.FraudFlag `json:"flags"`
+}
+
+// DetectFraud runs every fraud rule and queues a flag for each user a rule matches.
+func (uc *FraudUseCase) DetectFraud(ctx context.Context) error {
+	now := time.Now()
+	since := now.AddDate(0, 0, -uc.policy.LookbackDays)
+	signupsSince := now.Add(-time.Duration(uc.policy.SignupWindowHours) * time.Hour)
+
+	rules := []struct {
+		rule    domain.FraudRule
+		enabled bool
+		find    func() ([]*domain.FraudFinding, error)
+	}{
+		{domain.FraudSharedPhonePrefix, uc.policy.PhonePrefixLength > 0 && uc.policy.AccountsPerPhonePrefix > 0, func() ([]*domain.FraudFinding, error) {
+			return uc.fraudRepo.FindSharedPhonePrefixes(ctx, uc.policy.PhonePrefixLength, uc.policy.AccountsPerPhonePrefix, signupsSince)
+		}},
+		{domain.FraudSharedDevice, uc.policy.AccountsPerDevice > 0, func() ([]*domain.FraudFinding, error) {
+			return uc.fraudRepo.FindSharedDevices(ctx, uc.policy.AccountsPerDevice, since)
+		}},
+		{domain.FraudEarnRedeemCycle, uc.policy.EarnRedeemHours > 0 && uc.policy.EarnRedeemCycles > 0, func() ([]*domain.FraudFinding, error) {
+			return uc.fraudRepo.FindEarnRedeemCycles(ctx, uc.policy.EarnRedeemHours, uc.policy.EarnRedeemCycles, since)
+		}},
+		{domain.FraudReturnsAfterEarning, uc.policy.ReturnsAfterEarning > 0, func() ([]*domain.FraudFinding, error) {
+			return uc.fraudRepo.FindReturnsAfterEarning(ctx, uc.policy.ReturnsAfterEarning, since)
+		}},
+		{domain.FraudStaffAdjustments, uc.policy.StaffAdjustmentPoints > 0, func() ([]*domain.FraudFinding, error) {
+			return uc.fraudRepo.FindStaffAdjustments(ctx, uc.policy.StaffAdjustmentPoints, since)
+		}},
+	}
+
+	for _, r := range rules {
+		if !r.enabled {
+			continue
+		}
+		findings, err := r.find()
+		if err != nil {
+			return err
+		}
+		flagged := 0
+		for _, finding := range findings {
+			flag := &domain.FraudFlag{Rule: r.rule, UserID: finding.UserID, Details: finding.Details}
+			created, err := uc.fraudRepo.CreateFraudFlag(ctx, flag, since)
+			if err != nil {
+				return err
+			}
+			if created {
+				flagged++
+			}
+		}
+		if flagged > 0 {
+			log.Printf("Fraud rule %s flagged %d users", r.rule, flagged)
+		}
+	}
+	return nil
+}
+
+// GetFraudFlags returns the review queue, optionally only the flags with the status.
+func (uc *FraudUseCase) GetFraudFlags(ctx context.Context, status domain.FraudFlagStatus) (*GetFraudFlagsResponse, error) {
+	switch status {
+	case "", domain.FraudFlagOpen, domain.FraudFlagDismissed, domain.FraudFlagConfirmed:
+	default:
+		return nil, fmt.Errorf("invalid status: %s", status)
+	}
+	flags, err := uc.fraudRepo.GetFraudFlags(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	if flags == nil {
+		flags = []*domain.FraudFlag{}
+	}
+	return &GetFraudFlagsResponse{Flags: flags}, nil
+}
+
+func (uc *FraudUseCase) GetFraudFlag(ctx context.Context, flagID int) (*domain.FraudFlag, error) {
+	return uc.fraudRepo.GetFraudFlagByID(ctx, flagID)
+}
+
+// ConfirmFraudFlag marks the flag as abuse and, if asked, freezes the flagged user's points with the note as the reason.
+func (uc *FraudUseCase) ConfirmFraudFlag(ctx context.Context, flagID, reviewerID int, req *ReviewFraudFlagRequest) (*domain.FraudFlag, error) {
+	flag, err := uc.review(ctx, flagID, reviewerID, domain.FraudFlagConfirmed, req.Note)
+	if err != nil {
+		return nil, err
+	}
+	if req.FreezePoints {
+		if err := uc.loyaltyUseCase.freezePoints(ctx, flag.UserID, reviewerID, &PointFreezeRequest{Reason: flag.ReviewNote}, &flag.ID); err != nil {
+			return nil, err
+		}
+	}
+	return flag, nil
+}
+
+// DismissFraudFlag marks the flag as harmless. The rule does not flag the user again until the lookback has passed.
+func (uc *FraudUseCase) DismissFraudFlag(ctx context.Context, flagID, reviewerID int, req *ReviewFraudFlagRequest) (*domain.FraudFlag, error) {
+	if req.FreezePoints {
+		return nil, fmt.Errorf("points can only be frozen when confirming a flag")
+	}
+	return uc.review(ctx, flagID, reviewerID, domain.FraudFlagDismissed, req.Note)
+}
+
+// review closes an open flag. Staff cannot review flags raised on themselves.
+func (uc *FraudUseCase) review(ctx context.Context, flagID, reviewerID int, status domain.FraudFlagStatus, note string) (*domain.FraudFlag, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, fmt.Errorf("note is required")
+	}
+	flag, err := uc.fraudRepo.GetFraudFlagByID(ctx, flagID)
+	if err != nil {
+		return nil, err
+	}
+	if flag.UserID == reviewerID {
+		return nil, fmt.Errorf("flags must be reviewed by someone other than the flagged user")
+	}
+	flag.Status = status
+	flag.ReviewedBy = &reviewerID
+	flag.ReviewNote = note
+	if err := uc.fraudRepo.ReviewFraudFlag(ctx, flag); err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
@@ -0,0 +1,249 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// fraudQuery is a rule query the fake fraud repository was asked to run.
+type fraudQuery struct {
+	thresholds []int
+	since      time.Time
+}
+
+type fakeFraudRepo struct {
+	domain.FraudRepository
+	findings map[domain.FraudRule][]*domain.FraudFinding
+	flagged  map[string]bool // Open or recently reviewed flags, by rule and user
+	queries  map[domain.FraudRule]fraudQuery
+	created  []*domain.FraudFlag
+	flags    map[int]*domain.FraudFlag
+}
+
+func (r *fakeFraudRepo) find(rule domain.FraudRule, since time.Time, thresholds ...int) ([]*domain.FraudFinding, error) {
+	if r.queries == nil {
+		r.queries = map[domain.FraudRule]fraudQuery{}
+	}
+	r.queries[rule] = fraudQuery{thresholds: thresholds, since: since}
+	return r.findings[rule], nil
+}
+
+func (r *fakeFraudRepo) FindSharedPhonePrefixes(ctx context.Context, prefixLength, minAccounts int, since time.Time) ([]*domain.FraudFinding, error) {
+	return r.find(domain.FraudSharedPhonePrefix, since, prefixLength, minAccounts)
+}
+
+func (r *fakeFraudRepo) FindSharedDevices(ctx context.Context, minAccounts int, since time.Time) ([]*domain.FraudFinding, error) {
+	return r.find(domain.FraudSharedDevice, since, minAccounts)
+}
+
+func (r *fakeFraudRepo) FindEarnRedeemCycles(ctx context.Context, withinHours, minCycles int, since time.Time) ([]*domain.FraudFinding, error) {
+	return r.find(domain.FraudEarnRedeemCycle, since, withinHours, minCycles)
+}
+
+func (r *fakeFraudRepo) FindReturnsAfterEarning(ctx context.Context, minReturns int, since time.Time) ([]*domain.FraudFinding, error) {
+	return r.find(domain.FraudReturnsAfterEarning, since, minReturns)
+}
+
+func (r *fakeFraudRepo) FindStaffAdjustments(ctx context.Context, minPoints int, since time.Time) ([]*domain.FraudFinding, error) {
+	return r.find(domain.FraudStaffAdjustments, since, minPoints)
+}
+
+func (r *fakeFraudRepo) CreateFraudFlag(ctx context.Context, flag *domain.FraudFlag, reviewedSince time.Time) (bool, error) {
+	if r.flagged[fmt.Sprintf("%s:%d", flag.Rule, flag.UserID)] {
+		return false, nil
+	}
+	r.created = append(r.created, flag)
+	return true, nil
+}
+
+func (r *fakeFraudRepo) GetFraudFlagByID(ctx context.Context, flagID int) (*domain.FraudFlag, error) {
+	if stored, ok := r.flags[flagID]; ok {
+		flag := *stored
+		return &flag, nil
+	}
+	return nil, fmt.Errorf("fraud flag not found")
+}
+
+func (r *fakeFraudRepo) ReviewFraudFlag(ctx context.Context, flag *domain.FraudFlag) error {
+	if r.flags[flag.ID].Status != domain.FraudFlagOpen {
+		return fmt.Errorf("fraud flag is not open")
+	}
+	stored := *flag
+	r.flags[flag.ID] = &stored
+	return nil
+}
+
+func TestDetectFraud(t *testing.T) {
+	const lookbackDays, signupHours = 30, 48
+	tests := []struct {
+		name           string
+		policy         FraudPolicy
+		rule           domain.FraudRule
+		wantThresholds []int
+		signups        bool // The rule looks at recent sign-ups rather than the lookback
+	}{
+		{name: "shared phone prefix", policy: FraudPolicy{PhonePrefixLength: 8, AccountsPerPhonePrefix: 5}, rule: domain.FraudSharedPhonePrefix, wantThresholds: []int{8, 5}, signups: true},
+		{name: "shared device", policy: FraudPolicy{AccountsPerDevice: 4}, rule: domain.FraudSharedDevice, wantThresholds: []int{4}},
+		{name: "earn and redeem cycles", policy: FraudPolicy{EarnRedeemHours: 24, EarnRedeemCycles: 3}, rule: domain.FraudEarnRedeemCycle, wantThresholds: []int{24, 3}},
+		{name: "returns after earning", policy: FraudPolicy{ReturnsAfterEarning: 3}, rule: domain.FraudReturnsAfterEarning, wantThresholds: []int{3}},
+		{name: "staff adjustments", policy: FraudPolicy{StaffAdjustmentPoints: 20000}, rule: domain.FraudStaffAdjustments, wantThresholds: []int{20000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy
+			policy.LookbackDays, policy.SignupWindowHours = lookbackDays, signupHours
+			repo := &fakeFraudRepo{
+				findings: map[domain.FraudRule][]*domain.FraudFinding{tt.rule: {{UserID: 5, Details: "found"}, {UserID: 6, Details: "found"}}},
+				flagged:  map[string]bool{fmt.Sprintf("%s:6", tt.rule): true},
+			}
+			uc := NewFraudUseCase(repo, nil, policy)
+
+			if err := uc.DetectFraud(context.Background()); err != nil {
+				t.Fatalf("DetectFraud() error = %v", err)
+			}
+			if len(repo.queries) != 1 {
+				t.Fatalf("ran %d rules, want only %s", len(repo.queries), tt.rule)
+			}
+			query, ok := repo.queries[tt.rule]
+			if !ok {
+				t.Fatalf("rule %s not run", tt.rule)
+			}
+			if !reflect.DeepEqual(query.thresholds, tt.wantThresholds) {
+				t.Errorf("thresholds = %v, want %v", query.thresholds, tt.wantThresholds)
+			}
+			wantSince := time.Now().AddDate(0, 0, -lookbackDays)
+			if tt.signups {
+				wantSince = time.Now().Add(-signupHours * time.Hour)
+			}
+			if query.since.Sub(wantSince).Abs() > time.Minute {
+				t.Errorf("looked back to %v, want %v", query.since, wantSince)
+			}
+			// User 6 is flagged already
+			want := []*domain.FraudFlag{{Rule: tt.rule, UserID: 5, Details: "found"}}
+			if !reflect.DeepEqual(repo.created, want) {
+				t.Errorf("created flags = %+v, want %+v", repo.created, want)
+			}
+		})
+	}
+}
+
+func TestDetectFraudRulesTurnedOff(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy FraudPolicy
+	}{
+		{name: "every threshold zero", policy: FraudPolicy{LookbackDays: 30, SignupWindowHours: 48}},
+		{name: "phone prefix without a length", policy: FraudPolicy{LookbackDays: 30, AccountsPerPhonePrefix: 5}},
+		{name: "phone prefix without an account count", policy: FraudPolicy{LookbackDays: 30, PhonePrefixLength: 8}},
+		{name: "cycles without a window", policy: FraudPolicy{LookbackDays: 30, EarnRedeemCycles: 3}},
+		{name: "window without a cycle count", policy: FraudPolicy{LookbackDays: 30, EarnRedeemHours: 24}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeFraudRepo{}
+			uc := NewFraudUseCase(repo, nil, tt.policy)
+
+			if err := uc.DetectFraud(context.Background()); err != nil {
+				t.Fatalf("DetectFraud() error = %v", err)
+			}
+			if len(repo.queries) != 0 {
+				t.Errorf("ran rules %v, want none", repo.queries)
+			}
+		})
+	}
+}
+
+type fakeFreezingRepo struct {
+	domain.PointFreezeRepository
+	freezes []*domain.PointFreeze
+}
+
+func (r *fakeFreezingRepo) FreezePoints(ctx context.Context, freeze *domain.PointFreeze) error {
+	r.freezes = append(r.freezes, freeze)
+	return nil
+}
+
+func TestReviewFraudFlag(t *testing.T) {
+	const (
+		flaggedID  = 5
+		reviewerID = 9
+	)
+	tests := []struct {
+		name       string
+		confirm    bool
+		reviewerID int
+		status     domain.FraudFlagStatus
+		req        ReviewFraudFlagRequest
+		wantStatus domain.FraudFlagStatus
+		wantFrozen bool
+		wantErr    string
+	}{
+		{name: "confirmed", confirm: true, reviewerID: reviewerID, status: domain.FraudFlagOpen, req: ReviewFraudFlagRequest{Note: "Same person"}, wantStatus: domain.FraudFlagConfirmed},
+		{
+			name: "confirmed and frozen", confirm: true, reviewerID: reviewerID, status: domain.FraudFlagOpen,
+			req: ReviewFraudFlagRequest{Note: " Same person ", FreezePoints: true}, wantStatus: domain.FraudFlagConfirmed, wantFrozen: true,
+		},
+		{name: "dismissed", reviewerID: reviewerID, status: domain.FraudFlagOpen, req: ReviewFraudFlagRequest{Note: "Family members"}, wantStatus: domain.FraudFlagDismissed},
+		{
+			name: "dismissed and frozen", reviewerID: reviewerID, status: domain.FraudFlagOpen,
+			req: ReviewFraudFlagRequest{Note: "Family members", FreezePoints: true}, wantErr: "points can only be frozen when confirming a flag",
+		},
+		{name: "without a note", confirm: true, reviewerID: reviewerID, status: domain.FraudFlagOpen, req: ReviewFraudFlagRequest{Note: "  "}, wantErr: "note is required"},
+		{
+			name: "reviewed by the flagged user", confirm: true, reviewerID: flaggedID, status: domain.FraudFlagOpen,
+			req: ReviewFraudFlagRequest{Note: "Not me"}, wantErr: "flags must be reviewed by someone other than the flagged user",
+		},
+		{name: "already reviewed", reviewerID: reviewerID, status: domain.FraudFlagConfirmed, req: ReviewFraudFlagRequest{Note: "Family members"}, wantErr: "fraud flag is not open"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeFraudRepo{flags: map[int]*domain.FraudFlag{1: {ID: 1, Rule: domain.FraudSharedDevice, UserID: flaggedID, Status: tt.status}}}
+			freezes := &fakeFreezingRepo{}
+			users := &fakeMemberUserRepo{&fakeUserRepo{loyalties: map[int]*domain.UserLoyalty{flaggedID: {UserID: flaggedID}}}}
+			loyaltyUseCase := NewLoyaltyUseCase(users, nil, nil, freezes, nil, RedemptionPolicy{}, ExpiryPolicy{}, TierPolicy{})
+			uc := NewFraudUseCase(repo, loyaltyUseCase, FraudPolicy{})
+
+			var flag *domain.FraudFlag
+			var err error
+			if tt.confirm {
+				flag, err = uc.ConfirmFraudFlag(context.Background(), 1, tt.reviewerID, &tt.req)
+			} else {
+				flag, err = uc.DismissFraudFlag(context.Background(), 1, tt.reviewerID, &tt.req)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("review error = %v, want %q", err, tt.wantErr)
+				}
+				if repo.flags[1].Status != tt.status || len(freezes.freezes) != 0 {
+					t.Errorf("flag status = %q with %d freezes, want %q left alone", repo.flags[1].Status, len(freezes.freezes), tt.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("review error = %v", err)
+			}
+			if flag.Status != tt.wantStatus || repo.flags[1].Status != tt.wantStatus {
+				t.Errorf("flag status = %q, stored %q, want %q", flag.Status, repo.flags[1].Status, tt.wantStatus)
+			}
+			if flag.ReviewedBy == nil || *flag.ReviewedBy != reviewerID {
+				t.Errorf("reviewed by %v, want %d", flag.ReviewedBy, reviewerID)
+			}
+			if !tt.wantFrozen {
+				if len(freezes.freezes) != 0 {
+					t.Errorf("froze points %d times, want never", len(freezes.freezes))
+				}
+				return
+			}
+			frozenBy, flagID := reviewerID, 1
+			want := []*domain.PointFreeze{{UserID: flaggedID, Reason: "Same person", FlagID: &flagID, FrozenBy: &frozenBy}}
+			if !reflect.DeepEqual(freezes.freezes, want) {
+				t.Errorf("freezes = %+v, want points of user %d frozen by %d for flag 1", freezes.freezes, flaggedID, reviewerID)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// LifecyclePolicy sets the lifecycle bonuses. A bonus of zero points is not granted at all.
+type LifecyclePolicy struct {
+	BirthdayPoints    int
+	AnniversaryPoints int
+	WinBackDays       int // How long a member goes without a purchase or a store visit before the win-back bonus
+	WinBackPoints     int
+}
+
+// lifecycleTemplate is the notification sent with a lifecycle bonus.
+// {name}, {points} and {years} in it are replaced with the member's name, the points and the years of membership.
+type lifecycleTemplate struct {
+	Type    string
+	Title   string
+	Message string
+}
+
+var lifecycleTemplates = map[domain.LifecycleBonusKind]lifecycleTemplate{
+	domain.LifecycleBirthday: {
+		Type:    "birthday_bonus",
+		Title:   "С днём рождения!",
+		Message: "{name}, поздравляем вас с днём рождения! В подарок мы начислили вам {points} баллов.",
+	},
+	domain.LifecycleAnniversary: {
+		Type:    "anniversary_bonus",
+		Title:   "Годовщина в Kingsman",
+		Message: "{name}, вы с нами уже {years} г. Спасибо, что выбираете нас! Начислили вам {points} баллов.",
+	},
+	domain.LifecycleWinBack: {
+		Type:    "win_back",
+		Title:   "Мы скучаем по вам",
+		Message: "{name}, давно вас не видели! Мы начислили вам {points} баллов — ждём вас за покупками.",
+	},
+}
+
+// LifecycleUseCase grants bonuses on birthdays, membership anniversaries and to members who have stopped coming.
+type LifecycleUseCase struct {
+	lifecycleRepo       domain.LifecycleBonusRepository
+	loyaltyUseCase      *LoyaltyUseCase
+	notificationUseCase *NotificationUseCase
+	policy              LifecyclePolicy
+}
+
+func NewLifecycleUseCase(lifecycleRepo domain.LifecycleBonusRepository, loyaltyUseCase *LoyaltyUseCase, notificationUseCase *NotificationUseCase, policy LifecyclePolicy) *LifecycleUseCase {
+	return &LifecycleUseCase{
+		lifecycleRepo:       lifecycleRepo,
+		loyaltyUseCase:      loyaltyUseCase,
+		notificationUseCase: notificationUseCase,
+		policy:              policy,
+	}
+}
+
+// GrantLifecycleBonuses grants every lifecycle bonus due today. Each bonus is granted once per member and period,
+// so the job can run any number of times a day.
+func (uc *LifecycleUseCase) GrantLifecycleBonuses(ctx context.Context) error {
+	now := time.Now()
+
+	if uc.policy.BirthdayPoints > 0 {
+		candidates, err := uc.lifecycleRepo.GetBirthdayCandidates(ctx, now)
+		if err != nil {
+			return err
+		}
+		uc.grantAll(ctx, domain.LifecycleBirthday, uc.policy.BirthdayPoints, candidates, now)
+	}
+
+	if uc.policy.AnniversaryPoints > 0 {
+		candidates, err := uc.lifecycleRepo.GetAnniversaryCandidates(ctx, now)
+		if err != nil {
+			return err
+		}
+		uc.grantAll(ctx, domain.LifecycleAnniversary, uc.policy.AnniversaryPoints, candidates, now)
+	}
+
+	if uc.policy.WinBackPoints > 0 && uc.policy.WinBackDays > 0 {
+		candidates, err := uc.lifecycleRepo.GetWinBackCandidates(ctx, now.AddDate(0, 0, -uc.policy.WinBackDays))
+		if err != nil {
+			return err
+		}
+		uc.grantAll(ctx, domain.LifecycleWinBack, uc.policy.WinBackPoints, candidates, now)
+	}
+	return nil
+}
+
+func (uc *LifecycleUseCase) grantAll(ctx context.Context, kind domain.LifecycleBonusKind, points int, candidates []*domain.LifecycleCandidate, now time.Time) {
+	for _, candidate := range candidates {
+		if err := uc.grant(ctx, kind, points, candidate, now); err != nil {
+			log.Printf("Failed to grant %s bonus to user %d: %v", kind, candidate.UserID, err)
+		}
+	}
+}
+
+// grant posts the points before recording the bonus; if recording fails, the next run finds the member
+// again and the ledger's idempotency key keeps the points from being posted twice.
+func (uc *LifecycleUseCase) grant(ctx context.Context, kind domain.LifecycleBonusKind, points int, candidate *domain.LifecycleCandidate, now time.Time) error {
+	reference := fmt.Sprintf("lifecycle:%s:%s", kind, candidate.Period)
+	key := fmt.Sprintf("lifecycle:%s:%d:%s", kind, candidate.UserID, candidate.Period)
+	if err := uc.loyaltyUseCase.AddLoyaltyPoints(ctx, candidate.UserID, points, string(kind), reference, key); err != nil {
+		return err
+	}
+
+	bonus := &domain.LifecycleBonus{UserID: candidate.UserID, Kind: kind, Period: candidate.Period, Points: points}
+	recorded, err := uc.lifecycleRepo.RecordLifecycleBonus(ctx, bonus)
+	if err != nil || !recorded {
+		return err
+	}
+
+	template := lifecycleTemplates[kind]
+	message := strings.NewReplacer(
+		"{name}", candidate.Username,
+		"{points}", strconv.Itoa(points),
+		"{years}", strconv.Itoa(now.Year()-candidate.Since.Year()),
+	).Replace(template.Message)
+	notificationReq := &SendNotificationRequest{
+		UserID:  strconv.Itoa(candidate.UserID),
+		Type:    template.Type,
+		Title:   template.Title,
+		Message: message,
+	}
+	// Win-backs are marketing, so members who have not agreed to it get the points without the message
+	var consentErr *ConsentRequiredError
+	if err := uc.notificationUseCase.SendNotification(ctx, notificationReq); err != nil && !errors.As(err, &consentErr) {
+		log.Printf("Failed to notify user %d about %s bonus: %v", candidate.UserID, kind, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const (
+	loginFailureWindow      = 24 * time.Hour
+	accountLockoutThreshold = 5  // Failed passwords for one account before it is locked
+	ipLockoutThreshold      = 20 // Failed passwords from one address, across accounts, before it is locked
+	baseLockout             = time.Minute
+	maxLockout              = time.Hour
+)
+
+// LoginThrottle locks out password guessing, both against one account and from one address.
+// Every failure past the threshold doubles the lockout, up to maxLockout.
+// An account lock only blocks password login; SMS codes and social login still work for the real owner.
+type LoginThrottle struct {
+	store domain.RateLimitStore
+}
+
+// NewLoginThrottle creates a new LoginThrottle.
+func NewLoginThrottle(store domain.RateLimitStore) *LoginThrottle {
+	return &LoginThrottle{store: store}
+}
+
+// Check refuses the attempt while the account or the address is locked.
+func (t *LoginThrottle) Check(ctx context.Context, account, ipAddress string) error {
+	for _, key := range t.keys(account, ipAddress) {
+		lockedFor, err := t.store.LockedFor(ctx, key)
+		if err != nil {
+			log.Printf("Login throttle store error: %v", err)
+			continue
+		}
+		if lockedFor > 0 {
+			return &RetryAfterError{
+				Message:    fmt.Sprintf("too many failed attempts, try again in %d seconds", int(math.Ceil(lockedFor.Seconds()))),
+				RetryAfter: lockedFor,
+			}
+		}
+	}
+	return nil
+}
+
+// Failure counts a failed attempt and locks the account or the address once it crosses its threshold.
+func (t *LoginThrottle) Failure(ctx context.Context, account, ipAddress string) {
+	keys := t.keys(account, ipAddress)
+	thresholds := []int{accountLockoutThreshold, ipLockoutThreshold}
+	for i, key := range keys {
+		count, err := t.store.RecordFailure(ctx, key, loginFailureWindow)
+		if err != nil {
+			log.Printf("Login throttle store error: %v", err)
+			continue
+		}
+		if count < thresholds[i] {
+			continue
+		}
+		lockout := maxLockout
+		if shift := count - thresholds[i]; shift < 10 && baseLockout<<uint(shift) < maxLockout {
+			lockout = baseLockout << uint(shift)
+		}
+		if err := t.store.Lock(ctx, key, lockout); err != nil {
+			log.Printf("Login throttle store error: %v", err)
+		}
+	}
+}
+
+// Success clears the account's failures. Failures from the address are kept,
+// so logging in to one's own account does not reset a credential stuffing count.
+func (t *LoginThrottle) Success(ctx context.Context, account string) {
+	if err := t.store.ResetFailures(ctx, t.keys(account, "")[0]); err != nil {
+		log.Printf("Login throttle store error: %v", err)
+	}
+}
+
+func (t *LoginThrottle) keys(account, ipAddress string) []string {
+	return []string{
+		"login:account:" + strings.ToLower(strings.TrimSpace(account)),
+		"login:ip:" + ipAddress,
+	}
+}
@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const maxStatementPageSize = 100
+
+type GetLoyaltyTransactionsResponse struct {
+	Balance      int                    `json:"balance"`
+	Transactions []*domain.LoyaltyPoint `json:"transactions"`
+	Total        int                    `json:"total"`
+	Limit        int                    `json:"limit"`
+	Offset       int                    `json:"offset"`
+}
+
+// LedgerReconciliationReport lists what a reconciliation run found and what it fixed.
+type LedgerReconciliationReport struct {
+	Mismatches         []*domain.LedgerMismatch `json:"mismatches"`
+	UnbalancedEntryIDs []int                    `json:"unbalanced_entry_ids"` // Need investigating by hand; never repaired
+	Repaired           int                      `json:"repaired"`
+}
+
+// GetLoyaltyTransactions returns a page of the user's statement, newest entries first.
+func (uc *LoyaltyUseCase) GetLoyaltyTransactions(ctx context.Context, userID, limit, offset int) (*GetLoyaltyTransactionsResponse, error) {
+	if limit <= 0 || limit > maxStatementPageSize {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxStatementPageSize)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset cannot be negative")
+	}
+
+	entries, total, err := uc.ledgerRepo.GetEntriesByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []*domain.LoyaltyPoint{}
+	}
+
+	resp := &GetLoyaltyTransactionsResponse{Transactions: entries, Total: total, Limit: limit, Offset: offset}
+	userLoyalty, err := uc.userRepo.GetUserLoyalty(ctx, userID)
+	if err != nil && err.Error() != "user loyalty not found" {
+		return nil, fmt.Errorf("failed to get user loyalty: %w", err)
+	}
+	if userLoyalty != nil {
+		resp.Balance = userLoyalty.CurrentPoints
+	}
+	return resp, nil
+}
+
+// ReconcileLedger compares every member's cached balances with the ledger.
+// With repair set, balances that disagree with the entries are reset to them, the entries being
+// the record of truth. Members whose postings disagree with their entries are only reported.
+func (uc *LoyaltyUseCase) ReconcileLedger(ctx context.Context, repair bool) (*LedgerReconciliationReport, error) {
+	mismatches, err := uc.ledgerRepo.GetBalanceMismatches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unbalanced, err := uc.ledgerRepo.GetUnbalancedEntryIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LedgerReconciliationReport{Mismatches: mismatches, UnbalancedEntryIDs: unbalanced}
+	if report.Mismatches == nil {
+		report.Mismatches = []*domain.LedgerMismatch{}
+	}
+	if report.UnbalancedEntryIDs == nil {
+		report.UnbalancedEntryIDs = []int{}
+	}
+	if !repair {
+		return report, nil
+	}
+
+	for _, m := range report.Mismatches {
+		if m.PostingsBalance != m.LedgerBalance {
+			continue
+		}
+		if err := uc.ledgerRepo.RepairBalance(ctx, m.UserID); err != nil {
+			log.Printf("Failed to repair loyalty balance of user %d: %v", m.UserID, err)
+			continue
+		}
+		m.Repaired = true
+		report.Repaired++
+	}
+	return report, nil
+}
+
+// reverseEntry posts an entry undoing the given one. An entry is only ever reversed once.
+func (uc *LoyaltyUseCase) reverseEntry(ctx context.Context, original *domain.LoyaltyPoint, pointType string) error {
+	return uc.reversePoints(ctx, original, original.Points, pointType)
+}
+
+// reversePoints posts an entry undoing the given number of the entry's points, up to all of them.
+// An entry is only ever reversed once, however many of its points the reversal covers.
+func (uc *LoyaltyUseCase) reversePoints(ctx context.Context, original *domain.LoyaltyPoint, points int, pointType string) error {
+	reversal := &domain.LoyaltyPoint{
+		UserID:         original.UserID,
+		Points:         -points,
+		Type:           pointType,
+		Kind:           domain.LedgerReverse,
+		IdempotencyKey: fmt.Sprintf("reverse:%d", original.ID),
+		Reference:      original.Reference,
+		ReversesID:     &original.ID,
+	}
+	if _, err := uc.postEntry(ctx, reversal); err != nil {
+		return err
+	}
+	return nil
+}
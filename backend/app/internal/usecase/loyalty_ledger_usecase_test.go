@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+func (r *fakeUserRepo) GetAllLoyaltyTiers(ctx context.Context) ([]*domain.LoyaltyTier, error) {
+	var tiers []*domain.LoyaltyTier
+	for _, tier := range r.tiers {
+		tiers = append(tiers, tier)
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinPoints < tiers[j].MinPoints })
+	return tiers, nil
+}
+
+func (r *fakeLedgerRepo) GetEntriesByUserID(ctx context.Context, userID, limit, offset int) ([]*domain.LoyaltyPoint, int, error) {
+	var entries []*domain.LoyaltyPoint
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if r.entries[i].UserID == userID {
+			entries = append(entries, r.entries[i])
+		}
+	}
+	total := len(entries)
+	entries = entries[min(offset, total):min(offset+limit, total)]
+	return entries, total, nil
+}
+
+func TestAddLoyaltyPoints(t *testing.T) {
+	const userID = 1
+	type credit struct {
+		points int
+		key    string
+	}
+	tests := []struct {
+		name        string
+		credits     []credit
+		wantBalance int
+		wantEntries int
+		wantErr     string
+	}{
+		{name: "credits add up", credits: []credit{{100, "order:1:earn"}, {50, "order:2:earn"}}, wantBalance: 150, wantEntries: 2},
+		{name: "repeated key posts once", credits: []credit{{100, "order:1:earn"}, {100, "order:1:earn"}, {100, "order:1:earn"}}, wantBalance: 100, wantEntries: 1},
+		{name: "repeated key keeps the first amount", credits: []credit{{100, "order:1:earn"}, {300, "order:1:earn"}}, wantBalance: 100, wantEntries: 1},
+		{name: "zero points", credits: []credit{{0, "order:1:earn"}}, wantErr: "points must be positive"},
+		{name: "negative points", credits: []credit{{-10, "order:1:earn"}}, wantErr: "points must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserRepo{}
+			uc, ledger := newTestLoyaltyUseCase(users, nil)
+			var err error
+			for _, c := range tt.credits {
+				if err = uc.AddLoyaltyPoints(context.Background(), userID, c.points, "purchase", "", c.key); err != nil {
+					break
+				}
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("AddLoyaltyPoints() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddLoyaltyPoints() error = %v", err)
+			}
+			if got := users.loyalties[userID].CurrentPoints; got != tt.wantBalance {
+				t.Errorf("balance = %d, want %d", got, tt.wantBalance)
+			}
+			if len(ledger.entries) != tt.wantEntries {
+				t.Errorf("posted %d entries, want %d", len(ledger.entries), tt.wantEntries)
+			}
+			for _, entry := range ledger.entries {
+				if entry.Kind != domain.LedgerEarn {
+					t.Errorf("entry kind = %q, want %q", entry.Kind, domain.LedgerEarn)
+				}
+			}
+		})
+	}
+}
+
+func TestGetLoyaltyTransactions(t *testing.T) {
+	const userID = 1
+	users := &fakeUserRepo{}
+	uc, _ := newTestLoyaltyUseCase(users, nil)
+	ctx := context.Background()
+	for i, points := range []int{100, 200, 300} {
+		if err := uc.AddLoyaltyPoints(ctx, userID, points, "purchase", "", fmt.Sprintf("order:%d:earn", i)); err != nil {
+			t.Fatalf("AddLoyaltyPoints() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		limit       int
+		offset      int
+		wantBalance []int // balance_after of the entries returned, newest first
+		wantErr     string
+	}{
+		{name: "newest first", limit: 20, wantBalance: []int{600, 300, 100}},
+		{name: "page", limit: 1, offset: 1, wantBalance: []int{300}},
+		{name: "past the end", limit: 20, offset: 5, wantBalance: []int{}},
+		{name: "zero limit", limit: 0, wantErr: "limit must be between 1 and 100"},
+		{name: "limit too large", limit: 101, wantErr: "limit must be between 1 and 100"},
+		{name: "negative offset", limit: 20, offset: -1, wantErr: "offset cannot be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := uc.GetLoyaltyTransactions(ctx, userID, tt.limit, tt.offset)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("GetLoyaltyTransactions() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetLoyaltyTransactions() error = %v", err)
+			}
+			if resp.Balance != 600 || resp.Total != 3 {
+				t.Errorf("balance, total = %d, %d, want 600, 3", resp.Balance, resp.Total)
+			}
+			got := []int{}
+			for _, entry := range resp.Transactions {
+				got = append(got, entry.BalanceAfter)
+			}
+			if !reflect.DeepEqual(got, tt.wantBalance) {
+				t.Errorf("balances after = %v, want %v", got, tt.wantBalance)
+			}
+		})
+	}
+}
+
+type fakeReconciliationLedgerRepo struct {
+	domain.LoyaltyLedgerRepository
+	mismatches []*domain.LedgerMismatch
+	unbalanced []int
+	failRepair map[int]bool
+	repaired   []int
+}
+
+func (r *fakeReconciliationLedgerRepo) GetBalanceMismatches(ctx context.Context) ([]*domain.LedgerMismatch, error) {
+	return r.mismatches, nil
+}
+
+func (r *fakeReconciliationLedgerRepo) GetUnbalancedEntryIDs(ctx context.Context) ([]int, error) {
+	return r.unbalanced, nil
+}
+
+func (r *fakeReconciliationLedgerRepo) RepairBalance(ctx context.Context, userID int) error {
+	if r.failRepair[userID] {
+		return fmt.Errorf("connection reset")
+	}
+	r.repaired = append(r.repaired, userID)
+	return nil
+}
+
+func TestReconcileLedger(t *testing.T) {
+	tests := []struct {
+		name         string
+		mismatches   []*domain.LedgerMismatch
+		unbalanced   []int
+		failRepair   map[int]bool
+		repair       bool
+		wantRepaired []int
+	}{
+		{name: "ledger agrees", repair: true},
+		{
+			name:       "report only",
+			mismatches: []*domain.LedgerMismatch{{UserID: 1, LedgerBalance: 100, PostingsBalance: 100, LoyaltyBalance: 90, UserBalance: 90}},
+			repair:     false,
+		},
+		{
+			name:         "stale cached balance repaired",
+			mismatches:   []*domain.LedgerMismatch{{UserID: 1, LedgerBalance: 100, PostingsBalance: 100, LoyaltyBalance: 90, UserBalance: 100}},
+			repair:       true,
+			wantRepaired: []int{1},
+		},
+		{
+			name: "postings disagreeing with entries left for a person",
+			mismatches: []*domain.LedgerMismatch{
+				{UserID: 1, LedgerBalance: 100, PostingsBalance: 80, LoyaltyBalance: 100, UserBalance: 100},
+				{UserID: 2, LedgerBalance: 50, PostingsBalance: 50, LoyaltyBalance: 0, UserBalance: 0},
+			},
+			unbalanced:   []int{17},
+			repair:       true,
+			wantRepaired: []int{2},
+		},
+		{
+			name: "failed repair does not stop the others",
+			mismatches: []*domain.LedgerMismatch{
+				{UserID: 1, LedgerBalance: 100, PostingsBalance: 100},
+				{UserID: 2, LedgerBalance: 50, PostingsBalance: 50},
+			},
+			failRepair:   map[int]bool{1: true},
+			repair:       true,
+			wantRepaired: []int{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := &fakeReconciliationLedgerRepo{mismatches: tt.mismatches, unbalanced: tt.unbalanced, failRepair: tt.failRepair}
+			uc := NewLoyaltyUseCase(&fakeUserRepo{}, ledger, nil, nil, nil, RedemptionPolicy{}, ExpiryPolicy{}, TierPolicy{})
+
+			report, err := uc.ReconcileLedger(context.Background(), tt.repair)
+			if err != nil {
+				t.Fatalf("ReconcileLedger() error = %v", err)
+			}
+			if !reflect.DeepEqual(ledger.repaired, tt.wantRepaired) {
+				t.Errorf("repaired users = %v, want %v", ledger.repaired, tt.wantRepaired)
+			}
+			if report.Repaired != len(tt.wantRepaired) {
+				t.Errorf("report.Repaired = %d, want %d", report.Repaired, len(tt.wantRepaired))
+			}
+			if len(report.Mismatches) != len(tt.mismatches) || report.UnbalancedEntryIDs == nil || len(report.UnbalancedEntryIDs) != len(tt.unbalanced) {
+				t.Errorf("report = %+v, want every mismatch and unbalanced entry listed", report)
+			}
+			for _, m := range report.Mismatches {
+				want := false
+				for _, id := range tt.wantRepaired {
+					want = want || id == m.UserID
+				}
+				if m.Repaired != want {
+					t.Errorf("mismatch of user %d repaired = %v, want %v", m.UserID, m.Repaired, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// LoyaltyTierRequest defines a tier. Multipliers left at zero default to 1.
+type LoyaltyTierRequest struct {
+	Name                 string              `json:"name"`
+	MinPoints            int                 `json:"min_points"`
+	Description          string              `json:"description"`
+	Benefits             domain.TierBenefits `json:"benefits"`
+	PointValueMultiplier float64             `json:"point_value_multiplier"`
+}
+
+// CreateLoyaltyTier adds a tier. Members move into it at their next tier evaluation.
+func (uc *LoyaltyUseCase) CreateLoyaltyTier(ctx context.Context, req *LoyaltyTierRequest) (*LoyaltyTierResponse, error) {
+	tier, err := tierFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.userRepo.CreateLoyaltyTier(ctx, tier); err != nil {
+		return nil, err
+	}
+	return tierResponse(tier), nil
+}
+
+// UpdateLoyaltyTier changes a tier. New benefits apply from the next order; a changed
+// threshold moves members at their next tier evaluation.
+func (uc *LoyaltyUseCase) UpdateLoyaltyTier(ctx context.Context, tierID int, req *LoyaltyTierRequest) (*LoyaltyTierResponse, error) {
+	tier, err := tierFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	tier.ID = tierID
+	if err := uc.userRepo.UpdateLoyaltyTier(ctx, tier); err != nil {
+		return nil, err
+	}
+	return tierResponse(tier), nil
+}
+
+// DeleteLoyaltyTier removes a tier that no member holds.
+func (uc *LoyaltyUseCase) DeleteLoyaltyTier(ctx context.Context, tierID int) error {
+	return uc.userRepo.DeleteLoyaltyTier(ctx, tierID)
+}
+
+// GetMemberBenefits returns the benefits of the user's current tier, or none if they have no tier.
+// A discount set by staff replaces the tier's.
+func (uc *LoyaltyUseCase) GetMemberBenefits(ctx context.Context, userID int) (domain.TierBenefits, error) {
+	benefits := domain.TierBenefits{PointsMultiplier: 1}
+	tier, err := uc.memberTier(ctx, userID)
+	if err != nil {
+		return benefits, err
+	}
+	if tier != nil {
+		benefits = tier.Benefits
+	}
+
+	override, err := uc.discountOverride(ctx, userID)
+	if err != nil {
+		return benefits, err
+	}
+	if override != nil {
+		benefits.DiscountPercent = override.DiscountPercent
+	}
+	return benefits, nil
+}
+
+// memberTier returns the user's current tier, or nil if they have none.
+func (uc *LoyaltyUseCase) memberTier(ctx context.Context, userID int) (*domain.LoyaltyTier, error) {
+	return currentTier(ctx, uc.userRepo, userID)
+}
+
+// hasEarlyAccess reports whether the user's tier opens products and promotions to them before everyone else.
+func hasEarlyAccess(ctx context.Context, userRepo domain.UserRepository, userID int) (bool, error) {
+	tier, err := currentTier(ctx, userRepo, userID)
+	if err != nil {
+		return false, err
+	}
+	return tier != nil && tier.Benefits.EarlyAccess, nil
+}
+
+// inEarlyAccess reports whether something released to everyone at until is still only open to members with early access.
+// An unreadable date keeps it closed.
+func inEarlyAccess(until *string, now time.Time) bool {
+	if until == nil {
+		return false
+	}
+	releasedAt, err := time.Parse(time.RFC3339Nano, *until)
+	if err != nil {
+		return true
+	}
+	return now.Before(releasedAt)
+}
+
+// currentTier returns the user's current tier, or nil if they have none.
+func currentTier(ctx context.Context, userRepo domain.UserRepository, userID int) (*domain.LoyaltyTier, error) {
+	userLoyalty, err := userRepo.GetUserLoyalty(ctx, userID)
+	if err != nil {
+		if err.Error() == "user loyalty not found" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user loyalty: %w", err)
+	}
+	if userLoyalty.CurrentTierID == 0 {
+		return nil, nil
+	}
+	tier, err := userRepo.GetLoyaltyTierByID(ctx, userLoyalty.CurrentTierID)
+	if err != nil {
+		if err.Error() == "loyalty tier not found" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get loyalty tier: %w", err)
+	}
+	return tier, nil
+}
+
+func tierFromRequest(req *LoyaltyTierRequest) (*domain.LoyaltyTier, error) {
+	tier := &domain.LoyaltyTier{
+		Name:                 strings.TrimSpace(req.Name),
+		MinPoints:            req.MinPoints,
+		Description:          strings.TrimSpace(req.Description),
+		Benefits:             req.Benefits,
+		PointValueMultiplier: req.PointValueMultiplier,
+	}
+	if tier.Benefits.PointsMultiplier == 0 {
+		tier.Benefits.PointsMultiplier = 1
+	}
+	if tier.PointValueMultiplier == 0 {
+		tier.PointValueMultiplier = 1
+	}
+
+	switch {
+	case tier.Name == "":
+		return nil, fmt.Errorf("name is required")
+	case tier.MinPoints < 0:
+		return nil, fmt.Errorf("min_points cannot be negative")
+	case tier.Benefits.DiscountPercent < 0 || tier.Benefits.DiscountPercent > 100:
+		return nil, fmt.Errorf("discount_percent must be between 0 and 100")
+	case tier.Benefits.PointsMultiplier < 0 || tier.Benefits.PointsMultiplier > 10:
+		return nil, fmt.Errorf("points_multiplier must be between 0 and 10")
+	case tier.PointValueMultiplier < 0 || tier.PointValueMultiplier > 10:
+		return nil, fmt.Errorf("point_value_multiplier must be between 0 and 10")
+	}
+	return tier, nil
+}
+
+func tierResponse(tier *domain.LoyaltyTier) *LoyaltyTierResponse {
+	return &LoyaltyTierResponse{
+		ID:                   tier.ID,
+		Name:                 tier.Name,
+		MinPoints:            tier.MinPoints,
+		Description:          tier.Description,
+		Benefits:             tier.Benefits,
+		PointValueMultiplier: tier.PointValueMultiplier,
+	}
+}
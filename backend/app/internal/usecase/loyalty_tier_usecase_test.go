@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+func TestTierFromRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     LoyaltyTierRequest
+		want    *domain.LoyaltyTier
+		wantErr string
+	}{
+		{
+			name: "multipliers default to 1",
+			req:  LoyaltyTierRequest{Name: " Silver ", MinPoints: 1000, Description: " Second tier ", Benefits: domain.TierBenefits{DiscountPercent: 10}},
+			want: &domain.LoyaltyTier{Name: "Silver", MinPoints: 1000, Description: "Second tier",
+				Benefits: domain.TierBenefits{DiscountPercent: 10, PointsMultiplier: 1}, PointValueMultiplier: 1},
+		},
+		{
+			name: "multipliers kept",
+			req: LoyaltyTierRequest{Name: "Platinum", MinPoints: 10000,
+				Benefits: domain.TierBenefits{DiscountPercent: 20, PointsMultiplier: 1.5}, PointValueMultiplier: 1.2},
+			want: &domain.LoyaltyTier{Name: "Platinum", MinPoints: 10000,
+				Benefits: domain.TierBenefits{DiscountPercent: 20, PointsMultiplier: 1.5}, PointValueMultiplier: 1.2},
+		},
+		{
+			name: "entry tier with no discount",
+			req:  LoyaltyTierRequest{Name: "Bronze"},
+			want: &domain.LoyaltyTier{Name: "Bronze", Benefits: domain.TierBenefits{PointsMultiplier: 1}, PointValueMultiplier: 1},
+		},
+		{name: "no name", req: LoyaltyTierRequest{Name: "  "}, wantErr: "name is required"},
+		{name: "negative threshold", req: LoyaltyTierRequest{Name: "T", MinPoints: -1}, wantErr: "min_points cannot be negative"},
+		{name: "negative discount", req: LoyaltyTierRequest{Name: "T", Benefits: domain.TierBenefits{DiscountPercent: -5}}, wantErr: "discount_percent must be between 0 and 100"},
+		{name: "discount above 100", req: LoyaltyTierRequest{Name: "T", Benefits: domain.TierBenefits{DiscountPercent: 101}}, wantErr: "discount_percent must be between 0 and 100"},
+		{name: "points multiplier above 10", req: LoyaltyTierRequest{Name: "T", Benefits: domain.TierBenefits{PointsMultiplier: 11}}, wantErr: "points_multiplier must be between 0 and 10"},
+		{name: "negative points multiplier", req: LoyaltyTierRequest{Name: "T", Benefits: domain.TierBenefits{PointsMultiplier: -1}}, wantErr: "points_multiplier must be between 0 and 10"},
+		{name: "point value multiplier above 10", req: LoyaltyTierRequest{Name: "T", PointValueMultiplier: 10.5}, wantErr: "point_value_multiplier must be between 0 and 10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tier, err := tierFromRequest(&tt.req)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("tierFromRequest() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("tierFromRequest() error = %v", err)
+			}
+			if !reflect.DeepEqual(tier, tt.want) {
+				t.Errorf("tierFromRequest() = %+v, want %+v", tier, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckoutPolicyServiceFees(t *testing.T) {
+	policy := CheckoutPolicy{ShippingFee: 10, AlterationFee: 15}
+	tests := []struct {
+		name            string
+		services        OrderServices
+		benefits        domain.TierBenefits
+		wantShipping    float64
+		wantAlterations float64
+	}{
+		{name: "collected in store", services: OrderServices{}},
+		{name: "delivered", services: OrderServices{Delivery: true}, wantShipping: 10},
+		{name: "alterations charged per item", services: OrderServices{Alterations: 2}, wantAlterations: 30},
+		{name: "free shipping", services: OrderServices{Delivery: true, Alterations: 1}, benefits: domain.TierBenefits{FreeShipping: true}, wantAlterations: 15},
+		{name: "free alterations", services: OrderServices{Delivery: true, Alterations: 3}, benefits: domain.TierBenefits{FreeAlterations: true}, wantShipping: 10},
+		{name: "both waived", services: OrderServices{Delivery: true, Alterations: 3}, benefits: domain.TierBenefits{FreeShipping: true, FreeAlterations: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shipping, alterations := policy.serviceFees(tt.services, tt.benefits)
+			if shipping != tt.wantShipping || alterations != tt.wantAlterations {
+				t.Errorf("serviceFees() = %v, %v, want %v, %v", shipping, alterations, tt.wantShipping, tt.wantAlterations)
+			}
+		})
+	}
+}
+
+func TestInEarlyAccess(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	strPtr := func(v string) *string { return &v }
+	tests := []struct {
+		name  string
+		until *string
+		want  bool
+	}{
+		{name: "released to everyone", until: nil, want: false},
+		{name: "before release", until: strPtr("2026-03-02T00:00:00Z"), want: true},
+		{name: "after release", until: strPtr("2026-02-28T00:00:00Z"), want: false},
+		{name: "at release", until: strPtr("2026-03-01T12:00:00Z"), want: false},
+		{name: "unreadable date stays closed", until: strPtr("next week"), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inEarlyAccess(tt.until, now); got != tt.want {
+				t.Errorf("inEarlyAccess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const (
+	otpCodeLength     = 6
+	otpCodeTTL        = 5 * time.Minute
+	otpResendCooldown = time.Minute
+	otpMaxAttempts    = 5
+)
+
+// OTPUseCase handles login by one-time codes sent over SMS.
+type OTPUseCase struct {
+	otpRepo        domain.OTPRepository
+	userRepo       domain.UserRepository
+	smsSender      domain.SMSSender
+	userUseCase    *UserUseCase
+	sessionUseCase *SessionUseCase
+}
+
+// NewOTPUseCase creates a new OTPUseCase.
+func NewOTPUseCase(otpRepo domain.OTPRepository, userRepo domain.UserRepository, smsSender domain.SMSSender, userUseCase *UserUseCase, sessionUseCase *SessionUseCase) *OTPUseCase {
+	return &OTPUseCase{otpRepo: otpRepo, userRepo: userRepo, smsSender: smsSender, userUseCase: userUseCase, sessionUseCase: sessionUseCase}
+}
+
+type RequestOTPRequest struct {
+	PhoneNumber string `json:"phone_number"`
+}
+
+type RequestOTPResponse struct {
+	ExpiresIn   int `json:"expires_in"`   // Seconds until the code expires
+	ResendAfter int `json:"resend_after"` // Seconds until a new code may be requested
+}
+
+type VerifyOTPRequest struct {
+	PhoneNumber string `json:"phone_number"`
+	Code        string `json:"code"`
+}
+
+// RequestOTP sends a new login code to the phone number, replacing any earlier one.
+func (uc *OTPUseCase) RequestOTP(ctx context.Context, req *RequestOTPRequest) (*RequestOTPResponse, error) {
+	phoneNumber, err := normalizePhoneNumber(req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := uc.otpRepo.GetOTPCode(ctx, phoneNumber)
+	if err != nil && err.Error() != "OTP code not found" {
+		return nil, err
+	}
+	if existing != nil {
+		if wait := time.Until(existing.LastSentAt.Add(otpResendCooldown)); wait > 0 {
+			return nil, &RetryAfterError{
+				Message:    fmt.Sprintf("a new code can be requested in %d seconds", int(wait.Seconds())+1),
+				RetryAfter: wait,
+			}
+		}
+	}
+
+	code, err := generateOTPCode()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	otp := &domain.OTPCode{
+		PhoneNumber: phoneNumber,
+		CodeHash:    hashOTPCode(phoneNumber, code),
+		Attempts:    0,
+		ExpiresAt:   now.Add(otpCodeTTL),
+		LastSentAt:  now,
+	}
+	if err := uc.otpRepo.SaveOTPCode(ctx, otp); err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Код для входа в MR.KINGSMAN: %s. Никому не сообщайте его.", code)
+	if err := uc.smsSender.Send(ctx, phoneNumber, message); err != nil {
+		return nil, fmt.Errorf("failed to send OTP code: %w", err)
+	}
+
+	return &RequestOTPResponse{
+		ExpiresIn:   int(otpCodeTTL.Seconds()),
+		ResendAfter: int(otpResendCooldown.Seconds()),
+	}, nil
+}
+
+// VerifyOTP checks a login code and opens a session, registering the phone number if it is new.
+func (uc *OTPUseCase) VerifyOTP(ctx context.Context, req *VerifyOTPRequest, meta *SessionMetadata) (*LoginUserResponse, error) {
+	phoneNumber, err := normalizePhoneNumber(req.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkOTPCode(ctx, uc.otpRepo, phoneNumber, req.Code); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetUserByPhoneNumber(ctx, phoneNumber)
+	if err != nil && err.Error() != "user not found" {
+		return nil, fmt.Errorf("failed to get user by phone number: %w", err)
+	}
+	if user == nil {
+		qrCodeString := uuid.New().String()
+		verifiedAt := time.Now().Format(time.RFC3339)
+		user = &domain.User{
+			Username:        phoneNumber,
+			PhoneNumber:     phoneNumber,
+			PhoneVerifiedAt: &verifiedAt,
+			QRCode:          &qrCodeString,
+			LoyaltyStatus:   "Bronze",
+			Role:            domain.RoleCustomer,
+		}
+		if err := uc.userUseCase.createUser(ctx, user); err != nil {
+			return nil, err
+		}
+	} else if user.PhoneVerifiedAt == nil {
+		if err := uc.userRepo.MarkPhoneVerified(ctx, user.ID, phoneNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	return uc.sessionUseCase.StartSession(ctx, user, meta)
+}
+
+// checkOTPCode verifies and consumes the code sent to a normalized phone number.
+// It is shared by login and by phone number changes, which prove ownership the same way.
+// Every check counts as an attempt before the code is compared, so parallel guesses cannot exceed the limit.
+func checkOTPCode(ctx context.Context, otpRepo domain.OTPRepository, phoneNumber, code string) error {
+	otp, err := otpRepo.ClaimOTPAttempt(ctx, phoneNumber, otpMaxAttempts)
+	if err != nil {
+		if err.Error() != "OTP code not found" {
+			return err
+		}
+		// Only to tell the user why; the attempt was refused either way
+		otp, err := otpRepo.GetOTPCode(ctx, phoneNumber)
+		if err != nil && err.Error() != "OTP code not found" {
+			return err
+		}
+		if otp != nil && time.Now().Before(otp.ExpiresAt) && otp.Attempts >= otpMaxAttempts {
+			if err := otpRepo.DeleteOTPCode(ctx, phoneNumber); err != nil {
+				return err
+			}
+			return fmt.Errorf("too many attempts, request a new code")
+		}
+		return fmt.Errorf("code expired or was not requested")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(otp.CodeHash), []byte(hashOTPCode(phoneNumber, strings.TrimSpace(code)))) != 1 {
+		return fmt.Errorf("invalid code")
+	}
+
+	// Codes are single-use, so of two requests with the right code only one gets through
+	if err := otpRepo.ConsumeOTPCode(ctx, phoneNumber, otp.CodeHash); err != nil {
+		if err.Error() == "OTP code not found" {
+			return fmt.Errorf("code expired or was not requested")
+		}
+		return err
+	}
+	return nil
+}
+
+// normalizePhoneNumber reduces a phone number to digits, rewriting the Russian trunk prefix 8 to the country code 7.
+func normalizePhoneNumber(phoneNumber string) (string, error) {
+	var digits strings.Builder
+	for _, r := range phoneNumber {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' || r == ' ' || r == '-' || r == '(' || r == ')':
+		default:
+			return "", fmt.Errorf("invalid phone number")
+		}
+	}
+
+	normalized := digits.String()
+	if len(normalized) == 11 && normalized[0] == '8' {
+		normalized = "7" + normalized[1:]
+	}
+	if len(normalized) < 10 || len(normalized) > 15 {
+		return "", fmt.Errorf("invalid phone number")
+	}
+	return normalized, nil
+}
+
+func generateOTPCode() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpCodeLength; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate OTP code: %w", err)
+	}
+	return fmt.Sprintf("%0*d", otpCodeLength, n), nil
+}
+
+func hashOTPCode(phoneNumber, code string) string {
+	sum := sha256.Sum256([]byte(phoneNumber + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
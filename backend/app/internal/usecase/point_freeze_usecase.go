@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// PointFreezeResponse is whether a member's points are frozen, with every freeze and unfreeze.
+type PointFreezeResponse struct {
+	Frozen bool                            `json:"frozen"`
+	Freeze *domain.PointFreeze             `json:"freeze,omitempty"`
+	Audit  []*domain.PointFreezeAuditEntry `json:"audit"`
+}
+
+// PointFreezeRequest freezes or unfreezes a member's points.
+type PointFreezeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// GetPointFreeze returns the member's freeze, if any, and its history.
+func (uc *LoyaltyUseCase) GetPointFreeze(ctx context.Context, userID int) (*PointFreezeResponse, error) {
+	if _, err := uc.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	freeze, err := uc.pointFreeze(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := uc.pointFreezeRepo.GetPointFreezeAudit(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if audit == nil {
+		audit = []*domain.PointFreezeAuditEntry{}
+	}
+	return &PointFreezeResponse{Frozen: freeze != nil, Freeze: freeze, Audit: audit}, nil
+}
+
+// FreezePoints stops the member from spending their points until they are unfrozen. The member keeps
+// their balance and keeps earning; the change, who made it and why are kept in the audit trail.
+func (uc *LoyaltyUseCase) FreezePoints(ctx context.Context, userID, adminID int, req *PointFreezeRequest) (*PointFreezeResponse, error) {
+	if err := uc.freezePoints(ctx, userID, adminID, req, nil); err != nil {
+		return nil, err
+	}
+	return uc.GetPointFreeze(ctx, userID)
+}
+
+// UnfreezePoints lets the member spend their points again, recording the change in the audit trail.
+func (uc *LoyaltyUseCase) UnfreezePoints(ctx context.Context, userID, adminID int, req *PointFreezeRequest) error {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	return uc.pointFreezeRepo.UnfreezePoints(ctx, userID, adminID, reason)
+}
+
+// freezePoints freezes the member's points, optionally for the fraud flag that led to it.
+func (uc *LoyaltyUseCase) freezePoints(ctx context.Context, userID, adminID int, req *PointFreezeRequest, flagID *int) error {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return fmt.Errorf("reason is required")
+	}
+	if _, err := uc.userRepo.GetUserByID(ctx, userID); err != nil {
+		return err
+	}
+	freeze := &domain.PointFreeze{
+		UserID:   userID,
+		Reason:   reason,
+		FlagID:   flagID,
+		FrozenBy: &adminID,
+	}
+	return uc.pointFreezeRepo.FreezePoints(ctx, freeze)
+}
+
+// pointFreeze returns the member's freeze, or nil if their points are not frozen.
+func (uc *LoyaltyUseCase) pointFreeze(ctx context.Context, userID int) (*domain.PointFreeze, error) {
+	freeze, err := uc.pointFreezeRepo.GetPointFreeze(ctx, userID)
+	if err != nil {
+		if err.Error() == "point freeze not found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return freeze, nil
+}
+
+// checkPointsNotFrozen fails if the member may not spend their points.
+func (uc *LoyaltyUseCase) checkPointsNotFrozen(ctx context.Context, userID int) error {
+	freeze, err := uc.pointFreeze(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if freeze != nil {
+		return fmt.Errorf("loyalty points are frozen")
+	}
+	return nil
+}
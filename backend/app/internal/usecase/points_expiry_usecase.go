@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// expiryWarningDays are how long before points expire their owner is warned.
+// Closest first, so points already inside the short window are not warned about twice in one run.
+var expiryWarningDays = []int{7, 30}
+
+// expiryNoticeDays is the least time points accrued without an expiry date are given once they get one,
+// longer than the earliest warning so that every member is warned before losing anything.
+const expiryNoticeDays = 31
+
+// ExpirePoints writes off the points of every member that have reached their expiry date.
+// Points accrued before expiry was introduced, or while it was off, are first dated by the current lifetime.
+func (uc *LoyaltyUseCase) ExpirePoints(ctx context.Context) error {
+	if uc.expiryPolicy.PointsLifetimeMonths > 0 {
+		notBefore := time.Now().AddDate(0, 0, expiryNoticeDays)
+		dated, err := uc.ledgerRepo.DateCreditsWithoutExpiry(ctx, uc.expiryPolicy.PointsLifetimeMonths, notBefore)
+		if err != nil {
+			return err
+		}
+		if dated > 0 {
+			log.Printf("Dated the expiry of %d loyalty point credits accrued without one", dated)
+		}
+	}
+
+	userIDs, err := uc.ledgerRepo.GetUserIDsWithExpiredPoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for _, userID := range userIDs {
+		entry := &domain.LoyaltyPoint{
+			UserID:         userID,
+			Type:           "expiration",
+			Kind:           domain.LedgerExpire,
+			IdempotencyKey: fmt.Sprintf("expire:%d:%s", userID, today),
+		}
+		if _, err := uc.ledgerRepo.ExpirePoints(ctx, entry); err != nil {
+			log.Printf("Failed to expire points of user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// SendExpiryWarnings notifies members whose points expire within one of the warning windows.
+// Each lot of points is warned about once per window.
+func (uc *LoyaltyUseCase) SendExpiryWarnings(ctx context.Context) error {
+	for _, days := range expiryWarningDays {
+		lots, err := uc.ledgerRepo.GetUnwarnedExpiringLots(ctx, days)
+		if err != nil {
+			return err
+		}
+
+		// Lots come ordered by user, so each user gets one notification per window
+		for start := 0; start < len(lots); {
+			end := start
+			points := 0
+			var entryIDs []int
+			for ; end < len(lots) && lots[end].UserID == lots[start].UserID; end++ {
+				points += lots[end].Remaining
+				entryIDs = append(entryIDs, lots[end].EntryID)
+			}
+			userID, lastExpiry := lots[start].UserID, lots[end-1].ExpiresAt
+			start = end
+
+			notificationReq := &SendNotificationRequest{
+				UserID:  strconv.Itoa(userID),
+				Type:    "points_expiring",
+				Title:   "Баллы скоро сгорят",
+				Message: fmt.Sprintf("До %s сгорят %d баллов. Успейте потратить их на покупки.", expiryDate(*lastExpiry), points),
+			}
+			if err := uc.notificationUseCase.SendNotification(ctx, notificationReq); err != nil {
+				log.Printf("Failed to warn user %d about expiring points: %v", userID, err)
+				continue
+			}
+			if err := uc.ledgerRepo.MarkLotsWarned(ctx, entryIDs, days); err != nil {
+				log.Printf("Failed to record expiry warning for user %d: %v", userID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// getExpirySchedule sums the user's unspent points by the date they expire.
+func (uc *LoyaltyUseCase) getExpirySchedule(ctx context.Context, userID int) ([]*PointsExpiry, error) {
+	lots, err := uc.ledgerRepo.GetPointLotsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule []*PointsExpiry
+	for _, lot := range lots {
+		if lot.ExpiresAt == nil {
+			continue
+		}
+		date := expiryDate(*lot.ExpiresAt)
+		if n := len(schedule); n > 0 && schedule[n-1].Date == date {
+			schedule[n-1].Points += lot.Remaining
+			continue
+		}
+		schedule = append(schedule, &PointsExpiry{Date: date, Points: lot.Remaining})
+	}
+	return schedule, nil
+}
+
+// postEntry posts an entry to the ledger, dating the expiry of credits by the expiry policy.
+func (uc *LoyaltyUseCase) postEntry(ctx context.Context, entry *domain.LoyaltyPoint) (bool, error) {
+	if entry.Points > 0 && uc.expiryPolicy.PointsLifetimeMonths > 0 {
+		expiresAt := time.Now().AddDate(0, uc.expiryPolicy.PointsLifetimeMonths, 0).Format(time.RFC3339)
+		entry.ExpiresAt = &expiresAt
+	}
+	return uc.ledgerRepo.PostEntry(ctx, entry)
+}
+
+// expiryDate returns the date part of a timestamp.
+func expiryDate(timestamp string) string {
+	if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil {
+		return t.Format("2006-01-02")
+	}
+	return timestamp
+}
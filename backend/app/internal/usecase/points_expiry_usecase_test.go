@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type fakeExpiryLedgerRepo struct {
+	domain.LoyaltyLedgerRepository
+	expiredUserIDs []int
+	expiries       []*domain.LoyaltyPoint
+	datedLifetime  int
+	datedNotBefore time.Time
+	lots           map[int][]*domain.PointLot // Unspent lots by user
+	expiring       map[int][]*domain.PointLot // Unwarned lots by warning window, ordered by user
+	warned         map[int][]int              // Entry IDs marked warned, by warning window
+}
+
+func (r *fakeExpiryLedgerRepo) DateCreditsWithoutExpiry(ctx context.Context, lifetimeMonths int, notBefore time.Time) (int, error) {
+	r.datedLifetime, r.datedNotBefore = lifetimeMonths, notBefore
+	return 0, nil
+}
+
+func (r *fakeExpiryLedgerRepo) GetUserIDsWithExpiredPoints(ctx context.Context) ([]int, error) {
+	return r.expiredUserIDs, nil
+}
+
+func (r *fakeExpiryLedgerRepo) ExpirePoints(ctx context.Context, entry *domain.LoyaltyPoint) (bool, error) {
+	r.expiries = append(r.expiries, entry)
+	return true, nil
+}
+
+func (r *fakeExpiryLedgerRepo) GetPointLotsByUserID(ctx context.Context, userID int) ([]*domain.PointLot, error) {
+	return r.lots[userID], nil
+}
+
+func (r *fakeExpiryLedgerRepo) GetUnwarnedExpiringLots(ctx context.Context, daysBefore int) ([]*domain.PointLot, error) {
+	return r.expiring[daysBefore], nil
+}
+
+func (r *fakeExpiryLedgerRepo) MarkLotsWarned(ctx context.Context, entryIDs []int, daysBefore int) error {
+	if r.warned == nil {
+		r.warned = map[int][]int{}
+	}
+	r.warned[daysBefore] = append(r.warned[daysBefore], entryIDs...)
+	return nil
+}
+
+type fakeNotificationRepo struct {
+	domain.NotificationRepository
+	failFor       map[int]bool
+	notifications []*domain.Notification
+}
+
+func (r *fakeNotificationRepo) CreateNotification(ctx context.Context, notification *domain.Notification) error {
+	if r.failFor[notification.UserID] {
+		return fmt.Errorf("connection reset")
+	}
+	r.notifications = append(r.notifications, notification)
+	return nil
+}
+
+func TestPostEntryDatesExpiry(t *testing.T) {
+	tests := []struct {
+		name           string
+		lifetimeMonths int
+		points         int
+		wantExpiry     bool
+	}{
+		{name: "credit expires after the lifetime", lifetimeMonths: 12, points: 100, wantExpiry: true},
+		{name: "debit has no expiry", lifetimeMonths: 12, points: -100},
+		{name: "expiry turned off", lifetimeMonths: 0, points: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUserRepo{loyalties: map[int]*domain.UserLoyalty{1: {UserID: 1, CurrentPoints: 500}}}
+			ledger := &fakeLedgerRepo{users: users}
+			uc := NewLoyaltyUseCase(users, ledger, nil, nil, nil, RedemptionPolicy{}, ExpiryPolicy{PointsLifetimeMonths: tt.lifetimeMonths}, TierPolicy{})
+
+			entry := &domain.LoyaltyPoint{UserID: 1, Points: tt.points, Kind: domain.LedgerAdjust, IdempotencyKey: "k"}
+			if _, err := uc.postEntry(context.Background(), entry); err != nil {
+				t.Fatalf("postEntry() error = %v", err)
+			}
+			if !tt.wantExpiry {
+				if entry.ExpiresAt != nil {
+					t.Errorf("ExpiresAt = %s, want none", *entry.ExpiresAt)
+				}
+				return
+			}
+			if entry.ExpiresAt == nil {
+				t.Fatal("ExpiresAt not set")
+			}
+			want := time.Now().AddDate(0, tt.lifetimeMonths, 0).Format("2006-01-02")
+			if got := expiryDate(*entry.ExpiresAt); got != want {
+				t.Errorf("expires on %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestExpirePoints(t *testing.T) {
+	tests := []struct {
+		name           string
+		lifetimeMonths int
+		expiredUserIDs []int
+		wantDated      bool
+	}{
+		{name: "undated credits dated first", lifetimeMonths: 12, expiredUserIDs: []int{3, 5}, wantDated: true},
+		{name: "nothing dated with expiry turned off", lifetimeMonths: 0, expiredUserIDs: []int{3}},
+		{name: "nothing expired", lifetimeMonths: 6, wantDated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := &fakeExpiryLedgerRepo{expiredUserIDs: tt.expiredUserIDs}
+			uc := NewLoyaltyUseCase(&fakeUserRepo{}, ledger, nil, nil, nil, RedemptionPolicy{}, ExpiryPolicy{PointsLifetimeMonths: tt.lifetimeMonths}, TierPolicy{})
+
+			if err := uc.ExpirePoints(context.Background()); err != nil {
+				t.Fatalf("ExpirePoints() error = %v", err)
+			}
+			if tt.wantDated {
+				notice := time.Now().AddDate(0, 0, expiryNoticeDays)
+				if ledger.datedLifetime != tt.lifetimeMonths || ledger.datedNotBefore.Sub(notice).Abs() > time.Minute {
+					t.Errorf("dated by %d months not before %v, want %d months not before %v", ledger.datedLifetime, ledger.datedNotBefore, tt.lifetimeMonths, notice)
+				}
+			} else if ledger.datedLifetime != 0 {
+				t.Errorf("credits dated by %d months, want none dated", ledger.datedLifetime)
+			}
+
+			today := time.Now().Format("2006-01-02")
+			var keys []string
+			for _, entry := range ledger.expiries {
+				keys = append(keys, entry.IdempotencyKey)
+				if entry.Kind != domain.LedgerExpire {
+					t.Errorf("expiry kind = %q, want %q", entry.Kind, domain.LedgerExpire)
+				}
+			}
+			var wantKeys []string
+			for _, userID := range tt.expiredUserIDs {
+				wantKeys = append(wantKeys, fmt.Sprintf("expire:%d:%s", userID, today))
+			}
+			if !reflect.DeepEqual(keys, wantKeys) {
+				t.Errorf("expiry keys = %v, want %v", keys, wantKeys)
+			}
+		})
+	}
+}
+
+func TestSendExpiryWarnings(t *testing.T) {
+	strPtr := func(v string) *string { return &v }
+	lot := func(entryID, userID, remaining int, expiresAt string) *domain.PointLot {
+		return &domain.PointLot{EntryID: entryID, UserID: userID, Remaining: remaining, ExpiresAt: strPtr(expiresAt)}
+	}
+	tests := []struct {
+		name         string
+		expiring     map[int][]*domain.PointLot
+		failFor      map[int]bool
+		wantMessages []string
+		wantWarned   map[int][]int
+	}{
+		{name: "nothing expiring", wantMessages: nil, wantWarned: nil},
+		{
+			name: "one warning per member and window",
+			expiring: map[int][]*domain.PointLot{
+				7:  {lot(1, 10, 100, "2026-03-05T00:00:00Z"), lot(2, 10, 50, "2026-03-06T00:00:00Z"), lot(3, 20, 30, "2026-03-04T00:00:00Z")},
+				30: {lot(4, 10, 200, "2026-03-25T00:00:00Z")},
+			},
+			wantMessages: []string{
+				"До 2026-03-06 сгорят 150 баллов. Успейте потратить их на покупки.",
+				"До 2026-03-04 сгорят 30 баллов. Успейте потратить их на покупки.",
+				"До 2026-03-25 сгорят 200 баллов. Успейте потратить их на покупки.",
+			},
+			wantWarned: map[int][]int{7: {1, 2, 3}, 30: {4}},
+		},
+		{
+			name:         "unsent warning is tried again",
+			expiring:     map[int][]*domain.PointLot{7: {lot(1, 10, 100, "2026-03-05T00:00:00Z"), lot(2, 20, 30, "2026-03-04T00:00:00Z")}},
+			failFor:      map[int]bool{10: true},
+			wantMessages: []string{"До 2026-03-04 сгорят 30 баллов. Успейте потратить их на покупки."},
+			wantWarned:   map[int][]int{7: {2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ledger := &fakeExpiryLedgerRepo{expiring: tt.expiring}
+			notifications := &fakeNotificationRepo{failFor: tt.failFor}
+			notificationUseCase := NewNotificationUseCase(notifications, nil, nil, nil, nil)
+			uc := NewLoyaltyUseCase(&fakeUserRepo{}, ledger, nil, nil, notificationUseCase, RedemptionPolicy{}, ExpiryPolicy{PointsLifetimeMonths: 12}, TierPolicy{})
+
+			if err := uc.SendExpiryWarnings(context.Background()); err != nil {
+				t.Fatalf("SendExpiryWarnings() error = %v", err)
+			}
+			var messages []string
+			for _, n := range notifications.notifications {
+				messages = append(messages, n.Message)
+			}
+			if !reflect.DeepEqual(messages, tt.wantMessages) {
+				t.Errorf("messages = %q, want %q", messages, tt.wantMessages)
+			}
+			if !reflect.DeepEqual(ledger.warned, tt.wantWarned) {
+				t.Errorf("warned lots = %v, want %v", ledger.warned, tt.wantWarned)
+			}
+		})
+	}
+}
+
+func TestGetExpirySchedule(t *testing.T) {
+	strPtr := func(v string) *string { return &v }
+	ledger := &fakeExpiryLedgerRepo{lots: map[int][]*domain.PointLot{1: {
+		{EntryID: 1, Remaining: 100, ExpiresAt: strPtr("2026-03-05T08:00:00Z")},
+		{EntryID: 2, Remaining: 50, ExpiresAt: strPtr("2026-03-05T20:00:00Z")},
+		{EntryID: 3, Remaining: 70, ExpiresAt: strPtr("2026-04-01T00:00:00Z")},
+		{EntryID: 4, Remaining: 500}, // Never expires
+	}}}
+	uc := NewLoyaltyUseCase(&fakeUserRepo{}, ledger, nil, nil, nil, RedemptionPolicy{}, ExpiryPolicy{}, TierPolicy{})
+
+	schedule, err := uc.getExpirySchedule(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("getExpirySchedule() error = %v", err)
+	}
+	want := []*PointsExpiry{{Date: "2026-03-05", Points: 150}, {Date: "2026-04-01", Points: 70}}
+	if !reflect.DeepEqual(schedule, want) {
+		t.Errorf("getExpirySchedule() = %v, want %v", schedule, want)
+	}
+}
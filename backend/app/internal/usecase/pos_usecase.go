@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// POSUseCase serves staff and in-store terminals identifying customers by their discount card.
+type POSUseCase struct {
+	userRepo           domain.UserRepository
+	storeRepo          domain.StoreRepository
+	cardCodeSigner     *CardCodeSigner
+	loyaltyUseCase     *LoyaltyUseCase
+	earningRuleUseCase *EarningRuleUseCase
+	challengeUseCase   *ChallengeUseCase
+}
+
+func NewPOSUseCase(userRepo domain.UserRepository, storeRepo domain.StoreRepository, cardCodeSigner *CardCodeSigner, loyaltyUseCase *LoyaltyUseCase, earningRuleUseCase *EarningRuleUseCase, challengeUseCase *ChallengeUseCase) *POSUseCase {
+	return &POSUseCase{
+		userRepo:           userRepo,
+		storeRepo:          storeRepo,
+		cardCodeSigner:     cardCodeSigner,
+		loyaltyUseCase:     loyaltyUseCase,
+		earningRuleUseCase: earningRuleUseCase,
+		challengeUseCase:   challengeUseCase,
+	}
+}
+
+type ScanCustomerRequest struct {
+	QRCode  string `json:"qr_code"`  // The code shown on the customer's card in the app or in a wallet
+	StoreID int    `json:"store_id"` // Store the customer is in
+}
+
+// ScanCustomerResponse is what the till needs to serve a customer. Contact details are left out.
+type ScanCustomerResponse struct {
+	UserID   string                `json:"user_id"`
+	Username string                `json:"username"`
+	Tier     *LoyaltyTierResponse  `json:"tier,omitempty"`
+	Balance  int                   `json:"balance"`
+	Offers   []*domain.EarningRule `json:"offers"` // Earning rules the customer can use in the store today
+}
+
+// ScanCustomer identifies a customer by the code on their discount card and records their visit to the store.
+func (uc *POSUseCase) ScanCustomer(ctx context.Context, req *ScanCustomerRequest) (*ScanCustomerResponse, error) {
+	if req.QRCode == "" {
+		return nil, fmt.Errorf("qr_code is required")
+	}
+	if _, err := uc.storeRepo.GetStoreByID(ctx, req.StoreID); err != nil {
+		return nil, err
+	}
+
+	user, err := memberByCardCode(ctx, uc.userRepo, uc.cardCodeSigner, req.QRCode)
+	if err != nil {
+		return nil, err
+	}
+
+	tier, err := uc.loyaltyUseCase.memberTier(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	offers, err := uc.earningRuleUseCase.GetMemberOffers(ctx, user.ID, req.StoreID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.challengeUseCase.RecordStoreVisit(ctx, user.ID, req.StoreID, "scan"); err != nil {
+		log.Printf("Failed to record visit of user %d to store %d: %v", user.ID, req.StoreID, err)
+	}
+
+	resp := &ScanCustomerResponse{
+		UserID:   strconv.Itoa(user.ID),
+		Username: user.Username,
+		Balance:  user.CurrentPoints,
+		Offers:   offers,
+	}
+	if tier != nil {
+		resp.Tier = tierResponse(tier)
+	}
+	return resp, nil
+}
+
+// memberByCardCode returns the member whose discount card shows the code, which proves they are at the till.
+func memberByCardCode(ctx context.Context, userRepo domain.UserRepository, signer *CardCodeSigner, code string) (*domain.User, error) {
+	userID, err := signer.ParseUserID(code)
+	if err != nil {
+		return nil, err
+	}
+	user, err := userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, fmt.Errorf("invalid card code")
+		}
+		return nil, err
+	}
+	// Deleted accounts have no card seed, so their codes never verify
+	if user.QRCode == nil {
+		return nil, fmt.Errorf("invalid card code")
+	}
+	if err := signer.Verify(code, *user.QRCode, time.Now()); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
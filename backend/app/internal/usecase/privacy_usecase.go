@@ -0,0 +1,323 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// PrivacyUseCase handles consent management and personal data export under 152-FZ and GDPR.
+type PrivacyUseCase struct {
+	consentRepo      domain.ConsentRepository
+	userRepo         domain.UserRepository
+	orderRepo        domain.OrderRepository
+	orderItemRepo    domain.OrderItemRepository
+	notificationRepo domain.NotificationRepository
+	identityRepo     domain.IdentityRepository
+	sessionRepo      domain.SessionRepository
+}
+
+// NewPrivacyUseCase creates a new PrivacyUseCase.
+func NewPrivacyUseCase(
+	consentRepo domain.ConsentRepository,
+	userRepo domain.UserRepository,
+	orderRepo domain.OrderRepository,
+	orderItemRepo domain.OrderItemRepository,
+	notificationRepo domain.NotificationRepository,
+	identityRepo domain.IdentityRepository,
+	sessionRepo domain.SessionRepository,
+) *PrivacyUseCase {
+	return &PrivacyUseCase{
+		consentRepo:      consentRepo,
+		userRepo:         userRepo,
+		orderRepo:        orderRepo,
+		orderItemRepo:    orderItemRepo,
+		notificationRepo: notificationRepo,
+		identityRepo:     identityRepo,
+		sessionRepo:      sessionRepo,
+	}
+}
+
+type GetConsentTextsResponse struct {
+	Texts []*domain.ConsentText `json:"texts"`
+}
+
+type PublishConsentTextRequest struct {
+	Purpose domain.ConsentPurpose `json:"purpose"`
+	Text    string                `json:"text"`
+}
+
+// ConsentStatus is the user's current decision for one purpose.
+type ConsentStatus struct {
+	Purpose       domain.ConsentPurpose `json:"purpose"`
+	Granted       bool                  `json:"granted"`
+	TextVersion   int                   `json:"text_version,omitempty"` // Version the user last decided on
+	LatestVersion int                   `json:"latest_version"`
+	UpdatedAt     string                `json:"updated_at,omitempty"`
+}
+
+type GetConsentsResponse struct {
+	Consents []*ConsentStatus `json:"consents"`
+}
+
+type ConsentChange struct {
+	Purpose     domain.ConsentPurpose `json:"purpose"`
+	Granted     bool                  `json:"granted"`
+	TextVersion int                   `json:"text_version"` // Version of the text shown to the user
+}
+
+type UpdateConsentsRequest struct {
+	Consents []ConsentChange `json:"consents"`
+}
+
+// GetConsentTexts returns the current wording for every purpose.
+func (uc *PrivacyUseCase) GetConsentTexts(ctx context.Context) (*GetConsentTextsResponse, error) {
+	texts, err := uc.consentRepo.GetLatestConsentTexts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if texts == nil {
+		texts = []*domain.ConsentText{}
+	}
+	return &GetConsentTextsResponse{Texts: texts}, nil
+}
+
+// PublishConsentText adds a new version of the wording for a purpose.
+// Consents given to earlier versions stay valid; users are asked to renew them.
+func (uc *PrivacyUseCase) PublishConsentText(ctx context.Context, req *PublishConsentTextRequest) (*domain.ConsentText, error) {
+	if !req.Purpose.IsValid() {
+		return nil, fmt.Errorf("unknown consent purpose %q", req.Purpose)
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+
+	text := &domain.ConsentText{Purpose: req.Purpose, Text: strings.TrimSpace(req.Text)}
+	if err := uc.consentRepo.CreateConsentText(ctx, text); err != nil {
+		return nil, err
+	}
+	return text, nil
+}
+
+// GetConsents returns the user's decision for every purpose, defaulting to not granted.
+func (uc *PrivacyUseCase) GetConsents(ctx context.Context, userID string) (*GetConsentsResponse, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	texts, err := uc.consentRepo.GetLatestConsentTexts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	latestVersions := make(map[domain.ConsentPurpose]int)
+	for _, text := range texts {
+		latestVersions[text.Purpose] = text.Version
+	}
+
+	records, err := uc.consentRepo.GetCurrentConsents(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[domain.ConsentPurpose]*domain.ConsentRecord)
+	for _, record := range records {
+		current[record.Purpose] = record
+	}
+
+	resp := &GetConsentsResponse{Consents: []*ConsentStatus{}}
+	for _, purpose := range domain.ConsentPurposes {
+		status := &ConsentStatus{Purpose: purpose, LatestVersion: latestVersions[purpose]}
+		if record, ok := current[purpose]; ok {
+			status.Granted = record.Granted
+			status.TextVersion = record.TextVersion
+			status.UpdatedAt = record.CreatedAt
+		}
+		resp.Consents = append(resp.Consents, status)
+	}
+	return resp, nil
+}
+
+// UpdateConsents records the user's decisions, along with where they were made from.
+// A consent can only be given to the latest version of its text.
+func (uc *PrivacyUseCase) UpdateConsents(ctx context.Context, userID string, req *UpdateConsentsRequest, meta *SessionMetadata) (*GetConsentsResponse, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+	if len(req.Consents) == 0 {
+		return nil, fmt.Errorf("no consents to update")
+	}
+
+	texts, err := uc.consentRepo.GetLatestConsentTexts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	latestVersions := make(map[domain.ConsentPurpose]int)
+	for _, text := range texts {
+		latestVersions[text.Purpose] = text.Version
+	}
+
+	records := make([]*domain.ConsentRecord, 0, len(req.Consents))
+	for _, change := range req.Consents {
+		latest, ok := latestVersions[change.Purpose]
+		if !change.Purpose.IsValid() || !ok {
+			return nil, fmt.Errorf("unknown consent purpose %q", change.Purpose)
+		}
+		textVersion := change.TextVersion
+		if change.Granted && textVersion != latest {
+			return nil, fmt.Errorf("consent to %s must be given to the current text, version %d", change.Purpose, latest)
+		}
+		if textVersion == 0 {
+			textVersion = latest
+		}
+		if _, err := uc.consentRepo.GetConsentText(ctx, change.Purpose, textVersion); err != nil {
+			return nil, err
+		}
+
+		record := &domain.ConsentRecord{
+			UserID:      id,
+			Purpose:     change.Purpose,
+			Granted:     change.Granted,
+			TextVersion: textVersion,
+		}
+		if meta != nil {
+			record.IPAddress = meta.IPAddress
+			record.UserAgent = meta.Device
+		}
+		records = append(records, record)
+	}
+
+	for _, record := range records {
+		if err := uc.consentRepo.CreateConsentRecord(ctx, record); err != nil {
+			return nil, err
+		}
+	}
+	return uc.GetConsents(ctx, userID)
+}
+
+// UserDataExport is everything stored about a user.
+type UserDataExport struct {
+	ExportedAt        string                    `json:"exported_at"`
+	Profile           *domain.User              `json:"profile"`
+	Loyalty           *domain.UserLoyalty       `json:"loyalty,omitempty"`
+	LoyaltyPoints     []*domain.LoyaltyPoint    `json:"loyalty_points"`
+	LoyaltyActivities []*domain.LoyaltyActivity `json:"loyalty_activities"`
+	Orders            []*domain.Order           `json:"orders"`
+	Notifications     []*domain.Notification    `json:"notifications"`
+	Consents          []*domain.ConsentRecord   `json:"consents"`
+	LinkedAccounts    []*domain.UserIdentity    `json:"linked_accounts"`
+	Sessions          []*domain.Session         `json:"sessions"`
+}
+
+// ExportUserData collects everything stored about the user.
+func (uc *PrivacyUseCase) ExportUserData(ctx context.Context, userID string) (*UserDataExport, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	export := &UserDataExport{ExportedAt: time.Now().Format(time.RFC3339)}
+
+	if export.Profile, err = uc.userRepo.GetUserByID(ctx, id); err != nil {
+		return nil, err
+	}
+	export.Loyalty, err = uc.userRepo.GetUserLoyalty(ctx, id)
+	if err != nil && err.Error() != "user loyalty not found" {
+		return nil, err
+	}
+	if export.LoyaltyPoints, err = uc.userRepo.GetLoyaltyPointsByUserID(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.LoyaltyActivities, err = uc.userRepo.GetLoyaltyActivitiesByUserID(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.Orders, err = uc.orderRepo.GetOrdersByUserID(ctx, userID, nil); err != nil {
+		return nil, err
+	}
+	for _, order := range export.Orders {
+		items, err := uc.orderItemRepo.GetOrderItemsByOrderID(ctx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			order.Items = append(order.Items, *item)
+		}
+	}
+	if export.Notifications, err = uc.notificationRepo.GetNotificationsByUserID(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.Consents, err = uc.consentRepo.GetConsentHistory(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.LinkedAccounts, err = uc.identityRepo.GetIdentitiesByUserID(ctx, id); err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = uc.sessionRepo.GetActiveSessionsByUserID(ctx, id); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// ExportUserDataArchive packs the export into a ZIP archive with one JSON file per section.
+func (uc *PrivacyUseCase) ExportUserDataArchive(ctx context.Context, userID string) ([]byte, error) {
+	export, err := uc.ExportUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"loyalty.json", map[string]interface{}{
+			"summary":    export.Loyalty,
+			"points":     export.LoyaltyPoints,
+			"activities": export.LoyaltyActivities,
+		}},
+		{"orders.json", export.Orders},
+		{"notifications.json", export.Notifications},
+		{"consents.json", export.Consents},
+		{"linked_accounts.json", export.LinkedAccounts},
+		{"sessions.json", export.Sessions},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to archive: %w", file.name, err)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// missingConsent returns the first required purpose the user has not granted, or "" if all are granted.
+func missingConsent(current []*domain.ConsentRecord, required []domain.ConsentPurpose) domain.ConsentPurpose {
+	granted := make(map[domain.ConsentPurpose]bool)
+	for _, record := range current {
+		granted[record.Purpose] = record.Granted
+	}
+	for _, purpose := range required {
+		if !granted[purpose] {
+			return purpose
+		}
+	}
+	return ""
+}
@@ -0,0 +1,242 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const (
+	referralCodeLength   = 8
+	referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789" // No 0/O or 1/I, which are easy to mistype
+)
+
+// ReferralPolicy sets what a referral earns and when.
+type ReferralPolicy struct {
+	ReferrerPoints int
+	RefereePoints  int
+	HoldDays       int // How long the referee's first order must stand before anyone is rewarded, so returned orders earn nothing
+}
+
+// ReferralUseCase handles invite codes and rewards members for the members they bring in.
+type ReferralUseCase struct {
+	referralRepo        domain.ReferralRepository
+	orderRepo           domain.OrderRepository
+	loyaltyUseCase      *LoyaltyUseCase
+	notificationUseCase *NotificationUseCase
+	policy              ReferralPolicy
+	appBaseURL          string
+}
+
+// appBaseURL is the address of the web app that opens invite links.
+func NewReferralUseCase(referralRepo domain.ReferralRepository, orderRepo domain.OrderRepository, loyaltyUseCase *LoyaltyUseCase, notificationUseCase *NotificationUseCase, policy ReferralPolicy, appBaseURL string) *ReferralUseCase {
+	return &ReferralUseCase{
+		referralRepo:        referralRepo,
+		orderRepo:           orderRepo,
+		loyaltyUseCase:      loyaltyUseCase,
+		notificationUseCase: notificationUseCase,
+		policy:              policy,
+		appBaseURL:          strings.TrimSuffix(appBaseURL, "/"),
+	}
+}
+
+type GetReferralStatsResponse struct {
+	Code         string `json:"code"`
+	Link         string `json:"link"`
+	Invited      int    `json:"invited"`
+	Pending      int    `json:"pending"`   // Registered but not yet paid for an order
+	Qualified    int    `json:"qualified"` // Paid; rewarded once the hold period is over
+	Rewarded     int    `json:"rewarded"`
+	Rejected     int    `json:"rejected"`
+	PointsEarned int    `json:"points_earned"`
+}
+
+// GetReferralStats returns the user's invite code and link and how their invitations are doing.
+func (uc *ReferralUseCase) GetReferralStats(ctx context.Context, userID int) (*GetReferralStatsResponse, error) {
+	code, err := uc.getReferralCode(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	referrals, err := uc.referralRepo.GetReferralsByReferrerID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &GetReferralStatsResponse{
+		Code:    code,
+		Link:    fmt.Sprintf("%s/register?ref=%s", uc.appBaseURL, code),
+		Invited: len(referrals),
+	}
+	for _, referral := range referrals {
+		switch referral.Status {
+		case domain.ReferralPending:
+			resp.Pending++
+		case domain.ReferralQualified:
+			resp.Qualified++
+		case domain.ReferralRewarded:
+			resp.Rewarded++
+			resp.PointsEarned += referral.ReferrerPoints
+		case domain.ReferralRejected:
+			resp.Rejected++
+		}
+	}
+	return resp, nil
+}
+
+// getReferralCode returns the user's invite code, creating it the first time.
+func (uc *ReferralUseCase) getReferralCode(ctx context.Context, userID int) (string, error) {
+	for attempt := 0; attempt < 3; attempt++ {
+		code, err := uc.referralRepo.GetReferralCode(ctx, userID)
+		if err == nil {
+			return code, nil
+		}
+		if err.Error() != "referral code not found" {
+			return "", err
+		}
+
+		if code, err = generateReferralCode(); err != nil {
+			return "", err
+		}
+		// Another member may hold the code; if so, try a fresh one
+		if err := uc.referralRepo.CreateReferralCode(ctx, userID, code); err != nil && err.Error() != "referral code already exists" {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("failed to create referral code")
+}
+
+// FindReferrer returns the member an invite code belongs to.
+func (uc *ReferralUseCase) FindReferrer(ctx context.Context, code string) (int, error) {
+	referrerID, err := uc.referralRepo.GetUserIDByReferralCode(ctx, strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		if err.Error() == "referral code not found" {
+			return 0, fmt.Errorf("invalid referral code")
+		}
+		return 0, err
+	}
+	return referrerID, nil
+}
+
+// AttributeReferral records that a new member registered with the referrer's invite code.
+// A member registering from a device the referrer uses is taken for a self-referral and never rewarded.
+func (uc *ReferralUseCase) AttributeReferral(ctx context.Context, referrerID, refereeID int, meta *SessionMetadata) error {
+	referral := &domain.Referral{ReferrerID: referrerID, RefereeID: refereeID, Status: domain.ReferralPending}
+	if meta != nil {
+		referral.Device, referral.IPAddress = meta.Device, meta.IPAddress
+	}
+
+	shared, err := uc.referralRepo.SharesDevice(ctx, referrerID, refereeID, referral.Device, referral.IPAddress)
+	if err != nil {
+		return err
+	}
+	if shared {
+		referral.Status = domain.ReferralRejected
+		referral.RejectionReason = "registered from the referrer's device"
+	}
+	return uc.referralRepo.CreateReferral(ctx, referral)
+}
+
+// QualifyReferral starts the hold period of the referral of a member who has paid for an order.
+// Only the first paid order counts; later ones find the referral already qualified.
+func (uc *ReferralUseCase) QualifyReferral(ctx context.Context, refereeID, orderID int) error {
+	rewardAfter := time.Now().AddDate(0, 0, uc.policy.HoldDays)
+	if _, err := uc.referralRepo.QualifyReferral(ctx, refereeID, orderID, rewardAfter); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RewardReferrals rewards both members of every referral past its hold period.
+// A referral whose order was cancelled waits for the referee's next paid order instead.
+func (uc *ReferralUseCase) RewardReferrals(ctx context.Context) error {
+	referrals, err := uc.referralRepo.GetReferralsDueForReward(ctx)
+	if err != nil {
+		return err
+	}
+	for _, referral := range referrals {
+		if err := uc.rewardReferral(ctx, referral); err != nil {
+			log.Printf("Failed to reward referral %d: %v", referral.ID, err)
+		}
+	}
+	return nil
+}
+
+func (uc *ReferralUseCase) rewardReferral(ctx context.Context, referral *domain.Referral) error {
+	if referral.OrderID == nil {
+		return uc.referralRepo.ResetReferral(ctx, referral.ID)
+	}
+	order, err := uc.orderRepo.GetOrderByID(ctx, *referral.OrderID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			return uc.referralRepo.ResetReferral(ctx, referral.ID)
+		}
+		return err
+	}
+	if order.Status == "cancelled" {
+		return uc.referralRepo.ResetReferral(ctx, referral.ID)
+	}
+
+	// Checked again, as either member may since have signed in from the other's device
+	shared, err := uc.referralRepo.SharesDevice(ctx, referral.ReferrerID, referral.RefereeID, referral.Device, referral.IPAddress)
+	if err != nil {
+		return err
+	}
+	if shared {
+		return uc.referralRepo.RejectReferral(ctx, referral.ID, "referee used the referrer's device")
+	}
+
+	reference := fmt.Sprintf("referral:%d", referral.ID)
+	if uc.policy.ReferrerPoints > 0 {
+		if err := uc.loyaltyUseCase.AddLoyaltyPoints(ctx, referral.ReferrerID, uc.policy.ReferrerPoints, "referral", reference, reference+":referrer"); err != nil {
+			return err
+		}
+	}
+	if uc.policy.RefereePoints > 0 {
+		if err := uc.loyaltyUseCase.AddLoyaltyPoints(ctx, referral.RefereeID, uc.policy.RefereePoints, "referral", reference, reference+":referee"); err != nil {
+			return err
+		}
+	}
+	referral.ReferrerPoints, referral.RefereePoints = uc.policy.ReferrerPoints, uc.policy.RefereePoints
+	if err := uc.referralRepo.MarkReferralRewarded(ctx, referral); err != nil {
+		return err
+	}
+
+	uc.notifyReward(ctx, referral.ReferrerID, referral.ReferrerPoints, "Приглашённый вами друг совершил первую покупку. Вам начислено %d баллов.")
+	uc.notifyReward(ctx, referral.RefereeID, referral.RefereePoints, "Спасибо за первую покупку по приглашению! Вам начислено %d баллов.")
+	return nil
+}
+
+func (uc *ReferralUseCase) notifyReward(ctx context.Context, userID, points int, format string) {
+	if points <= 0 {
+		return
+	}
+	notificationReq := &SendNotificationRequest{
+		UserID:  strconv.Itoa(userID),
+		Type:    "referral_reward",
+		Title:   "Баллы за приглашение",
+		Message: fmt.Sprintf(format, points),
+	}
+	if err := uc.notificationUseCase.SendNotification(ctx, notificationReq); err != nil {
+		log.Printf("Failed to notify user %d about referral reward: %v", userID, err)
+	}
+}
+
+func generateReferralCode() (string, error) {
+	code := make([]byte, referralCodeLength)
+	max := big.NewInt(int64(len(referralCodeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate referral code: %w", err)
+		}
+		code[i] = referralCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
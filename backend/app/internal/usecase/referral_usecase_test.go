@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type fakeReferralRepo struct {
+	domain.ReferralRepository
+	shared      bool
+	failMarks   int // MarkReferralRewarded calls to fail before one succeeds
+	referrals   map[int]*domain.Referral
+	rewardAfter time.Time
+}
+
+func (r *fakeReferralRepo) SharesDevice(ctx context.Context, referrerID, refereeID int, device, ipAddress string) (bool, error) {
+	return r.shared, nil
+}
+
+func (r *fakeReferralRepo) CreateReferral(ctx context.Context, referral *domain.Referral) error {
+	referral.ID = len(r.referrals) + 1
+	r.referrals[referral.ID] = referral
+	return nil
+}
+
+func (r *fakeReferralRepo) QualifyReferral(ctx context.Context, refereeID, orderID int, rewardAfter time.Time) (bool, error) {
+	r.rewardAfter = rewardAfter
+	return true, nil
+}
+
+func (r *fakeReferralRepo) GetReferralsDueForReward(ctx context.Context) ([]*domain.Referral, error) {
+	var due []*domain.Referral
+	for _, referral := range r.referrals {
+		if referral.Status == domain.ReferralQualified {
+			due = append(due, referral)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeReferralRepo) MarkReferralRewarded(ctx context.Context, referral *domain.Referral) error {
+	if r.failMarks > 0 {
+		r.failMarks--
+		return fmt.Errorf("connection reset")
+	}
+	r.referrals[referral.ID].Status = domain.ReferralRewarded
+	return nil
+}
+
+func (r *fakeReferralRepo) ResetReferral(ctx context.Context, referralID int) error {
+	r.referrals[referralID].Status, r.referrals[referralID].OrderID = domain.ReferralPending, nil
+	return nil
+}
+
+func (r *fakeReferralRepo) RejectReferral(ctx context.Context, referralID int, reason string) error {
+	r.referrals[referralID].Status, r.referrals[referralID].RejectionReason = domain.ReferralRejected, reason
+	return nil
+}
+
+type fakeOrderRepo struct {
+	domain.OrderRepository
+	orders map[int]*domain.Order
+}
+
+func (r *fakeOrderRepo) GetOrderByID(ctx context.Context, orderID int) (*domain.Order, error) {
+	if order, ok := r.orders[orderID]; ok {
+		return order, nil
+	}
+	return nil, fmt.Errorf("order not found")
+}
+
+func TestAttributeReferral(t *testing.T) {
+	tests := []struct {
+		name       string
+		shared     bool
+		wantStatus domain.ReferralStatus
+		wantReason string
+	}{
+		{name: "new member", wantStatus: domain.ReferralPending},
+		{name: "self-referral from the referrer's device", shared: true, wantStatus: domain.ReferralRejected, wantReason: "registered from the referrer's device"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			referrals := &fakeReferralRepo{shared: tt.shared, referrals: map[int]*domain.Referral{}}
+			uc := NewReferralUseCase(referrals, nil, nil, nil, ReferralPolicy{}, "")
+
+			meta := &SessionMetadata{Device: "iPhone", IPAddress: "10.0.0.1"}
+			if err := uc.AttributeReferral(context.Background(), 10, 20, meta); err != nil {
+				t.Fatalf("AttributeReferral() error = %v", err)
+			}
+			referral := referrals.referrals[1]
+			if referral == nil {
+				t.Fatal("referral not created")
+			}
+			if referral.Status != tt.wantStatus || referral.RejectionReason != tt.wantReason {
+				t.Errorf("referral status, reason = %q, %q, want %q, %q", referral.Status, referral.RejectionReason, tt.wantStatus, tt.wantReason)
+			}
+			if referral.ReferrerID != 10 || referral.RefereeID != 20 || referral.Device != "iPhone" || referral.IPAddress != "10.0.0.1" {
+				t.Errorf("referral = %+v, want referrer 10 and referee 20 on the session's device", referral)
+			}
+		})
+	}
+}
+
+func TestQualifyReferral(t *testing.T) {
+	referrals := &fakeReferralRepo{}
+	uc := NewReferralUseCase(referrals, nil, nil, nil, ReferralPolicy{HoldDays: 14}, "")
+
+	if err := uc.QualifyReferral(context.Background(), 20, 5); err != nil {
+		t.Fatalf("QualifyReferral() error = %v", err)
+	}
+	if want := time.Now().AddDate(0, 0, 14); referrals.rewardAfter.Sub(want).Abs() > time.Minute {
+		t.Errorf("rewarded after %v, want %v", referrals.rewardAfter, want)
+	}
+}
+
+func TestRewardReferrals(t *testing.T) {
+	const (
+		referrerID = 10
+		refereeID  = 20
+		orderID    = 5
+	)
+	intPtr := func(v int) *int { return &v }
+	tests := []struct {
+		name             string
+		policy           ReferralPolicy
+		orderID          *int
+		orders           map[int]*domain.Order
+		shared           bool
+		failMarks        int
+		runs             int
+		wantStatus       domain.ReferralStatus
+		wantReferrer     int
+		wantReferee      int
+		wantNotification int
+	}{
+		{
+			name: "both members rewarded", policy: ReferralPolicy{ReferrerPoints: 500, RefereePoints: 200},
+			orderID: intPtr(orderID), orders: map[int]*domain.Order{orderID: {ID: orderID, Status: "paid"}}, runs: 1,
+			wantStatus: domain.ReferralRewarded, wantReferrer: 500, wantReferee: 200, wantNotification: 2,
+		},
+		{
+			name: "retry after a failed update rewards once", policy: ReferralPolicy{ReferrerPoints: 500, RefereePoints: 200},
+			orderID: intPtr(orderID), orders: map[int]*domain.Order{orderID: {ID: orderID, Status: "paid"}}, failMarks: 1, runs: 2,
+			wantStatus: domain.ReferralRewarded, wantReferrer: 500, wantReferee: 200, wantNotification: 2,
+		},
+		{
+			name: "referee bonus only", policy: ReferralPolicy{RefereePoints: 200},
+			orderID: intPtr(orderID), orders: map[int]*domain.Order{orderID: {ID: orderID, Status: "paid"}}, runs: 1,
+			wantStatus: domain.ReferralRewarded, wantReferee: 200, wantNotification: 1,
+		},
+		{
+			name: "cancelled order waits for the next one", policy: ReferralPolicy{ReferrerPoints: 500, RefereePoints: 200},
+			orderID: intPtr(orderID), orders: map[int]*domain.Order{orderID: {ID: orderID, Status: "cancelled"}}, runs: 1,
+			wantStatus: domain.ReferralPending,
+		},
+		{
+			name: "deleted order waits for the next one", policy: ReferralPolicy{ReferrerPoints: 500, RefereePoints: 200},
+			orderID: intPtr(orderID), runs: 1,
+			wantStatus: domain.ReferralPending,
+		},
+		{
+			name: "no order waits for one", policy: ReferralPolicy{ReferrerPoints: 500, RefereePoints: 200},
+			runs: 1, wantStatus: domain.ReferralPending,
+		},
+		{
+			name: "referee on the referrer's device", policy: ReferralPolicy{ReferrerPoints: 500, RefereePoints: 200},
+			orderID: intPtr(orderID), orders: map[int]*domain.Order{orderID: {ID: orderID, Status: "paid"}}, shared: true, runs: 1,
+			wantStatus: domain.ReferralRejected,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			referrals := &fakeReferralRepo{shared: tt.shared, failMarks: tt.failMarks, referrals: map[int]*domain.Referral{
+				1: {ID: 1, ReferrerID: referrerID, RefereeID: refereeID, Status: domain.ReferralQualified, OrderID: tt.orderID},
+			}}
+			users := &fakeUserRepo{}
+			loyaltyUseCase, _ := newTestLoyaltyUseCase(users, nil)
+			notifications := &fakeNotificationRepo{}
+			notificationUseCase := NewNotificationUseCase(notifications, nil, nil, nil, nil)
+			uc := NewReferralUseCase(referrals, &fakeOrderRepo{orders: tt.orders}, loyaltyUseCase, notificationUseCase, tt.policy, "")
+
+			for i := 0; i < tt.runs; i++ {
+				if err := uc.RewardReferrals(context.Background()); err != nil {
+					t.Fatalf("RewardReferrals() error = %v", err)
+				}
+			}
+			if got := referrals.referrals[1].Status; got != tt.wantStatus {
+				t.Errorf("referral status = %q, want %q", got, tt.wantStatus)
+			}
+			balance := func(userID int) int {
+				if loyalty, ok := users.loyalties[userID]; ok {
+					return loyalty.CurrentPoints
+				}
+				return 0
+			}
+			if balance(referrerID) != tt.wantReferrer || balance(refereeID) != tt.wantReferee {
+				t.Errorf("referrer, referee balances = %d, %d, want %d, %d", balance(referrerID), balance(refereeID), tt.wantReferrer, tt.wantReferee)
+			}
+			if len(notifications.notifications) != tt.wantNotification {
+				t.Errorf("sent %d notifications, want %d", len(notifications.notifications), tt.wantNotification)
+			}
+		})
+	}
+}
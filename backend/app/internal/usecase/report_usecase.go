@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const (
+	reportMonthLayout     = "2006-01"
+	defaultReportMonths   = 12
+	maxReportMonths       = 120
+	defaultForecastMonths = 12
+	maxForecastMonths     = 60
+)
+
+// ReportUseCase reports on the loyalty program for finance and marketing.
+type ReportUseCase struct {
+	reportRepo       domain.LoyaltyReportRepository
+	redemptionPolicy RedemptionPolicy
+}
+
+func NewReportUseCase(reportRepo domain.LoyaltyReportRepository, redemptionPolicy RedemptionPolicy) *ReportUseCase {
+	return &ReportUseCase{reportRepo: reportRepo, redemptionPolicy: redemptionPolicy}
+}
+
+// ReportPeriod is the months a report covers, both given as YYYY-MM and both included.
+// Without them a report covers the last 12 months up to the current one.
+type ReportPeriod struct {
+	From string
+	To   string
+}
+
+// TierLiabilityResponse is the points held by a tier's members and what they are worth.
+type TierLiabilityResponse struct {
+	*domain.TierLiability
+	Value float64 `json:"value"`
+}
+
+// LiabilityReport is what the program owes members for the points they hold.
+type LiabilityReport struct {
+	AsOf              string                   `json:"as_of"`
+	OutstandingPoints int                      `json:"outstanding_points"`
+	Members           int                      `json:"members"`
+	PointValue        float64                  `json:"point_value"` // Before the tier multiplier
+	Liability         float64                  `json:"liability"`   // Money the outstanding points pay for at checkout
+	Tiers             []*TierLiabilityResponse `json:"tiers"`
+}
+
+// PointsFlowResponse is a month of points earned and burned.
+type PointsFlowResponse struct {
+	*domain.PointsFlow
+	Net      int     `json:"net"`       // Change to the outstanding points
+	BurnRate float64 `json:"burn_rate"` // Points redeemed per point earned
+}
+
+type PointsFlowReport struct {
+	Months []*PointsFlowResponse `json:"months"`
+}
+
+type ExpiringPointsReport struct {
+	Months []*domain.ExpiringPoints `json:"months"`
+}
+
+type TierDistributionReport struct {
+	Snapshots []*domain.TierSnapshot `json:"snapshots"`
+}
+
+type ActiveMembersReport struct {
+	Months []*domain.ActiveMembers `json:"months"`
+}
+
+// GetLiabilityReport returns the points members hold now and their value. Points are valued at what they
+// pay for at checkout, so members of tiers with a point value multiplier hold more per point.
+func (uc *ReportUseCase) GetLiabilityReport(ctx context.Context) (*LiabilityReport, error) {
+	liabilities, err := uc.reportRepo.GetPointsLiability(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LiabilityReport{
+		AsOf:       time.Now().Format(time.RFC3339),
+		PointValue: uc.redemptionPolicy.PointValue,
+		Tiers:      []*TierLiabilityResponse{},
+	}
+	for _, l := range liabilities {
+		multiplier := l.PointValueMultiplier
+		if multiplier <= 0 {
+			multiplier = 1
+		}
+		value := math.Round(float64(l.Points)*uc.redemptionPolicy.PointValue*multiplier*100) / 100
+		report.Tiers = append(report.Tiers, &TierLiabilityResponse{TierLiability: l, Value: value})
+		report.OutstandingPoints += l.Points
+		report.Members += l.Members
+		report.Liability += value
+	}
+	report.Liability = math.Round(report.Liability*100) / 100
+	return report, nil
+}
+
+// GetPointsFlowReport returns the points earned, redeemed, expired and adjusted in each month of the period.
+func (uc *ReportUseCase) GetPointsFlowReport(ctx context.Context, period ReportPeriod) (*PointsFlowReport, error) {
+	from, to, err := period.bounds()
+	if err != nil {
+		return nil, err
+	}
+	flows, err := uc.reportRepo.GetPointsFlow(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PointsFlowReport{Months: []*PointsFlowResponse{}}
+	for _, f := range flows {
+		month := &PointsFlowResponse{
+			PointsFlow: f,
+			Net:        f.Earned - f.Redeemed - f.Expired + f.Adjusted + f.Reversed,
+		}
+		if f.Earned > 0 {
+			month.BurnRate = math.Round(float64(f.Redeemed)/float64(f.Earned)*1000) / 1000
+		}
+		report.Months = append(report.Months, month)
+	}
+	return report, nil
+}
+
+// GetExpiringPointsReport forecasts how many unspent points expire in each of the coming months,
+// assuming members spend none of them. Points past expiry that the daily job has not expired yet come first.
+func (uc *ReportUseCase) GetExpiringPointsReport(ctx context.Context, months int) (*ExpiringPointsReport, error) {
+	if months == 0 {
+		months = defaultForecastMonths
+	}
+	if months < 1 || months > maxForecastMonths {
+		return nil, fmt.Errorf("months must be between 1 and %d", maxForecastMonths)
+	}
+	until := startOfMonth(time.Now()).AddDate(0, months, 0)
+	expiring, err := uc.reportRepo.GetExpiringPoints(ctx, until)
+	if err != nil {
+		return nil, err
+	}
+	if expiring == nil {
+		expiring = []*domain.ExpiringPoints{}
+	}
+	return &ExpiringPointsReport{Months: expiring}, nil
+}
+
+// GetTierDistributionReport returns how many members each tier had at the end of each month of the period,
+// as recorded by the daily snapshot; the current month shows the latest snapshot.
+func (uc *ReportUseCase) GetTierDistributionReport(ctx context.Context, period ReportPeriod) (*TierDistributionReport, error) {
+	from, to, err := period.bounds()
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := uc.reportRepo.GetTierSnapshots(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if snapshots == nil {
+		snapshots = []*domain.TierSnapshot{}
+	}
+	return &TierDistributionReport{Snapshots: snapshots}, nil
+}
+
+// GetActiveMembersReport returns how many members were active in each month of the period.
+func (uc *ReportUseCase) GetActiveMembersReport(ctx context.Context, period ReportPeriod) (*ActiveMembersReport, error) {
+	from, to, err := period.bounds()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := uc.reportRepo.GetActiveMembers(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if counts == nil {
+		counts = []*domain.ActiveMembers{}
+	}
+	return &ActiveMembersReport{Months: counts}, nil
+}
+
+// RecordTierSnapshot records today's tier distribution for the tier distribution report.
+func (uc *ReportUseCase) RecordTierSnapshot(ctx context.Context) error {
+	return uc.reportRepo.RecordTierSnapshot(ctx)
+}
+
+// bounds returns the start of the first month of the period and the start of the month after the last.
+func (p ReportPeriod) bounds() (time.Time, time.Time, error) {
+	to := startOfMonth(time.Now())
+	if p.To != "" {
+		t, err := time.ParseInLocation(reportMonthLayout, p.To, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to: expected YYYY-MM")
+		}
+		to = t
+	}
+	from := to.AddDate(0, 1-defaultReportMonths, 0)
+	if p.From != "" {
+		t, err := time.ParseInLocation(reportMonthLayout, p.From, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from: expected YYYY-MM")
+		}
+		from = t
+	}
+
+	end := to.AddDate(0, 1, 0)
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+	}
+	if from.AddDate(0, maxReportMonths, 0).Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("a report covers at most %d months", maxReportMonths)
+	}
+	return from, end, nil
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+type fakeReportRepo struct {
+	domain.LoyaltyReportRepository
+	liabilities []*domain.TierLiability
+	flows       []*domain.PointsFlow
+	until       time.Time
+}
+
+func (r *fakeReportRepo) GetPointsLiability(ctx context.Context) ([]*domain.TierLiability, error) {
+	return r.liabilities, nil
+}
+
+func (r *fakeReportRepo) GetPointsFlow(ctx context.Context, from, to time.Time) ([]*domain.PointsFlow, error) {
+	return r.flows, nil
+}
+
+func (r *fakeReportRepo) GetExpiringPoints(ctx context.Context, until time.Time) ([]*domain.ExpiringPoints, error) {
+	r.until = until
+	return nil, nil
+}
+
+func TestGetLiabilityReport(t *testing.T) {
+	tests := []struct {
+		name          string
+		liabilities   []*domain.TierLiability
+		wantValues    []float64
+		wantPoints    int
+		wantMembers   int
+		wantLiability float64
+	}{
+		{name: "no members", wantValues: []float64{}},
+		{
+			name: "valued with the tier multiplier",
+			liabilities: []*domain.TierLiability{
+				{TierName: "Bronze", PointValueMultiplier: 1, Members: 10, Points: 5000},
+				{TierName: "Gold", PointValueMultiplier: 1.5, Members: 2, Points: 3000},
+			},
+			wantValues: []float64{500, 450}, wantPoints: 8000, wantMembers: 12, wantLiability: 950,
+		},
+		{
+			name:        "members without a tier valued at the base",
+			liabilities: []*domain.TierLiability{{TierName: "", Members: 3, Points: 1234}},
+			wantValues:  []float64{123.4}, wantPoints: 1234, wantMembers: 3, wantLiability: 123.4,
+		},
+		{
+			name:        "rounded to cents",
+			liabilities: []*domain.TierLiability{{TierName: "Silver", PointValueMultiplier: 1.17, Members: 1, Points: 333}},
+			wantValues:  []float64{38.96}, wantPoints: 333, wantMembers: 1, wantLiability: 38.96,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := NewReportUseCase(&fakeReportRepo{liabilities: tt.liabilities}, RedemptionPolicy{PointValue: 0.1})
+
+			report, err := uc.GetLiabilityReport(context.Background())
+			if err != nil {
+				t.Fatalf("GetLiabilityReport() error = %v", err)
+			}
+			values := []float64{}
+			for _, tier := range report.Tiers {
+				values = append(values, tier.Value)
+			}
+			if !reflect.DeepEqual(values, tt.wantValues) {
+				t.Errorf("tier values = %v, want %v", values, tt.wantValues)
+			}
+			if report.OutstandingPoints != tt.wantPoints || report.Members != tt.wantMembers || report.Liability != tt.wantLiability {
+				t.Errorf("points, members, liability = %d, %d, %v, want %d, %d, %v",
+					report.OutstandingPoints, report.Members, report.Liability, tt.wantPoints, tt.wantMembers, tt.wantLiability)
+			}
+		})
+	}
+}
+
+func TestGetPointsFlowReport(t *testing.T) {
+	tests := []struct {
+		name         string
+		flow         *domain.PointsFlow
+		wantNet      int
+		wantBurnRate float64
+	}{
+		{name: "earned and redeemed", flow: &domain.PointsFlow{Month: "2026-01", Earned: 3000, Redeemed: 1000}, wantNet: 2000, wantBurnRate: 0.333},
+		{
+			name:    "expiries, adjustments and reversals",
+			flow:    &domain.PointsFlow{Month: "2026-02", Earned: 1000, Redeemed: 500, Expired: 200, Adjusted: -50, Reversed: -100},
+			wantNet: 150, wantBurnRate: 0.5,
+		},
+		{name: "nothing earned", flow: &domain.PointsFlow{Month: "2026-03", Redeemed: 400}, wantNet: -400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := NewReportUseCase(&fakeReportRepo{flows: []*domain.PointsFlow{tt.flow}}, RedemptionPolicy{})
+
+			report, err := uc.GetPointsFlowReport(context.Background(), ReportPeriod{})
+			if err != nil {
+				t.Fatalf("GetPointsFlowReport() error = %v", err)
+			}
+			if month := report.Months[0]; month.Net != tt.wantNet || month.BurnRate != tt.wantBurnRate {
+				t.Errorf("net, burn rate = %d, %v, want %d, %v", month.Net, month.BurnRate, tt.wantNet, tt.wantBurnRate)
+			}
+		})
+	}
+}
+
+func TestGetExpiringPointsReport(t *testing.T) {
+	thisMonth := startOfMonth(time.Now())
+	tests := []struct {
+		name      string
+		months    int
+		wantUntil time.Time
+		wantErr   string
+	}{
+		{name: "default forecast", months: 0, wantUntil: thisMonth.AddDate(0, 12, 0)},
+		{name: "one month", months: 1, wantUntil: thisMonth.AddDate(0, 1, 0)},
+		{name: "longest forecast", months: 60, wantUntil: thisMonth.AddDate(0, 60, 0)},
+		{name: "negative", months: -1, wantErr: "months must be between 1 and 60"},
+		{name: "too long", months: 61, wantErr: "months must be between 1 and 60"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeReportRepo{}
+			uc := NewReportUseCase(repo, RedemptionPolicy{})
+
+			report, err := uc.GetExpiringPointsReport(context.Background(), tt.months)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("GetExpiringPointsReport() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetExpiringPointsReport() error = %v", err)
+			}
+			if !repo.until.Equal(tt.wantUntil) {
+				t.Errorf("forecast until %v, want %v", repo.until, tt.wantUntil)
+			}
+			if report.Months == nil {
+				t.Error("Months = nil, want an empty list")
+			}
+		})
+	}
+}
+
+func TestReportPeriodBounds(t *testing.T) {
+	month := func(year int, m time.Month) time.Time { return time.Date(year, m, 1, 0, 0, 0, 0, time.Local) }
+	thisMonth := startOfMonth(time.Now())
+	tests := []struct {
+		name     string
+		period   ReportPeriod
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  string
+	}{
+		{name: "last 12 months by default", wantFrom: thisMonth.AddDate(0, -11, 0), wantTo: thisMonth.AddDate(0, 1, 0)},
+		{name: "12 months up to the given one", period: ReportPeriod{To: "2025-06"}, wantFrom: month(2024, time.July), wantTo: month(2025, time.July)},
+		{name: "both months included", period: ReportPeriod{From: "2025-01", To: "2025-03"}, wantFrom: month(2025, time.January), wantTo: month(2025, time.April)},
+		{name: "single month", period: ReportPeriod{From: "2025-12", To: "2025-12"}, wantFrom: month(2025, time.December), wantTo: month(2026, time.January)},
+		{name: "longest period", period: ReportPeriod{From: "2015-01", To: "2024-12"}, wantFrom: month(2015, time.January), wantTo: month(2025, time.January)},
+		{name: "too long", period: ReportPeriod{From: "2015-01", To: "2025-01"}, wantErr: "a report covers at most 120 months"},
+		{name: "from after to", period: ReportPeriod{From: "2025-04", To: "2025-03"}, wantErr: "from must not be after to"},
+		{name: "bad from", period: ReportPeriod{From: "2025-4"}, wantErr: "invalid from: expected YYYY-MM"},
+		{name: "bad to", period: ReportPeriod{To: "March 2025"}, wantErr: "invalid to: expected YYYY-MM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := tt.period.bounds()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("bounds() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bounds() error = %v", err)
+			}
+			if !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("bounds() = %v, %v, want %v, %v", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
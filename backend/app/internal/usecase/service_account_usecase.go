@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const (
+	apiKeyPrefix            = "kgs_"
+	apiKeyDisplayPrefixLen  = 12             // apiKeyPrefix plus the first characters of the secret
+	apiKeyTouchInterval     = time.Minute    // Last-used time is written at most this often per key
+	defaultRotationGrace    = 24 * time.Hour // How long a rotated-out key keeps working by default
+	maxRotationGrace        = 7 * 24 * time.Hour
+	maxAPIKeyLifetimeInDays = 3650
+)
+
+// ServiceAccountUseCase manages service accounts and authenticates their API keys.
+type ServiceAccountUseCase struct {
+	serviceAccountRepo domain.ServiceAccountRepository
+}
+
+// NewServiceAccountUseCase creates a new ServiceAccountUseCase.
+func NewServiceAccountUseCase(serviceAccountRepo domain.ServiceAccountRepository) *ServiceAccountUseCase {
+	return &ServiceAccountUseCase{serviceAccountRepo: serviceAccountRepo}
+}
+
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type GetServiceAccountsResponse struct {
+	ServiceAccounts []*domain.ServiceAccount `json:"service_accounts"`
+}
+
+type CreateAPIKeyRequest struct {
+	Scopes        []domain.APIScope `json:"scopes"`
+	ExpiresInDays int               `json:"expires_in_days,omitempty"` // Zero for a key that does not expire
+}
+
+type RotateAPIKeyRequest struct {
+	GracePeriodHours *int `json:"grace_period_hours,omitempty"` // How long the old key keeps working; 24 by default
+	ExpiresInDays    int  `json:"expires_in_days,omitempty"`    // Lifetime of the new key; zero for a key that does not expire
+}
+
+// CreateAPIKeyResponse carries the plaintext key, which is shown only once and cannot be recovered.
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey *domain.APIKey `json:"api_key"`
+}
+
+// CreateServiceAccount registers a new non-human client. It has no keys until one is issued.
+func (uc *ServiceAccountUseCase) CreateServiceAccount(ctx context.Context, req *CreateServiceAccountRequest) (*domain.ServiceAccount, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	account := &domain.ServiceAccount{Name: strings.TrimSpace(req.Name), Description: strings.TrimSpace(req.Description)}
+	if err := uc.serviceAccountRepo.CreateServiceAccount(ctx, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetServiceAccounts lists every service account with its keys.
+func (uc *ServiceAccountUseCase) GetServiceAccounts(ctx context.Context) (*GetServiceAccountsResponse, error) {
+	accounts, err := uc.serviceAccountRepo.GetServiceAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		if account.Keys, err = uc.serviceAccountRepo.GetAPIKeysByServiceAccountID(ctx, account.ID); err != nil {
+			return nil, err
+		}
+	}
+	if accounts == nil {
+		accounts = []*domain.ServiceAccount{}
+	}
+	return &GetServiceAccountsResponse{ServiceAccounts: accounts}, nil
+}
+
+// DisableServiceAccount stops the account and all of its keys from authenticating.
+func (uc *ServiceAccountUseCase) DisableServiceAccount(ctx context.Context, accountID int) error {
+	return uc.serviceAccountRepo.DisableServiceAccount(ctx, accountID)
+}
+
+// CreateAPIKey issues a new key with the given scopes to an enabled service account.
+func (uc *ServiceAccountUseCase) CreateAPIKey(ctx context.Context, accountID int, req *CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+	for _, scope := range req.Scopes {
+		if !scope.IsValid() {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+	expiresAt, err := apiKeyExpiry(req.ExpiresInDays)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := uc.serviceAccountRepo.GetServiceAccountByID(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if account.DisabledAt != nil {
+		return nil, fmt.Errorf("service account is disabled")
+	}
+	return uc.issueAPIKey(ctx, accountID, req.Scopes, expiresAt)
+}
+
+// RotateAPIKey issues a replacement key with the same scopes, and lets the old key
+// keep working for a grace period so terminals can be switched over without downtime.
+func (uc *ServiceAccountUseCase) RotateAPIKey(ctx context.Context, accountID, keyID int, req *RotateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	grace := defaultRotationGrace
+	if req.GracePeriodHours != nil {
+		grace = time.Duration(*req.GracePeriodHours) * time.Hour
+		if grace < 0 || grace > maxRotationGrace {
+			return nil, fmt.Errorf("grace_period_hours must be between 0 and %d", int(maxRotationGrace.Hours()))
+		}
+	}
+	expiresAt, err := apiKeyExpiry(req.ExpiresInDays)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := uc.getAccountKey(ctx, accountID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("api key is revoked")
+	}
+
+	resp, err := uc.issueAPIKey(ctx, accountID, key.Scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if grace == 0 {
+		err = uc.serviceAccountRepo.RevokeAPIKey(ctx, keyID)
+	} else {
+		err = uc.serviceAccountRepo.ExpireAPIKey(ctx, keyID, time.Now().Add(grace))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RevokeAPIKey stops a key from authenticating immediately.
+func (uc *ServiceAccountUseCase) RevokeAPIKey(ctx context.Context, accountID, keyID int) error {
+	if _, err := uc.getAccountKey(ctx, accountID, keyID); err != nil {
+		return err
+	}
+	return uc.serviceAccountRepo.RevokeAPIKey(ctx, keyID)
+}
+
+// Authenticate resolves a presented API key and records its use.
+func (uc *ServiceAccountUseCase) Authenticate(ctx context.Context, rawKey, ipAddress string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, fmt.Errorf("invalid api key")
+	}
+
+	key, err := uc.serviceAccountRepo.GetActiveAPIKeyByHash(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		if err.Error() == "api key not found" {
+			return nil, fmt.Errorf("invalid api key")
+		}
+		return nil, err
+	}
+
+	// Terminals call often, so the last-used time is only written once in a while
+	if key.LastUsedAt == nil || key.LastUsedIP != ipAddress || lastUsedBefore(*key.LastUsedAt, time.Now().Add(-apiKeyTouchInterval)) {
+		if err := uc.serviceAccountRepo.TouchAPIKey(ctx, key.ID, ipAddress); err != nil {
+			log.Printf("Failed to record api key usage: %v", err)
+		}
+	}
+	return key, nil
+}
+
+func (uc *ServiceAccountUseCase) issueAPIKey(ctx context.Context, accountID int, scopes []domain.APIScope, expiresAt *string) (*CreateAPIKeyResponse, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	rawKey := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	key := &domain.APIKey{
+		ServiceAccountID: accountID,
+		Prefix:           rawKey[:apiKeyDisplayPrefixLen],
+		KeyHash:          hashAPIKey(rawKey),
+		Scopes:           scopes,
+		ExpiresAt:        expiresAt,
+	}
+	if err := uc.serviceAccountRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, err
+	}
+	return &CreateAPIKeyResponse{Key: rawKey, APIKey: key}, nil
+}
+
+// getAccountKey returns the key only if it belongs to the given account.
+func (uc *ServiceAccountUseCase) getAccountKey(ctx context.Context, accountID, keyID int) (*domain.APIKey, error) {
+	key, err := uc.serviceAccountRepo.GetAPIKeyByID(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.ServiceAccountID != accountID {
+		return nil, fmt.Errorf("api key not found")
+	}
+	return key, nil
+}
+
+// apiKeyExpiry returns when a key issued now for the given number of days expires, or nil for zero days.
+func apiKeyExpiry(days int) (*string, error) {
+	if days < 0 || days > maxAPIKeyLifetimeInDays {
+		return nil, fmt.Errorf("expires_in_days must be between 0 and %d", maxAPIKeyLifetimeInDays)
+	}
+	if days == 0 {
+		return nil, nil
+	}
+	expiresAt := time.Now().AddDate(0, 0, days).Format(time.RFC3339)
+	return &expiresAt, nil
+}
+
+// hashAPIKey hashes a key for storage. Keys are long and random, so a fast hash is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func lastUsedBefore(lastUsedAt string, threshold time.Time) bool {
+	t, err := time.Parse(time.RFC3339Nano, lastUsedAt)
+	return err != nil || t.Before(threshold)
+}
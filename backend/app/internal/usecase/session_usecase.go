@@ -0,0 +1,286 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// SessionUseCase issues access and refresh tokens and manages the server-side sessions behind them.
+type SessionUseCase struct {
+	sessionRepo domain.SessionRepository
+	userRepo    domain.UserRepository
+	tokenIssuer domain.TokenIssuer
+}
+
+// NewSessionUseCase creates a new SessionUseCase.
+func NewSessionUseCase(sessionRepo domain.SessionRepository, userRepo domain.UserRepository, tokenIssuer domain.TokenIssuer) *SessionUseCase {
+	return &SessionUseCase{sessionRepo: sessionRepo, userRepo: userRepo, tokenIssuer: tokenIssuer}
+}
+
+// SessionMetadata describes the client a session is opened from.
+type SessionMetadata struct {
+	Device    string
+	IPAddress string
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type SessionResponse struct {
+	ID         string `json:"id"`
+	Device     string `json:"device"`
+	IPAddress  string `json:"ip_address"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+	Current    bool   `json:"current"`
+}
+
+type GetSessionsResponse struct {
+	Sessions []*SessionResponse `json:"sessions"`
+}
+
+// StartSession opens a new session for an authenticated user and returns its token pair.
+func (uc *SessionUseCase) StartSession(ctx context.Context, user *domain.User, meta *SessionMetadata) (*LoginUserResponse, error) {
+	refreshToken, refreshTokenHash, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &domain.Session{
+		ID:               uuid.New().String(),
+		UserID:           user.ID,
+		RefreshTokenHash: refreshTokenHash,
+		Device:           meta.Device,
+		IPAddress:        meta.IPAddress,
+		ExpiresAt:        time.Now().Add(refreshTokenTTL).Format(time.RFC3339),
+	}
+	if err := uc.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return uc.issueTokens(user, session.ID, refreshToken)
+}
+
+// RefreshSession exchanges a refresh token for a new token pair, rotating the refresh token.
+// Presenting an already rotated refresh token revokes the whole session, since it means the token leaked.
+func (uc *SessionUseCase) RefreshSession(ctx context.Context, req *RefreshTokenRequest, meta *SessionMetadata) (*LoginUserResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, fmt.Errorf("refresh token is required")
+	}
+	presentedHash := hashRefreshToken(req.RefreshToken)
+
+	session, err := uc.sessionRepo.GetSessionByRefreshTokenHash(ctx, presentedHash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	if session.RefreshTokenHash != presentedHash {
+		if err := uc.sessionRepo.RevokeSession(ctx, session.ID); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		return nil, fmt.Errorf("refresh token reuse detected, session revoked")
+	}
+
+	active, err := uc.sessionRepo.IsSessionActive(ctx, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check session: %w", err)
+	}
+	if !active {
+		return nil, fmt.Errorf("session expired or revoked")
+	}
+
+	user, err := uc.userRepo.GetUserByID(ctx, session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session user: %w", err)
+	}
+
+	refreshToken, refreshTokenHash, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(refreshTokenTTL).Format(time.RFC3339)
+	if err := uc.sessionRepo.RotateRefreshToken(ctx, session.ID, presentedHash, refreshTokenHash, meta.IPAddress, expiresAt); err != nil {
+		return nil, fmt.Errorf("invalid refresh token")
+	}
+
+	return uc.issueTokens(user, session.ID, refreshToken)
+}
+
+// Authenticate verifies an access token and checks that its session is still active.
+func (uc *SessionUseCase) Authenticate(ctx context.Context, tokenString string) (*domain.Claims, error) {
+	claims, err := uc.tokenIssuer.Parse(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token")
+	}
+	// Password reset and verification tokens are signed by the same keys but never grant access
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("invalid token")
+	}
+
+	active, err := uc.IsSessionActive(ctx, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, fmt.Errorf("session expired or revoked")
+	}
+	return claims, nil
+}
+
+// IsSessionActive reports whether the session has been neither revoked nor expired.
+func (uc *SessionUseCase) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return false, nil
+	}
+	return uc.sessionRepo.IsSessionActive(ctx, sessionID)
+}
+
+// Logout revokes the session the request was authenticated with.
+func (uc *SessionUseCase) Logout(ctx context.Context, sessionID string) error {
+	if err := uc.sessionRepo.RevokeSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to log out: %w", err)
+	}
+	return nil
+}
+
+// GetSessions lists a user's active sessions, marking the one the request came from.
+func (uc *SessionUseCase) GetSessions(ctx context.Context, userID, currentSessionID string) (*GetSessionsResponse, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	sessions, err := uc.sessionRepo.GetActiveSessionsByUserID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	resp := &GetSessionsResponse{Sessions: []*SessionResponse{}}
+	for _, session := range sessions {
+		resp.Sessions = append(resp.Sessions, &SessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+	return resp, nil
+}
+
+// RevokeSession revokes one of the user's own sessions.
+func (uc *SessionUseCase) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return fmt.Errorf("session not found")
+	}
+
+	session, err := uc.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != id {
+		return fmt.Errorf("session not found")
+	}
+
+	if err := uc.sessionRepo.RevokeSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// signedInSince reports whether the user's session was opened at or after the time.
+// Refreshing tokens keeps a session's creation time, so only a new sign-in counts.
+func (uc *SessionUseCase) signedInSince(ctx context.Context, userID int, sessionID string, since time.Time) (bool, error) {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return false, nil
+	}
+	session, err := uc.sessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		if err.Error() == "session not found" {
+			return false, nil
+		}
+		return false, err
+	}
+	if session.UserID != userID || session.RevokedAt != nil {
+		return false, nil
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, session.CreatedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse session creation time: %w", err)
+	}
+	return !createdAt.Before(since), nil
+}
+
+// RevokeOtherSessions revokes every session of the user except the current one.
+func (uc *SessionUseCase) RevokeOtherSessions(ctx context.Context, userID, currentSessionID string) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+	if err := uc.sessionRepo.RevokeUserSessions(ctx, id, currentSessionID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+func (uc *SessionUseCase) issueTokens(user *domain.User, sessionID, refreshToken string) (*LoginUserResponse, error) {
+	expirationTime := time.Now().Add(accessTokenTTL)
+	claims := &domain.Claims{
+		UserID:    strconv.Itoa(user.ID),
+		Role:      user.Role,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	tokenString, err := uc.tokenIssuer.Issue(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &LoginUserResponse{
+		Token:        tokenString,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expirationTime.Format(time.RFC3339),
+	}, nil
+}
+
+// generateRefreshToken returns a random opaque refresh token and the hash stored for it.
+func generateRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
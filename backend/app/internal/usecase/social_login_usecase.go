@@ -0,0 +1,306 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+// SocialLoginUseCase handles login and account linking through external OpenID Connect providers.
+type SocialLoginUseCase struct {
+	identityRepo   domain.IdentityRepository
+	userRepo       domain.UserRepository
+	providers      map[string]domain.OIDCProvider
+	userUseCase    *UserUseCase
+	sessionUseCase *SessionUseCase
+}
+
+// NewSocialLoginUseCase creates a new SocialLoginUseCase for the given providers, keyed by name.
+func NewSocialLoginUseCase(identityRepo domain.IdentityRepository, userRepo domain.UserRepository, providers map[string]domain.OIDCProvider, userUseCase *UserUseCase, sessionUseCase *SessionUseCase) *SocialLoginUseCase {
+	return &SocialLoginUseCase{identityRepo: identityRepo, userRepo: userRepo, providers: providers, userUseCase: userUseCase, sessionUseCase: sessionUseCase}
+}
+
+type StartSocialLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type SocialLoginCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+type GetIdentitiesResponse struct {
+	Identities []*domain.UserIdentity `json:"identities"`
+}
+
+// StartLogin begins an authorization-code flow with the provider.
+// userID is set when an authenticated user links a new identity, and empty for a login.
+func (uc *SocialLoginUseCase) StartLogin(ctx context.Context, providerName, userID string) (*StartSocialLoginResponse, error) {
+	provider, ok := uc.providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %s", providerName)
+	}
+
+	state, err := randomURLSafeString(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomURLSafeString(32)
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := randomURLSafeString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	oauthState := &domain.OAuthState{
+		State:        state,
+		Provider:     providerName,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid userID format: %w", err)
+		}
+		oauthState.UserID = &id
+	}
+	if err := uc.identityRepo.SaveOAuthState(ctx, oauthState); err != nil {
+		return nil, err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	if err != nil {
+		return nil, err
+	}
+	return &StartSocialLoginResponse{AuthorizationURL: authURL}, nil
+}
+
+// CompleteLogin finishes a login flow and opens a session.
+// The provider identity is matched to an already linked user, then to a user with the same verified
+// email or phone number, and otherwise a new user is registered.
+func (uc *SocialLoginUseCase) CompleteLogin(ctx context.Context, providerName string, req *SocialLoginCallbackRequest, meta *SessionMetadata) (*LoginUserResponse, error) {
+	oauthState, external, err := uc.exchange(ctx, providerName, req)
+	if err != nil {
+		return nil, err
+	}
+	if oauthState.UserID != nil {
+		return nil, fmt.Errorf("state belongs to an account linking flow")
+	}
+
+	user, err := uc.findOrCreateUser(ctx, providerName, external)
+	if err != nil {
+		return nil, err
+	}
+	return uc.sessionUseCase.StartSession(ctx, user, meta)
+}
+
+// CompleteLink finishes a linking flow started by the authenticated user.
+func (uc *SocialLoginUseCase) CompleteLink(ctx context.Context, userID, providerName string, req *SocialLoginCallbackRequest) (*domain.UserIdentity, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	oauthState, external, err := uc.exchange(ctx, providerName, req)
+	if err != nil {
+		return nil, err
+	}
+	if oauthState.UserID == nil || *oauthState.UserID != id {
+		return nil, fmt.Errorf("invalid state")
+	}
+
+	existing, err := uc.identityRepo.GetIdentity(ctx, providerName, external.Subject)
+	if err != nil && err.Error() != "identity not found" {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.UserID == id {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("this %s account is linked to another user", providerName)
+	}
+
+	return uc.linkIdentity(ctx, id, providerName, external)
+}
+
+// GetIdentities lists the external accounts linked to a user.
+func (uc *SocialLoginUseCase) GetIdentities(ctx context.Context, userID string) (*GetIdentitiesResponse, error) {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	identities, err := uc.identityRepo.GetIdentitiesByUserID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if identities == nil {
+		identities = []*domain.UserIdentity{}
+	}
+	return &GetIdentitiesResponse{Identities: identities}, nil
+}
+
+// UnlinkIdentity removes a linked account, as long as the user keeps another way to log in.
+func (uc *SocialLoginUseCase) UnlinkIdentity(ctx context.Context, userID, providerName string) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	user, err := uc.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	identities, err := uc.identityRepo.GetIdentitiesByUserID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.PasswordHash == "" && user.PhoneNumber == "" && len(identities) <= 1 {
+		return fmt.Errorf("cannot unlink the only way to log in")
+	}
+
+	return uc.identityRepo.DeleteIdentity(ctx, id, providerName)
+}
+
+// exchange consumes the flow state and trades the authorization code for the provider's identity.
+func (uc *SocialLoginUseCase) exchange(ctx context.Context, providerName string, req *SocialLoginCallbackRequest) (*domain.OAuthState, *domain.ExternalIdentity, error) {
+	provider, ok := uc.providers[providerName]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown provider %s", providerName)
+	}
+	if req.Code == "" || req.State == "" {
+		return nil, nil, fmt.Errorf("code and state are required")
+	}
+
+	oauthState, err := uc.identityRepo.ConsumeOAuthState(ctx, req.State)
+	if err != nil {
+		if err.Error() == "OAuth state not found" {
+			return nil, nil, fmt.Errorf("invalid or expired state")
+		}
+		return nil, nil, err
+	}
+	if oauthState.Provider != providerName {
+		return nil, nil, fmt.Errorf("invalid or expired state")
+	}
+
+	external, err := provider.Exchange(ctx, req.Code, oauthState.CodeVerifier, oauthState.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oauthState, external, nil
+}
+
+func (uc *SocialLoginUseCase) findOrCreateUser(ctx context.Context, providerName string, external *domain.ExternalIdentity) (*domain.User, error) {
+	identity, err := uc.identityRepo.GetIdentity(ctx, providerName, external.Subject)
+	if err != nil && err.Error() != "identity not found" {
+		return nil, err
+	}
+	if identity != nil {
+		return uc.userRepo.GetUserByID(ctx, identity.UserID)
+	}
+
+	email := ""
+	if external.EmailVerified {
+		email = external.Email
+	}
+	phoneNumber := ""
+	if external.PhoneNumberVerified {
+		if normalized, err := normalizePhoneNumber(external.PhoneNumber); err == nil {
+			phoneNumber = normalized
+		}
+	}
+
+	var user *domain.User
+	if email != "" {
+		user, err = uc.userRepo.GetUserByEmail(ctx, email)
+		if err != nil && err.Error() != "user not found" {
+			return nil, err
+		}
+		// Anyone can register with someone else's address, so only a confirmed one proves it is the same person.
+		// The new account is then created without the address, which stays with its current owner.
+		if user != nil && user.EmailVerifiedAt == nil {
+			user = nil
+			email = ""
+		}
+	}
+	if user == nil && phoneNumber != "" {
+		user, err = uc.userRepo.GetUserByPhoneNumber(ctx, phoneNumber)
+		if err != nil && err.Error() != "user not found" {
+			return nil, err
+		}
+		// The same goes for a phone number the account was registered with but never entered a login code for
+		if user != nil && user.PhoneVerifiedAt == nil {
+			user = nil
+			phoneNumber = ""
+		}
+	}
+
+	if user == nil {
+		username := external.Name
+		if username == "" {
+			username = email
+		}
+		if username == "" {
+			username = providerName + " user"
+		}
+		qrCodeString := uuid.New().String()
+		user = &domain.User{
+			Username:      username,
+			PhoneNumber:   phoneNumber,
+			Email:         email,
+			QRCode:        &qrCodeString,
+			LoyaltyStatus: "Bronze",
+			Role:          domain.RoleCustomer,
+		}
+		verifiedAt := time.Now().Format(time.RFC3339)
+		if email != "" {
+			user.EmailVerifiedAt = &verifiedAt
+		}
+		if phoneNumber != "" {
+			user.PhoneVerifiedAt = &verifiedAt
+		}
+		if err := uc.userUseCase.createUser(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := uc.linkIdentity(ctx, user.ID, providerName, external); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (uc *SocialLoginUseCase) linkIdentity(ctx context.Context, userID int, providerName string, external *domain.ExternalIdentity) (*domain.UserIdentity, error) {
+	identity := &domain.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  external.Subject,
+		Email:    external.Email,
+	}
+	if err := uc.identityRepo.CreateIdentity(ctx, identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func randomURLSafeString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
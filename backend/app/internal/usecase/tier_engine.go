@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// TierPolicy sets how members qualify for tiers and how they lose them.
+// A member qualifies for the highest tier whose min_points they earned on purchases within the qualification window;
+// birthday, referral, challenge and other bonuses do not count.
+// One who no longer qualifies keeps their tier for the grace period, then drops a single tier;
+// if they still fall short, the next drop follows after another grace period.
+type TierPolicy struct {
+	QualificationMonths int // Length of the rolling window points are counted over
+	GracePeriodDays     int
+}
+
+// ReevaluateTiers re-checks every member's tier against their rolling points,
+// starting grace periods and downgrading members whose grace has run out.
+func (uc *LoyaltyUseCase) ReevaluateTiers(ctx context.Context) error {
+	loyalties, err := uc.userRepo.GetUserLoyalties(ctx)
+	if err != nil {
+		return err
+	}
+	for _, userLoyalty := range loyalties {
+		if err := uc.evaluateTier(ctx, userLoyalty.UserID, true); err != nil {
+			log.Printf("Failed to evaluate tier of user %d: %v", userLoyalty.UserID, err)
+		}
+	}
+	return nil
+}
+
+// evaluateTier upgrades the member to the tier they qualify for. With allowDowngrade set,
+// it also starts, ends or acts on the grace period of a member who no longer qualifies.
+func (uc *LoyaltyUseCase) evaluateTier(ctx context.Context, userID int, allowDowngrade bool) error {
+	userLoyalty, err := uc.userRepo.GetUserLoyalty(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user loyalty: %w", err)
+	}
+	tiers, err := uc.userRepo.GetAllLoyaltyTiers(ctx) // Sorted by min_points
+	if err != nil {
+		return fmt.Errorf("failed to get all loyalty tiers: %w", err)
+	}
+	if len(tiers) == 0 {
+		return nil
+	}
+
+	since := time.Now().AddDate(0, -uc.tierPolicy.QualificationMonths, 0)
+	qualifyingPoints, err := uc.ledgerRepo.GetQualifyingPoints(ctx, userID, since)
+	if err != nil {
+		return err
+	}
+
+	qualified, current := 0, -1
+	for i, tier := range tiers {
+		if qualifyingPoints >= tier.MinPoints {
+			qualified = i
+		}
+		if tier.ID == userLoyalty.CurrentTierID {
+			current = i
+		}
+	}
+
+	switch {
+	case current < 0 || qualified > current:
+		return uc.changeTier(ctx, userID, tiers[qualified], "tier_upgrade", fmt.Sprintf("Upgraded to %s tier", tiers[qualified].Name))
+	case qualified == current:
+		if userLoyalty.TierGraceUntil != nil {
+			return uc.userRepo.SetTierGraceUntil(ctx, userID, nil)
+		}
+		return nil
+	case !allowDowngrade:
+		return nil
+	case userLoyalty.TierGraceUntil == nil && uc.tierPolicy.GracePeriodDays > 0:
+		graceUntil := time.Now().AddDate(0, 0, uc.tierPolicy.GracePeriodDays)
+		return uc.userRepo.SetTierGraceUntil(ctx, userID, &graceUntil)
+	case userLoyalty.TierGraceUntil != nil && !graceEnded(*userLoyalty.TierGraceUntil):
+		return nil
+	}
+
+	// Soft landing: one tier down, whatever the member qualifies for
+	lower := tiers[current-1]
+	if err := uc.changeTier(ctx, userID, lower, "tier_downgrade", fmt.Sprintf("Moved down to %s tier", lower.Name)); err != nil {
+		return err
+	}
+	notificationReq := &SendNotificationRequest{
+		UserID:  strconv.Itoa(userID),
+		Type:    "tier_downgrade",
+		Title:   "Уровень программы лояльности изменился",
+		Message: fmt.Sprintf("Ваш уровень изменился на %s. Совершайте покупки, чтобы вернуть прежний уровень.", lower.Name),
+	}
+	if err := uc.notificationUseCase.SendNotification(ctx, notificationReq); err != nil {
+		log.Printf("Failed to notify user %d about tier downgrade: %v", userID, err)
+	}
+	return nil
+}
+
+// changeTier moves the member to the tier and records the change in their activity.
+func (uc *LoyaltyUseCase) changeTier(ctx context.Context, userID int, tier *domain.LoyaltyTier, activityType, description string) error {
+	// Update user's loyalty status in the main user table as well
+	if err := uc.userRepo.UpdateUserTier(ctx, userID, tier); err != nil {
+		return fmt.Errorf("failed to update user loyalty status: %w", err)
+	}
+	activity := &domain.LoyaltyActivity{
+		UserID:      userID,
+		Type:        activityType,
+		Description: description,
+	}
+	if err := uc.userRepo.CreateLoyaltyActivity(ctx, activity); err != nil {
+		return fmt.Errorf("failed to create %s activity: %w", activityType, err)
+	}
+	return nil
+}
+
+func graceEnded(graceUntil string) bool {
+	t, err := time.Parse(time.RFC3339Nano, graceUntil)
+	return err != nil || !time.Now().Before(t)
+}
@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// fakeTierUserRepo moves members between the tiers of the user repository it wraps and records their activity.
+type fakeTierUserRepo struct {
+	*fakeUserRepo
+	activities []*domain.LoyaltyActivity
+}
+
+func (r *fakeTierUserRepo) UpdateUserTier(ctx context.Context, userID int, tier *domain.LoyaltyTier) error {
+	r.loyalties[userID].CurrentTierID = tier.ID
+	r.loyalties[userID].TierGraceUntil = nil
+	return nil
+}
+
+func (r *fakeTierUserRepo) SetTierGraceUntil(ctx context.Context, userID int, graceUntil *time.Time) error {
+	r.loyalties[userID].TierGraceUntil = nil
+	if graceUntil != nil {
+		until := graceUntil.Format(time.RFC3339Nano)
+		r.loyalties[userID].TierGraceUntil = &until
+	}
+	return nil
+}
+
+func (r *fakeTierUserRepo) CreateLoyaltyActivity(ctx context.Context, activity *domain.LoyaltyActivity) error {
+	r.activities = append(r.activities, activity)
+	return nil
+}
+
+type fakeQualifyingLedgerRepo struct {
+	domain.LoyaltyLedgerRepository
+	points int
+	since  time.Time
+}
+
+func (r *fakeQualifyingLedgerRepo) GetQualifyingPoints(ctx context.Context, userID int, since time.Time) (int, error) {
+	r.since = since
+	return r.points, nil
+}
+
+func TestEvaluateTier(t *testing.T) {
+	const (
+		userID = 1
+		bronze = 1
+		silver = 2
+		gold   = 3
+	)
+	at := func(days int) *string {
+		v := time.Now().AddDate(0, 0, days).Format(time.RFC3339Nano)
+		return &v
+	}
+	tests := []struct {
+		name           string
+		tierID         int
+		graceUntil     *string
+		points         int
+		graceDays      int
+		allowDowngrade bool
+		wantTier       int
+		wantGrace      string // "none", "kept" or "started"
+		wantActivity   string
+		wantNotified   bool
+	}{
+		{name: "new member gets the entry tier", points: 0, graceDays: 30, allowDowngrade: true, wantTier: bronze, wantGrace: "none", wantActivity: "tier_upgrade"},
+		{name: "upgrade", tierID: bronze, points: 1500, graceDays: 30, wantTier: silver, wantGrace: "none", wantActivity: "tier_upgrade"},
+		{name: "upgrade skips tiers", tierID: bronze, points: 6000, graceDays: 30, wantTier: gold, wantGrace: "none", wantActivity: "tier_upgrade"},
+		{name: "upgrade ends the grace period", tierID: silver, graceUntil: at(10), points: 6000, graceDays: 30, allowDowngrade: true, wantTier: gold, wantGrace: "none", wantActivity: "tier_upgrade"},
+		{name: "qualifying again ends the grace period", tierID: gold, graceUntil: at(10), points: 5000, graceDays: 30, allowDowngrade: true, wantTier: gold, wantGrace: "none"},
+		{name: "falling short starts the grace period", tierID: gold, points: 100, graceDays: 30, allowDowngrade: true, wantTier: gold, wantGrace: "started"},
+		{name: "tier kept within the grace period", tierID: gold, graceUntil: at(10), points: 100, graceDays: 30, allowDowngrade: true, wantTier: gold, wantGrace: "kept"},
+		{name: "one tier down when the grace period ends", tierID: gold, graceUntil: at(-1), points: 100, graceDays: 30, allowDowngrade: true, wantTier: silver, wantGrace: "none", wantActivity: "tier_downgrade", wantNotified: true},
+		{name: "one tier down at once without a grace period", tierID: gold, points: 100, graceDays: 0, allowDowngrade: true, wantTier: silver, wantGrace: "none", wantActivity: "tier_downgrade", wantNotified: true},
+		{name: "no downgrade on a purchase", tierID: gold, graceUntil: at(-1), points: 100, graceDays: 30, wantTier: gold, wantGrace: "kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeTierUserRepo{fakeUserRepo: &fakeUserRepo{
+				loyalties: map[int]*domain.UserLoyalty{userID: {UserID: userID, CurrentTierID: tt.tierID, TierGraceUntil: tt.graceUntil}},
+				tiers: map[int]*domain.LoyaltyTier{
+					bronze: {ID: bronze, Name: "Bronze", MinPoints: 0},
+					silver: {ID: silver, Name: "Silver", MinPoints: 1000},
+					gold:   {ID: gold, Name: "Gold", MinPoints: 5000},
+				},
+			}}
+			ledger := &fakeQualifyingLedgerRepo{points: tt.points}
+			notifications := &fakeNotificationRepo{}
+			notificationUseCase := NewNotificationUseCase(notifications, nil, nil, nil, nil)
+			policy := TierPolicy{QualificationMonths: 12, GracePeriodDays: tt.graceDays}
+			uc := NewLoyaltyUseCase(users, ledger, nil, nil, notificationUseCase, RedemptionPolicy{}, ExpiryPolicy{}, policy)
+
+			if err := uc.evaluateTier(context.Background(), userID, tt.allowDowngrade); err != nil {
+				t.Fatalf("evaluateTier() error = %v", err)
+			}
+			if window := time.Now().AddDate(-1, 0, 0); ledger.since.Sub(window).Abs() > time.Minute {
+				t.Errorf("points counted since %v, want %v", ledger.since, window)
+			}
+			loyalty := users.loyalties[userID]
+			if loyalty.CurrentTierID != tt.wantTier {
+				t.Errorf("tier = %d, want %d", loyalty.CurrentTierID, tt.wantTier)
+			}
+
+			switch tt.wantGrace {
+			case "none":
+				if loyalty.TierGraceUntil != nil {
+					t.Errorf("grace until %s, want no grace period", *loyalty.TierGraceUntil)
+				}
+			case "kept":
+				if loyalty.TierGraceUntil != tt.graceUntil {
+					t.Errorf("grace until %v, want it kept at %s", loyalty.TierGraceUntil, *tt.graceUntil)
+				}
+			case "started":
+				want := time.Now().AddDate(0, 0, tt.graceDays)
+				if loyalty.TierGraceUntil == nil {
+					t.Fatalf("grace period not started, want until %v", want)
+				}
+				if got, err := time.Parse(time.RFC3339Nano, *loyalty.TierGraceUntil); err != nil || got.Sub(want).Abs() > time.Minute {
+					t.Errorf("grace until %s, want %v", *loyalty.TierGraceUntil, want)
+				}
+			}
+
+			var activity string
+			if len(users.activities) > 1 {
+				t.Errorf("recorded %d activities, want at most one", len(users.activities))
+			} else if len(users.activities) == 1 {
+				activity = users.activities[0].Type
+			}
+			if activity != tt.wantActivity {
+				t.Errorf("activity = %q, want %q", activity, tt.wantActivity)
+			}
+			if notified := len(notifications.notifications) > 0; notified != tt.wantNotified {
+				t.Errorf("notified = %v, want %v", notified, tt.wantNotified)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	passwordResetTokenTTL     = time.Hour
+	emailVerificationTokenTTL = 48 * time.Hour
+	minPasswordLength         = 8
+)
+
+// VerificationUseCase handles the emailed links for password reset and email verification.
+// The links carry signed tokens that expire and can be used only once.
+type VerificationUseCase struct {
+	userRepo    domain.UserRepository
+	tokenRepo   domain.OneTimeTokenRepository
+	sessionRepo domain.SessionRepository
+	tokenIssuer domain.TokenIssuer
+	mailSender  domain.MailSender
+	appBaseURL  string
+}
+
+// NewVerificationUseCase creates a new VerificationUseCase.
+// appBaseURL is the address of the web app that opens the emailed links.
+func NewVerificationUseCase(userRepo domain.UserRepository, tokenRepo domain.OneTimeTokenRepository, sessionRepo domain.SessionRepository, tokenIssuer domain.TokenIssuer, mailSender domain.MailSender, appBaseURL string) *VerificationUseCase {
+	return &VerificationUseCase{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		sessionRepo: sessionRepo,
+		tokenIssuer: tokenIssuer,
+		mailSender:  mailSender,
+		appBaseURL:  strings.TrimSuffix(appBaseURL, "/"),
+	}
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// RequestPasswordReset emails a reset link if an account uses the address.
+// It reports success either way so the endpoint cannot be used to find out who is registered.
+func (uc *VerificationUseCase) RequestPasswordReset(ctx context.Context, req *ForgotPasswordRequest) error {
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return fmt.Errorf("email is required")
+	}
+
+	user, err := uc.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+
+	token, err := uc.issueToken(ctx, user, domain.TokenPurposePasswordReset, passwordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Здравствуйте, %s!\n\nЧтобы задать новый пароль, перейдите по ссылке:\n%s/reset-password?token=%s\n\nСсылка действует %d минут. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n",
+		user.Username, uc.appBaseURL, token, int(passwordResetTokenTTL.Minutes()))
+	if err := uc.mailSender.Send(ctx, user.Email, "Восстановление пароля MR.KINGSMAN", body); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a reset link and logs the user out everywhere.
+func (uc *VerificationUseCase) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+	if err := validatePassword(req.Password); err != nil {
+		return err
+	}
+
+	claims, err := uc.useToken(ctx, req.Token, domain.TokenPurposePasswordReset)
+	if err != nil {
+		return err
+	}
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	if err := uc.userRepo.UpdateUserPassword(ctx, userID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	// Other reset links sent earlier must not work after the password has changed
+	if err := uc.tokenRepo.InvalidateOneTimeTokens(ctx, userID, domain.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+	if err := uc.sessionRepo.RevokeUserSessions(ctx, userID, ""); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	// Following the link proves the user receives mail at the address
+	if claims.Email != "" {
+		if err := uc.userRepo.MarkEmailVerified(ctx, userID, claims.Email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendEmailVerification emails a verification link to the user's current address.
+func (uc *VerificationUseCase) SendEmailVerification(ctx context.Context, userID string) error {
+	id, err := strconv.Atoi(userID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	user, err := uc.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return fmt.Errorf("no email address to verify")
+	}
+	if user.EmailVerifiedAt != nil {
+		return fmt.Errorf("email is already verified")
+	}
+	return uc.sendEmailVerification(ctx, user)
+}
+
+// VerifyEmail confirms the address a verification link was sent to,
+// as long as it is still the user's address.
+func (uc *VerificationUseCase) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) error {
+	claims, err := uc.useToken(ctx, req.Token, domain.TokenPurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+	userID, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid userID format: %w", err)
+	}
+
+	user, err := uc.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(user.Email, claims.Email) {
+		return fmt.Errorf("the email address has changed since the link was sent")
+	}
+	return uc.userRepo.MarkEmailVerified(ctx, userID, user.Email)
+}
+
+func (uc *VerificationUseCase) sendEmailVerification(ctx context.Context, user *domain.User) error {
+	token, err := uc.issueToken(ctx, user, domain.TokenPurposeEmailVerification, emailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Здравствуйте, %s!\n\nПодтвердите адрес электронной почты, перейдя по ссылке:\n%s/verify-email?token=%s\n\nСсылка действует %d часов.\n",
+		user.Username, uc.appBaseURL, token, int(emailVerificationTokenTTL.Hours()))
+	if err := uc.mailSender.Send(ctx, user.Email, "Подтверждение email MR.KINGSMAN", body); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// emailChanged invalidates reset links sent to the user's previous address and asks to confirm the new one.
+func (uc *VerificationUseCase) emailChanged(ctx context.Context, user *domain.User) error {
+	if err := uc.tokenRepo.InvalidateOneTimeTokens(ctx, user.ID, domain.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+	uc.trySendEmailVerification(ctx, user)
+	return nil
+}
+
+// trySendEmailVerification is used right after registration and profile changes,
+// where a mail delivery failure should not fail the request; the user can ask for the link again.
+func (uc *VerificationUseCase) trySendEmailVerification(ctx context.Context, user *domain.User) {
+	if err := uc.sendEmailVerification(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+}
+
+// issueToken signs a purpose token for the user and records its ID so it can be used once.
+func (uc *VerificationUseCase) issueToken(ctx context.Context, user *domain.User, purpose domain.TokenPurpose, ttl time.Duration) (string, error) {
+	now := time.Now()
+	oneTimeToken := &domain.OneTimeToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := uc.tokenRepo.CreateOneTimeToken(ctx, oneTimeToken); err != nil {
+		return "", err
+	}
+
+	claims := &domain.Claims{
+		UserID:  strconv.Itoa(user.ID),
+		Purpose: purpose,
+		Email:   user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        oneTimeToken.ID,
+			ExpiresAt: jwt.NewNumericDate(oneTimeToken.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+	token, err := uc.tokenIssuer.Issue(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return token, nil
+}
+
+// useToken verifies a purpose token's signature and marks it used.
+func (uc *VerificationUseCase) useToken(ctx context.Context, tokenString string, purpose domain.TokenPurpose) (*domain.Claims, error) {
+	claims, err := uc.tokenIssuer.Parse(tokenString)
+	if err != nil || claims.Purpose != purpose || claims.ID == "" {
+		return nil, fmt.Errorf("invalid or expired link")
+	}
+
+	oneTimeToken, err := uc.tokenRepo.UseOneTimeToken(ctx, claims.ID, purpose)
+	if err != nil {
+		if err.Error() == "one-time token not found" {
+			return nil, fmt.Errorf("invalid or expired link")
+		}
+		return nil, err
+	}
+	if strconv.Itoa(oneTimeToken.UserID) != claims.UserID {
+		return nil, fmt.Errorf("invalid or expired link")
+	}
+	return claims, nil
+}
+
+func validatePassword(password string) error {
+	if len([]rune(password)) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", minPasswordLength)
+	}
+	return nil
+}
@@ -0,0 +1,456 @@
+package usecase
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mkbagandov/kingsman/backend/app/internal/domain"
+)
+
+// WalletPolicy sets what the wallet passes say about the shop and where devices fetch updates from.
+type WalletPolicy struct {
+	OrganizationName string
+	WebServiceURL    string // Public base URL of the pass web service, without the /v1 suffix Apple appends
+	AssetsDir        string // Optional directory with icon.png, logo.png and their @2x versions
+}
+
+// walletPassImages are the pass images taken from the assets directory when present.
+var walletPassImages = []string{"icon.png", "icon@2x.png", "icon@3x.png", "logo.png", "logo@2x.png", "logo@3x.png", "strip.png", "strip@2x.png"}
+
+// WalletUseCase issues discount cards for Apple Wallet and Google Wallet and keeps them up to date.
+// Either wallet is optional: it is nil when the server has no credentials for it.
+type WalletUseCase struct {
+	walletRepo     domain.WalletPassRepository
+	userRepo       domain.UserRepository
+	loyaltyUseCase *LoyaltyUseCase
+	cardCodeSigner *CardCodeSigner
+	appleWallet    domain.AppleWallet
+	googleWallet   domain.GoogleWallet
+	policy         WalletPolicy
+}
+
+func NewWalletUseCase(walletRepo domain.WalletPassRepository, userRepo domain.UserRepository, loyaltyUseCase *LoyaltyUseCase, cardCodeSigner *CardCodeSigner, appleWallet domain.AppleWallet, googleWallet domain.GoogleWallet, policy WalletPolicy) *WalletUseCase {
+	return &WalletUseCase{
+		walletRepo:     walletRepo,
+		userRepo:       userRepo,
+		loyaltyUseCase: loyaltyUseCase,
+		cardCodeSigner: cardCodeSigner,
+		appleWallet:    appleWallet,
+		googleWallet:   googleWallet,
+		policy:         policy,
+	}
+}
+
+// GooglePassResponse is the "Save to Google Wallet" link of the card and the loyalty object it saves.
+type GooglePassResponse struct {
+	SaveURL string                      `json:"save_url"`
+	Object  *domain.GoogleLoyaltyObject `json:"object"`
+}
+
+// UpdatedSerialsResponse lists the passes on a device that changed since the tag it sent last time.
+type UpdatedSerialsResponse struct {
+	SerialNumbers []string `json:"serialNumbers"`
+	LastUpdated   string   `json:"lastUpdated"`
+}
+
+// walletCard is what a pass shows, read fresh for every pass built.
+type walletCard struct {
+	pass     *domain.WalletPass
+	user     *domain.User
+	tier     *domain.LoyaltyTier
+	discount float64 // Percent taken off orders, including an override set by staff
+	code     string
+}
+
+// GetApplePass returns the member's discount card as a signed .pkpass bundle.
+func (uc *WalletUseCase) GetApplePass(ctx context.Context, userID int) ([]byte, error) {
+	if uc.appleWallet == nil {
+		return nil, fmt.Errorf("apple wallet is not configured")
+	}
+	pass, err := uc.getOrCreatePass(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	card, err := uc.loadCard(ctx, pass)
+	if err != nil {
+		return nil, err
+	}
+	return uc.buildApplePass(card)
+}
+
+// GetGooglePass returns the link that saves the member's discount card to Google Wallet.
+func (uc *WalletUseCase) GetGooglePass(ctx context.Context, userID int) (*GooglePassResponse, error) {
+	if uc.googleWallet == nil {
+		return nil, fmt.Errorf("google wallet is not configured")
+	}
+	pass, err := uc.getOrCreatePass(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	card, err := uc.loadCard(ctx, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	object := uc.googleObject(card)
+	saveURL, err := uc.googleWallet.SaveURL(object)
+	if err != nil {
+		return nil, err
+	}
+	return &GooglePassResponse{SaveURL: saveURL, Object: object}, nil
+}
+
+// RegisterDevice subscribes a device to push updates of a pass. It returns false if the device was already subscribed.
+func (uc *WalletUseCase) RegisterDevice(ctx context.Context, deviceLibraryID, passTypeID, serialNumber, authToken, pushToken string) (bool, error) {
+	if _, err := uc.authorizePass(ctx, passTypeID, serialNumber, authToken); err != nil {
+		return false, err
+	}
+	if pushToken == "" {
+		return false, fmt.Errorf("pushToken is required")
+	}
+	return uc.walletRepo.RegisterWalletDevice(ctx, deviceLibraryID, serialNumber, pushToken)
+}
+
+// UnregisterDevice stops push updates of a pass to a device, as when the pass is removed from it.
+func (uc *WalletUseCase) UnregisterDevice(ctx context.Context, deviceLibraryID, passTypeID, serialNumber, authToken string) error {
+	if _, err := uc.authorizePass(ctx, passTypeID, serialNumber, authToken); err != nil {
+		return err
+	}
+	return uc.walletRepo.UnregisterWalletDevice(ctx, deviceLibraryID, serialNumber)
+}
+
+// GetUpdatedSerials returns the passes on the device updated since the given tag, or nil if there are none.
+// The tag is the update time of the newest pass in microseconds, opaque to the device.
+func (uc *WalletUseCase) GetUpdatedSerials(ctx context.Context, deviceLibraryID, passTypeID, passesUpdatedSince string) (*UpdatedSerialsResponse, error) {
+	if uc.appleWallet == nil {
+		return nil, fmt.Errorf("apple wallet is not configured")
+	}
+	if passTypeID != uc.appleWallet.PassTypeID() {
+		return nil, nil
+	}
+
+	var updatedSince *time.Time
+	if passesUpdatedSince != "" {
+		micros, err := strconv.ParseInt(passesUpdatedSince, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid passesUpdatedSince")
+		}
+		since := time.UnixMicro(micros)
+		updatedSince = &since
+	}
+
+	passes, err := uc.walletRepo.GetWalletPassesByDevice(ctx, deviceLibraryID, updatedSince)
+	if err != nil {
+		return nil, err
+	}
+	if len(passes) == 0 {
+		return nil, nil
+	}
+
+	resp := &UpdatedSerialsResponse{SerialNumbers: make([]string, 0, len(passes))}
+	var lastUpdated time.Time
+	for _, pass := range passes {
+		resp.SerialNumbers = append(resp.SerialNumbers, pass.SerialNumber)
+		if pass.UpdatedAt.After(lastUpdated) {
+			lastUpdated = pass.UpdatedAt
+		}
+	}
+	resp.LastUpdated = strconv.FormatInt(lastUpdated.UnixMicro(), 10)
+	return resp, nil
+}
+
+// GetLatestPass returns the current version of a pass for a device, with the time it last changed.
+func (uc *WalletUseCase) GetLatestPass(ctx context.Context, passTypeID, serialNumber, authToken string) ([]byte, time.Time, error) {
+	pass, err := uc.authorizePass(ctx, passTypeID, serialNumber, authToken)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	card, err := uc.loadCard(ctx, pass)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	bundle, err := uc.buildApplePass(card)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return bundle, pass.UpdatedAt, nil
+}
+
+// UpdatePasses brings every pass whose points, tier or card code changed up to date: Apple devices
+// holding it are asked to fetch it again and the saved Google Wallet card is rewritten.
+func (uc *WalletUseCase) UpdatePasses(ctx context.Context) error {
+	if uc.appleWallet == nil && uc.googleWallet == nil {
+		return nil
+	}
+
+	now := time.Now()
+	passes, err := uc.walletRepo.GetStaleWalletPasses(ctx, uc.cardCodeSigner.WalletCodeWindow(now))
+	if err != nil {
+		return err
+	}
+	for _, pass := range passes {
+		if err := uc.walletRepo.MarkWalletPassUpdated(ctx, pass); err != nil {
+			log.Printf("Failed to update wallet pass of user %d: %v", pass.UserID, err)
+			continue
+		}
+		if uc.appleWallet != nil {
+			uc.pushPass(ctx, pass)
+		}
+		if uc.googleWallet != nil {
+			card, err := uc.loadCard(ctx, pass)
+			if err == nil {
+				err = uc.googleWallet.UpdateLoyaltyObject(ctx, uc.googleObject(card))
+			}
+			if err != nil {
+				log.Printf("Failed to update Google Wallet card of user %d: %v", pass.UserID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (uc *WalletUseCase) pushPass(ctx context.Context, pass *domain.WalletPass) {
+	pushTokens, err := uc.walletRepo.GetWalletPushTokens(ctx, pass.SerialNumber)
+	if err != nil {
+		log.Printf("Failed to get wallet devices of user %d: %v", pass.UserID, err)
+		return
+	}
+	for _, pushToken := range pushTokens {
+		err := uc.appleWallet.Push(ctx, pushToken)
+		if err == nil {
+			continue
+		}
+		// The device dropped the pass or was reset; it registers again if the pass comes back
+		if err.Error() == "push token is no longer valid" {
+			if err := uc.walletRepo.DeleteWalletPushToken(ctx, pushToken); err != nil {
+				log.Printf("Failed to delete wallet push token: %v", err)
+			}
+			continue
+		}
+		log.Printf("Failed to push wallet pass of user %d: %v", pass.UserID, err)
+	}
+}
+
+// getOrCreatePass returns the member's pass, creating it the first time the member asks for one.
+func (uc *WalletUseCase) getOrCreatePass(ctx context.Context, userID int) (*domain.WalletPass, error) {
+	pass, err := uc.walletRepo.GetWalletPassByUserID(ctx, userID)
+	if err == nil {
+		return pass, nil
+	}
+	if err.Error() != "wallet pass not found" {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	tier, err := uc.loyaltyUseCase.memberTier(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	authToken, err := randomURLSafeString(24)
+	if err != nil {
+		return nil, err
+	}
+
+	pass = &domain.WalletPass{
+		UserID:       userID,
+		SerialNumber: uuid.NewString(),
+		AuthToken:    authToken,
+		Points:       user.CurrentPoints,
+		CodeWindow:   uc.cardCodeSigner.WalletCodeWindow(time.Now()),
+	}
+	if tier != nil {
+		pass.TierID = &tier.ID
+	}
+	// Two requests racing here both insert; only the first row is kept, so read back whichever won
+	if err := uc.walletRepo.CreateWalletPass(ctx, pass); err != nil {
+		return nil, err
+	}
+	return uc.walletRepo.GetWalletPassByUserID(ctx, userID)
+}
+
+// authorizePass checks the token an Apple device presents for a pass.
+func (uc *WalletUseCase) authorizePass(ctx context.Context, passTypeID, serialNumber, authToken string) (*domain.WalletPass, error) {
+	if uc.appleWallet == nil {
+		return nil, fmt.Errorf("apple wallet is not configured")
+	}
+	if passTypeID != uc.appleWallet.PassTypeID() {
+		return nil, fmt.Errorf("wallet pass not found")
+	}
+	pass, err := uc.walletRepo.GetWalletPassBySerialNumber(ctx, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(pass.AuthToken), []byte(authToken)) != 1 {
+		return nil, fmt.Errorf("invalid wallet pass token")
+	}
+	return pass, nil
+}
+
+func (uc *WalletUseCase) loadCard(ctx context.Context, pass *domain.WalletPass) (*walletCard, error) {
+	user, err := uc.userRepo.GetUserByID(ctx, pass.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// Deleted accounts have no card seed; their passes are removed with the account
+	if user.QRCode == nil {
+		return nil, fmt.Errorf("wallet pass not found")
+	}
+	tier, err := uc.loyaltyUseCase.memberTier(ctx, pass.UserID)
+	if err != nil {
+		return nil, err
+	}
+	benefits, err := uc.loyaltyUseCase.GetMemberBenefits(ctx, pass.UserID)
+	if err != nil {
+		return nil, err
+	}
+	code, _ := uc.cardCodeSigner.SignWallet(user.ID, *user.QRCode, time.Now())
+	return &walletCard{pass: pass, user: user, tier: tier, discount: benefits.DiscountPercent, code: code}, nil
+}
+
+func (uc *WalletUseCase) googleObject(card *walletCard) *domain.GoogleLoyaltyObject {
+	points := card.user.CurrentPoints
+	object := &domain.GoogleLoyaltyObject{
+		ID:            uc.googleWallet.IssuerID() + "." + card.pass.SerialNumber,
+		ClassID:       uc.googleWallet.ClassID(),
+		State:         "ACTIVE",
+		AccountID:     strconv.Itoa(card.user.ID),
+		AccountName:   card.user.Username,
+		LoyaltyPoints: &domain.GoogleLoyaltyPoints{Label: "Баллы", Balance: domain.GoogleLoyaltyBalance{Int: &points}},
+		Barcode:       &domain.GoogleBarcode{Type: "QR_CODE", Value: card.code},
+	}
+	if card.tier != nil {
+		object.SecondaryLoyaltyPoints = &domain.GoogleLoyaltyPoints{Label: "Уровень", Balance: domain.GoogleLoyaltyBalance{String: card.tier.Name}}
+	}
+	return object
+}
+
+type applePassField struct {
+	Key           string      `json:"key"`
+	Label         string      `json:"label,omitempty"`
+	Value         interface{} `json:"value"`
+	ChangeMessage string      `json:"changeMessage,omitempty"` // Shown on the lock screen when the value changes
+}
+
+type applePassBarcode struct {
+	Format          string `json:"format"`
+	Message         string `json:"message"`
+	MessageEncoding string `json:"messageEncoding"`
+}
+
+// buildApplePass assembles the .pkpass bundle: pass.json, the images, manifest.json with the SHA-1 of
+// every file, and the signature of the manifest.
+func (uc *WalletUseCase) buildApplePass(card *walletCard) ([]byte, error) {
+	tierName := "—"
+	if card.tier != nil {
+		tierName = card.tier.Name
+	}
+	discount := strconv.FormatFloat(card.discount, 'f', -1, 64) + "%"
+	barcode := applePassBarcode{Format: "PKBarcodeFormatQR", Message: card.code, MessageEncoding: "iso-8859-1"}
+
+	passJSON := map[string]interface{}{
+		"formatVersion":       1,
+		"passTypeIdentifier":  uc.appleWallet.PassTypeID(),
+		"teamIdentifier":      uc.appleWallet.TeamID(),
+		"serialNumber":        card.pass.SerialNumber,
+		"organizationName":    uc.policy.OrganizationName,
+		"description":         "Дисконтная карта " + uc.policy.OrganizationName,
+		"logoText":            uc.policy.OrganizationName,
+		"foregroundColor":     "rgb(255, 255, 255)",
+		"backgroundColor":     "rgb(20, 20, 20)",
+		"labelColor":          "rgb(200, 170, 110)",
+		"authenticationToken": card.pass.AuthToken,
+		"barcodes":            []applePassBarcode{barcode},
+		"barcode":             barcode, // For iOS versions before 9
+		"storeCard": map[string][]applePassField{
+			"headerFields":    {{Key: "points", Label: "Баллы", Value: card.user.CurrentPoints, ChangeMessage: "Ваш баланс: %@ баллов"}},
+			"primaryFields":   {{Key: "tier", Label: "Уровень", Value: tierName, ChangeMessage: "Ваш уровень: %@"}},
+			"secondaryFields": {{Key: "member", Label: "Участник", Value: card.user.Username}},
+			"auxiliaryFields": {{Key: "discount", Label: "Скидка", Value: discount}},
+		},
+	}
+	if uc.policy.WebServiceURL != "" {
+		passJSON["webServiceURL"] = uc.policy.WebServiceURL
+	}
+
+	files := map[string][]byte{}
+	var err error
+	if files["pass.json"], err = json.Marshal(passJSON); err != nil {
+		return nil, fmt.Errorf("failed to encode pass.json: %w", err)
+	}
+	if uc.policy.AssetsDir != "" {
+		for _, name := range walletPassImages {
+			data, err := os.ReadFile(filepath.Join(uc.policy.AssetsDir, name))
+			if err != nil {
+				continue
+			}
+			files[name] = data
+		}
+	}
+	// Wallet refuses passes without an icon
+	if files["icon.png"] == nil {
+		if files["icon.png"], err = placeholderIcon(); err != nil {
+			return nil, err
+		}
+	}
+
+	manifest := map[string]string{}
+	for name, data := range files {
+		sum := sha1.Sum(data)
+		manifest[name] = hex.EncodeToString(sum[:])
+	}
+	if files["manifest.json"], err = json.Marshal(manifest); err != nil {
+		return nil, fmt.Errorf("failed to encode manifest.json: %w", err)
+	}
+	if files["signature"], err = uc.appleWallet.SignManifest(files["manifest.json"]); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write pass bundle: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write pass bundle: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write pass bundle: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// placeholderIcon draws a plain square icon for servers that have no pass images configured.
+func placeholderIcon() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 58, 58))
+	for x := 0; x < 58; x++ {
+		for y := 0; y < 58; y++ {
+			img.Set(x, y, color.RGBA{R: 20, G: 20, B: 20, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to draw pass icon: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -16,29 +16,40 @@ function UserProfile() {
   const [error, setError] = useState(null);
   const [isModalOpen, setIsModalOpen] = useState(false); // State for modal visibility
 
+  // The card code rotates every 30 seconds, so the image is fetched again before the shown one expires
+  const fetchQRCode = async () => {
+    const qrCodeRes = await getUserQRCode();
+    // Convert arraybuffer to base64 for image display
+    const base64Image = btoa(
+      new Uint8Array(qrCodeRes.data).reduce(
+        (data, byte) => data + String.fromCharCode(byte),
+        ''
+      )
+    );
+    setQrCode(`data:image/png;base64,${base64Image}`);
+  };
+
+  useEffect(() => {
+    const timer = setInterval(() => {
+      fetchQRCode().catch(() => {}); // Keep showing the last code until the next attempt
+    }, 15000);
+    return () => clearInterval(timer);
+  }, []);
+
   useEffect(() => {
     const fetchData = async () => {
       try {
         // Calls to API functions no longer need userID parameter
-        const [userRes, loyaltyRes, discountRes, qrCodeRes] = await Promise.all([
+        const [userRes, loyaltyRes, discountRes] = await Promise.all([
           getUserProfile(),
           getUserLoyaltyProfile(),
           getUserDiscountCard(),
-          getUserQRCode()
+          fetchQRCode()
         ]);
         setUserProfile(userRes.data);
         setLoyaltyProfile(loyaltyRes.data);
         setDiscountCard(discountRes.data);
 
-        // Convert arraybuffer to base64 for image display
-        const base64Image = btoa(
-          new Uint8Array(qrCodeRes.data).reduce(
-            (data, byte) => data + String.fromCharCode(byte),
-            ''
-          )
-        );
-        setQrCode(`data:image/png;base64,${base64Image}`);
-
       } catch (err) {
         setError(err.response?.data?.error || err.message);
       } finally {